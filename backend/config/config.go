@@ -1,30 +1,438 @@
 package config
 
 import (
-	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
+// DefaultWatchlistRoles are high-risk roles watched when WATCHLIST_ROLES is unset
+var DefaultWatchlistRoles = []string{
+	"roles/owner",
+	"roles/iam.securityAdmin",
+	"roles/iam.serviceAccountAdmin",
+	"roles/iam.serviceAccountKeyAdmin",
+	"roles/iam.serviceAccountTokenCreator",
+	"roles/resourcemanager.projectIamAdmin",
+	"roles/resourcemanager.organizationAdmin",
+}
+
 // Config holds the application configuration
 type Config struct {
 	ProjectID string
 	Port      string
+	CacheTTL  time.Duration
+	DataDir   string
+
+	// Runtime holds the settings adjustable via /api/admin/config: scan interval,
+	// enabled collectors, trusted domains, watchlist roles, and redaction
+	Runtime *Runtime
+	// Flags gate experimental subsystems; FEATURE_FLAGS sets them per
+	// deployment and /api/admin/flags overrides them at runtime
+	Flags *Flags
+
+	// RunMode is RunModeServer, RunModeCloudRun, which schedules nothing
+	// in-process and scans only when POST /api/scans is called, or
+	// RunModeWorker, which only works on queued scan tasks
+	RunMode string
+	// ScanDispatch is DispatchLocal or DispatchQueue, which hands the projects
+	// of discovery scans to worker processes; ProjectScanWorkers is how many
+	// projects this process scans at once, and ScanTaskLease how long a worker
+	// may hold a queued project before another one takes over
+	ScanDispatch       string
+	ProjectScanWorkers int
+	ScanTaskLease      time.Duration
+
+	// Maximum GCP API calls per scan; zero means unlimited
+	ScanCallBudget int
+	// How long a scan may call GCP; zero means unlimited
+	ScanTimeout time.Duration
+	// POST /api/scans and POST /api/scans/trigger accept Google OIDC tokens
+	// for this audience from these service accounts; an empty audience
+	// disables them
+	ScanTriggerAudience   string
+	ScanTriggerPrincipals []string
+
+	// Concurrent per-resource GetIamPolicy calls per collector
+	PolicyWorkers int
+
+	// Cache TTL of direct group memberships and nesting limit of group expansion
+	GroupCacheTTL time.Duration
+	GroupMaxDepth int
+
+	// Incremental scans rerun only collectors whose assets changed, with a full
+	// scan at least every FullScanInterval
+	IncrementalScans bool
+	FullScanInterval time.Duration
+
+	// Expensive endpoints (access matrix, direct GCP reads): requests per minute
+	// and burst per client IP, and requests in flight overall; zero disables a limit
+	RateLimitPerMinute int
+	RateLimitBurst     int
+	HeavyConcurrency   int
+
+	// Audit log of API calls, queryable at /api/admin/audit, and how long its
+	// events are kept (zero keeps them forever)
+	AuditLog       bool
+	AuditRetention time.Duration
+
+	// Key for hashed email redaction; random per process when empty
+	RedactionKey string
+
+	// Bearer token required by the /api/admin endpoints; empty disables them
+	AdminToken string
+	// Audience of the signed assertion Identity-Aware Proxy adds to requests;
+	// callers are identified as IAP users only when it is set and verifies
+	IAPAudience string
+
+	// Serve the frontend embedded in the binary (builds with the embedfrontend
+	// tag only) on every path outside /api
+	ServeFrontend bool
+
+	// Persistence: "sqlite" (default, state.db in DataDir), "postgres" (DatabaseURL),
+	// "file" (the JSON state file in DataDir), or any other registered driver
+	StoreDriver string
+	DatabaseURL string
+	// Driver-specific settings from STORE_OPTIONS ("key=value,key=value")
+	StoreOptions map[string]string
+
+	// Optional Redis shared cache for role definitions and scanned snapshots
+	RedisURL string
+
+	// SCIM directory connector for principal enrichment (optional)
+	SCIMURL   string
+	SCIMToken string
+
+	// Collector scope: which collectors run and where (empty means all).
+	// The enabled collectors live in Runtime.
+	DisabledCollectors []string
+	ExtraCollectors    []string // opt-in collectors such as "billing"
+	ScanRegions        []string
+	ScanZones          []string
+
+	// Project discovery: scan every active project under an organization or
+	// folder ("organizations/N" or "folders/N") except the excluded ones
+	ScanParent      string
+	ExcludeProjects []string
+
+	// Optional YAML/JSON file with policy rules (SoD, ...); built-in defaults otherwise
+	RulesFile string
+
+	// Optional Git repository rules, the baseline, and exceptions are synced
+	// from, read-only, every GitOpsInterval
+	GitOpsRepo     string
+	GitOpsRef      string
+	GitOpsPath     string // directory within the repository
+	GitOpsInterval time.Duration
+
+	// Service account key findings: maximum key age and active keys per account
+	SAKeyMaxAge    time.Duration
+	SAKeyMaxActive int
+
+	// Service accounts without authentication for this long are reported as dormant
+	DormantSAAfter time.Duration
+
+	// Finding exceptions may not run longer than this
+	ExceptionMaxDuration time.Duration
+
+	// Retention of per-snapshot history (scores, posture metrics): the newest record
+	// of each of the last N days, weeks, and months is kept
+	RetentionDaily     int
+	RetentionWeekly    int
+	RetentionMonthly   int
+	CompactionInterval time.Duration // zero disables background compaction
+
+	// Alert destinations (optional)
+	AlertWebhookURL string
+	SlackWebhookURL string
+
+	// Email digest: sent every DigestInterval to DigestRecipients through SMTP
+	// or SendGrid when recipients are set
+	DigestRecipients  []string
+	DigestFrom        string
+	DigestInterval    time.Duration
+	DigestTemplateDir string
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	SendGridAPIKey    string
+	// Paging: alerts at or above PageMinSeverity also trigger PagerDuty
+	// incidents and Opsgenie alerts when a key is set
+	PagerDutyRoutingKey string
+	OpsgenieAPIKey      string
+	OpsgenieAPIURL      string
+	PageMinSeverity     string
+	// Jira: findings at or above JiraMinSeverity get an issue in JiraProject,
+	// closed when the finding resolves, when JiraURL is set
+	JiraURL             string
+	JiraEmail           string
+	JiraAPIToken        string
+	JiraProject         string
+	JiraIssueType       string
+	JiraLabel           string
+	JiraCloseTransition string
+	JiraMinSeverity     string
+
+	// Origins allowed to call the API from a browser
+	CORSAllowedOrigins []string
 }
 
-// Load loads the configuration from environment variables
+// Load loads the configuration from environment variables. Every variable
+// may also be given as NAME_FILE, the path of a file holding the value (such
+// as a mounted Kubernetes secret), or in structured form with a GAV_ prefix
+// and "__" between segments (GAV_SCAN__INTERVAL for SCAN_INTERVAL). All
+// problems are reported together.
 func Load() (*Config, error) {
-	projectID := os.Getenv("GCP_PROJECT_ID")
+	l := newLoader()
+
+	projectID := l.lookup("GCP_PROJECT_ID")
 	if projectID == "" {
-		return nil, fmt.Errorf("GCP_PROJECT_ID environment variable is required")
+		l.errorf("GCP_PROJECT_ID is required")
+	}
+
+	// Google client libraries read the credentials path themselves; export it
+	// when it came from a structured or _FILE variable, and check it early
+	if credentials := l.lookup("GOOGLE_APPLICATION_CREDENTIALS"); credentials != "" {
+		if _, err := os.Stat(credentials); err != nil {
+			l.errorf("GOOGLE_APPLICATION_CREDENTIALS: %v", err)
+		}
+		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
 	}
 
-	port := os.Getenv("PORT")
+	port := l.lookup("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	return &Config{
+	cacheTTL := l.getDuration("CACHE_TTL", 5*time.Minute)
+	scanInterval := l.getDuration("SCAN_INTERVAL", 0)
+	scanCallBudget := l.getInt("SCAN_CALL_BUDGET", 0)
+	runMode := l.getString("RUN_MODE", RunModeServer)
+	var defaultScanTimeout time.Duration
+	if runMode == RunModeCloudRun {
+		defaultScanTimeout = cloudRunScanTimeout
+	}
+	scanTimeout := l.getDuration("SCAN_TIMEOUT", defaultScanTimeout)
+	storeDriver := l.getString("STORE_DRIVER", "sqlite")
+	scanDispatch := l.getString("SCAN_DISPATCH", DispatchLocal)
+	l.checkRunMode(runMode, scanInterval, storeDriver, scanDispatch)
+	projectScanWorkers := l.getInt("PROJECT_SCAN_WORKERS", 1)
+	if projectScanWorkers < 0 {
+		l.errorf("invalid PROJECT_SCAN_WORKERS %d (must not be negative)", projectScanWorkers)
+	}
+	scanTaskLease := l.getDuration("SCAN_TASK_LEASE", 10*time.Minute)
+	if scanTaskLease <= 0 {
+		l.errorf("invalid SCAN_TASK_LEASE %s (must be positive)", scanTaskLease)
+	}
+	scanTriggerAudience := l.lookup("SCAN_TRIGGER_AUDIENCE")
+	scanTriggerPrincipals := l.getList("SCAN_TRIGGER_SERVICE_ACCOUNTS", nil)
+	if scanTriggerAudience != "" && len(scanTriggerPrincipals) == 0 {
+		l.errorf("SCAN_TRIGGER_SERVICE_ACCOUNTS is required with SCAN_TRIGGER_AUDIENCE; any Google account can mint a token for the audience")
+	}
+	if runMode == RunModeCloudRun && scanTriggerAudience == "" {
+		l.errorf("SCAN_TRIGGER_AUDIENCE is required with RUN_MODE=cloudrun, which scans only when POST /api/scans is called with an OIDC token")
+	}
+	policyWorkers := l.getInt("IAM_POLICY_WORKERS", 16)
+	groupCacheTTL := l.getDuration("GROUP_CACHE_TTL", 15*time.Minute)
+	groupMaxDepth := l.getInt("GROUP_MAX_DEPTH", 10)
+	incrementalScans := l.getBool("INCREMENTAL_SCANS", false)
+	fullScanInterval := l.getDuration("FULL_SCAN_INTERVAL", 24*time.Hour)
+	rateLimitPerMinute := l.getInt("RATE_LIMIT_PER_MINUTE", 60)
+	rateLimitBurst := l.getInt("RATE_LIMIT_BURST", 10)
+	heavyConcurrency := l.getInt("HEAVY_REQUEST_CONCURRENCY", 4)
+	auditLog := l.getBool("AUDIT_LOG", true)
+	serveFrontend := l.getBool("SERVE_FRONTEND", true)
+	auditRetention := l.getDuration("AUDIT_RETENTION", 90*24*time.Hour)
+	saKeyMaxAge := l.getDuration("SA_KEY_MAX_AGE", 90*24*time.Hour)
+	saKeyMaxActive := l.getInt("SA_KEY_MAX_ACTIVE", 2)
+	dormantSAAfter := l.getDuration("DORMANT_SA_AFTER", 90*24*time.Hour)
+	exceptionMaxDuration := l.getDuration("EXCEPTION_MAX_DURATION", 365*24*time.Hour)
+	if exceptionMaxDuration <= 0 {
+		l.errorf("invalid EXCEPTION_MAX_DURATION %s (must be positive)", exceptionMaxDuration)
+	}
+
+	gitOpsInterval := l.getDuration("GITOPS_INTERVAL", 5*time.Minute)
+	if gitOpsInterval <= 0 {
+		l.errorf("invalid GITOPS_INTERVAL %s (must be positive)", gitOpsInterval)
+	}
+
+	retentionDaily := l.getInt("RETENTION_DAILY", 30)
+	retentionWeekly := l.getInt("RETENTION_WEEKLY", 12)
+	retentionMonthly := l.getInt("RETENTION_MONTHLY", 12)
+	compactionInterval := l.getDuration("COMPACTION_INTERVAL", 24*time.Hour)
+	redaction := l.getString("REDACTION_MODE", "off")
+	if redaction != "off" && redaction != "partial" && redaction != "hash" {
+		l.errorf("invalid REDACTION_MODE %q (want off, partial, or hash)", redaction)
+	}
+
+	scanParent := l.lookup("SCAN_PARENT")
+	if scanParent != "" && !strings.HasPrefix(scanParent, "organizations/") && !strings.HasPrefix(scanParent, "folders/") {
+		l.errorf("invalid SCAN_PARENT %q (want organizations/N or folders/N)", scanParent)
+	}
+
+	featureFlags, err := parseFlags(l.getList("FEATURE_FLAGS", nil))
+	l.add(err)
+
+	storeOptions := make(map[string]string)
+	for _, option := range l.getList("STORE_OPTIONS", nil) {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			l.errorf("invalid STORE_OPTIONS entry %q (want key=value)", option)
+			continue
+		}
+		storeOptions[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	digestInterval := l.getDuration("DIGEST_INTERVAL", 7*24*time.Hour)
+	digestRecipients := l.getList("DIGEST_RECIPIENTS", nil)
+	if len(digestRecipients) > 0 {
+		if l.lookup("DIGEST_FROM") == "" {
+			l.errorf("DIGEST_FROM is required when DIGEST_RECIPIENTS is set")
+		}
+		if l.lookup("SMTP_HOST") == "" && l.lookup("SENDGRID_API_KEY") == "" {
+			l.errorf("SMTP_HOST or SENDGRID_API_KEY is required when DIGEST_RECIPIENTS is set")
+		}
+		if digestInterval <= 0 {
+			l.errorf("invalid DIGEST_INTERVAL %s (must be positive)", digestInterval)
+		}
+	}
+
+	pageMinSeverity := l.getString("PAGE_MIN_SEVERITY", "high")
+	switch pageMinSeverity {
+	case "info", "warning", "high", "critical":
+	default:
+		l.errorf("invalid PAGE_MIN_SEVERITY %q (want info, warning, high, or critical)", pageMinSeverity)
+	}
+
+	jiraMinSeverity := l.getString("JIRA_MIN_SEVERITY", "high")
+	if l.lookup("JIRA_URL") != "" {
+		if l.lookup("JIRA_PROJECT") == "" || l.lookup("JIRA_API_TOKEN") == "" {
+			l.errorf("JIRA_PROJECT and JIRA_API_TOKEN are required when JIRA_URL is set")
+		}
+		switch jiraMinSeverity {
+		case "low", "medium", "high", "critical":
+		default:
+			l.errorf("invalid JIRA_MIN_SEVERITY %q (want low, medium, high, or critical)", jiraMinSeverity)
+		}
+	}
+
+	dataDir := l.lookup("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+
+	cfg := &Config{
 		ProjectID: projectID,
 		Port:      port,
-	}, nil
+		CacheTTL:  cacheTTL,
+		Flags:     NewFlags(featureFlags),
+		Runtime: NewRuntime(RuntimeSettings{
+			ScanInterval:      scanInterval,
+			EnabledCollectors: l.getList("COLLECTORS", nil),
+			TrustedDomains:    l.getList("TRUSTED_DOMAINS", nil),
+			WatchlistRoles:    l.getList("WATCHLIST_ROLES", DefaultWatchlistRoles),
+			Redaction:         redaction,
+		}),
+		RunMode:               runMode,
+		ScanDispatch:          scanDispatch,
+		ProjectScanWorkers:    projectScanWorkers,
+		ScanTaskLease:         scanTaskLease,
+		ScanCallBudget:        scanCallBudget,
+		ScanTimeout:           scanTimeout,
+		ScanTriggerAudience:   scanTriggerAudience,
+		ScanTriggerPrincipals: scanTriggerPrincipals,
+		PolicyWorkers:         policyWorkers,
+		GroupCacheTTL:         groupCacheTTL,
+		GroupMaxDepth:         groupMaxDepth,
+		IncrementalScans:      incrementalScans,
+		FullScanInterval:      fullScanInterval,
+		RateLimitPerMinute:    rateLimitPerMinute,
+		RateLimitBurst:        rateLimitBurst,
+		HeavyConcurrency:      heavyConcurrency,
+		AuditLog:              auditLog,
+		AuditRetention:        auditRetention,
+		RedactionKey:          l.lookup("REDACTION_KEY"),
+		AdminToken:            l.lookup("ADMIN_TOKEN"),
+		IAPAudience:           l.lookup("IAP_AUDIENCE"),
+		ServeFrontend:         serveFrontend,
+		DataDir:               dataDir,
+		StoreDriver:           storeDriver,
+		DatabaseURL:           l.lookup("DATABASE_URL"),
+		StoreOptions:          storeOptions,
+		RedisURL:              l.lookup("REDIS_URL"),
+		SCIMURL:               l.lookup("SCIM_URL"),
+		SCIMToken:             l.lookup("SCIM_TOKEN"),
+		DisabledCollectors:    l.getList("DISABLED_COLLECTORS", nil),
+		ExtraCollectors:       l.getList("EXTRA_COLLECTORS", nil),
+		ScanRegions:           l.getList("SCAN_REGIONS", nil),
+		ScanZones:             l.getList("SCAN_ZONES", nil),
+		ScanParent:            scanParent,
+		ExcludeProjects:       l.getList("EXCLUDE_PROJECTS", nil),
+		RulesFile:             l.lookup("RULES_FILE"),
+		GitOpsRepo:            l.lookup("GITOPS_REPO"),
+		GitOpsRef:             l.getString("GITOPS_REF", "main"),
+		GitOpsPath:            l.lookup("GITOPS_PATH"),
+		GitOpsInterval:        gitOpsInterval,
+		SAKeyMaxAge:           saKeyMaxAge,
+		SAKeyMaxActive:        saKeyMaxActive,
+		DormantSAAfter:        dormantSAAfter,
+		ExceptionMaxDuration:  exceptionMaxDuration,
+		RetentionDaily:        retentionDaily,
+		RetentionWeekly:       retentionWeekly,
+		RetentionMonthly:      retentionMonthly,
+		CompactionInterval:    compactionInterval,
+		AlertWebhookURL:       l.lookup("ALERT_WEBHOOK_URL"),
+		SlackWebhookURL:       l.lookup("SLACK_WEBHOOK_URL"),
+		DigestRecipients:      digestRecipients,
+		DigestFrom:            l.lookup("DIGEST_FROM"),
+		DigestInterval:        digestInterval,
+		DigestTemplateDir:     l.lookup("DIGEST_TEMPLATE_DIR"),
+		SMTPHost:              l.lookup("SMTP_HOST"),
+		SMTPPort:              l.getString("SMTP_PORT", "587"),
+		SMTPUsername:          l.lookup("SMTP_USERNAME"),
+		SMTPPassword:          l.lookup("SMTP_PASSWORD"),
+		SendGridAPIKey:        l.lookup("SENDGRID_API_KEY"),
+		PagerDutyRoutingKey:   l.lookup("PAGERDUTY_ROUTING_KEY"),
+		OpsgenieAPIKey:        l.lookup("OPSGENIE_API_KEY"),
+		OpsgenieAPIURL:        l.lookup("OPSGENIE_API_URL"),
+		PageMinSeverity:       pageMinSeverity,
+		JiraURL:               l.lookup("JIRA_URL"),
+		JiraEmail:             l.lookup("JIRA_EMAIL"),
+		JiraAPIToken:          l.lookup("JIRA_API_TOKEN"),
+		JiraProject:           l.lookup("JIRA_PROJECT"),
+		JiraIssueType:         l.getString("JIRA_ISSUE_TYPE", "Task"),
+		JiraLabel:             l.getString("JIRA_LABEL", "gcp-access-visualizer"),
+		JiraCloseTransition:   l.lookup("JIRA_CLOSE_TRANSITION"),
+		JiraMinSeverity:       jiraMinSeverity,
+		CORSAllowedOrigins:    l.getList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "http://localhost:3000"}),
+	}
+
+	l.checkStructured()
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Features reports which optional integrations and behaviors are enabled
+func (c *Config) Features() map[string]bool {
+	return map[string]bool{
+		"adminApi":         c.AdminToken != "",
+		"auditLog":         c.AuditLog,
+		"incrementalScans": c.IncrementalScans,
+		"projectDiscovery": c.ScanParent != "",
+		"scanWorkers":      c.ScanDispatch == DispatchQueue,
+		"redisCache":       c.RedisURL != "",
+		"scimEnrichment":   c.SCIMURL != "",
+		"customRules":      c.RulesFile != "",
+		"gitOps":           c.GitOpsRepo != "",
+		"compaction":       c.CompactionInterval > 0,
+		"alertWebhook":     c.AlertWebhookURL != "",
+		"slack":            c.SlackWebhookURL != "",
+		"emailDigest":      len(c.DigestRecipients) > 0,
+		"pagerDuty":        c.PagerDutyRoutingKey != "",
+		"opsgenie":         c.OpsgenieAPIKey != "",
+		"jira":             c.JiraURL != "",
+	}
 }
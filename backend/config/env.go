@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structuredPrefix starts the structured form of a variable name, in which
+// "__" separates segments: GAV_SCAN__INTERVAL sets SCAN_INTERVAL
+const structuredPrefix = "GAV_"
+
+// loader reads variables for Load and collects every problem instead of
+// stopping at the first
+type loader struct {
+	// structured maps the plain names of GAV_ variables to their values, and
+	// origin back to the GAV_ names, for error messages
+	structured map[string]string
+	origin     map[string]string
+	seen       map[string]bool
+	problems   []string
+}
+
+// newLoader indexes the structured variables of the environment
+func newLoader() *loader {
+	l := &loader{
+		structured: make(map[string]string),
+		origin:     make(map[string]string),
+		seen:       make(map[string]bool),
+	}
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, structuredPrefix) {
+			continue
+		}
+		name := strings.ReplaceAll(strings.TrimPrefix(key, structuredPrefix), "__", "_")
+		l.structured[name] = value
+		l.origin[name] = key
+	}
+	return l
+}
+
+// lookup returns a variable's value: the variable itself, else the contents
+// of the file named by NAME_FILE (trailing newlines trimmed), else its
+// structured form. Empty when unset.
+func (l *loader) lookup(name string) string {
+	l.seen[name] = true
+	if value := l.raw(name); value != "" {
+		return value
+	}
+	path := l.raw(name + "_FILE")
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.errorf("%s_FILE: %v", name, err)
+		return ""
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+// raw reads a variable or, when it is unset, its structured form
+func (l *loader) raw(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return l.structured[name]
+}
+
+// describe names where a variable was read from, for error messages
+func (l *loader) describe(name string) string {
+	if os.Getenv(name) == "" {
+		if key, ok := l.origin[name]; ok {
+			return key
+		}
+		if os.Getenv(name+"_FILE") != "" || l.structured[name+"_FILE"] != "" {
+			return name + "_FILE"
+		}
+	}
+	return name
+}
+
+// errorf records a problem
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
+}
+
+// add records err, if any
+func (l *loader) add(err error) {
+	if err != nil {
+		l.problems = append(l.problems, err.Error())
+	}
+}
+
+// checkStructured reports GAV_ variables that set nothing, such as typos
+func (l *loader) checkStructured() {
+	var unknown []string
+	for name, key := range l.origin {
+		if !l.seen[name] && !l.seen[strings.TrimSuffix(name, "_FILE")] {
+			unknown = append(unknown, fmt.Sprintf("%s (read as %s)", key, name))
+		}
+	}
+	sort.Strings(unknown)
+	for _, variable := range unknown {
+		l.errorf("unknown setting %s", variable)
+	}
+}
+
+// err returns every recorded problem as one error
+func (l *loader) err() error {
+	switch len(l.problems) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("invalid configuration: %s", l.problems[0])
+	}
+	return fmt.Errorf("invalid configuration (%d problems):\n  - %s", len(l.problems), strings.Join(l.problems, "\n  - "))
+}
+
+// getString reads a string from the environment
+func (l *loader) getString(name, fallback string) string {
+	if value := l.lookup(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getDuration reads a duration such as "5m" from the environment
+func (l *loader) getDuration(name string, fallback time.Duration) time.Duration {
+	value := l.lookup(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		l.errorf("invalid %s %q: want a duration such as 90s, 15m, or 24h", l.describe(name), value)
+		return fallback
+	}
+	return parsed
+}
+
+// getInt reads an integer from the environment
+func (l *loader) getInt(name string, fallback int) int {
+	value := l.lookup(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.errorf("invalid %s %q: want an integer", l.describe(name), value)
+		return fallback
+	}
+	return parsed
+}
+
+// getBool reads a boolean such as "true" or "1" from the environment
+func (l *loader) getBool(name string, fallback bool) bool {
+	value := l.lookup(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errorf("invalid %s %q: want true or false", l.describe(name), value)
+		return fallback
+	}
+	return parsed
+}
+
+// getList reads a comma-separated list from the environment
+func (l *loader) getList(name string, fallback []string) []string {
+	value := l.lookup(name)
+	if value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FlagsSettingKey is the store setting under which flag overrides made through
+// the admin API are persisted
+const FlagsSettingKey = "flags"
+
+// Feature flags
+const (
+	// FlagGroupExpansion resolves Google Group memberships (nested groups
+	// included) through Cloud Identity for effective access
+	FlagGroupExpansion = "groupExpansion"
+	// FlagAuditLogOverlay reads Cloud Audit Logs for grant events in evidence
+	// bundles and Privileged Access Manager elevations
+	FlagAuditLogOverlay = "auditLogOverlay"
+	// FlagWriteRemediation is reserved for remediation that changes IAM
+	// policies; nothing writes to GCP yet, and it stays off until something does
+	FlagWriteRemediation = "writeRemediation"
+)
+
+// Flag is a feature flag and its built-in default
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// KnownFlags lists every feature flag
+var KnownFlags = []Flag{
+	{FlagGroupExpansion, "Resolve Google Group memberships through Cloud Identity for effective access", true},
+	{FlagAuditLogOverlay, "Read Cloud Audit Logs for grant events and PAM elevations", true},
+	{FlagWriteRemediation, "Allow remediation that changes IAM policies (reserved; no subsystem uses it yet)", false},
+}
+
+// FlagState is a flag's current value and where it comes from
+type FlagState struct {
+	Flag
+	Enabled bool `json:"enabled"`
+	// Source is "default", "env" (FEATURE_FLAGS), or "admin" (an override
+	// set through the admin API)
+	Source string `json:"source"`
+}
+
+// Flags holds the feature flag values. It is safe for concurrent use.
+type Flags struct {
+	mu        sync.RWMutex
+	env       map[string]bool
+	overrides map[string]bool
+}
+
+// NewFlags creates the flags with values set by the environment
+func NewFlags(env map[string]bool) *Flags {
+	return &Flags{env: env, overrides: map[string]bool{}}
+}
+
+// Enabled reports whether a flag is on: an admin override wins over the
+// environment, which wins over the default. Unknown flags are off.
+func (f *Flags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if value, ok := f.overrides[name]; ok {
+		return value
+	}
+	if value, ok := f.env[name]; ok {
+		return value
+	}
+	for _, flag := range KnownFlags {
+		if flag.Name == name {
+			return flag.Default
+		}
+	}
+	return false
+}
+
+// List returns the state of every known flag
+func (f *Flags) List() []FlagState {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	states := make([]FlagState, 0, len(KnownFlags))
+	for _, flag := range KnownFlags {
+		state := FlagState{Flag: flag, Enabled: flag.Default, Source: "default"}
+		if value, ok := f.env[flag.Name]; ok {
+			state.Enabled, state.Source = value, "env"
+		}
+		if value, ok := f.overrides[flag.Name]; ok {
+			state.Enabled, state.Source = value, "admin"
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// Values returns whether each known flag is on
+func (f *Flags) Values() map[string]bool {
+	values := make(map[string]bool, len(KnownFlags))
+	for _, flag := range KnownFlags {
+		values[flag.Name] = f.Enabled(flag.Name)
+	}
+	return values
+}
+
+// Overrides returns a copy of the admin overrides
+func (f *Flags) Overrides() map[string]bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	overrides := make(map[string]bool, len(f.overrides))
+	for name, value := range f.overrides {
+		overrides[name] = value
+	}
+	return overrides
+}
+
+// SetOverrides replaces the admin overrides; every name must be a known flag
+func (f *Flags) SetOverrides(overrides map[string]bool) error {
+	for name := range overrides {
+		if !KnownFlag(name) {
+			return fmt.Errorf("unknown feature flag %q (want %s)", name, strings.Join(flagNames(), ", "))
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.overrides = make(map[string]bool, len(overrides))
+	for name, value := range overrides {
+		f.overrides[name] = value
+	}
+	return nil
+}
+
+// KnownFlag reports whether name is a feature flag
+func KnownFlag(name string) bool {
+	for _, flag := range KnownFlags {
+		if flag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// flagNames returns the names of the known flags, sorted
+func flagNames() []string {
+	names := make([]string, 0, len(KnownFlags))
+	for _, flag := range KnownFlags {
+		names = append(names, flag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFlags reads FEATURE_FLAGS entries such as "groupExpansion=off" or
+// "writeRemediation=true"
+func parseFlags(entries []string) (map[string]bool, error) {
+	flags := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name, raw, ok := strings.Cut(entry, "=")
+		name, raw = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(raw))
+		if !ok || !KnownFlag(name) {
+			return nil, fmt.Errorf("invalid FEATURE_FLAGS entry %q (want name=on|off with name one of %s)", entry, strings.Join(flagNames(), ", "))
+		}
+		switch raw {
+		case "on":
+			flags[name] = true
+		case "off":
+			flags[name] = false
+		default:
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid FEATURE_FLAGS value %q for %s (want on, off, true, or false)", raw, name)
+			}
+			flags[name] = value
+		}
+	}
+	return flags, nil
+}
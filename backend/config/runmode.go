@@ -0,0 +1,81 @@
+package config
+
+import "time"
+
+// Run modes selectable with RUN_MODE
+const (
+	// RunModeServer is a long-running process, as on Kubernetes or Compute
+	// Engine: scans (SCAN_INTERVAL), digests, compaction, and GitOps syncs run
+	// on in-process schedules.
+	RunModeServer = "server"
+	// RunModeCloudRun is tuned for running on Cloud Run, which throttles CPU
+	// between requests and replaces instances at will:
+	//   - nothing is scheduled in-process; Cloud Scheduler calls POST /api/scans
+	//     with an OIDC token (and POST /api/admin/gitops/sync, if GitOps is
+	//     used), and the digest and compaction run after each scan
+	//   - scans run within the request that triggers it and stop calling GCP at
+	//     SCAN_TIMEOUT (default 4m, inside Cloud Run's default 5m request timeout)
+	//   - the store must outlive instances: postgres on Cloud SQL; sqlite and
+	//     file are rejected
+	RunModeCloudRun = "cloudrun"
+	// RunModeWorker runs no API: the process works on the per-project tasks of
+	// scans dispatched with SCAN_DISPATCH=queue, claiming them from the store it
+	// shares with the replicas that scan
+	RunModeWorker = "worker"
+)
+
+// Scan dispatch modes selectable with SCAN_DISPATCH
+const (
+	// DispatchLocal scans the discovered projects of a scan in-process, with
+	// PROJECT_SCAN_WORKERS at a time
+	DispatchLocal = "local"
+	// DispatchQueue queues each discovered project as a task in the store, for
+	// worker processes (RUN_MODE=worker) and PROJECT_SCAN_WORKERS goroutines of
+	// the scanning replica to claim
+	DispatchQueue = "queue"
+)
+
+// cloudRunScanTimeout is the default SCAN_TIMEOUT in Cloud Run mode, leaving
+// a minute of the default request timeout for hooks and listeners
+const cloudRunScanTimeout = 4 * time.Minute
+
+// CloudRun reports whether the backend runs in Cloud Run mode
+func (c *Config) CloudRun() bool {
+	return c.RunMode == RunModeCloudRun
+}
+
+// Worker reports whether the process is a scan worker
+func (c *Config) Worker() bool {
+	return c.RunMode == RunModeWorker
+}
+
+// checkRunMode records settings that do not work in the run mode or with the
+// scan dispatch mode
+func (l *loader) checkRunMode(mode string, scanInterval time.Duration, storeDriver, dispatch string) {
+	switch dispatch {
+	case DispatchLocal:
+	case DispatchQueue:
+		if storeDriver == "file" {
+			l.errorf("SCAN_DISPATCH=queue needs a store with a task queue; use sqlite or postgres instead of STORE_DRIVER=file")
+		}
+	default:
+		l.errorf("invalid SCAN_DISPATCH %q (want %s or %s)", dispatch, DispatchLocal, DispatchQueue)
+	}
+
+	switch mode {
+	case RunModeServer:
+	case RunModeWorker:
+		if storeDriver == "file" {
+			l.errorf("RUN_MODE=worker needs a store with a task queue; use sqlite or postgres instead of STORE_DRIVER=file")
+		}
+	case RunModeCloudRun:
+		if scanInterval > 0 {
+			l.errorf("SCAN_INTERVAL is not supported with RUN_MODE=cloudrun; have Cloud Scheduler call POST /api/scans instead")
+		}
+		if storeDriver == "sqlite" || storeDriver == "file" {
+			l.errorf("STORE_DRIVER=%s keeps state on the instance disk, which Cloud Run discards; use postgres (Cloud SQL) with RUN_MODE=cloudrun", storeDriver)
+		}
+	default:
+		l.errorf("invalid RUN_MODE %q (want %s, %s, or %s)", mode, RunModeServer, RunModeCloudRun, RunModeWorker)
+	}
+}
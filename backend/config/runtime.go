@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RuntimeSettingsKey is the store setting under which runtime settings are persisted
+const RuntimeSettingsKey = "runtime"
+
+// RuntimeSettings are the settings operators can change without a redeploy
+type RuntimeSettings struct {
+	ScanInterval      time.Duration // zero disables background scans
+	EnabledCollectors []string      // empty enables all collectors
+	TrustedDomains    []string
+	WatchlistRoles    []string
+	Redaction         string // email masking in responses: "off", "partial", or "hash"
+}
+
+// runtimeSettingsJSON is the API and persisted form of RuntimeSettings
+type runtimeSettingsJSON struct {
+	ScanInterval      string   `json:"scanInterval"`
+	EnabledCollectors []string `json:"enabledCollectors"`
+	TrustedDomains    []string `json:"trustedDomains"`
+	WatchlistRoles    []string `json:"watchlistRoles"`
+	Redaction         string   `json:"redaction"`
+}
+
+// MarshalJSON encodes the scan interval as a duration string such as "15m"
+func (s RuntimeSettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(runtimeSettingsJSON{
+		ScanInterval:      s.ScanInterval.String(),
+		EnabledCollectors: orEmpty(s.EnabledCollectors),
+		TrustedDomains:    orEmpty(s.TrustedDomains),
+		WatchlistRoles:    orEmpty(s.WatchlistRoles),
+		Redaction:         orOff(s.Redaction),
+	})
+}
+
+// UnmarshalJSON decodes settings written by MarshalJSON
+func (s *RuntimeSettings) UnmarshalJSON(data []byte) error {
+	var raw runtimeSettingsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	interval := time.Duration(0)
+	if raw.ScanInterval != "" {
+		parsed, err := time.ParseDuration(raw.ScanInterval)
+		if err != nil {
+			return fmt.Errorf("invalid scanInterval %q: %w", raw.ScanInterval, err)
+		}
+		interval = parsed
+	}
+
+	*s = RuntimeSettings{
+		ScanInterval:      interval,
+		EnabledCollectors: raw.EnabledCollectors,
+		TrustedDomains:    raw.TrustedDomains,
+		WatchlistRoles:    raw.WatchlistRoles,
+		Redaction:         raw.Redaction,
+	}
+	return nil
+}
+
+// orEmpty returns an empty list instead of nil so JSON shows []
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// orOff returns "off" for an unset redaction mode
+func orOff(mode string) string {
+	if mode == "" {
+		return "off"
+	}
+	return mode
+}
+
+// Runtime holds the live RuntimeSettings. It is safe for concurrent use.
+type Runtime struct {
+	mu        sync.RWMutex
+	settings  RuntimeSettings
+	listeners []func(RuntimeSettings)
+}
+
+// NewRuntime creates a runtime holder with initial settings
+func NewRuntime(settings RuntimeSettings) *Runtime {
+	return &Runtime{settings: settings}
+}
+
+// Get returns the current settings
+func (r *Runtime) Get() RuntimeSettings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.settings
+}
+
+// Set replaces the settings and notifies the change listeners
+func (r *Runtime) Set(settings RuntimeSettings) {
+	r.mu.Lock()
+	r.settings = settings
+	listeners := append([]func(RuntimeSettings){}, r.listeners...)
+	r.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(settings)
+	}
+}
+
+// OnChange registers a function called with the new settings after every Set
+func (r *Runtime) OnChange(listener func(RuntimeSettings)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// ScanInterval returns the current background scan interval
+func (r *Runtime) ScanInterval() time.Duration {
+	return r.Get().ScanInterval
+}
+
+// TrustedDomains returns the current trusted domains
+func (r *Runtime) TrustedDomains() []string {
+	return r.Get().TrustedDomains
+}
+
+// WatchlistRoles returns the current watchlist roles
+func (r *Runtime) WatchlistRoles() []string {
+	return r.Get().WatchlistRoles
+}
+
+// Redaction returns the current email redaction mode
+func (r *Runtime) Redaction() string {
+	return r.Get().Redaction
+}
@@ -0,0 +1,148 @@
+package analysis
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// PathAccessLevel is an access level a conditional binding on a path requires
+type PathAccessLevel struct {
+	Name string `json:"name"`
+	// Level is nil when the level could not be resolved
+	Level *gcp.AccessLevel `json:"level,omitempty"`
+	// Requirement describes what a request must meet, e.g.
+	// "from 10.0.0.0/8 on a corp-owned device"
+	Requirement string `json:"requirement,omitempty"`
+}
+
+// ReferencedAccessLevels lists the access levels named by IAM conditions in the matrix
+func ReferencedAccessLevels(matrix *gcp.AccessMatrix) []string {
+	seen := make(map[string]bool)
+	for _, resource := range matrix.Resources {
+		for _, binding := range resource.Conditions {
+			for _, name := range gcp.AccessLevelReferences(binding.Condition.Expression) {
+				seen[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pathAccessLevels resolves the access levels the conditions on a path refer to
+func pathAccessLevels(path AccessPath, accessContext *gcp.AccessContext) []PathAccessLevel {
+	levels := []PathAccessLevel{}
+	seen := make(map[string]bool)
+	for _, step := range path.Steps {
+		for _, condition := range step.Conditions {
+			for _, name := range gcp.AccessLevelReferences(condition.Expression) {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				level := PathAccessLevel{Name: name, Level: accessContext.Level(name)}
+				if level.Level != nil {
+					level.Requirement = DescribeAccessLevel(*level.Level, accessContext)
+				}
+				levels = append(levels, level)
+			}
+		}
+	}
+	return levels
+}
+
+// DescribeAccessLevel renders the constraints of an access level as a phrase,
+// e.g. "from 10.0.0.0/8 or 192.168.0.0/16 on an encrypted device". Required
+// levels are expanded when accessContext holds them.
+func DescribeAccessLevel(level gcp.AccessLevel, accessContext *gcp.AccessContext) string {
+	return describeAccessLevel(level, accessContext, map[string]bool{level.Name: true})
+}
+
+func describeAccessLevel(level gcp.AccessLevel, accessContext *gcp.AccessContext, expanding map[string]bool) string {
+	if level.Expression != "" {
+		return "custom `" + level.Expression + "`"
+	}
+	parts := make([]string, 0, len(level.Conditions))
+	for _, condition := range level.Conditions {
+		parts = append(parts, describeLevelCondition(condition, accessContext, expanding))
+	}
+	separator := " and "
+	if level.Combining == "OR" {
+		separator = "; or "
+	}
+	return strings.Join(parts, separator)
+}
+
+// describeLevelCondition joins the non-empty constraints of one condition
+func describeLevelCondition(condition gcp.AccessLevelCondition, accessContext *gcp.AccessContext, expanding map[string]bool) string {
+	constraints := []string{}
+	if len(condition.IPSubnetworks) > 0 {
+		constraints = append(constraints, "from "+strings.Join(condition.IPSubnetworks, " or "))
+	}
+	if len(condition.VPCNetworks) > 0 {
+		constraints = append(constraints, "from VPC network "+strings.Join(condition.VPCNetworks, " or "))
+	}
+	if len(condition.Regions) > 0 {
+		constraints = append(constraints, "in "+strings.Join(condition.Regions, " or "))
+	}
+	if len(condition.Members) > 0 {
+		constraints = append(constraints, "as "+strings.Join(condition.Members, " or "))
+	}
+	for _, name := range condition.RequiredAccessLevels {
+		required := accessContext.Level(name)
+		if required == nil || expanding[name] {
+			constraints = append(constraints, "meeting "+name)
+			continue
+		}
+		expanding[name] = true
+		constraints = append(constraints, "meeting "+strconv.Quote(required.Title)+" ("+describeAccessLevel(*required, accessContext, expanding)+")")
+		delete(expanding, name)
+	}
+	if condition.Device != nil {
+		constraints = append(constraints, describeDevice(*condition.Device))
+	}
+
+	if len(constraints) == 0 {
+		return "any request"
+	}
+	described := strings.Join(constraints, ", ")
+	if condition.Negate {
+		return "not (" + described + ")"
+	}
+	return described
+}
+
+// describeDevice renders a device policy, e.g. "on a corp-owned device with screen lock"
+func describeDevice(device gcp.DeviceConstraints) string {
+	owned := "a"
+	if device.RequireCorpOwned {
+		owned = "a corp-owned"
+	}
+	requirements := []string{}
+	if device.RequireScreenlock {
+		requirements = append(requirements, "screen lock")
+	}
+	if device.RequireAdminApproval {
+		requirements = append(requirements, "admin approval")
+	}
+	if len(device.EncryptionStatuses) > 0 {
+		requirements = append(requirements, "encryption "+strings.Join(device.EncryptionStatuses, "/"))
+	}
+	if len(device.ManagementLevels) > 0 {
+		requirements = append(requirements, "management "+strings.Join(device.ManagementLevels, "/"))
+	}
+	if len(device.OSConstraints) > 0 {
+		requirements = append(requirements, "OS "+strings.Join(device.OSConstraints, " or "))
+	}
+	if len(requirements) == 0 {
+		return "on " + owned + " device"
+	}
+	return "on " + owned + " device with " + strings.Join(requirements, ", ")
+}
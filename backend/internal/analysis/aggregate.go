@@ -0,0 +1,155 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Aggregation dimensions
+const (
+	DimPrincipal     = "principal"
+	DimPrincipalType = "principalType"
+	DimTeam          = "team" // enriched team, or owner for service accounts
+	DimRole          = "role"
+	DimTier          = "tier" // the role's tier
+	DimResource      = "resource"
+	DimResourceType  = "resourceType"
+	DimProject       = "project"
+	DimRegion        = "region"
+)
+
+// AggregateDimensions lists the dimensions Aggregate can group by
+var AggregateDimensions = []string{DimPrincipal, DimPrincipalType, DimTeam, DimRole, DimTier, DimResource, DimResourceType, DimProject, DimRegion}
+
+// Aggregation metrics; principals and resources count distinct values
+const AggregateCount = "count" // (principal, resource, role) grants
+
+// AggregateRow is the metric of one combination of dimension values
+type AggregateRow struct {
+	Keys  map[string]string `json:"keys"` // dimension -> value
+	Value int               `json:"value"`
+}
+
+// ValidGroupBy reports whether groupBy is a comma-separated list of distinct
+// aggregation dimensions
+func ValidGroupBy(groupBy string) bool {
+	seen := make(map[string]bool)
+	for _, dimension := range strings.Split(groupBy, ",") {
+		if seen[dimension] || !contains(AggregateDimensions, dimension) {
+			return false
+		}
+		seen[dimension] = true
+	}
+	return true
+}
+
+// Aggregate pivots the matrix's grants by the dimensions and computes metric
+// (AggregateCount, TopPrincipals, or TopResources) per combination, largest
+// first. ok is false for an unknown dimension or metric. tier returns a
+// role's privilege tier.
+func Aggregate(matrix *gcp.AccessMatrix, dimensions []string, metric string, tier func(role string) string) (rows []AggregateRow, ok bool) {
+	for _, dimension := range dimensions {
+		if !contains(AggregateDimensions, dimension) {
+			return nil, false
+		}
+	}
+	if metric != AggregateCount && metric != TopPrincipals && metric != TopResources {
+		return nil, false
+	}
+
+	users := make(map[string]gcp.User, len(matrix.Users))
+	for _, user := range matrix.Users {
+		users[user.Email] = user
+	}
+	resources := make(map[string]gcp.Resource, len(matrix.Resources))
+	for _, resource := range matrix.Resources {
+		resources[resource.ID] = resource
+	}
+
+	value := func(dimension string, entry gcp.AccessEntry, role string) string {
+		switch dimension {
+		case DimPrincipal:
+			return entry.UserEmail
+		case DimRole:
+			return role
+		case DimTier:
+			return tier(role)
+		case DimResource:
+			return entry.ResourceID
+		case DimResourceType:
+			return entry.ResourceType
+		case DimProject:
+			return resources[entry.ResourceID].Project
+		case DimRegion:
+			return resources[entry.ResourceID].Region
+		}
+		user, found := users[entry.UserEmail]
+		if !found {
+			user = gcp.ParseMember(entry.UserEmail)
+		}
+		if dimension == DimPrincipalType {
+			return user.Type
+		}
+		if user.Team != "" {
+			return user.Team
+		}
+		if user.Owner != "" {
+			return user.Owner
+		}
+		return noTeam
+	}
+
+	type group struct {
+		keys     []string
+		grants   int
+		distinct map[string]bool
+	}
+	groups := make(map[string]*group)
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			keys := make([]string, len(dimensions))
+			for i, dimension := range dimensions {
+				keys[i] = value(dimension, entry, role)
+			}
+			id := strings.Join(keys, "\x00")
+			g := groups[id]
+			if g == nil {
+				g = &group{keys: keys, distinct: make(map[string]bool)}
+				groups[id] = g
+			}
+			g.grants++
+			switch metric {
+			case TopPrincipals:
+				g.distinct[entry.UserEmail] = true
+			case TopResources:
+				g.distinct[entry.ResourceID] = true
+			}
+		}
+	}
+
+	rows = []AggregateRow{}
+	for _, g := range groups {
+		row := AggregateRow{Keys: make(map[string]string, len(dimensions)), Value: g.grants}
+		if metric != AggregateCount {
+			row.Value = len(g.distinct)
+		}
+		for i, dimension := range dimensions {
+			row.Keys[dimension] = g.keys[i]
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Value != rows[j].Value {
+			return rows[i].Value > rows[j].Value
+		}
+		for _, dimension := range dimensions {
+			if a, b := rows[i].Keys[dimension], rows[j].Keys[dimension]; a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	return rows, true
+}
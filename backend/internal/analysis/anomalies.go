@@ -0,0 +1,218 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+const (
+	// clusterSimilarity is the minimum Jaccard similarity between a
+	// principal's role set and a cluster leader's for it to join the cluster
+	clusterSimilarity = 0.5
+	// minClusterSize is the smallest cluster whose members are compared
+	minClusterSize = 3
+	// maxAnomalyShare is the largest fraction of a cluster that may hold a role
+	// for it to count as unusual
+	maxAnomalyShare = 0.2
+)
+
+// RoleCluster is a set of principals holding similar roles
+type RoleCluster struct {
+	ID        int      `json:"id"`
+	Leader    string   `json:"leader"` // the principal the cluster was formed around
+	Members   []string `json:"members"`
+	CoreRoles []string `json:"coreRoles"` // roles held by at least half the members
+}
+
+// RoleAnomaly is a grant that is unusual for a principal compared to the
+// principals with the most similar role sets
+type RoleAnomaly struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+	Tier      string `json:"tier"`
+	Severity  string `json:"severity"`
+	Cluster   int    `json:"cluster"`
+	Peers     int    `json:"peers"`   // other members of the cluster
+	Holders   int    `json:"holders"` // peers that also hold the role
+	// CoOccurrence is the share of all principals holding the cluster's most
+	// common role that also hold this one
+	CoOccurrence float64  `json:"coOccurrence"`
+	Resources    []string `json:"resources"`
+	Explanation  string   `json:"explanation"`
+}
+
+// RoleAnomalies clusters principals by the similarity of their role sets and
+// flags write-or-higher roles held by few of a principal's cluster peers.
+// Clusters are formed greedily around the principals with the most roles, so
+// a principal whose roles are a superset of a common set joins that set's
+// cluster and its extra roles stand out. tier returns a role's privilege tier.
+func RoleAnomalies(matrix *gcp.AccessMatrix, tier func(role string) string) ([]RoleCluster, []RoleAnomaly) {
+	holdings := principalRoleResources(matrix)
+
+	var principals []string
+	for _, user := range matrix.Users {
+		switch user.Type {
+		case "user", "serviceAccount", "group":
+			if len(holdings[user.Email]) > 0 {
+				principals = append(principals, user.Email)
+			}
+		}
+	}
+	sort.Slice(principals, func(i, j int) bool {
+		a, b := len(holdings[principals[i]]), len(holdings[principals[j]])
+		if a != b {
+			return a > b
+		}
+		return principals[i] < principals[j]
+	})
+
+	// role -> principals holding it, for co-occurrence
+	holders := make(map[string]map[string]bool)
+	for _, principal := range principals {
+		for role := range holdings[principal] {
+			if holders[role] == nil {
+				holders[role] = make(map[string]bool)
+			}
+			holders[role][principal] = true
+		}
+	}
+
+	clusters := []RoleCluster{}
+	for _, principal := range principals {
+		best, bestSimilarity := -1, 0.0
+		for i, cluster := range clusters {
+			similarity := jaccard(holdings[principal], holdings[cluster.Leader])
+			if similarity >= clusterSimilarity && similarity > bestSimilarity {
+				best, bestSimilarity = i, similarity
+			}
+		}
+		if best < 0 {
+			clusters = append(clusters, RoleCluster{ID: len(clusters) + 1, Leader: principal})
+			best = len(clusters) - 1
+		}
+		clusters[best].Members = append(clusters[best].Members, principal)
+	}
+
+	anomalies := []RoleAnomaly{}
+	for i := range clusters {
+		cluster := &clusters[i]
+		sort.Strings(cluster.Members)
+
+		counts := make(map[string]int)
+		for _, member := range cluster.Members {
+			for role := range holdings[member] {
+				counts[role]++
+			}
+		}
+		var common string
+		for role, count := range counts {
+			if count*2 >= len(cluster.Members) {
+				cluster.CoreRoles = append(cluster.CoreRoles, role)
+			}
+			if count > counts[common] || count == counts[common] && role < common {
+				common = role
+			}
+		}
+		sort.Strings(cluster.CoreRoles)
+
+		if len(cluster.Members) < minClusterSize {
+			continue
+		}
+		peers := len(cluster.Members) - 1
+		for _, member := range cluster.Members {
+			for role, resources := range holdings[member] {
+				others := counts[role] - 1
+				if float64(others) > maxAnomalyShare*float64(peers) {
+					continue
+				}
+				roleTier := tier(role)
+				if gcp.TierRank(roleTier) < gcp.TierRank(gcp.TierWrite) {
+					continue
+				}
+
+				anomaly := RoleAnomaly{
+					Principal: member,
+					Role:      role,
+					Tier:      roleTier,
+					Severity:  SeverityMedium,
+					Cluster:   cluster.ID,
+					Peers:     peers,
+					Holders:   others,
+					Resources: dedupeSorted(resources),
+				}
+				if gcp.TierRank(roleTier) >= gcp.TierRank(gcp.TierAdmin) {
+					anomaly.Severity = SeverityHigh
+				}
+
+				var explanation strings.Builder
+				if others == 0 {
+					fmt.Fprintf(&explanation, "%s is the only one of %d principals with a similar role set holding %s (%s tier)",
+						member, len(cluster.Members), role, roleTier)
+				} else {
+					fmt.Fprintf(&explanation, "%s holds %s (%s tier), which only %d of its %d peers with a similar role set also hold",
+						member, role, roleTier, others, peers)
+				}
+				if common != "" && common != role {
+					withCommon := 0
+					for principal := range holders[common] {
+						if holders[role][principal] {
+							withCommon++
+						}
+					}
+					anomaly.CoOccurrence = float64(withCommon) / float64(len(holders[common]))
+					fmt.Fprintf(&explanation, "; across the organization %d of %d holders of the cluster's most common role %s also hold it",
+						withCommon, len(holders[common]), common)
+				}
+				anomaly.Explanation = explanation.String()
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Severity != b.Severity {
+			return SeverityRank(a.Severity) > SeverityRank(b.Severity)
+		}
+		if a.CoOccurrence != b.CoOccurrence {
+			return a.CoOccurrence < b.CoOccurrence
+		}
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		return a.Role < b.Role
+	})
+	return clusters, anomalies
+}
+
+// jaccard returns the Jaccard similarity of two role sets
+func jaccard(a, b map[string][]string) float64 {
+	shared := 0
+	for role := range a {
+		if _, ok := b[role]; ok {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// dedupeSorted returns the distinct values sorted
+func dedupeSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/rules"
+)
+
+// DriftGrant is a binding outside the expected-access baseline
+type DriftGrant struct {
+	Principal    string `json:"principal"`
+	Role         string `json:"role"`
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Tier         string `json:"tier"`
+}
+
+// Drift compares the actual bindings with the baseline
+type Drift struct {
+	// Unexpected are bindings no baseline entry allows
+	Unexpected []DriftGrant `json:"unexpected"`
+	// Missing are baseline entries no binding matches
+	Missing []rules.BaselineEntry `json:"missing"`
+	Matched int                   `json:"matched"` // bindings the baseline allows
+	Entries int                   `json:"entries"`
+}
+
+// BaselineDrift checks every binding on the matrix resources against the
+// baseline. Inherited access is not checked per resource; the binding on the
+// project is. tier returns a role's privilege tier.
+func BaselineDrift(matrix *gcp.AccessMatrix, baseline rules.Baseline, tier func(role string) string) Drift {
+	entries := baseline.Entries()
+	drift := Drift{Unexpected: []DriftGrant{}, Missing: []rules.BaselineEntry{}, Entries: len(entries)}
+	used := make([]bool, len(entries))
+
+	for _, resource := range matrix.Resources {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				principal := gcp.ParseMember(member).Email
+				allowed := false
+				for i, entry := range entries {
+					if entry.Matches(principal, role, resource.ID) {
+						allowed, used[i] = true, true
+					}
+				}
+				if allowed {
+					drift.Matched++
+					continue
+				}
+				drift.Unexpected = append(drift.Unexpected, DriftGrant{
+					Principal:    principal,
+					Role:         role,
+					ResourceID:   resource.ID,
+					ResourceName: resource.Name,
+					ResourceType: resource.Type,
+					Tier:         tier(role),
+				})
+			}
+		}
+	}
+
+	for i, entry := range entries {
+		if !used[i] {
+			drift.Missing = append(drift.Missing, entry)
+		}
+	}
+	sort.Slice(drift.Unexpected, func(i, j int) bool {
+		a, b := drift.Unexpected[i], drift.Unexpected[j]
+		if a.Tier != b.Tier {
+			return gcp.TierRank(a.Tier) > gcp.TierRank(b.Tier)
+		}
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.ResourceID < b.ResourceID
+	})
+	return drift
+}
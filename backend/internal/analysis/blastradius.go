@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Sort orders for principals
+const (
+	SortByEmail       = "email"
+	SortByBlastRadius = "blastRadius"
+	SortByTier        = "tier"
+)
+
+// ApplyBlastRadius attaches to each user its blast radius: the resources it reaches
+// through its own grants plus those of every service account it can impersonate
+func ApplyBlastRadius(users []gcp.User, matrix *gcp.AccessMatrix, index *PathIndex) {
+	tiers := make(map[string]map[string]string) // principal -> resource -> tier
+	for _, entry := range matrix.Access {
+		if tiers[entry.UserEmail] == nil {
+			tiers[entry.UserEmail] = make(map[string]string)
+		}
+		tiers[entry.UserEmail][entry.ResourceID] = entry.Tier
+	}
+
+	for i := range users {
+		radius := blastRadius(tiers, index, users[i].Email)
+		users[i].BlastRadius = &radius
+	}
+}
+
+// blastRadius computes one principal's blast radius from the per-principal tiers
+func blastRadius(tiers map[string]map[string]string, index *PathIndex, email string) gcp.BlastRadius {
+	radius := gcp.BlastRadius{Direct: len(tiers[email])}
+	reached := make(map[string]bool)
+	for id, tier := range tiers[email] {
+		reached[id] = true
+		radius.HighestTier = gcp.MaxTier(radius.HighestTier, tier)
+	}
+
+	impersonated := make(map[string]bool)
+	for _, hop := range index.impersonations(email, nil) {
+		if impersonated[hop.email] {
+			continue
+		}
+		impersonated[hop.email] = true
+		for id, tier := range tiers[hop.email] {
+			if !reached[id] {
+				reached[id] = true
+				radius.ViaImpersonation++
+			}
+			radius.HighestTier = gcp.MaxTier(radius.HighestTier, tier)
+		}
+	}
+
+	radius.Resources = len(reached)
+	radius.ServiceAccounts = len(impersonated)
+	radius.CanModifyIAM = gcp.TierRank(radius.HighestTier) >= gcp.TierRank(gcp.TierAdmin)
+	return radius
+}
+
+// SortUsers orders users by email, by blast radius (largest first), or by highest
+// tier reached (most privileged first). It returns false if the order is unknown.
+func SortUsers(users []gcp.User, order string) bool {
+	radius := func(u gcp.User) gcp.BlastRadius {
+		if u.BlastRadius == nil {
+			return gcp.BlastRadius{}
+		}
+		return *u.BlastRadius
+	}
+
+	var less func(a, b gcp.User) bool
+	switch order {
+	case SortByEmail:
+		less = func(a, b gcp.User) bool { return false }
+	case SortByBlastRadius:
+		less = func(a, b gcp.User) bool {
+			ra, rb := radius(a), radius(b)
+			if ra.Resources != rb.Resources {
+				return ra.Resources > rb.Resources
+			}
+			return gcp.TierRank(ra.HighestTier) > gcp.TierRank(rb.HighestTier)
+		}
+	case SortByTier:
+		less = func(a, b gcp.User) bool {
+			ra, rb := radius(a), radius(b)
+			if gcp.TierRank(ra.HighestTier) != gcp.TierRank(rb.HighestTier) {
+				return gcp.TierRank(ra.HighestTier) > gcp.TierRank(rb.HighestTier)
+			}
+			return ra.Resources > rb.Resources
+		}
+	default:
+		return false
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if less(users[i], users[j]) {
+			return true
+		}
+		if less(users[j], users[i]) {
+			return false
+		}
+		return users[i].Email < users[j].Email
+	})
+	return true
+}
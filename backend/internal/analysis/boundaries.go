@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"strings"
+
+	"gcp-access-visualizer/internal/conditions"
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// BoundaryCheck is whether Principal Access Boundary policies let a principal
+// use its role bindings on the scanned project's resources
+type BoundaryCheck struct {
+	Principal string `json:"principal"`
+	// Allowed is true when no boundary applies or one includes the project,
+	// false when the principal is bound by boundaries that all exclude it, and
+	// unknown when that depends on hierarchy or identity details not scanned
+	Allowed conditions.Result `json:"allowed"`
+	// Policies lists the boundaries that do or may apply to the principal
+	Policies []string `json:"policies"`
+}
+
+// BoundaryIndex evaluates Principal Access Boundary policies. Every resource
+// in the matrix belongs to the scanned project, so a boundary either lets a
+// principal use its roles on all of them or on none.
+type BoundaryIndex struct {
+	boundaries []gcp.AccessBoundary
+	scope      gcp.BoundaryScope
+}
+
+// NewBoundaryIndex indexes the boundaries bound around the scanned project
+func NewBoundaryIndex(boundaries []gcp.AccessBoundary, scope gcp.BoundaryScope) *BoundaryIndex {
+	return &BoundaryIndex{boundaries: boundaries, scope: scope}
+}
+
+// Check evaluates the boundaries for a principal given as in the access matrix,
+// e.g. "sa@my-project.iam.gserviceaccount.com". A nil index allows everything.
+func (b *BoundaryIndex) Check(principal string) BoundaryCheck {
+	check := BoundaryCheck{Principal: principal, Allowed: conditions.True, Policies: []string{}}
+	if b == nil {
+		return check
+	}
+
+	bound := false          // some boundary certainly applies
+	coveredByBound := false // ... and certainly includes the project
+	maybeCovered := false   // a boundary that may apply may include the project
+	allMaybeCover := true   // every boundary that may apply includes the project
+	for _, boundary := range b.boundaries {
+		applies := b.applies(boundary, principal)
+		if applies == conditions.False {
+			continue
+		}
+		check.Policies = append(check.Policies, boundary.Policy)
+		covers := b.covers(boundary.Resources)
+
+		if applies == conditions.True {
+			bound = true
+			if covers == conditions.True {
+				coveredByBound = true
+			}
+		}
+		if covers != conditions.False {
+			maybeCovered = true
+		}
+		if covers != conditions.True {
+			allMaybeCover = false
+		}
+	}
+
+	switch {
+	case len(check.Policies) == 0, coveredByBound:
+		check.Allowed = conditions.True
+	case bound && !maybeCovered:
+		check.Allowed = conditions.False
+	case !bound && allMaybeCover:
+		check.Allowed = conditions.True
+	default:
+		check.Allowed = conditions.Unknown
+	}
+	return check
+}
+
+// applies reports whether principal is in the boundary's principal set and
+// meets the binding's condition
+func (b *BoundaryIndex) applies(boundary gcp.AccessBoundary, principal string) conditions.Result {
+	inSet := b.inPrincipalSet(boundary.PrincipalSet, principal)
+	if inSet == conditions.False || boundary.Condition == nil {
+		return inSet
+	}
+
+	ctx := conditions.Context{PrincipalType: principalType(principal), PrincipalSubject: principal}
+	result, err := conditions.Evaluate(boundary.Condition.Expression, ctx)
+	if err != nil {
+		result = conditions.Unknown
+	}
+	return conditions.Combine(inSet, result)
+}
+
+// inPrincipalSet reports whether principal belongs to a policy binding target.
+// Project, folder, and organization sets hold the service accounts (and pool
+// identities) of the projects below them; organization sets also hold the
+// organization's Google Workspace or Cloud Identity users.
+func (b *BoundaryIndex) inPrincipalSet(set, principal string) conditions.Result {
+	kind, id, ok := strings.Cut(strings.TrimPrefix(set, "//cloudresourcemanager.googleapis.com/"), "/")
+	if !ok || strings.HasPrefix(set, "//iam.googleapis.com/") {
+		// Workforce and workload identity pools hold the principal:// identities below them
+		if strings.HasPrefix(principal, "principal://"+strings.TrimPrefix(set, "//")+"/") {
+			return conditions.True
+		}
+		return conditions.False
+	}
+
+	saProject := gcp.ServiceAccountProject(principal)
+	ours := saProject == b.scope.ProjectID
+	switch kind {
+	case "projects":
+		switch {
+		case saProject == "":
+			return conditions.False
+		case id == saProject || (ours && id == b.scope.ProjectNumber):
+			return conditions.True
+		case ours || id == b.scope.ProjectID || id == b.scope.ProjectNumber:
+			return conditions.False
+		}
+		return conditions.Unknown // id may be the number of the account's project
+	case "folders":
+		if saProject == "" {
+			return conditions.False
+		}
+		return b.inAncestor(ours, "folders/"+id)
+	case "organizations":
+		if saProject == "" && principalType(principal) != "iam.googleapis.com/WorkspaceIdentity" {
+			return conditions.False
+		}
+		if saProject == "" {
+			return conditions.Unknown // membership in the organization's directory is not scanned
+		}
+		return b.inAncestor(ours, "organizations/"+id)
+	}
+	return conditions.Unknown
+}
+
+// inAncestor reports whether a folder or organization contains the project of a
+// service account; ours is whether that is the scanned project
+func (b *BoundaryIndex) inAncestor(ours bool, ancestor string) conditions.Result {
+	switch {
+	case !ours:
+		return conditions.Unknown
+	case ancestor == b.scope.Parent:
+		return conditions.True
+	case b.noHigherAncestors():
+		return conditions.False
+	}
+	return conditions.Unknown // higher ancestors are not scanned
+}
+
+// covers reports whether the boundary rules include the scanned project
+func (b *BoundaryIndex) covers(resources []string) conditions.Result {
+	result := conditions.False
+	for _, resource := range resources {
+		name := strings.TrimPrefix(resource, "//cloudresourcemanager.googleapis.com/")
+		kind, id, _ := strings.Cut(name, "/")
+		switch {
+		case kind == "projects" && (id == b.scope.ProjectID || id == b.scope.ProjectNumber):
+			return conditions.True
+		case kind == "projects":
+			continue
+		case name == b.scope.Parent:
+			return conditions.True
+		case (kind == "folders" || kind == "organizations") && !b.noHigherAncestors():
+			result = conditions.Unknown
+		}
+	}
+	return result
+}
+
+// noHigherAncestors reports whether the project's parent, if any, is its only
+// ancestor: it sits directly below an organization or outside any
+func (b *BoundaryIndex) noHigherAncestors() bool {
+	return b.scope.Parent == "" || strings.HasPrefix(b.scope.Parent, "organizations/")
+}
+
+// principalType returns the principal.type a policy binding condition sees
+func principalType(principal string) string {
+	switch {
+	case strings.HasSuffix(principal, ".gserviceaccount.com"):
+		return "iam.googleapis.com/ServiceAccount"
+	case strings.Contains(principal, "/workforcePools/"):
+		return "iam.googleapis.com/WorkforcePoolIdentity"
+	case strings.Contains(principal, "/workloadIdentityPools/"):
+		return "iam.googleapis.com/WorkloadPoolIdentity"
+	case strings.Contains(principal, "@"):
+		return "iam.googleapis.com/WorkspaceIdentity"
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/rules"
+)
+
+// CheckPolicy is what a proposed change is checked against
+type CheckPolicy struct {
+	Tier        func(role string) string   // a role's privilege tier
+	Permissions func(role string) []string // a role's permissions, for the toxic rules
+	SoD         []rules.SoDRule
+	Toxic       []rules.ToxicRule
+	Watchlist   []string
+	// Baseline, when set, must allow every proposed binding
+	Baseline rules.Baseline
+	// MaxTier, when set, is the highest tier a proposed binding may grant
+	MaxTier string
+}
+
+// CheckedGrant is one proposed binding and the access it adds
+type CheckedGrant struct {
+	Binding     Binding          `json:"binding"`
+	RoleTier    string           `json:"roleTier"`
+	Watchlisted bool             `json:"watchlisted"`
+	Resources   []GainedResource `json:"resources"` // empty when the binding already exists
+	// InBaseline is unset when no baseline is checked
+	InBaseline *bool `json:"inBaseline,omitempty"`
+}
+
+// CheckBlastRadius summarizes the access a change adds
+type CheckBlastRadius struct {
+	Principals  int    `json:"principals"`  // principals gaining access
+	Resources   int    `json:"resources"`   // distinct resources they gain access on
+	NewAccess   int    `json:"newAccess"`   // (principal, resource) pairs with no access today
+	Escalations int    `json:"escalations"` // (principal, resource) pairs whose tier rises
+	HighestTier string `json:"highestTier,omitempty"`
+}
+
+// CheckResult is the verdict on a proposed set of bindings
+type CheckResult struct {
+	Passed bool `json:"passed"`
+	// Failures explains, one line each, why the check did not pass
+	Failures []string       `json:"failures"`
+	Grants   []CheckedGrant `json:"grants"`
+	// Unresolved are bindings on resources not in the snapshot, e.g. ones the
+	// same change creates; they are not checked and do not fail the check
+	Unresolved        []Binding          `json:"unresolved"`
+	SoDViolations     []SoDViolation     `json:"sodViolations"`     // introduced by the change
+	ToxicCombinations []ToxicCombination `json:"toxicCombinations"` // introduced by the change
+	BlastRadius       CheckBlastRadius   `json:"blastRadius"`
+}
+
+// CheckBindings applies all the proposed bindings to a copy of the matrix
+// and reports what the change as a whole would introduce: separation-of-duties
+// conflicts and toxic combinations no principal has today, watchlisted roles,
+// bindings outside the baseline, and grants above policy.MaxTier. Violations
+// that already exist do not fail the check.
+func CheckBindings(matrix *gcp.AccessMatrix, bindings []Binding, policy CheckPolicy) CheckResult {
+	result := CheckResult{
+		Failures:          []string{},
+		Grants:            []CheckedGrant{},
+		Unresolved:        []Binding{},
+		SoDViolations:     []SoDViolation{},
+		ToxicCombinations: []ToxicCombination{},
+	}
+
+	resources := make(map[string]gcp.Resource, len(matrix.Resources))
+	for _, res := range matrix.Resources {
+		resources[res.ID] = res
+	}
+	entries := make(map[string]*gcp.AccessEntry, len(matrix.Access))
+	order := make([]string, 0, len(matrix.Access))
+	for _, entry := range matrix.Access {
+		entry := entry
+		key := entry.UserEmail + "\x00" + entry.ResourceID
+		entries[key] = &entry
+		order = append(order, key)
+	}
+	var baseline []rules.BaselineEntry
+	if policy.Baseline != nil {
+		baseline = policy.Baseline.Entries()
+	}
+
+	principals := make(map[string]bool)
+	gained := make(map[string]bool)
+	newAccess := make(map[string]bool)
+	escalations := make(map[string]bool)
+	for _, binding := range bindings {
+		target, ok := resources[binding.ResourceID]
+		if !ok {
+			result.Unresolved = append(result.Unresolved, binding)
+			continue
+		}
+
+		roleTier := policy.Tier(binding.Role)
+		grant := CheckedGrant{
+			Binding:     binding,
+			RoleTier:    roleTier,
+			Watchlisted: contains(policy.Watchlist, binding.Role),
+			Resources:   []GainedResource{},
+		}
+		if grant.Watchlisted {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s would be granted watchlisted role %s on %s", binding.Principal, binding.Role, binding.ResourceID))
+		}
+		if policy.MaxTier != "" && gcp.TierRank(roleTier) > gcp.TierRank(policy.MaxTier) {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s on %s grants %s tier, above the allowed %s", binding.Role, binding.ResourceID, roleTier, policy.MaxTier))
+		}
+		if policy.Baseline != nil {
+			allowed := false
+			for _, entry := range baseline {
+				allowed = allowed || entry.Matches(binding.Principal, binding.Role, binding.ResourceID)
+			}
+			grant.InBaseline = &allowed
+			if !allowed {
+				result.Failures = append(result.Failures, fmt.Sprintf("%s with %s on %s is outside the baseline", binding.Principal, binding.Role, binding.ResourceID))
+			}
+		}
+
+		for _, res := range grantScope(matrix, target, binding.Role) {
+			key := binding.Principal + "\x00" + res.ID
+			entry, exists := entries[key]
+			if exists && contains(entry.Roles, binding.Role) {
+				continue
+			}
+			if !exists {
+				entry = &gcp.AccessEntry{
+					UserEmail:    binding.Principal,
+					ResourceID:   res.ID,
+					ResourceName: res.Name,
+					ResourceType: res.Type,
+				}
+				entries[key] = entry
+				order = append(order, key)
+				newAccess[key] = true
+			}
+
+			newTier := gcp.MaxTier(entry.Tier, roleTier)
+			grant.Resources = append(grant.Resources, GainedResource{
+				ResourceID:   res.ID,
+				ResourceName: res.Name,
+				ResourceType: res.Type,
+				CurrentTier:  entry.Tier,
+				NewTier:      newTier,
+				NewAccess:    !exists,
+				Escalates:    gcp.TierRank(newTier) > gcp.TierRank(entry.Tier),
+			})
+			if exists && gcp.TierRank(newTier) > gcp.TierRank(entry.Tier) {
+				escalations[key] = true
+			}
+			entry.Roles = append(append([]string{}, entry.Roles...), binding.Role)
+			entry.Tier = newTier
+
+			principals[binding.Principal] = true
+			gained[res.ID] = true
+			result.BlastRadius.HighestTier = gcp.MaxTier(result.BlastRadius.HighestTier, roleTier)
+		}
+		sort.Slice(grant.Resources, func(i, j int) bool {
+			return grant.Resources[i].ResourceID < grant.Resources[j].ResourceID
+		})
+		result.Grants = append(result.Grants, grant)
+	}
+
+	result.BlastRadius.Principals = len(principals)
+	result.BlastRadius.Resources = len(gained)
+	result.BlastRadius.NewAccess = len(newAccess)
+	for key := range escalations {
+		if !newAccess[key] {
+			result.BlastRadius.Escalations++
+		}
+	}
+
+	after := &gcp.AccessMatrix{
+		Users:     matrix.Users,
+		Resources: matrix.Resources,
+		Access:    make([]gcp.AccessEntry, 0, len(order)),
+	}
+	for _, key := range order {
+		after.Access = append(after.Access, *entries[key])
+	}
+
+	existingSoD := make(map[string]bool)
+	for _, violation := range EvaluateSoD(matrix, policy.SoD) {
+		existingSoD[violation.RuleID+"\x00"+violation.Principal] = true
+	}
+	for _, violation := range EvaluateSoD(after, policy.SoD) {
+		if !existingSoD[violation.RuleID+"\x00"+violation.Principal] {
+			result.SoDViolations = append(result.SoDViolations, violation)
+			result.Failures = append(result.Failures, fmt.Sprintf("%s would violate separation-of-duties rule %s", violation.Principal, violation.RuleID))
+		}
+	}
+
+	existingToxic := make(map[string]bool)
+	for _, combination := range EvaluateToxic(matrix, policy.Toxic, policy.Permissions) {
+		existingToxic[combination.RuleID+"\x00"+combination.Principal] = true
+	}
+	for _, combination := range EvaluateToxic(after, policy.Toxic, policy.Permissions) {
+		if !existingToxic[combination.RuleID+"\x00"+combination.Principal] {
+			result.ToxicCombinations = append(result.ToxicCombinations, combination)
+			result.Failures = append(result.Failures, fmt.Sprintf("%s would hold toxic combination %s", combination.Principal, combination.RuleID))
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result
+}
@@ -0,0 +1,226 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// ComparedGrant is a role one principal reaches on a resource and the other does not
+type ComparedGrant struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Role         string `json:"role"`
+	Tier         string `json:"tier"`
+	// Via is how the holder reaches the role: "direct", or the group or
+	// service account the shortest path goes through
+	Via string `json:"via"`
+}
+
+// PrincipalComparison is the symmetric difference of two principals'
+// effective access
+type PrincipalComparison struct {
+	A      string          `json:"a"`
+	B      string          `json:"b"`
+	OnlyA  []ComparedGrant `json:"onlyA"` // what B lacks to match A
+	OnlyB  []ComparedGrant `json:"onlyB"` // what B holds beyond A
+	Shared int             `json:"shared"`
+}
+
+// ComparePrincipals compares the (resource, role) pairs each principal reaches
+// through direct bindings, group membership, and service account
+// impersonation. Project roles are compared on the project rather than on
+// every resource inheriting them. tier returns a role's privilege tier.
+func ComparePrincipals(matrix *gcp.AccessMatrix, index *PathIndex, a, b string, tier func(role string) string) PrincipalComparison {
+	comparison := PrincipalComparison{A: a, B: b, OnlyA: []ComparedGrant{}, OnlyB: []ComparedGrant{}}
+
+	for _, resource := range matrix.Resources {
+		rolesA := reachedRoles(index.Paths(a, resource.ID, nil))
+		rolesB := reachedRoles(index.Paths(b, resource.ID, nil))
+		grant := func(role, via string) ComparedGrant {
+			return ComparedGrant{
+				ResourceID:   resource.ID,
+				ResourceName: resource.Name,
+				ResourceType: resource.Type,
+				Role:         role,
+				Tier:         tier(role),
+				Via:          via,
+			}
+		}
+		for role, via := range rolesA {
+			if _, ok := rolesB[role]; ok {
+				comparison.Shared++
+			} else {
+				comparison.OnlyA = append(comparison.OnlyA, grant(role, via))
+			}
+		}
+		for role, via := range rolesB {
+			if _, ok := rolesA[role]; !ok {
+				comparison.OnlyB = append(comparison.OnlyB, grant(role, via))
+			}
+		}
+	}
+
+	sortCompared(comparison.OnlyA)
+	sortCompared(comparison.OnlyB)
+	return comparison
+}
+
+// reachedRoles maps each role the paths grant, other than through project
+// inheritance, to how the shortest path reaches it
+func reachedRoles(paths []AccessPath) map[string]string {
+	roles := make(map[string]string)
+	shortest := make(map[string]int)
+	for _, path := range paths {
+		via, inherited := "direct", false
+		for _, step := range path.Steps {
+			switch step.Kind {
+			case StepInherited:
+				inherited = true
+			case StepMembership, StepImpersonation:
+				if via == "direct" {
+					via = step.To
+				}
+			}
+		}
+		if inherited {
+			continue
+		}
+		if length, ok := shortest[path.Role]; !ok || len(path.Steps) < length {
+			shortest[path.Role] = len(path.Steps)
+			roles[path.Role] = via
+		}
+	}
+	return roles
+}
+
+// sortCompared orders grants most privileged first, then by resource and role
+func sortCompared(grants []ComparedGrant) {
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Tier != b.Tier {
+			return gcp.TierRank(a.Tier) > gcp.TierRank(b.Tier)
+		}
+		if a.ResourceID != b.ResourceID {
+			return a.ResourceID < b.ResourceID
+		}
+		return a.Role < b.Role
+	})
+}
+
+// ProjectGrant is a role a principal holds in a project, on the project
+// itself or on resources in it
+type ProjectGrant struct {
+	Principal string   `json:"principal"`
+	Role      string   `json:"role"`
+	Tier      string   `json:"tier"`
+	Resources []string `json:"resources"`
+}
+
+// ProjectComparison is the difference in IAM posture between two projects
+type ProjectComparison struct {
+	A      string         `json:"a"`
+	B      string         `json:"b"`
+	OnlyA  []ProjectGrant `json:"onlyA"` // (principal, role) pairs bound in A but nowhere in B
+	OnlyB  []ProjectGrant `json:"onlyB"`
+	Shared int            `json:"shared"`
+	// Principals and roles bound anywhere in one project but not the other
+	PrincipalsOnlyA []string `json:"principalsOnlyA"`
+	PrincipalsOnlyB []string `json:"principalsOnlyB"`
+	RolesOnlyA      []string `json:"rolesOnlyA"`
+	RolesOnlyB      []string `json:"rolesOnlyB"`
+}
+
+// ProjectPlaceholder replaces a project's ID in the emails of its own service
+// accounts so that, e.g., deployer@staging.iam.gserviceaccount.com and
+// deployer@prod.iam.gserviceaccount.com compare as the same principal
+const ProjectPlaceholder = "{project}"
+
+// CompareProjects diffs the (principal, role) bindings on two projects and
+// their resources, given by project ID. Inherited bindings are not repeated
+// per resource. tier returns a role's privilege tier.
+func CompareProjects(matrix *gcp.AccessMatrix, a, b string, tier func(role string) string) ProjectComparison {
+	grantsA, grantsB := projectGrants(matrix, a, tier), projectGrants(matrix, b, tier)
+	comparison := ProjectComparison{A: a, B: b, OnlyA: []ProjectGrant{}, OnlyB: []ProjectGrant{}}
+
+	principalsA, principalsB := make(map[string]bool), make(map[string]bool)
+	rolesA, rolesB := make(map[string]bool), make(map[string]bool)
+	for key, grant := range grantsA {
+		principalsA[grant.Principal], rolesA[grant.Role] = true, true
+		if _, ok := grantsB[key]; ok {
+			comparison.Shared++
+		} else {
+			comparison.OnlyA = append(comparison.OnlyA, *grant)
+		}
+	}
+	for key, grant := range grantsB {
+		principalsB[grant.Principal], rolesB[grant.Role] = true, true
+		if _, ok := grantsA[key]; !ok {
+			comparison.OnlyB = append(comparison.OnlyB, *grant)
+		}
+	}
+
+	comparison.PrincipalsOnlyA, comparison.PrincipalsOnlyB = setDifference(principalsA, principalsB), setDifference(principalsB, principalsA)
+	comparison.RolesOnlyA, comparison.RolesOnlyB = setDifference(rolesA, rolesB), setDifference(rolesB, rolesA)
+	sortProjectGrants(comparison.OnlyA)
+	sortProjectGrants(comparison.OnlyB)
+	return comparison
+}
+
+// projectGrants collects the bindings on the project's resources keyed by
+// principal and role, with the project's own service accounts normalized
+func projectGrants(matrix *gcp.AccessMatrix, project string, tier func(role string) string) map[string]*ProjectGrant {
+	grants := make(map[string]*ProjectGrant)
+	for _, resource := range matrix.Resources {
+		if !inProject(resource.ID, project) {
+			continue
+		}
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				principal := strings.Replace(gcp.ParseMember(member).Email, "@"+project+".", "@"+ProjectPlaceholder+".", 1)
+				key := principal + "::" + role
+				grant := grants[key]
+				if grant == nil {
+					grant = &ProjectGrant{Principal: principal, Role: role, Tier: tier(role)}
+					grants[key] = grant
+				}
+				grant.Resources = append(grant.Resources, resource.ID)
+			}
+		}
+	}
+	for _, grant := range grants {
+		grant.Resources = dedupeSorted(grant.Resources)
+	}
+	return grants
+}
+
+// setDifference returns the members of a not in b, sorted
+func setDifference(a, b map[string]bool) []string {
+	difference := []string{}
+	for value := range a {
+		if !b[value] {
+			difference = append(difference, value)
+		}
+	}
+	sort.Strings(difference)
+	return difference
+}
+
+// sortProjectGrants orders grants most privileged first, then by principal and role
+func sortProjectGrants(grants []ProjectGrant) {
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Tier != b.Tier {
+			return gcp.TierRank(a.Tier) > gcp.TierRank(b.Tier)
+		}
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		return a.Role < b.Role
+	})
+}
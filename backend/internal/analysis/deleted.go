@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/gcp/resourcename"
+)
+
+// DeletedBinding is a role binding that still names a deleted principal
+type DeletedBinding struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Role         string `json:"role"`
+	// Command removes the binding with gcloud; empty for resource types
+	// without a remove-iam-policy-binding command
+	Command string `json:"command,omitempty"`
+}
+
+// DeletedPrincipal is a deleted user, service account, or group that is
+// still bound on resources. Its bindings grant nothing and only clutter
+// policies, but they count toward policy size limits and obscure reviews.
+type DeletedPrincipal struct {
+	Member   string           `json:"member"` // e.g. "deleted:user:alice@example.com?uid=123"
+	Type     string           `json:"type"`   // type of the principal before deletion
+	Email    string           `json:"email"`
+	UID      string           `json:"uid,omitempty"`
+	Bindings []DeletedBinding `json:"bindings"`
+}
+
+// DeletedPrincipals lists the deleted principals left in resource policies
+// with every binding that names them. Only bindings recorded on a resource
+// are listed, not the access the matrix derives from project inheritance.
+func DeletedPrincipals(matrix *gcp.AccessMatrix) []DeletedPrincipal {
+	byMember := make(map[string]*DeletedPrincipal)
+	for _, resource := range matrix.Resources {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				memberType, email, uid, ok := gcp.DeletedMember(member)
+				if !ok {
+					continue
+				}
+				principal := byMember[member]
+				if principal == nil {
+					principal = &DeletedPrincipal{Member: member, Type: memberType, Email: email, UID: uid}
+					byMember[member] = principal
+				}
+				principal.Bindings = append(principal.Bindings, DeletedBinding{
+					ResourceID:   resource.ID,
+					ResourceName: resource.Name,
+					ResourceType: resource.Type,
+					Role:         role,
+					Command:      RemoveBindingCommand(resource.ID, role, member),
+				})
+			}
+		}
+	}
+
+	deleted := []DeletedPrincipal{}
+	for _, principal := range byMember {
+		sort.Slice(principal.Bindings, func(i, j int) bool {
+			a, b := principal.Bindings[i], principal.Bindings[j]
+			if a.ResourceID != b.ResourceID {
+				return a.ResourceID < b.ResourceID
+			}
+			return a.Role < b.Role
+		})
+		deleted = append(deleted, *principal)
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].Member < deleted[j].Member })
+	return deleted
+}
+
+// DeletedPrincipalFindings reports each deleted principal that is still bound
+func DeletedPrincipalFindings(deleted []DeletedPrincipal) []Finding {
+	findings := []Finding{}
+	for _, principal := range deleted {
+		finding := newFinding("deleted-principal", SeverityLow, principal.Member,
+			fmt.Sprintf("Deleted %s %s is still bound", principal.Type, principal.Email),
+			fmt.Sprintf("The deleted principal is still named in %d bindings. They grant nothing, but clutter policies and count toward their size limit. Remove them; /api/deleted-principals/plan generates the commands.", len(principal.Bindings)))
+		finding.Details = map[string]string{
+			"member":   principal.Member,
+			"type":     principal.Type,
+			"email":    principal.Email,
+			"bindings": strconv.Itoa(len(principal.Bindings)),
+		}
+		if principal.UID != "" {
+			finding.Details["uid"] = principal.UID
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// DeletedPrincipalPlan renders a shell script that removes every binding of
+// the deleted principals. Bindings without a gcloud command are listed as
+// comments to be removed by editing the resource's policy.
+func DeletedPrincipalPlan(deleted []DeletedPrincipal) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Removes role bindings that name deleted principals\nset -e\n")
+	for _, principal := range deleted {
+		fmt.Fprintf(&b, "\n# %s\n", principal.Member)
+		for _, binding := range principal.Bindings {
+			if binding.Command == "" {
+				fmt.Fprintf(&b, "# Edit the IAM policy of %s to remove %s from %s\n", binding.ResourceID, principal.Member, binding.Role)
+				continue
+			}
+			b.WriteString(binding.Command + "\n")
+		}
+	}
+	return b.String()
+}
+
+// RemoveBindingCommand returns the gcloud command that removes member from
+// role on a resource, or "" if gcloud has no such command for its type
+func RemoveBindingCommand(resourceID, role, member string) string {
+	name := resourcename.Parse(resourceID)
+	var group, target string
+	switch name.FriendlyType {
+	case "project":
+		group, target = "projects", name.DisplayName
+	case "folder":
+		group, target = "resource-manager folders", name.DisplayName
+	case "organization":
+		group, target = "organizations", name.DisplayName
+	case "storage":
+		group, target = "storage buckets", "gs://"+name.DisplayName
+	case "serviceaccount":
+		group, target = "iam service-accounts", name.DisplayName
+	default:
+		return ""
+	}
+	return fmt.Sprintf("gcloud %s remove-iam-policy-binding %s --member='%s' --role='%s'", group, target, member, role)
+}
@@ -0,0 +1,143 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// DomainGrant is one resource a domain principal can reach
+type DomainGrant struct {
+	ResourceID   string   `json:"resourceId"`
+	ResourceName string   `json:"resourceName"`
+	ResourceType string   `json:"resourceType"`
+	Roles        []string `json:"roles"`
+	Tier         string   `json:"tier"`
+}
+
+// DomainExposure is the effective access of a domain: member, i.e. what every
+// account in the Google Workspace or Cloud Identity domain can do
+type DomainExposure struct {
+	Domain      string        `json:"domain"`
+	Grants      []DomainGrant `json:"grants"`
+	HighestTier string        `json:"highestTier"`
+	// Privileged counts grants at write tier or above
+	Privileged int `json:"privileged"`
+	// Statements spell out the exposure, e.g. "Everyone at example.com can modify bucket-a"
+	Statements []string `json:"statements"`
+}
+
+// tierVerbs phrases each privilege tier as what a principal can do
+var tierVerbs = map[string]string{
+	gcp.TierRead:  "read",
+	gcp.TierWrite: "modify",
+	gcp.TierAdmin: "administer",
+	gcp.TierOwner: "own",
+}
+
+// DomainExposures lists every domain principal in the matrix with the grants
+// it holds, most privileged first. Unlike users or groups, a domain binding
+// has no member list to review: it extends to every current and future
+// account in the domain.
+func DomainExposures(matrix *gcp.AccessMatrix) []DomainExposure {
+	domains := make(map[string]bool)
+	for _, user := range matrix.Users {
+		if user.Type == "domain" {
+			domains[user.Email] = true
+		}
+	}
+
+	byDomain := make(map[string]*DomainExposure)
+	for _, entry := range matrix.Access {
+		if !domains[entry.UserEmail] {
+			continue
+		}
+		exposure := byDomain[entry.UserEmail]
+		if exposure == nil {
+			exposure = &DomainExposure{Domain: entry.UserEmail}
+			byDomain[entry.UserEmail] = exposure
+		}
+		exposure.Grants = append(exposure.Grants, DomainGrant{
+			ResourceID:   entry.ResourceID,
+			ResourceName: entry.ResourceName,
+			ResourceType: entry.ResourceType,
+			Roles:        entry.Roles,
+			Tier:         entry.Tier,
+		})
+	}
+
+	exposures := []DomainExposure{}
+	for _, exposure := range byDomain {
+		sort.SliceStable(exposure.Grants, func(i, j int) bool {
+			a, b := exposure.Grants[i], exposure.Grants[j]
+			if gcp.TierRank(a.Tier) != gcp.TierRank(b.Tier) {
+				return gcp.TierRank(a.Tier) > gcp.TierRank(b.Tier)
+			}
+			return a.ResourceID < b.ResourceID
+		})
+		for _, grant := range exposure.Grants {
+			exposure.HighestTier = gcp.MaxTier(exposure.HighestTier, grant.Tier)
+			if privilegedTier(grant.Tier) {
+				exposure.Privileged++
+			}
+			exposure.Statements = append(exposure.Statements, domainStatement(exposure.Domain, grant))
+		}
+		exposures = append(exposures, *exposure)
+	}
+
+	sort.Slice(exposures, func(i, j int) bool {
+		a, b := exposures[i], exposures[j]
+		if gcp.TierRank(a.HighestTier) != gcp.TierRank(b.HighestTier) {
+			return gcp.TierRank(a.HighestTier) > gcp.TierRank(b.HighestTier)
+		}
+		if a.Privileged != b.Privileged {
+			return a.Privileged > b.Privileged
+		}
+		return a.Domain < b.Domain
+	})
+	return exposures
+}
+
+// DomainFindings flags every domain binding at write tier or above. Such a
+// grant is effectively unbounded: anyone who joins the domain inherits it.
+func DomainFindings(exposures []DomainExposure) []Finding {
+	findings := []Finding{}
+	for _, exposure := range exposures {
+		for _, grant := range exposure.Grants {
+			if !privilegedTier(grant.Tier) {
+				continue
+			}
+			finding := newFinding("domain-privileged-binding", SeverityHigh, exposure.Domain+" on "+grant.ResourceID,
+				fmt.Sprintf("Domain-wide %s access to %s", grant.Tier, grant.ResourceName),
+				fmt.Sprintf("%s via %s. Bind the roles to a group of the people who need them instead.",
+					domainStatement(exposure.Domain, grant), strings.Join(grant.Roles, ", ")))
+			finding.Details = map[string]string{
+				"domain":       exposure.Domain,
+				"resourceId":   grant.ResourceID,
+				"resourceType": grant.ResourceType,
+				"roles":        strings.Join(grant.Roles, ","),
+				"tier":         grant.Tier,
+				"grants":       strconv.Itoa(len(exposure.Grants)),
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// privilegedTier reports whether a tier allows changing the resource
+func privilegedTier(tier string) bool {
+	return gcp.TierRank(tier) >= gcp.TierRank(gcp.TierWrite)
+}
+
+// domainStatement phrases a domain grant as "Everyone at example.com can modify bucket-a"
+func domainStatement(domain string, grant DomainGrant) string {
+	name := grant.ResourceName
+	if name == "" {
+		name = grant.ResourceID
+	}
+	return fmt.Sprintf("Everyone at %s can %s %s", domain, tierVerb(grant.Tier), name)
+}
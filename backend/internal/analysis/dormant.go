@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// DormantGrant is a role grant held by a dormant service account
+type DormantGrant struct {
+	ResourceID   string   `json:"resourceId"`
+	ResourceName string   `json:"resourceName"`
+	ResourceType string   `json:"resourceType"`
+	Roles        []string `json:"roles"`
+}
+
+// DormantServiceAccount is an enabled service account that holds role grants
+// but has not authenticated recently
+type DormantServiceAccount struct {
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName"`
+	Owner       string `json:"owner,omitempty"`
+	// LastAuthenticated is nil when no authentication was observed at all
+	LastAuthenticated *time.Time     `json:"lastAuthenticated"`
+	DaysInactive      int            `json:"daysInactive,omitempty"`
+	Grants            []DormantGrant `json:"grants"`
+	Plan              []string       `json:"plan"`
+}
+
+// DormantServiceAccounts returns enabled service accounts with grants in the matrix
+// whose last authentication is older than after, or who never authenticated.
+// lastAuth maps service account emails to their last authentication time.
+func DormantServiceAccounts(project string, matrix *gcp.AccessMatrix, accounts []gcp.ServiceAccount, lastAuth map[string]time.Time, after time.Duration, now time.Time) []DormantServiceAccount {
+	grants := make(map[string][]DormantGrant)
+	for _, entry := range matrix.Access {
+		grants[entry.UserEmail] = append(grants[entry.UserEmail], DormantGrant{
+			ResourceID:   entry.ResourceID,
+			ResourceName: entry.ResourceName,
+			ResourceType: entry.ResourceType,
+			Roles:        entry.Roles,
+		})
+	}
+
+	owners := make(map[string]string)
+	for _, user := range matrix.Users {
+		owners[user.Email] = user.Owner
+	}
+
+	dormant := []DormantServiceAccount{}
+	for _, account := range accounts {
+		if account.Disabled || len(grants[account.Email]) == 0 {
+			continue
+		}
+
+		result := DormantServiceAccount{
+			Email:       account.Email,
+			DisplayName: account.DisplayName,
+			Owner:       owners[account.Email],
+			Grants:      grants[account.Email],
+		}
+		if last, ok := lastAuth[account.Email]; ok {
+			if now.Sub(last) < after {
+				continue
+			}
+			result.LastAuthenticated = &last
+			result.DaysInactive = int(now.Sub(last).Hours() / 24)
+		}
+
+		sort.Slice(result.Grants, func(i, j int) bool {
+			return result.Grants[i].ResourceID < result.Grants[j].ResourceID
+		})
+		result.Plan = dormantPlan(project, result)
+		dormant = append(dormant, result)
+	}
+
+	sort.Slice(dormant, func(i, j int) bool {
+		return dormant[i].Email < dormant[j].Email
+	})
+	return dormant
+}
+
+// dormantPlan suggests how to retire a dormant service account safely:
+// confirm, disable, remove grants, and delete after a grace period
+func dormantPlan(project string, account DormantServiceAccount) []string {
+	var plan []string
+	if account.Owner != "" {
+		plan = append(plan, fmt.Sprintf("Confirm with %s that %s is no longer used", account.Owner, account.Email))
+	} else {
+		plan = append(plan, fmt.Sprintf("Identify an owner for %s and confirm it is no longer used", account.Email))
+	}
+
+	plan = append(plan, fmt.Sprintf("Disable it: gcloud iam service-accounts disable %s --project %s", account.Email, project))
+
+	projectResource := "//cloudresourcemanager.googleapis.com/projects/" + project
+	for _, grant := range account.Grants {
+		for _, role := range grant.Roles {
+			if grant.ResourceID == projectResource {
+				plan = append(plan, fmt.Sprintf("Remove the project grant: gcloud projects remove-iam-policy-binding %s --member=serviceAccount:%s --role=%s", project, account.Email, role))
+			}
+		}
+	}
+	if direct := countNonProjectGrants(account.Grants, projectResource); direct > 0 {
+		plan = append(plan, fmt.Sprintf("Remove its grants on %d other resources (see grants)", direct))
+	}
+
+	plan = append(plan, fmt.Sprintf("After a grace period without breakage, delete it: gcloud iam service-accounts delete %s --project %s", account.Email, project))
+	return plan
+}
+
+// countNonProjectGrants counts grants on resources other than the project itself
+func countNonProjectGrants(grants []DormantGrant, projectResource string) int {
+	count := 0
+	for _, grant := range grants {
+		if grant.ResourceID != projectResource {
+			count++
+		}
+	}
+	return count
+}
+
+// DormantServiceAccountFindings converts dormant service accounts into findings
+func DormantServiceAccountFindings(dormant []DormantServiceAccount) []Finding {
+	findings := []Finding{}
+	for _, account := range dormant {
+		lastSeen := "no authentication observed"
+		if account.LastAuthenticated != nil {
+			lastSeen = fmt.Sprintf("last authenticated %d days ago", account.DaysInactive)
+		}
+
+		finding := newFinding("sa-dormant", SeverityMedium, account.Email,
+			fmt.Sprintf("Dormant service account %s", account.Email),
+			fmt.Sprintf("The account holds grants on %d resources but %s. Confirm it is unused, then disable it and remove its grants.", len(account.Grants), lastSeen))
+		finding.Details = map[string]string{
+			"serviceAccount": account.Email,
+			"resources":      strconv.Itoa(len(account.Grants)),
+		}
+		if account.LastAuthenticated != nil {
+			finding.Details["lastAuthenticated"] = account.LastAuthenticated.Format(time.RFC3339)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
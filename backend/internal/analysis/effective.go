@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"time"
+
+	"gcp-access-visualizer/internal/conditions"
+)
+
+// EffectivePath is an explained path with the outcome of its conditions
+type EffectivePath struct {
+	ExplainedPath
+	// Applies is whether the path grants access at the evaluated time: true,
+	// false, or unknown when a condition or access boundary depends on
+	// attributes not modeled
+	Applies conditions.Result `json:"applies"`
+}
+
+// EffectiveAccess is whether a principal can access a resource at a given time
+type EffectiveAccess struct {
+	Principal  string    `json:"principal"`
+	ResourceID string    `json:"resourceId"`
+	At         time.Time `json:"at"`
+	// Access combines the paths: true if any applies, unknown if none does but
+	// some might, false otherwise
+	Access conditions.Result `json:"access"`
+	Paths  []EffectivePath   `json:"paths"`
+}
+
+// EvaluateAccess resolves the paths by which principal reaches the resource and
+// evaluates the IAM conditions on each as of at, and the Principal Access
+// Boundary policies on the principal that uses the role
+func EvaluateAccess(index *PathIndex, controls Controls, principal, resourceID string, at time.Time) EffectiveAccess {
+	effective := EffectiveAccess{
+		Principal:  principal,
+		ResourceID: resourceID,
+		At:         at,
+		Access:     conditions.False,
+		Paths:      []EffectivePath{},
+	}
+	for _, path := range Explain(index, controls, principal, resourceID).Paths {
+		applies := index.EvaluatePath(path.AccessPath, at)
+		if path.Boundary != nil {
+			applies = conditions.Combine(applies, path.Boundary.Allowed)
+		}
+		effective.Paths = append(effective.Paths, EffectivePath{ExplainedPath: path, Applies: applies})
+		switch {
+		case applies == conditions.True:
+			effective.Access = conditions.True
+		case applies == conditions.Unknown && effective.Access == conditions.False:
+			effective.Access = conditions.Unknown
+		}
+	}
+	return effective
+}
+
+// EvaluatePath evaluates the conditions along a path as of at. A conditional
+// binding is evaluated against the resource being accessed: the inheriting
+// resource for project-level bindings. Expressions that cannot be evaluated
+// count as unknown.
+func (x *PathIndex) EvaluatePath(path AccessPath, at time.Time) conditions.Result {
+	results := make([]conditions.Result, 0, len(path.Steps))
+	for i, step := range path.Steps {
+		if len(step.Conditions) == 0 {
+			continue
+		}
+		target := step.To
+		if i+1 < len(path.Steps) && path.Steps[i+1].Kind == StepInherited {
+			target = path.Steps[i+1].To
+		}
+		assetType := ""
+		if res := x.resources[target]; res != nil {
+			assetType = res.AssetType
+		}
+		ctx := conditions.ContextFor(target, assetType, at)
+
+		// The binding applies if any of its conditions holds
+		outcome := conditions.False
+		for _, condition := range step.Conditions {
+			result, err := conditions.Evaluate(condition.Expression, ctx)
+			if err != nil {
+				result = conditions.Unknown
+			}
+			if result == conditions.True {
+				outcome = conditions.True
+				break
+			}
+			if result == conditions.Unknown {
+				outcome = conditions.Unknown
+			}
+		}
+		results = append(results, outcome)
+	}
+	return conditions.Combine(results...)
+}
@@ -0,0 +1,32 @@
+package analysis
+
+import "gcp-access-visualizer/internal/gcp"
+
+// StandingEligibility lists eligible access whose roles the principal already
+// holds as standing access on the same resource. Such entitlements add no
+// just-in-time protection until the standing bindings are removed.
+func StandingEligibility(matrix *gcp.AccessMatrix) []gcp.EligibleAccess {
+	standing := make(map[string][]string, len(matrix.Access))
+	for _, entry := range matrix.Access {
+		standing[entry.UserEmail+"::"+entry.ResourceID] = entry.Roles
+	}
+
+	redundant := []gcp.EligibleAccess{}
+	for _, entry := range matrix.Eligible {
+		roles, ok := standing[entry.UserEmail+"::"+entry.ResourceID]
+		if !ok {
+			continue
+		}
+		held := true
+		for _, role := range entry.Roles {
+			if !contains(roles, role) {
+				held = false
+				break
+			}
+		}
+		if held {
+			redundant = append(redundant, entry)
+		}
+	}
+	return redundant
+}
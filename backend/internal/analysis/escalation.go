@@ -0,0 +1,174 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// EscalationPrimitives are roles on a service account that let the holder obtain
+// its privileges, with a short description of how
+var EscalationPrimitives = map[string]string{
+	"roles/owner":                          "act as or create keys for",
+	"roles/editor":                         "act as",
+	"roles/iam.serviceAccountTokenCreator": "mint access tokens for",
+	"roles/iam.serviceAccountKeyAdmin":     "create keys for",
+	"roles/iam.serviceAccountUser":         "attach to a workload and run as",
+	"roles/iam.workloadIdentityUser":       "impersonate via workload identity",
+	"roles/iam.serviceAccountAdmin":        "grant itself impersonation on",
+}
+
+// EscalationTargetRoles are project-level roles that allow rewriting IAM, which
+// is where an escalation chain ends
+var EscalationTargetRoles = []string{
+	"roles/owner",
+	"roles/resourcemanager.projectIamAdmin",
+	"roles/iam.securityAdmin",
+}
+
+// maxEscalationHops bounds the chain of service accounts followed
+const maxEscalationHops = 4
+
+// EscalationStep is one hop of an escalation chain: From gains To's privileges
+type EscalationStep struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Role      string `json:"role"`
+	Primitive string `json:"primitive"`
+	// Via is the access path that grants Role on the service account
+	Via []PathStep `json:"via"`
+}
+
+// EscalationPath is a chain of service accounts by which a principal that cannot
+// modify IAM reaches one that can
+type EscalationPath struct {
+	Principal  string           `json:"principal"`
+	Target     string           `json:"target"`     // service account holding TargetRole
+	TargetRole string           `json:"targetRole"` // IAM-modifying role at the end of the chain
+	Length     int              `json:"length"`
+	Steps      []EscalationStep `json:"steps"`
+}
+
+// escalationEdge is a primitive by which one principal takes over a service account
+type escalationEdge struct {
+	to   string
+	role string
+	via  []PathStep
+}
+
+// FindEscalationPaths finds, for every principal that does not already hold an
+// IAM-modifying project role, the shortest chain to each service account that does
+func FindEscalationPaths(matrix *gcp.AccessMatrix, index *PathIndex) []EscalationPath {
+	targets := make(map[string]string)
+	for email := range index.serviceAccounts {
+		if role := index.targetRole(email); role != "" {
+			targets[email] = role
+		}
+	}
+
+	paths := []EscalationPath{}
+	if len(targets) == 0 {
+		return paths
+	}
+
+	edges := make(map[string][]escalationEdge)
+	edgesFrom := func(principal string) []escalationEdge {
+		if cached, ok := edges[principal]; ok {
+			return cached
+		}
+		var result []escalationEdge
+		for email, grants := range index.serviceAccountGrants(principal, nil) {
+			for _, grant := range grants {
+				if _, ok := EscalationPrimitives[grant.Role]; ok {
+					result = append(result, escalationEdge{to: email, role: grant.Role, via: grant.Steps})
+					break
+				}
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].to < result[j].to })
+		edges[principal] = result
+		return result
+	}
+
+	for _, user := range matrix.Users {
+		if user.Type != "user" && user.Type != "serviceAccount" && user.Type != "group" {
+			continue
+		}
+		if index.targetRole(user.Email) != "" {
+			continue
+		}
+
+		// Breadth-first so the first chain found to each target is the shortest
+		previous := map[string]EscalationStep{}
+		visited := map[string]bool{user.Email: true}
+		frontier := []string{user.Email}
+		for hop := 0; hop < maxEscalationHops && len(frontier) > 0; hop++ {
+			var next []string
+			for _, from := range frontier {
+				for _, edge := range edgesFrom(from) {
+					if visited[edge.to] {
+						continue
+					}
+					visited[edge.to] = true
+					previous[edge.to] = EscalationStep{
+						From:      from,
+						To:        edge.to,
+						Role:      edge.role,
+						Primitive: EscalationPrimitives[edge.role],
+						Via:       edge.via,
+					}
+					next = append(next, edge.to)
+
+					if role, ok := targets[edge.to]; ok {
+						paths = append(paths, EscalationPath{
+							Principal:  user.Email,
+							Target:     edge.to,
+							TargetRole: role,
+							Steps:      chainTo(previous, user.Email, edge.to),
+						})
+					}
+				}
+			}
+			frontier = next
+		}
+	}
+
+	for i := range paths {
+		paths[i].Length = len(paths[i].Steps)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := paths[i], paths[j]
+		if a.Length != b.Length {
+			return a.Length < b.Length
+		}
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		return a.Target < b.Target
+	})
+	return paths
+}
+
+// targetRole returns the IAM-modifying role principal holds on the project, if any
+func (x *PathIndex) targetRole(principal string) string {
+	if x.project == nil {
+		return ""
+	}
+	for _, path := range x.bindingPaths(x.identities(principal), x.project, nil) {
+		if contains(EscalationTargetRoles, path.Role) {
+			return path.Role
+		}
+	}
+	return ""
+}
+
+// chainTo walks the breadth-first predecessors back from target to start
+func chainTo(previous map[string]EscalationStep, start, target string) []EscalationStep {
+	var steps []EscalationStep
+	for node := target; node != start; {
+		step := previous[node]
+		steps = append([]EscalationStep{step}, steps...)
+		node = step.From
+	}
+	return steps
+}
@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gcp-access-visualizer/internal/conditions"
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// ExplainedPath is an access path with a one-line description for display
+type ExplainedPath struct {
+	AccessPath
+	Summary string `json:"summary"`
+	// Boundary is set when Principal Access Boundary policies may limit the
+	// principal that finally uses the role
+	Boundary *BoundaryCheck `json:"boundary,omitempty"`
+	// AccessLevels are the access levels the conditions on the path require
+	AccessLevels []PathAccessLevel `json:"accessLevels,omitempty"`
+}
+
+// Explanation lists every distinct way a principal reaches a resource
+type Explanation struct {
+	Principal  string          `json:"principal"`
+	ResourceID string          `json:"resourceId"`
+	Paths      []ExplainedPath `json:"paths"`
+}
+
+// Controls are the policies besides role bindings that decide whether a path
+// grants access. Either may be nil when it could not be read.
+type Controls struct {
+	Boundaries    *BoundaryIndex
+	AccessContext *gcp.AccessContext
+}
+
+// Explain resolves the paths by which principal reaches the resource, shortest
+// first, and applies the controls to each
+func Explain(index *PathIndex, controls Controls, principal, resourceID string) Explanation {
+	explanation := Explanation{
+		Principal:  principal,
+		ResourceID: resourceID,
+		Paths:      []ExplainedPath{},
+	}
+	for _, path := range index.Paths(principal, resourceID, nil) {
+		explained := ExplainedPath{AccessPath: path, Summary: describePath(path)}
+		if levels := pathAccessLevels(path, controls.AccessContext); len(levels) > 0 {
+			explained.AccessLevels = levels
+			for _, level := range levels {
+				if level.Level != nil {
+					explained.Summary += fmt.Sprintf(" (%s: %s)", strconv.Quote(level.Level.Title), level.Requirement)
+				}
+			}
+		}
+		if check := controls.Boundaries.Check(actingPrincipal(principal, path)); len(check.Policies) > 0 {
+			explained.Boundary = &check
+			switch check.Allowed {
+			case conditions.False:
+				explained.Summary += " (blocked by a principal access boundary)"
+			case conditions.Unknown:
+				explained.Summary += " (may be blocked by a principal access boundary)"
+			}
+		}
+		explanation.Paths = append(explanation.Paths, explained)
+	}
+	return explanation
+}
+
+// actingPrincipal returns the principal that finally uses the role on a path:
+// the last impersonated service account, or the principal itself
+func actingPrincipal(principal string, path AccessPath) string {
+	for _, step := range path.Steps {
+		if step.Kind == StepImpersonation {
+			principal = step.To
+		}
+	}
+	return principal
+}
+
+// describePath renders a path as a readable chain, e.g.
+// "member of group eng@example.com → roles/viewer on project → inherited by bucket"
+func describePath(path AccessPath) string {
+	parts := make([]string, 0, len(path.Steps))
+	for _, step := range path.Steps {
+		switch step.Kind {
+		case StepMembership:
+			parts = append(parts, "member of "+step.To)
+		case StepBinding:
+			part := fmt.Sprintf("%s on %s", step.Role, lastSegment(step.To))
+			if len(step.Conditions) > 0 {
+				part += " if " + describeConditions(step.Conditions)
+			}
+			parts = append(parts, part)
+		case StepInherited:
+			parts = append(parts, "inherited by "+lastSegment(step.To))
+		case StepImpersonation:
+			parts = append(parts, "impersonates "+step.To)
+		}
+	}
+	if len(parts) == 1 && path.Steps[0].Kind == StepBinding {
+		return "direct binding: " + parts[0]
+	}
+	return strings.Join(parts, " → ")
+}
+
+// describeConditions names the alternative conditions of a binding by title,
+// falling back to the expression
+func describeConditions(conditions []gcp.Condition) string {
+	names := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		if condition.Title != "" {
+			names = append(names, strconv.Quote(condition.Title))
+		} else {
+			names = append(names, "`"+condition.Expression+"`")
+		}
+	}
+	return strings.Join(names, " or ")
+}
@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// ExposedIdentity is a service account a publicly reachable VM runs as, with
+// the access an attacker on the VM inherits
+type ExposedIdentity struct {
+	Email       string   `json:"email"`
+	HighestTier string   `json:"highestTier"`
+	Roles       []string `json:"roles"`
+	Resources   int      `json:"resources"`
+	// ProjectTier is the highest tier the account holds on the project itself
+	ProjectTier string `json:"projectTier,omitempty"`
+	// Sensitive is set for accounts with admin access anywhere or write
+	// access on the whole project
+	Sensitive bool `json:"sensitive"`
+}
+
+// ExposedVM is a VM reachable from the internet: it has an external IP and a
+// public ingress rule allows traffic to it
+type ExposedVM struct {
+	ResourceID  string   `json:"resourceId"`
+	Name        string   `json:"name"`
+	Location    string   `json:"location"`
+	ExternalIPs []string `json:"externalIps"`
+	// OpenPorts are the protocol and port specs allowed from any address,
+	// e.g. "tcp:22"; Rules are the firewall rules that allow them
+	OpenPorts       []string          `json:"openPorts"`
+	Rules           []string          `json:"rules"`
+	ServiceAccounts []ExposedIdentity `json:"serviceAccounts"`
+	// FullAPIAccess is set when the VM's tokens carry the cloud-platform
+	// scope; narrower scopes limit what its service accounts can do from it
+	FullAPIAccess bool `json:"fullApiAccess"`
+	// Risk is the severity of the combined exposure: "critical" or "high" when
+	// a sensitive service account is reachable, "" otherwise
+	Risk string `json:"risk,omitempty"`
+}
+
+// ExposedVMs cross-references firewall rules with the external IPs of VMs to
+// find those reachable from the internet, and attaches the access of the
+// service accounts they run as. Deny rules take away ports only when they
+// match the same public sources and cover the allowed spec.
+func ExposedVMs(matrix *gcp.AccessMatrix) []ExposedVM {
+	var rules []gcp.Resource
+	for _, resource := range matrix.Resources {
+		if resource.Type == "firewall" && resource.Firewall != nil {
+			rule := resource.Firewall
+			if rule.Direction == "INGRESS" && !rule.Disabled && rule.Public() {
+				rules = append(rules, resource)
+			}
+		}
+	}
+	identities := serviceAccountAccess(matrix)
+
+	exposed := []ExposedVM{}
+	for _, resource := range matrix.Resources {
+		instance := resource.Instance
+		if resource.Type != "vm" || instance == nil || len(instance.ExternalIPs) == 0 {
+			continue
+		}
+		vm := ExposedVM{
+			ResourceID:      resource.ID,
+			Name:            resource.Name,
+			Location:        resource.Location,
+			ExternalIPs:     instance.ExternalIPs,
+			OpenPorts:       []string{},
+			Rules:           []string{},
+			ServiceAccounts: []ExposedIdentity{},
+			FullAPIAccess:   instance.FullAPIAccess(),
+		}
+		for _, allow := range rules {
+			if allow.Firewall.Deny || !allow.Firewall.AppliesTo(instance) {
+				continue
+			}
+			for _, port := range allow.Firewall.Ports {
+				if !deniedPort(rules, instance, allow.Firewall.Priority, port) && !contains(vm.OpenPorts, port) {
+					vm.OpenPorts = append(vm.OpenPorts, port)
+					vm.Rules = appendUnique(vm.Rules, allow.Name)
+				}
+			}
+		}
+		if len(vm.OpenPorts) == 0 {
+			continue
+		}
+		sort.Strings(vm.OpenPorts)
+		sort.Strings(vm.Rules)
+
+		for _, email := range instance.ServiceAccounts {
+			identity, ok := identities[email]
+			if !ok {
+				identity = &ExposedIdentity{Email: email, Roles: []string{}}
+			}
+			vm.ServiceAccounts = append(vm.ServiceAccounts, *identity)
+			if identity.Sensitive {
+				vm.Risk = SeverityHigh
+				if vm.FullAPIAccess {
+					vm.Risk = SeverityCritical
+				}
+			}
+		}
+		exposed = append(exposed, vm)
+	}
+	sort.Slice(exposed, func(i, j int) bool {
+		if ri, rj := SeverityRank(exposed[i].Risk), SeverityRank(exposed[j].Risk); ri != rj {
+			return ri > rj
+		}
+		return exposed[i].ResourceID < exposed[j].ResourceID
+	})
+	return exposed
+}
+
+// ExposureFindings reports VMs that are reachable from the internet and run
+// as a sensitive service account: compromising the VM yields the account's
+// access, combining a network exposure with an identity exposure
+func ExposureFindings(exposed []ExposedVM) []Finding {
+	findings := []Finding{}
+	for _, vm := range exposed {
+		if vm.Risk == "" {
+			continue
+		}
+		var accounts []string
+		for _, identity := range vm.ServiceAccounts {
+			if identity.Sensitive {
+				accounts = append(accounts, fmt.Sprintf("%s (%s)", identity.Email, identity.HighestTier))
+			}
+		}
+		scope := "Its tokens carry the cloud-platform scope, so every role of the account is usable from the VM."
+		if !vm.FullAPIAccess {
+			scope = "Its access scopes narrow what the account's tokens can do from the VM, but scopes are not a security boundary."
+		}
+		finding := newFinding("exposed-vm-privileged-sa", vm.Risk, vm.ResourceID,
+			fmt.Sprintf("Internet-facing VM %s runs as a privileged service account", vm.Name),
+			fmt.Sprintf("%s is reachable on %s from any address and runs as %s. %s Restrict the firewall rules, remove the external IP, or attach a service account with less access.",
+				vm.Name, strings.Join(vm.OpenPorts, ", "), strings.Join(accounts, ", "), scope))
+		finding.Details = map[string]string{
+			"externalIps":     strings.Join(vm.ExternalIPs, ","),
+			"openPorts":       strings.Join(vm.OpenPorts, ","),
+			"rules":           strings.Join(vm.Rules, ","),
+			"serviceAccounts": strings.Join(accounts, ","),
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// deniedPort reports whether a public deny rule that takes precedence over an
+// allow rule of the given priority covers a port spec
+func deniedPort(rules []gcp.Resource, instance *gcp.InstanceDetails, priority int64, port string) bool {
+	protocol, _, _ := strings.Cut(port, ":")
+	for _, deny := range rules {
+		rule := deny.Firewall
+		if !rule.Deny || rule.Priority > priority || !rule.AppliesTo(instance) {
+			continue
+		}
+		for _, denied := range rule.Ports {
+			if denied == "all" || denied == port || denied == protocol {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serviceAccountAccess summarizes the access of every service account in the matrix
+func serviceAccountAccess(matrix *gcp.AccessMatrix) map[string]*ExposedIdentity {
+	identities := make(map[string]*ExposedIdentity)
+	for _, entry := range matrix.Access {
+		if !strings.HasSuffix(entry.UserEmail, ".gserviceaccount.com") {
+			continue
+		}
+		identity := identities[entry.UserEmail]
+		if identity == nil {
+			identity = &ExposedIdentity{Email: entry.UserEmail, Roles: []string{}}
+			identities[entry.UserEmail] = identity
+		}
+		identity.HighestTier = gcp.MaxTier(identity.HighestTier, entry.Tier)
+		identity.Roles = appendUnique(identity.Roles, entry.Roles...)
+		identity.Resources++
+		if entry.ResourceType == "project" {
+			identity.ProjectTier = gcp.MaxTier(identity.ProjectTier, entry.Tier)
+		}
+	}
+	for _, identity := range identities {
+		sort.Strings(identity.Roles)
+		identity.Sensitive = gcp.TierRank(identity.HighestTier) >= gcp.TierRank(gcp.TierAdmin) ||
+			gcp.TierRank(identity.ProjectTier) >= gcp.TierRank(gcp.TierWrite)
+	}
+	return identities
+}
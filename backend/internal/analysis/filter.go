@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Filter narrows an access matrix. Empty fields match everything.
+type Filter struct {
+	Project      string
+	ResourceType string
+	Role         string
+	Principal    string
+	Location     string // zone, region, or multi-region; regions also match their zones
+}
+
+// IsEmpty reports whether the filter matches everything
+func (f Filter) IsEmpty() bool {
+	return f == Filter{}
+}
+
+// FilterMatrix returns a new matrix containing only entries that match the filter,
+// along with the users and resources those entries reference
+func FilterMatrix(matrix *gcp.AccessMatrix, filter Filter) *gcp.AccessMatrix {
+	if filter.IsEmpty() {
+		return matrix
+	}
+
+	filtered := &gcp.AccessMatrix{
+		Users:     []gcp.User{},
+		Resources: []gcp.Resource{},
+		Access:    []gcp.AccessEntry{},
+		Warnings:  matrix.Warnings,
+		Projects:  matrix.Projects,
+		History:   matrix.History,
+	}
+	resources := make(map[string]gcp.Resource, len(matrix.Resources))
+	for _, res := range matrix.Resources {
+		resources[res.ID] = res
+	}
+
+	keptUsers := make(map[string]bool)
+	keptResources := make(map[string]bool)
+
+	for _, entry := range matrix.Access {
+		if filter.Principal != "" && !strings.EqualFold(entry.UserEmail, filter.Principal) {
+			continue
+		}
+		if filter.ResourceType != "" && entry.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.Project != "" && !inProject(entry.ResourceID, filter.Project) {
+			continue
+		}
+		if filter.Location != "" && !inLocation(resources[entry.ResourceID], filter.Location) {
+			continue
+		}
+
+		roles := entry.Roles
+		if filter.Role != "" {
+			if !contains(entry.Roles, filter.Role) {
+				continue
+			}
+			roles = []string{filter.Role}
+		}
+
+		filteredEntry := entry
+		filteredEntry.Roles = roles
+		filtered.Access = append(filtered.Access, filteredEntry)
+		keptUsers[entry.UserEmail] = true
+		keptResources[entry.ResourceID] = true
+	}
+
+	for _, entry := range matrix.Eligible {
+		if filter.Principal != "" && !strings.EqualFold(entry.UserEmail, filter.Principal) {
+			continue
+		}
+		if filter.ResourceType != "" && entry.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.Project != "" && !inProject(entry.ResourceID, filter.Project) {
+			continue
+		}
+		if filter.Role != "" && !contains(entry.Roles, filter.Role) {
+			continue
+		}
+		filtered.Eligible = append(filtered.Eligible, entry)
+	}
+
+	for _, user := range matrix.Users {
+		if keptUsers[user.Email] {
+			filtered.Users = append(filtered.Users, user)
+		}
+	}
+	for _, res := range matrix.Resources {
+		if keptResources[res.ID] {
+			filtered.Resources = append(filtered.Resources, res)
+		}
+	}
+
+	return filtered
+}
+
+// inProject reports whether a resource ID belongs to the given project
+func inProject(resourceID, project string) bool {
+	return strings.Contains(resourceID+"/", "/projects/"+project+"/")
+}
+
+// inLocation reports whether a resource is in the given location or region
+func inLocation(res gcp.Resource, location string) bool {
+	location = strings.ToLower(location)
+	return res.Location == location || res.Region == location
+}
+
+// contains checks if a slice contains a string
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
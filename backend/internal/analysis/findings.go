@@ -0,0 +1,165 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Finding severities, from least to most severe
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// severityRank orders severities for sorting and thresholds
+var severityRank = map[string]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SeverityRank returns the rank of a severity; unknown severities rank 0
+func SeverityRank(severity string) int {
+	return severityRank[severity]
+}
+
+// Finding is a security issue detected in the inventory or the access matrix
+type Finding struct {
+	ID          string            `json:"id"` // stable across scans: kind and subject
+	Kind        string            `json:"kind"`
+	Severity    string            `json:"severity"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Subject     string            `json:"subject"` // principal, key, or resource the finding is about
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// newFinding builds a finding whose ID is derived from its kind and subject
+func newFinding(kind, severity, subject, title, description string) Finding {
+	return Finding{
+		ID:          kind + ":" + subject,
+		Kind:        kind,
+		Severity:    severity,
+		Title:       title,
+		Description: description,
+		Subject:     subject,
+	}
+}
+
+// SortFindings orders findings by descending severity, then by ID
+func SortFindings(findings []Finding) {
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if SeverityRank(a.Severity) != SeverityRank(b.Severity) {
+			return SeverityRank(a.Severity) > SeverityRank(b.Severity)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// APIKeyFindings flags API keys that can call any API, can be used from anywhere, or both
+func APIKeyFindings(keys []gcp.APIKey) []Finding {
+	findings := []Finding{}
+	for _, key := range keys {
+		label := key.DisplayName
+		if label == "" {
+			label = key.UID
+		}
+
+		apiRestricted := len(key.Restrictions.APITargets) > 0
+		appRestricted := key.Restrictions.HasApplicationRestriction()
+
+		var finding Finding
+		switch {
+		case !apiRestricted && !appRestricted:
+			finding = newFinding("api-key-unrestricted", SeverityHigh, key.Name,
+				"Unrestricted API key "+label,
+				"The key can call every enabled API from any referrer, IP address, or app. Restrict it to the APIs and clients that use it.")
+		case !apiRestricted:
+			finding = newFinding("api-key-no-api-restriction", SeverityMedium, key.Name,
+				"API key "+label+" is not restricted to specific APIs",
+				"The key can call every enabled API. Add API restrictions for the services that use it.")
+		case !appRestricted:
+			finding = newFinding("api-key-no-application-restriction", SeverityMedium, key.Name,
+				"API key "+label+" can be used from anywhere",
+				"The key has no referrer, IP address, or app restrictions.")
+		default:
+			continue
+		}
+
+		finding.Details = map[string]string{
+			"displayName": key.DisplayName,
+			"apiTargets":  strings.Join(key.Restrictions.APITargets, ","),
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// KeyRotationPolicy sets the thresholds for service account key findings
+type KeyRotationPolicy struct {
+	MaxAge    time.Duration // keys older than this should be rotated; zero disables
+	MaxActive int           // service accounts with more active keys are flagged; zero disables
+}
+
+// ServiceAccountKeyFindings flags active user-managed keys that are older than the
+// policy allows or never expire, and service accounts with too many active keys
+func ServiceAccountKeyFindings(keys []gcp.ServiceAccountKey, policy KeyRotationPolicy, now time.Time) []Finding {
+	findings := []Finding{}
+	activeKeys := make(map[string]int)
+
+	for _, key := range keys {
+		if key.Disabled {
+			continue
+		}
+		activeKeys[key.ServiceAccount]++
+		subject := key.ServiceAccount + "/" + key.KeyID
+
+		if age := now.Sub(key.ValidAfter); policy.MaxAge > 0 && age > policy.MaxAge {
+			finding := newFinding("sa-key-too-old", SeverityHigh, subject,
+				fmt.Sprintf("Key of %s is %d days old", key.ServiceAccount, int(age.Hours()/24)),
+				fmt.Sprintf("User-managed keys should be rotated within %d days. Rotate or delete the key, or switch to workload identity.", int(policy.MaxAge.Hours()/24)))
+			finding.Details = map[string]string{
+				"serviceAccount": key.ServiceAccount,
+				"keyId":          key.KeyID,
+				"createdAt":      key.ValidAfter.Format(time.RFC3339),
+			}
+			findings = append(findings, finding)
+		}
+
+		// Keys without an expiry report a validity end in year 9999
+		if key.ValidBefore.Year() >= 9999 {
+			finding := newFinding("sa-key-no-expiry", SeverityMedium, subject,
+				fmt.Sprintf("Key of %s never expires", key.ServiceAccount),
+				"The key stays valid until it is deleted. Enforce an expiry with the iam.serviceAccountKeyExpiryHours organization policy.")
+			finding.Details = map[string]string{
+				"serviceAccount": key.ServiceAccount,
+				"keyId":          key.KeyID,
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	for account, count := range activeKeys {
+		if policy.MaxActive > 0 && count > policy.MaxActive {
+			finding := newFinding("sa-too-many-keys", SeverityMedium, account,
+				fmt.Sprintf("%s has %d active keys", account, count),
+				fmt.Sprintf("More than %d active keys per service account makes rotation and leak response harder.", policy.MaxActive))
+			finding.Details = map[string]string{
+				"serviceAccount": account,
+				"activeKeys":     strconv.Itoa(count),
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
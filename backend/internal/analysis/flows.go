@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Flow counts role grants along one principal-type → role-family → resource-type path
+type Flow struct {
+	PrincipalType string `json:"principalType"`
+	RoleFamily    string `json:"roleFamily"`
+	ResourceType  string `json:"resourceType"`
+	Count         int    `json:"count"`
+}
+
+// FlowLink is a weighted Sankey link between two stage nodes
+type FlowLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Value  int    `json:"value"`
+}
+
+// FlowSummary holds the aggregated flows and the Sankey links derived from them
+type FlowSummary struct {
+	Flows []Flow     `json:"flows"`
+	Links []FlowLink `json:"links"`
+}
+
+// BuildFlows aggregates every role grant in the matrix into principal-type →
+// role-family → resource-type flows
+func BuildFlows(matrix *gcp.AccessMatrix) *FlowSummary {
+	userTypes := make(map[string]string, len(matrix.Users))
+	for _, user := range matrix.Users {
+		userTypes[user.Email] = user.Type
+	}
+
+	type flowKey struct{ principalType, roleFamily, resourceType string }
+	counts := make(map[flowKey]int)
+	for _, entry := range matrix.Access {
+		principalType := userTypes[entry.UserEmail]
+		if principalType == "" {
+			principalType = "other"
+		}
+		for _, role := range entry.Roles {
+			counts[flowKey{principalType, RoleFamily(role), entry.ResourceType}]++
+		}
+	}
+
+	summary := &FlowSummary{Flows: []Flow{}, Links: []FlowLink{}}
+	links := make(map[[2]string]int)
+	for key, count := range counts {
+		summary.Flows = append(summary.Flows, Flow{
+			PrincipalType: key.principalType,
+			RoleFamily:    key.roleFamily,
+			ResourceType:  key.resourceType,
+			Count:         count,
+		})
+		links[[2]string{"principal:" + key.principalType, "role:" + key.roleFamily}] += count
+		links[[2]string{"role:" + key.roleFamily, "resource:" + key.resourceType}] += count
+	}
+
+	for pair, value := range links {
+		summary.Links = append(summary.Links, FlowLink{Source: pair[0], Target: pair[1], Value: value})
+	}
+
+	sort.Slice(summary.Flows, func(i, j int) bool {
+		a, b := summary.Flows[i], summary.Flows[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.PrincipalType != b.PrincipalType {
+			return a.PrincipalType < b.PrincipalType
+		}
+		if a.RoleFamily != b.RoleFamily {
+			return a.RoleFamily < b.RoleFamily
+		}
+		return a.ResourceType < b.ResourceType
+	})
+	sort.Slice(summary.Links, func(i, j int) bool {
+		a, b := summary.Links[i], summary.Links[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+
+	return summary
+}
+
+// RoleFamily groups a role by the service it belongs to.
+// Basic roles map to "basic" and custom roles to "custom".
+func RoleFamily(role string) string {
+	switch {
+	case role == "roles/owner" || role == "roles/editor" || role == "roles/viewer":
+		return "basic"
+	case strings.HasPrefix(role, "projects/") || strings.HasPrefix(role, "organizations/"):
+		return "custom"
+	case strings.HasPrefix(role, "roles/"):
+		name := strings.TrimPrefix(role, "roles/")
+		if idx := strings.Index(name, "."); idx > 0 {
+			return name[:idx]
+		}
+		return name
+	}
+	return "other"
+}
@@ -0,0 +1,237 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// GraphNode is a principal, resource, or resource-type cluster in the access graph
+type GraphNode struct {
+	ID      string `json:"id"`
+	Label   string `json:"label"`
+	Kind    string `json:"kind"` // "principal", "resource", "cluster"
+	Type    string `json:"type"` // principal type or resource type
+	Degree  int    `json:"degree"`
+	Cluster string `json:"cluster"` // layout hint grouping nodes of the same type
+}
+
+// GraphEdge is one or more role grants from a principal to a resource.
+// Bundled edges point at a cluster node and aggregate Weight resources.
+// Derived edges start at a resource: "identity" edges point at the service
+// account a workload runs as, "invokes" edges at the resource it calls.
+type GraphEdge struct {
+	Source  string   `json:"source"`
+	Target  string   `json:"target"`
+	Kind    string   `json:"kind"` // "grant", "identity", "invokes"
+	Roles   []string `json:"roles"`
+	Weight  int      `json:"weight"`
+	Bundled bool     `json:"bundled"`
+}
+
+// Graph is the node/edge representation of an access matrix
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphOptions controls graph construction
+type GraphOptions struct {
+	// BundleThreshold bundles the edges of principals with more than this many
+	// resources into one edge per resource type. Zero disables bundling.
+	BundleThreshold int
+}
+
+// BuildGraph converts an access matrix into nodes and edges ready for layout
+func BuildGraph(matrix *gcp.AccessMatrix, opts GraphOptions) *Graph {
+	graph := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+
+	principalDegree := make(map[string]int)
+	resourceDegree := make(map[string]int)
+	for _, entry := range matrix.Access {
+		principalDegree[entry.UserEmail]++
+		resourceDegree[entry.ResourceID]++
+	}
+
+	bundled := func(email string) bool {
+		return opts.BundleThreshold > 0 && principalDegree[email] > opts.BundleThreshold
+	}
+
+	for _, user := range matrix.Users {
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:      principalNodeID(user.Email),
+			Label:   user.Email,
+			Kind:    "principal",
+			Type:    user.Type,
+			Degree:  principalDegree[user.Email],
+			Cluster: "principal:" + user.Type,
+		})
+	}
+
+	for _, res := range matrix.Resources {
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:      resourceNodeID(res.ID),
+			Label:   res.Name,
+			Kind:    "resource",
+			Type:    res.Type,
+			Degree:  resourceDegree[res.ID],
+			Cluster: "resource:" + res.Type,
+		})
+	}
+
+	// Bundles are keyed by principal and resource type
+	type bundleKey struct{ email, resourceType string }
+	bundles := make(map[bundleKey]*GraphEdge)
+	clusterDegree := make(map[string]int)
+
+	for _, entry := range matrix.Access {
+		if !bundled(entry.UserEmail) {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				Source: principalNodeID(entry.UserEmail),
+				Target: resourceNodeID(entry.ResourceID),
+				Kind:   "grant",
+				Roles:  entry.Roles,
+				Weight: 1,
+			})
+			continue
+		}
+
+		key := bundleKey{entry.UserEmail, entry.ResourceType}
+		edge, exists := bundles[key]
+		if !exists {
+			edge = &GraphEdge{
+				Source:  principalNodeID(entry.UserEmail),
+				Target:  clusterNodeID(entry.ResourceType),
+				Kind:    "grant",
+				Bundled: true,
+			}
+			bundles[key] = edge
+			clusterDegree[entry.ResourceType]++
+		}
+		edge.Weight++
+		edge.Roles = mergeRoles(edge.Roles, entry.Roles)
+	}
+
+	if len(bundles) > 0 {
+		keys := make([]bundleKey, 0, len(bundles))
+		for key := range bundles {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].email != keys[j].email {
+				return keys[i].email < keys[j].email
+			}
+			return keys[i].resourceType < keys[j].resourceType
+		})
+		for _, key := range keys {
+			graph.Edges = append(graph.Edges, *bundles[key])
+		}
+
+		clusterTypes := make([]string, 0, len(clusterDegree))
+		for resourceType := range clusterDegree {
+			clusterTypes = append(clusterTypes, resourceType)
+		}
+		sort.Strings(clusterTypes)
+		for _, resourceType := range clusterTypes {
+			graph.Nodes = append(graph.Nodes, GraphNode{
+				ID:      clusterNodeID(resourceType),
+				Label:   fmt.Sprintf("%s resources", resourceType),
+				Kind:    "cluster",
+				Type:    resourceType,
+				Degree:  clusterDegree[resourceType],
+				Cluster: "resource:" + resourceType,
+			})
+		}
+	}
+
+	addIdentityEdges(graph, matrix)
+
+	return graph
+}
+
+// addIdentityEdges links workloads (scheduler jobs, triggers, data pipelines, ...)
+// to the service account they run as and to the resource they invoke, so a path
+// "system uses identity X to invoke Y" can be followed through the graph
+func addIdentityEdges(graph *Graph, matrix *gcp.AccessMatrix) {
+	principals := make(map[string]bool, len(matrix.Users))
+	for _, user := range matrix.Users {
+		principals[user.Email] = true
+	}
+
+	for _, res := range matrix.Resources {
+		if res.RunAs != "" {
+			if !principals[res.RunAs] {
+				principals[res.RunAs] = true
+				graph.Nodes = append(graph.Nodes, GraphNode{
+					ID:      principalNodeID(res.RunAs),
+					Label:   res.RunAs,
+					Kind:    "principal",
+					Type:    "serviceAccount",
+					Cluster: "principal:serviceAccount",
+				})
+			}
+			graph.Edges = append(graph.Edges, GraphEdge{
+				Source: resourceNodeID(res.ID),
+				Target: principalNodeID(res.RunAs),
+				Kind:   "identity",
+				Roles:  []string{},
+				Weight: 1,
+			})
+		}
+
+		if target := invokedResource(matrix.Resources, res.Invokes); target != "" {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				Source: resourceNodeID(res.ID),
+				Target: resourceNodeID(target),
+				Kind:   "invokes",
+				Roles:  []string{},
+				Weight: 1,
+			})
+		}
+	}
+}
+
+// invokedResource returns the ID of the resource named by invokes, matching either
+// a collector ID or an Asset Inventory full name ("//run.googleapis.com/" + name)
+func invokedResource(resources []gcp.Resource, invokes string) string {
+	if invokes == "" {
+		return ""
+	}
+	for _, res := range resources {
+		if res.ID == invokes || strings.HasSuffix(res.ID, ".googleapis.com/"+invokes) {
+			return res.ID
+		}
+	}
+	return ""
+}
+
+func principalNodeID(email string) string {
+	return "principal:" + email
+}
+
+func resourceNodeID(resourceID string) string {
+	return "resource:" + resourceID
+}
+
+func clusterNodeID(resourceType string) string {
+	return "cluster:" + resourceType
+}
+
+// mergeRoles appends roles not already present in existing
+func mergeRoles(existing, roles []string) []string {
+	for _, role := range roles {
+		found := false
+		for _, r := range existing {
+			if r == role {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, role)
+		}
+	}
+	return existing
+}
@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// GroupSummary sizes up one group principal for review prioritization
+type GroupSummary struct {
+	Email string `json:"email"`
+	// MembersKnown is false when the group's members could not be read
+	MembersKnown      bool `json:"membersKnown"`
+	DirectMembers     int  `json:"directMembers"`
+	TransitiveMembers int  `json:"transitiveMembers"` // unique non-group members through any nesting
+	NestedGroups      int  `json:"nestedGroups"`
+	// DirectGrants counts (resource, role) grants bound to the group itself;
+	// IndirectGrants those it receives as a member of other bound groups
+	DirectGrants   int    `json:"directGrants"`
+	IndirectGrants int    `json:"indirectGrants"`
+	Resources      int    `json:"resources"` // distinct resources reached directly or indirectly
+	HighestTier    string `json:"highestTier,omitempty"`
+	ParentGroups   int    `json:"parentGroups"`
+}
+
+// SummarizeGroups summarizes every group principal in the matrix, largest and
+// most privileged first. memberships maps group emails to their direct members
+// as returned by gcp.Client.GetGroupMemberships.
+func SummarizeGroups(matrix *gcp.AccessMatrix, memberships map[string][]string) []GroupSummary {
+	type grants struct {
+		count     int
+		resources map[string]string // resource -> tier
+	}
+	byPrincipal := make(map[string]*grants)
+	for _, entry := range matrix.Access {
+		g := byPrincipal[entry.UserEmail]
+		if g == nil {
+			g = &grants{resources: make(map[string]string)}
+			byPrincipal[entry.UserEmail] = g
+		}
+		g.count += len(entry.Roles)
+		g.resources[entry.ResourceID] = gcp.MaxTier(g.resources[entry.ResourceID], entry.Tier)
+	}
+
+	parents := make(map[string][]string) // member -> groups directly containing it
+	for group, members := range memberships {
+		for _, member := range members {
+			parents[member] = append(parents[member], group)
+		}
+	}
+
+	summaries := []GroupSummary{}
+	for _, user := range matrix.Users {
+		if user.Type != "group" {
+			continue
+		}
+		summary := GroupSummary{Email: user.Email}
+		direct, known := memberships[user.Email]
+		summary.MembersKnown = known
+		summary.DirectMembers = len(direct)
+		summary.TransitiveMembers, summary.NestedGroups = countMembers(user.Email, memberships)
+
+		reached := make(map[string]string)
+		if g := byPrincipal[user.Email]; g != nil {
+			summary.DirectGrants = g.count
+			for id, tier := range g.resources {
+				reached[id] = tier
+			}
+		}
+		ancestors := ancestorGroups(user.Email, parents)
+		summary.ParentGroups = len(ancestors)
+		for _, ancestor := range ancestors {
+			g := byPrincipal[ancestor]
+			if g == nil {
+				continue
+			}
+			summary.IndirectGrants += g.count
+			for id, tier := range g.resources {
+				reached[id] = gcp.MaxTier(reached[id], tier)
+			}
+		}
+		summary.Resources = len(reached)
+		for _, tier := range reached {
+			summary.HighestTier = gcp.MaxTier(summary.HighestTier, tier)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.TransitiveMembers != b.TransitiveMembers {
+			return a.TransitiveMembers > b.TransitiveMembers
+		}
+		if gcp.TierRank(a.HighestTier) != gcp.TierRank(b.HighestTier) {
+			return gcp.TierRank(a.HighestTier) > gcp.TierRank(b.HighestTier)
+		}
+		return a.Email < b.Email
+	})
+	return summaries
+}
+
+// countMembers counts the unique non-group members and nested groups below a
+// group. Members that have memberships of their own are groups.
+func countMembers(group string, memberships map[string][]string) (members, groups int) {
+	seen := map[string]bool{group: true}
+	queue := []string{group}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, member := range memberships[current] {
+			if seen[member] {
+				continue
+			}
+			seen[member] = true
+			if _, isGroup := memberships[member]; isGroup {
+				groups++
+				queue = append(queue, member)
+			} else {
+				members++
+			}
+		}
+	}
+	return members, groups
+}
+
+// ancestorGroups returns every group that contains principal, directly or through nesting
+func ancestorGroups(principal string, parents map[string][]string) []string {
+	seen := map[string]bool{principal: true}
+	var ancestors []string
+	queue := []string{principal}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, parent := range parents[current] {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			ancestors = append(ancestors, parent)
+			queue = append(queue, parent)
+		}
+	}
+	return ancestors
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"sort"
+	"sync"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Heatmap row dimensions
+const (
+	HeatmapByType = "type" // principal type: user, serviceAccount, group, ...
+	HeatmapByTeam = "team" // enriched team, or owner for service accounts
+)
+
+// noTeam labels principals without team or owner metadata
+const noTeam = "(none)"
+
+// HeatmapCell aggregates the access entries of one row and resource type
+type HeatmapCell struct {
+	Row        int    `json:"row"`    // index into Heatmap.Rows
+	Column     int    `json:"column"` // index into Heatmap.Columns
+	Entries    int    `json:"entries"`
+	Principals int    `json:"principals"`
+	Resources  int    `json:"resources"`
+	MaxTier    string `json:"maxTier"`
+}
+
+// Heatmap is a principal dimension × resource type aggregation of the access
+// matrix. Cells are sparse: combinations without access are omitted.
+type Heatmap struct {
+	RowsBy  string        `json:"rowsBy"`
+	Rows    []string      `json:"rows"`
+	Columns []string      `json:"columns"`
+	Cells   []HeatmapCell `json:"cells"`
+}
+
+// BuildHeatmap aggregates the matrix by rowsBy (HeatmapByType or
+// HeatmapByTeam) and resource type, with rows and columns sorted by name
+func BuildHeatmap(matrix *gcp.AccessMatrix, rowsBy string) *Heatmap {
+	users := make(map[string]gcp.User, len(matrix.Users))
+	for _, user := range matrix.Users {
+		users[user.Email] = user
+	}
+	rowOf := func(email string) string {
+		user, ok := users[email]
+		if !ok {
+			user = gcp.ParseMember(email)
+		}
+		if rowsBy == HeatmapByTeam {
+			switch {
+			case user.Team != "":
+				return user.Team
+			case user.Owner != "":
+				return user.Owner
+			}
+			return noTeam
+		}
+		return user.Type
+	}
+
+	type cellKey struct{ row, column string }
+	type cellData struct {
+		entries    int
+		principals map[string]bool
+		resources  map[string]bool
+		tier       string
+	}
+	cells := make(map[cellKey]*cellData)
+	rowSet := make(map[string]bool)
+	columnSet := make(map[string]bool)
+	for _, entry := range matrix.Access {
+		key := cellKey{rowOf(entry.UserEmail), entry.ResourceType}
+		cell := cells[key]
+		if cell == nil {
+			cell = &cellData{principals: make(map[string]bool), resources: make(map[string]bool)}
+			cells[key] = cell
+			rowSet[key.row] = true
+			columnSet[key.column] = true
+		}
+		cell.entries++
+		cell.principals[entry.UserEmail] = true
+		cell.resources[entry.ResourceID] = true
+		cell.tier = gcp.MaxTier(cell.tier, entry.Tier)
+	}
+
+	heatmap := &Heatmap{RowsBy: rowsBy, Rows: sortedSet(rowSet), Columns: sortedSet(columnSet), Cells: []HeatmapCell{}}
+	rowIndex := indexOf(heatmap.Rows)
+	columnIndex := indexOf(heatmap.Columns)
+	for key, cell := range cells {
+		heatmap.Cells = append(heatmap.Cells, HeatmapCell{
+			Row:        rowIndex[key.row],
+			Column:     columnIndex[key.column],
+			Entries:    cell.entries,
+			Principals: len(cell.principals),
+			Resources:  len(cell.resources),
+			MaxTier:    cell.tier,
+		})
+	}
+	sort.Slice(heatmap.Cells, func(i, j int) bool {
+		a, b := heatmap.Cells[i], heatmap.Cells[j]
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Column < b.Column
+	})
+	return heatmap
+}
+
+// HeatmapCache keeps the heatmaps of the latest snapshot so they are built
+// once per scan rather than per request
+type HeatmapCache struct {
+	mu         sync.Mutex
+	snapshotID string
+	heatmaps   map[string]*Heatmap // rowsBy -> heatmap
+}
+
+// Get returns the heatmap of the snapshot by rowsBy, building it on first use
+func (c *HeatmapCache) Get(snapshotID string, matrix *gcp.AccessMatrix, rowsBy string) *Heatmap {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshotID != snapshotID {
+		c.snapshotID = snapshotID
+		c.heatmaps = make(map[string]*Heatmap)
+	}
+	heatmap, ok := c.heatmaps[rowsBy]
+	if !ok {
+		heatmap = BuildHeatmap(matrix, rowsBy)
+		c.heatmaps[rowsBy] = heatmap
+	}
+	return heatmap
+}
+
+// sortedSet returns the members of set sorted
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// indexOf maps each value to its position in values
+func indexOf(values []string) map[string]int {
+	index := make(map[string]int, len(values))
+	for i, value := range values {
+		index[value] = i
+	}
+	return index
+}
@@ -0,0 +1,325 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Path step kinds
+const (
+	StepMembership    = "membership"    // the principal is covered by a group, domain, convenience, or public member
+	StepBinding       = "binding"       // a member holds a role on a resource
+	StepInherited     = "inherited"     // a project-level binding is inherited by the resource
+	StepImpersonation = "impersonation" // the principal can act as a service account
+)
+
+// PathStep is one hop of an access path
+type PathStep struct {
+	Kind string `json:"kind"`
+	From string `json:"from"` // principal or resource the hop starts at
+	To   string `json:"to"`   // group, resource, or service account the hop ends at
+	Role string `json:"role,omitempty"`
+	// Conditions restrict a conditional binding: it applies while any of them
+	// holds. Empty for unconditional bindings.
+	Conditions []gcp.Condition `json:"conditions,omitempty"`
+}
+
+// AccessPath is an ordered chain of steps from a principal to a resource
+type AccessPath struct {
+	Steps []PathStep `json:"steps"`
+	// Role is the role that finally grants access to the resource
+	Role string `json:"role"`
+}
+
+// key identifies a path for de-duplication
+func (p AccessPath) key() string {
+	var b strings.Builder
+	for _, step := range p.Steps {
+		b.WriteString(step.Kind + ">" + step.From + ">" + step.To + ">" + step.Role + "|")
+	}
+	return b.String()
+}
+
+// Binding identifies one member of one role binding on a resource
+type Binding struct {
+	Principal  string `json:"principal"` // member email, without the "user:" style prefix
+	Role       string `json:"role"`
+	ResourceID string `json:"resourceId"`
+}
+
+// identity is a principal the searched principal acts as, with the steps that led there
+type identity struct {
+	email string
+	chain []PathStep
+}
+
+// PathIndex resolves how principals reach resources from the raw IAM bindings
+// kept on the matrix resources, following group membership, project-level
+// inheritance, and one hop of service account impersonation
+type PathIndex struct {
+	resources map[string]*gcp.Resource
+	order     []string // resource IDs in matrix order
+	project   *gcp.Resource
+	groups    map[string][]string // group email -> direct member emails
+	// serviceAccounts maps service account emails to their resource, when scanned
+	serviceAccounts map[string]*gcp.Resource
+}
+
+// NewPathIndex indexes a matrix. groups maps group emails to their direct members
+// and may be nil when membership is unknown.
+func NewPathIndex(matrix *gcp.AccessMatrix, groups map[string][]string) *PathIndex {
+	index := &PathIndex{
+		resources:       make(map[string]*gcp.Resource, len(matrix.Resources)),
+		groups:          groups,
+		serviceAccounts: make(map[string]*gcp.Resource),
+	}
+
+	for i := range matrix.Resources {
+		res := &matrix.Resources[i]
+		index.resources[res.ID] = res
+		index.order = append(index.order, res.ID)
+		switch res.Type {
+		case "project":
+			index.project = res
+		case "serviceaccount":
+			index.serviceAccounts[serviceAccountEmail(res)] = res
+		}
+	}
+
+	// Service accounts without a policy of their own are only known as principals
+	for _, user := range matrix.Users {
+		if user.Type == "serviceAccount" {
+			if _, ok := index.serviceAccounts[user.Email]; !ok {
+				index.serviceAccounts[user.Email] = nil
+			}
+		}
+	}
+	return index
+}
+
+// lastSegment returns the final path segment of a resource name
+func lastSegment(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// serviceAccountEmail extracts the email from a service account resource
+func serviceAccountEmail(res *gcp.Resource) string {
+	if i := strings.LastIndex(res.ID, "/"); i >= 0 && strings.Contains(res.ID[i+1:], "@") {
+		return res.ID[i+1:]
+	}
+	return res.Name
+}
+
+// Paths returns every distinct path by which principal reaches the resource,
+// ignoring the excluded binding if one is given
+func (x *PathIndex) Paths(principal, resourceID string, exclude *Binding) []AccessPath {
+	res, ok := x.resources[resourceID]
+	if !ok {
+		return []AccessPath{}
+	}
+
+	paths := x.bindingPaths(x.identities(principal), res, exclude)
+
+	// One hop of impersonation: act as a service account that reaches the resource
+	for _, hop := range x.impersonations(principal, exclude) {
+		for _, path := range x.bindingPaths(x.identities(hop.email), res, exclude) {
+			steps := append(append([]PathStep{}, hop.chain...), path.Steps...)
+			paths = append(paths, AccessPath{Steps: steps, Role: path.Role})
+		}
+	}
+
+	return dedupePaths(paths)
+}
+
+// Reaches reports whether principal reaches the resource at all
+func (x *PathIndex) Reaches(principal, resourceID string, exclude *Binding) bool {
+	return len(x.Paths(principal, resourceID, exclude)) > 0
+}
+
+// identities returns the principal itself and every group, convenience, domain,
+// and public member that covers it, each with the membership steps that lead there
+func (x *PathIndex) identities(principal string) []identity {
+	result := []identity{{email: principal}}
+	seen := map[string]bool{principal: true}
+
+	// Breadth-first through (possibly nested) groups
+	for i := 0; i < len(result); i++ {
+		current := result[i]
+		for group, members := range x.groups {
+			if seen[group] || !contains(members, current.email) {
+				continue
+			}
+			seen[group] = true
+			result = append(result, identity{
+				email: group,
+				chain: appendStep(current.chain, PathStep{Kind: StepMembership, From: current.email, To: group}),
+			})
+		}
+	}
+
+	// Holders of a basic project role are covered by the matching convenience
+	// member (projectEditor:my-project, ...) used in legacy bucket policies,
+	// which may name the project by ID or number
+	if x.project != nil {
+		projects := []string{lastSegment(x.project.ID)}
+		if number := strings.TrimPrefix(x.project.Project, "projects/"); number != "" && number != projects[0] {
+			projects = append(projects, number)
+		}
+		for _, current := range append([]identity(nil), result...) {
+			for _, role := range boundRoles(x.project, current.email, nil) {
+				for _, project := range projects {
+					member := gcp.ConvenienceMemberFor(role, project)
+					if member == "" || seen[member] {
+						continue
+					}
+					seen[member] = true
+					result = append(result, identity{
+						email: member,
+						chain: appendStep(current.chain, PathStep{Kind: StepMembership, From: current.email, To: member}),
+					})
+				}
+			}
+		}
+	}
+
+	if at := strings.LastIndex(principal, "@"); at >= 0 {
+		domain := principal[at+1:]
+		result = append(result, identity{
+			email: domain,
+			chain: []PathStep{{Kind: StepMembership, From: principal, To: "domain:" + domain}},
+		})
+	}
+	for _, public := range []string{"allAuthenticatedUsers", "allUsers"} {
+		result = append(result, identity{
+			email: public,
+			chain: []PathStep{{Kind: StepMembership, From: principal, To: public}},
+		})
+	}
+	return result
+}
+
+// bindingPaths finds direct and project-inherited bindings of any identity on res
+func (x *PathIndex) bindingPaths(identities []identity, res *gcp.Resource, exclude *Binding) []AccessPath {
+	var paths []AccessPath
+	for _, id := range identities {
+		for _, role := range boundRoles(res, id.email, exclude) {
+			step := PathStep{Kind: StepBinding, From: id.email, To: res.ID, Role: role, Conditions: res.MemberConditions(role, id.email)}
+			paths = append(paths, AccessPath{
+				Steps: appendStep(id.chain, step),
+				Role:  role,
+			})
+		}
+
+		if x.project == nil || x.project.ID == res.ID {
+			continue
+		}
+		for _, role := range boundRoles(x.project, id.email, exclude) {
+			if !gcp.RoleAppliesTo(role, res.Type) {
+				continue
+			}
+			steps := appendStep(id.chain, PathStep{Kind: StepBinding, From: id.email, To: x.project.ID, Role: role, Conditions: x.project.MemberConditions(role, id.email)})
+			steps = append(steps, PathStep{Kind: StepInherited, From: x.project.ID, To: res.ID, Role: role})
+			paths = append(paths, AccessPath{Steps: steps, Role: role})
+		}
+	}
+	return paths
+}
+
+// impersonations returns the service accounts principal can act as, each as an
+// identity whose chain ends with the impersonation step
+func (x *PathIndex) impersonations(principal string, exclude *Binding) []identity {
+	var result []identity
+	for email, grants := range x.serviceAccountGrants(principal, exclude) {
+		for _, grant := range grants {
+			if !contains(ImpersonationRoles, grant.Role) {
+				continue
+			}
+			result = append(result, identity{
+				email: email,
+				chain: appendStep(grant.Steps, PathStep{Kind: StepImpersonation, From: principal, To: email, Role: grant.Role}),
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].email < result[j].email })
+	return result
+}
+
+// serviceAccountGrants returns, per service account other than principal, the
+// paths by which principal holds a role on it
+func (x *PathIndex) serviceAccountGrants(principal string, exclude *Binding) map[string][]AccessPath {
+	result := make(map[string][]AccessPath)
+	for email, res := range x.serviceAccounts {
+		if email == principal {
+			continue
+		}
+
+		var grants []AccessPath
+		if res != nil {
+			grants = x.bindingPaths(x.identities(principal), res, exclude)
+		} else if x.project != nil && strings.HasSuffix(email, "@"+lastSegment(x.project.ID)+".iam.gserviceaccount.com") {
+			// Unscanned service accounts of the project only inherit project-level grants
+			for _, id := range x.identities(principal) {
+				for _, role := range boundRoles(x.project, id.email, exclude) {
+					if gcp.RoleAppliesTo(role, "serviceaccount") {
+						grants = append(grants, AccessPath{
+							Steps: appendStep(id.chain, PathStep{Kind: StepBinding, From: id.email, To: x.project.ID, Role: role, Conditions: x.project.MemberConditions(role, id.email)}),
+							Role:  role,
+						})
+					}
+				}
+			}
+		}
+		if len(grants) > 0 {
+			result[email] = grants
+		}
+	}
+	return result
+}
+
+// boundRoles returns the roles on res that list member, skipping the excluded binding
+func boundRoles(res *gcp.Resource, member string, exclude *Binding) []string {
+	var roles []string
+	for role, members := range res.IAM {
+		if role == "inherited" {
+			continue
+		}
+		if exclude != nil && exclude.ResourceID == res.ID && exclude.Role == role && exclude.Principal == member {
+			continue
+		}
+		for _, m := range members {
+			if gcp.ParseMember(m).Email == member {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// appendStep returns chain with step appended, never sharing chain's backing array
+func appendStep(chain []PathStep, step PathStep) []PathStep {
+	return append(append(make([]PathStep, 0, len(chain)+1), chain...), step)
+}
+
+// dedupePaths removes repeated paths and orders them shortest first
+func dedupePaths(paths []AccessPath) []AccessPath {
+	seen := make(map[string]bool, len(paths))
+	result := []AccessPath{}
+	for _, path := range paths {
+		if key := path.key(); !seen[key] {
+			seen[key] = true
+			result = append(result, path)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Steps) != len(result[j].Steps) {
+			return len(result[i].Steps) < len(result[j].Steps)
+		}
+		return result[i].key() < result[j].key()
+	})
+	return result
+}
@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Peer group kinds
+const (
+	PeersByTeam  = "team"  // principals sharing enriched team metadata
+	PeersByGroup = "group" // the direct non-group members of a Google group
+)
+
+// minPeerGroupSize is the smallest peer group a baseline is computed for
+const minPeerGroupSize = 2
+
+// PeerGrant is one (resource, role) grant held by members of a peer group
+type PeerGrant struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Role         string `json:"role"`
+	Tier         string `json:"tier"`
+	Holders      int    `json:"holders"` // members of the peer group holding the grant
+}
+
+// PeerDeviation lists a member's grants above the peer group baseline
+type PeerDeviation struct {
+	Principal   string      `json:"principal"`
+	HighestTier string      `json:"highestTier"`
+	Grants      []PeerGrant `json:"grants"`
+}
+
+// PeerGroup is a peer group's baseline access and its members' deviations
+type PeerGroup struct {
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	// Baseline holds the grants held by at least the baseline share of members
+	Baseline   []PeerGrant     `json:"baseline"`
+	Deviations []PeerDeviation `json:"deviations"` // members without deviations are omitted
+}
+
+// PeerGroups computes the baseline access of every peer group of the given
+// kind and each member's direct grants outside it, groups with the most
+// deviations first. name, if set, narrows to one team or group. share is the
+// fraction of members, in (0, 1], that must hold a grant for it to be part of
+// the baseline. memberships is as returned by gcp.Client.GetGroupMemberships
+// and is only needed for PeersByGroup. tier returns a role's privilege tier.
+func PeerGroups(matrix *gcp.AccessMatrix, kind, name string, memberships map[string][]string, share float64, tier func(role string) string) []PeerGroup {
+	isGroup := make(map[string]bool)
+	for _, user := range matrix.Users {
+		if user.Type == "group" {
+			isGroup[strings.ToLower(user.Email)] = true
+		}
+	}
+	for group := range memberships {
+		isGroup[strings.ToLower(group)] = true
+	}
+
+	peers := make(map[string][]string) // peer group name -> members
+	switch kind {
+	case PeersByTeam:
+		for _, user := range matrix.Users {
+			if user.Team != "" && (user.Type == "user" || user.Type == "serviceAccount") {
+				peers[user.Team] = append(peers[user.Team], user.Email)
+			}
+		}
+	case PeersByGroup:
+		for group, members := range memberships {
+			for _, member := range members {
+				if !isGroup[strings.ToLower(member)] {
+					peers[group] = append(peers[group], member)
+				}
+			}
+		}
+	}
+
+	type grantKey struct{ resource, role string }
+	grants := make(map[string]map[grantKey]gcp.AccessEntry) // principal -> grants
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			held := grants[strings.ToLower(entry.UserEmail)]
+			if held == nil {
+				held = make(map[grantKey]gcp.AccessEntry)
+				grants[strings.ToLower(entry.UserEmail)] = held
+			}
+			held[grantKey{entry.ResourceID, role}] = entry
+		}
+	}
+	peerGrant := func(key grantKey, entry gcp.AccessEntry, holders int) PeerGrant {
+		return PeerGrant{
+			ResourceID:   key.resource,
+			ResourceName: entry.ResourceName,
+			ResourceType: entry.ResourceType,
+			Role:         key.role,
+			Tier:         tier(key.role),
+			Holders:      holders,
+		}
+	}
+	sortGrants := func(list []PeerGrant) {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.Tier != b.Tier {
+				return gcp.TierRank(a.Tier) > gcp.TierRank(b.Tier)
+			}
+			if a.ResourceID != b.ResourceID {
+				return a.ResourceID < b.ResourceID
+			}
+			return a.Role < b.Role
+		})
+	}
+
+	groups := []PeerGroup{}
+	for groupName, members := range peers {
+		if name != "" && !strings.EqualFold(groupName, name) {
+			continue
+		}
+		members = dedupeSorted(members)
+		if len(members) < minPeerGroupSize {
+			continue
+		}
+
+		counts := make(map[grantKey]int)
+		entries := make(map[grantKey]gcp.AccessEntry)
+		for _, member := range members {
+			for key, entry := range grants[strings.ToLower(member)] {
+				counts[key]++
+				entries[key] = entry
+			}
+		}
+		threshold := share * float64(len(members))
+
+		group := PeerGroup{Kind: kind, Name: groupName, Members: members, Baseline: []PeerGrant{}, Deviations: []PeerDeviation{}}
+		for key, count := range counts {
+			if float64(count) >= threshold {
+				group.Baseline = append(group.Baseline, peerGrant(key, entries[key], count))
+			}
+		}
+		sortGrants(group.Baseline)
+
+		for _, member := range members {
+			deviation := PeerDeviation{Principal: member}
+			for key, entry := range grants[strings.ToLower(member)] {
+				if float64(counts[key]) >= threshold {
+					continue
+				}
+				grant := peerGrant(key, entry, counts[key])
+				deviation.Grants = append(deviation.Grants, grant)
+				deviation.HighestTier = gcp.MaxTier(deviation.HighestTier, grant.Tier)
+			}
+			if len(deviation.Grants) > 0 {
+				sortGrants(deviation.Grants)
+				group.Deviations = append(group.Deviations, deviation)
+			}
+		}
+		sort.SliceStable(group.Deviations, func(i, j int) bool {
+			a, b := group.Deviations[i], group.Deviations[j]
+			if a.HighestTier != b.HighestTier {
+				return gcp.TierRank(a.HighestTier) > gcp.TierRank(b.HighestTier)
+			}
+			return len(a.Grants) > len(b.Grants)
+		})
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if len(a.Deviations) != len(b.Deviations) {
+			return len(a.Deviations) > len(b.Deviations)
+		}
+		return a.Name < b.Name
+	})
+	return groups
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// SpecialPrincipalFindings flags grants to the special members that do not name
+// an identity: allUsers (anyone on the internet), allAuthenticatedUsers (any
+// Google account, which anyone can create), and the projectOwner, projectEditor,
+// and projectViewer convenience members that silently extend a grant to every
+// holder of a basic project role.
+func SpecialPrincipalFindings(matrix *gcp.AccessMatrix) []Finding {
+	findings := []Finding{}
+	for _, entry := range matrix.Access {
+		member := gcp.ParseMember(entry.UserEmail)
+		privileged := privilegedTier(entry.Tier)
+
+		var finding Finding
+		switch member.Type {
+		case "allUsers":
+			severity := SeverityHigh
+			if privileged {
+				severity = SeverityCritical
+			}
+			finding = newFinding("public-binding", severity, "allUsers on "+entry.ResourceID,
+				fmt.Sprintf("%s is public", entry.ResourceName),
+				fmt.Sprintf("Anyone on the internet, without signing in, can %s %s. Remove allUsers unless the resource is meant to be public.", tierVerb(entry.Tier), entry.ResourceName))
+		case "allAuthenticatedUsers":
+			severity := SeverityMedium
+			if privileged {
+				severity = SeverityHigh
+			}
+			finding = newFinding("authenticated-binding", severity, "allAuthenticatedUsers on "+entry.ResourceID,
+				fmt.Sprintf("%s is open to any Google account", entry.ResourceName),
+				fmt.Sprintf("Any signed-in Google account, not just accounts in your organization, can %s %s. Grant a group or domain instead.", tierVerb(entry.Tier), entry.ResourceName))
+		case "projectOwner", "projectEditor", "projectViewer":
+			if !privileged {
+				continue
+			}
+			role, _ := gcp.ConvenienceRole(member.Type)
+			project := gcp.ConvenienceProject(entry.UserEmail)
+			finding = newFinding("convenience-binding", SeverityMedium, entry.UserEmail+" on "+entry.ResourceID,
+				fmt.Sprintf("Every %s holder of %s can %s %s", role, project, tierVerb(entry.Tier), entry.ResourceName),
+				fmt.Sprintf("The convenience member %s extends %s to everyone granted %s on project %s, now or later. Bind the principals that need it directly.", entry.UserEmail, strings.Join(entry.Roles, ", "), role, project))
+		default:
+			continue
+		}
+
+		finding.Details = map[string]string{
+			"principal":  entry.UserEmail,
+			"resourceId": entry.ResourceID,
+			"roles":      strings.Join(entry.Roles, ","),
+			"tier":       entry.Tier,
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// ExternalOwnerFindings flags principals outside the trusted domains that hold
+// roles/owner on a project: an outsider with full control over everything in it
+func ExternalOwnerFindings(matrix *gcp.AccessMatrix, trustedDomains []string) []Finding {
+	userTypes := make(map[string]string, len(matrix.Users))
+	for _, user := range matrix.Users {
+		userTypes[user.Email] = user.Type
+	}
+
+	findings := []Finding{}
+	for _, entry := range matrix.Access {
+		if entry.ResourceType != "project" || !contains(entry.Roles, "roles/owner") {
+			continue
+		}
+		if !IsExternalPrincipal(entry.UserEmail, userTypes[entry.UserEmail], trustedDomains) {
+			continue
+		}
+		finding := newFinding("external-owner", SeverityCritical, entry.UserEmail+" on "+entry.ResourceID,
+			fmt.Sprintf("External principal %s owns %s", entry.UserEmail, entry.ResourceName),
+			fmt.Sprintf("%s is outside your trusted domains and holds roles/owner on %s, which lets it change any resource and grant access to anyone. Remove the binding unless the ownership is intended.", entry.UserEmail, entry.ResourceName))
+		finding.Details = map[string]string{
+			"principal":  entry.UserEmail,
+			"resourceId": entry.ResourceID,
+			"roles":      strings.Join(entry.Roles, ","),
+			"tier":       entry.Tier,
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// tierVerb phrases a privilege tier as what a principal can do, e.g. "modify"
+func tierVerb(tier string) string {
+	if verb, ok := tierVerbs[tier]; ok {
+		return verb
+	}
+	return "access"
+}
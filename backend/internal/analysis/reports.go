@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Top report metrics
+const (
+	TopPrincipals = "principals" // principals by number of resources they can access
+	TopRoles      = "roles"      // roles by number of grants
+	TopResources  = "resources"  // resources by number of principals with access
+	TopOwners     = "owners"     // service account owners by number of resources their accounts reach
+	TopRegions    = "regions"    // regions by number of grants on resources located there
+)
+
+// TopItem is one ranked row of a top-N report
+type TopItem struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Type  string `json:"type,omitempty"`
+	Count int    `json:"count"`
+}
+
+// TopN ranks principals, roles, or resources in the matrix and returns the first limit items.
+// It returns false if the metric is unknown.
+func TopN(matrix *gcp.AccessMatrix, metric string, limit int) ([]TopItem, bool) {
+	var items []TopItem
+
+	switch metric {
+	case TopPrincipals:
+		counts := make(map[string]int)
+		for _, entry := range matrix.Access {
+			counts[entry.UserEmail]++
+		}
+		for _, user := range matrix.Users {
+			if counts[user.Email] > 0 {
+				items = append(items, TopItem{Key: user.Email, Label: principalLabel(user), Type: user.Type, Count: counts[user.Email]})
+			}
+		}
+
+	case TopRoles:
+		counts := make(map[string]int)
+		for _, entry := range matrix.Access {
+			for _, role := range entry.Roles {
+				counts[role]++
+			}
+		}
+		for role, count := range counts {
+			items = append(items, TopItem{Key: role, Label: role, Type: RoleFamily(role), Count: count})
+		}
+
+	case TopResources:
+		counts := make(map[string]int)
+		for _, entry := range matrix.Access {
+			counts[entry.ResourceID]++
+		}
+		for _, res := range matrix.Resources {
+			if counts[res.ID] > 0 {
+				items = append(items, TopItem{Key: res.ID, Label: res.Name, Type: res.Type, Count: counts[res.ID]})
+			}
+		}
+
+	case TopOwners:
+		owners := make(map[string]string, len(matrix.Users))
+		for _, user := range matrix.Users {
+			if user.Type == "serviceAccount" {
+				owner := user.Owner
+				if owner == "" {
+					owner = "unattributed"
+				}
+				owners[user.Email] = owner
+			}
+		}
+		// A resource reached by several of an owner's accounts counts once
+		reached := make(map[string]map[string]struct{})
+		for _, entry := range matrix.Access {
+			owner, ok := owners[entry.UserEmail]
+			if !ok {
+				continue
+			}
+			if reached[owner] == nil {
+				reached[owner] = make(map[string]struct{})
+			}
+			reached[owner][entry.ResourceID] = struct{}{}
+		}
+		for owner, resources := range reached {
+			items = append(items, TopItem{Key: owner, Label: owner, Type: "owner", Count: len(resources)})
+		}
+
+	case TopRegions:
+		regions := make(map[string]string, len(matrix.Resources))
+		for _, res := range matrix.Resources {
+			region := res.Region
+			if region == "" {
+				region = "global"
+			}
+			regions[res.ID] = region
+		}
+		counts := make(map[string]int)
+		for _, entry := range matrix.Access {
+			counts[regions[entry.ResourceID]]++
+		}
+		for region, count := range counts {
+			items = append(items, TopItem{Key: region, Label: region, Type: "region", Count: count})
+		}
+
+	default:
+		return nil, false
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []TopItem{}
+	}
+	return items, true
+}
+
+// principalLabel returns the most human-friendly name known for a principal
+func principalLabel(user gcp.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Email
+}
@@ -0,0 +1,152 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// consumerDomains are treated as external when no trusted domains are configured
+var consumerDomains = []string{"gmail.com", "googlemail.com"}
+
+// ScoreInputs carries data the score needs beyond the access matrix
+type ScoreInputs struct {
+	TrustedDomains []string
+	// UserManagedKeys is the number of active user-managed service account keys,
+	// or -1 if they could not be listed
+	UserManagedKeys int
+}
+
+// ScoreComponent is one penalty contributing to the least-privilege score
+type ScoreComponent struct {
+	Name       string  `json:"name"`
+	Value      float64 `json:"value"` // raw metric, e.g. a ratio or a count
+	Penalty    float64 `json:"penalty"`
+	MaxPenalty float64 `json:"maxPenalty"`
+	Available  bool    `json:"available"`
+	Detail     string  `json:"detail"`
+}
+
+// Score is a 0-100 least-privilege score for a project; higher is better
+type Score struct {
+	Project    string           `json:"project"`
+	Score      float64          `json:"score"`
+	Components []ScoreComponent `json:"components"`
+}
+
+// ComputeScore scores a project's least-privilege posture from its access matrix
+func ComputeScore(project string, matrix *gcp.AccessMatrix, inputs ScoreInputs) Score {
+	userTypes := make(map[string]string, len(matrix.Users))
+	for _, user := range matrix.Users {
+		userTypes[user.Email] = user.Type
+	}
+
+	totalGrants, basicGrants := 0, 0
+	allUsers, allAuthenticated := 0, 0
+	external := make(map[string]bool)
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			totalGrants++
+			if gcp.RoleKind(role) == "basic" {
+				basicGrants++
+			}
+		}
+		switch entry.UserEmail {
+		case "allUsers":
+			allUsers++
+		case "allAuthenticatedUsers":
+			allAuthenticated++
+		}
+		if IsExternalPrincipal(entry.UserEmail, userTypes[entry.UserEmail], inputs.TrustedDomains) {
+			external[entry.UserEmail] = true
+		}
+	}
+
+	basicRatio := 0.0
+	if totalGrants > 0 {
+		basicRatio = float64(basicGrants) / float64(totalGrants)
+	}
+
+	components := []ScoreComponent{
+		{
+			Name:       "basicRoles",
+			Value:      basicRatio,
+			Penalty:    30 * basicRatio,
+			MaxPenalty: 30,
+			Available:  true,
+			Detail:     "Share of grants using basic roles (owner/editor/viewer) instead of granular roles",
+		},
+		{
+			Name:       "unusedGrants",
+			MaxPenalty: 15,
+			Detail:     "Grants with no recorded usage; requires activity data",
+		},
+		{
+			Name:       "publicBindings",
+			Value:      float64(allUsers + allAuthenticated),
+			Penalty:    math.Min(20, 10*float64(allUsers)+5*float64(allAuthenticated)),
+			MaxPenalty: 20,
+			Available:  true,
+			Detail:     "Resources granting access to allUsers (anyone on the internet) or, at half the weight, allAuthenticatedUsers (any Google account)",
+		},
+		{
+			Name:       "externalPrincipals",
+			Value:      float64(len(external)),
+			Penalty:    math.Min(20, 2*float64(len(external))),
+			MaxPenalty: 20,
+			Available:  true,
+			Detail:     "Users and groups outside the trusted domains",
+		},
+		{
+			Name:       "serviceAccountKeys",
+			MaxPenalty: 15,
+			Detail:     "Active user-managed service account keys",
+		},
+	}
+	if inputs.UserManagedKeys >= 0 {
+		keys := &components[4]
+		keys.Value = float64(inputs.UserManagedKeys)
+		keys.Penalty = math.Min(15, 3*float64(inputs.UserManagedKeys))
+		keys.Available = true
+	}
+
+	score := 100.0
+	for _, component := range components {
+		score -= component.Penalty
+	}
+
+	return Score{
+		Project:    project,
+		Score:      math.Round(math.Max(0, score)*10) / 10,
+		Components: components,
+	}
+}
+
+// IsPublicPrincipal reports whether a member grants access to everyone
+func IsPublicPrincipal(email string) bool {
+	return email == "allUsers" || email == "allAuthenticatedUsers"
+}
+
+// IsExternalPrincipal reports whether a live user, group, or domain is outside the trusted domains.
+// Without trusted domains, only consumer accounts such as gmail.com count as external.
+func IsExternalPrincipal(email, principalType string, trustedDomains []string) bool {
+	if principalType != "user" && principalType != "group" && principalType != "domain" {
+		return false
+	}
+	if _, _, _, deleted := gcp.DeletedMember(email); deleted {
+		return false // a deleted principal's bindings grant nothing
+	}
+
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	if len(trustedDomains) == 0 {
+		return contains(consumerDomains, domain)
+	}
+	for _, trusted := range trustedDomains {
+		trusted = strings.ToLower(trusted)
+		if domain == trusted || strings.HasSuffix(domain, "."+trusted) {
+			return false
+		}
+	}
+	return true
+}
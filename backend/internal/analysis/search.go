@@ -0,0 +1,223 @@
+package analysis
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Search result types
+const (
+	ResultPrincipal = "principal"
+	ResultResource  = "resource"
+	ResultRole      = "role"
+	ResultProject   = "project"
+)
+
+// ResultTypes lists the search result types
+var ResultTypes = []string{ResultPrincipal, ResultResource, ResultRole, ResultProject}
+
+// SearchResult is one match of a global search
+type SearchResult struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Link     string `json:"link"` // API path with the details of the result
+	Score    int    `json:"score"`
+}
+
+// SearchIndex is an in-memory inverted index over the principals, resources,
+// roles, and projects of a snapshot
+type SearchIndex struct {
+	documents []SearchResult
+	fields    [][]string       // per document, its lowercased searchable fields
+	postings  map[string][]int // token -> documents containing it
+	tokens    []string         // sorted keys of postings, for prefix lookups
+}
+
+// NewSearchIndex indexes principal emails, names, teams, and owners; resource
+// IDs, names, types, and locations; role names and titles; and project IDs,
+// numbers, names, and labels. roleTitle returns a role's title, or "" when unknown.
+func NewSearchIndex(matrix *gcp.AccessMatrix, projects []gcp.ProjectInfo, roleTitle func(role string) string) *SearchIndex {
+	index := &SearchIndex{postings: make(map[string][]int)}
+
+	for _, user := range matrix.Users {
+		link := "/api/access?principal=" + url.QueryEscape(user.Email)
+		if user.Type == "group" {
+			link = "/api/groups/" + url.PathEscape(user.Email)
+		}
+		title := user.Email
+		if user.DisplayName != "" {
+			title = user.DisplayName + " <" + user.Email + ">"
+		}
+		index.add(SearchResult{Type: ResultPrincipal, ID: user.Email, Title: title, Subtitle: subtitle(user.Type, user.Team), Link: link},
+			user.Email, user.DisplayName, user.Team, user.Owner)
+	}
+
+	roles := make(map[string]bool)
+	for _, resource := range matrix.Resources {
+		index.add(SearchResult{
+			Type:     ResultResource,
+			ID:       resource.ID,
+			Title:    resource.Name,
+			Subtitle: subtitle(resource.Type, resource.Location),
+			Link:     "/api/search?resource=" + url.QueryEscape(resource.ID),
+		}, resource.ID, resource.Name, resource.Type, resource.Location)
+		for role := range resource.IAM {
+			roles[role] = true
+		}
+	}
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			roles[role] = true
+		}
+	}
+	delete(roles, "inherited")
+	for role := range roles {
+		title := roleTitle(role)
+		index.add(SearchResult{Type: ResultRole, ID: role, Title: role, Subtitle: title, Link: "/api/access?role=" + url.QueryEscape(role)},
+			role, title)
+	}
+
+	for _, project := range projects {
+		fields := []string{project.ID, project.Number, project.DisplayName}
+		for key, value := range project.Labels {
+			fields = append(fields, key+":"+value)
+		}
+		index.add(SearchResult{
+			Type:     ResultProject,
+			ID:       project.ID,
+			Title:    project.DisplayName,
+			Subtitle: project.ID,
+			Link:     "/api/projects/" + url.PathEscape(project.ID),
+		}, fields...)
+	}
+
+	index.tokens = make([]string, 0, len(index.postings))
+	for token := range index.postings {
+		index.tokens = append(index.tokens, token)
+	}
+	sort.Strings(index.tokens)
+	return index
+}
+
+// add indexes a document under the tokens of its fields
+func (s *SearchIndex) add(result SearchResult, fields ...string) {
+	id := len(s.documents)
+	s.documents = append(s.documents, result)
+
+	var lowered []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		lowered = append(lowered, strings.ToLower(field))
+		for _, token := range tokenize(field) {
+			if !seen[token] {
+				seen[token] = true
+				s.postings[token] = append(s.postings[token], id)
+			}
+		}
+	}
+	s.fields = append(s.fields, lowered)
+}
+
+// Search returns the documents matching every term of query, best first.
+// A term matches a token it is a prefix of; whole-token and whole-field
+// matches rank higher. types, if non-empty, restricts the result types.
+func (s *SearchIndex) Search(query string, types []string, limit int) []SearchResult {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []SearchResult{}
+	}
+
+	var scores map[int]int
+	for _, term := range terms {
+		matched := make(map[int]int)
+		start := sort.SearchStrings(s.tokens, term)
+		for _, token := range s.tokens[start:] {
+			if !strings.HasPrefix(token, term) {
+				break
+			}
+			weight := 1
+			if token == term {
+				weight = 2
+			}
+			for _, id := range s.postings[token] {
+				if weight > matched[id] {
+					matched[id] = weight
+				}
+			}
+		}
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for id, score := range scores {
+			if weight, ok := matched[id]; ok {
+				scores[id] = score + weight
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	whole := strings.ToLower(strings.TrimSpace(query))
+	results := []SearchResult{}
+	for id, score := range scores {
+		result := s.documents[id]
+		if len(types) > 0 && !contains(types, result.Type) {
+			continue
+		}
+		bonus := 0
+		for _, field := range s.fields[id] {
+			if field == whole {
+				bonus = 10
+				break
+			}
+			if strings.HasPrefix(field, whole) {
+				bonus = 5
+			}
+		}
+		result.Score = score + bonus
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.ID) != len(b.ID) {
+			return len(a.ID) < len(b.ID)
+		}
+		return a.ID < b.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// subtitle joins the non-empty parts of a result subtitle
+func subtitle(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, " · ")
+}
+
+// tokenize splits text into lowercased runs of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,333 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/rules"
+)
+
+// ImpersonationRoles grant the ability to act as or mint tokens for a service account
+var ImpersonationRoles = []string{
+	"roles/owner",
+	"roles/editor",
+	"roles/iam.serviceAccountTokenCreator",
+	"roles/iam.serviceAccountUser",
+	"roles/iam.workloadIdentityUser",
+}
+
+// ImpactedResource is a resource affected by a simulated change
+type ImpactedResource struct {
+	ResourceID   string   `json:"resourceId"`
+	ResourceName string   `json:"resourceName"`
+	ResourceType string   `json:"resourceType"`
+	Roles        []string `json:"roles"`
+}
+
+// SoleMemberBinding is a role binding whose only member is the simulated principal
+type SoleMemberBinding struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Role         string `json:"role"`
+}
+
+// RemovalImpact describes what breaks if a principal is removed
+type RemovalImpact struct {
+	Principal string `json:"principal"`
+	// ResourcesLost counts resources the principal can currently access
+	ResourcesLost int `json:"resourcesLost"`
+	// SoleAdministrator lists resources nobody else administers (admin tier or above)
+	SoleAdministrator []ImpactedResource `json:"soleAdministrator"`
+	// SoleImpersonator lists service accounts nobody else can impersonate
+	SoleImpersonator []ImpactedResource `json:"soleImpersonator"`
+	// SoleMemberBindings lists bindings that become empty without the principal
+	SoleMemberBindings []SoleMemberBinding `json:"soleMemberBindings"`
+}
+
+// SimulateRemovePrincipal computes the impact of removing a principal from every binding
+func SimulateRemovePrincipal(matrix *gcp.AccessMatrix, email string) RemovalImpact {
+	impact := RemovalImpact{
+		Principal:          email,
+		SoleAdministrator:  []ImpactedResource{},
+		SoleImpersonator:   []ImpactedResource{},
+		SoleMemberBindings: []SoleMemberBinding{},
+	}
+
+	// Index the other principals' access per resource
+	otherAdmins := make(map[string]bool)
+	otherImpersonators := make(map[string]bool)
+	var own []gcp.AccessEntry
+	for _, entry := range matrix.Access {
+		if entry.UserEmail == email {
+			own = append(own, entry)
+			continue
+		}
+		if gcp.TierRank(entry.Tier) >= gcp.TierRank(gcp.TierAdmin) {
+			otherAdmins[entry.ResourceID] = true
+		}
+		if hasAnyRole(entry.Roles, ImpersonationRoles) {
+			otherImpersonators[entry.ResourceID] = true
+		}
+	}
+
+	impact.ResourcesLost = len(own)
+	for _, entry := range own {
+		resource := ImpactedResource{
+			ResourceID:   entry.ResourceID,
+			ResourceName: entry.ResourceName,
+			ResourceType: entry.ResourceType,
+			Roles:        entry.Roles,
+		}
+		if gcp.TierRank(entry.Tier) >= gcp.TierRank(gcp.TierAdmin) && !otherAdmins[entry.ResourceID] {
+			impact.SoleAdministrator = append(impact.SoleAdministrator, resource)
+		}
+		if entry.ResourceType == "serviceaccount" && hasAnyRole(entry.Roles, ImpersonationRoles) && !otherImpersonators[entry.ResourceID] {
+			impact.SoleImpersonator = append(impact.SoleImpersonator, resource)
+		}
+	}
+
+	for _, res := range matrix.Resources {
+		for role, members := range res.IAM {
+			if len(members) == 1 && gcp.ParseMember(members[0]).Email == email {
+				impact.SoleMemberBindings = append(impact.SoleMemberBindings, SoleMemberBinding{
+					ResourceID:   res.ID,
+					ResourceName: res.Name,
+					ResourceType: res.Type,
+					Role:         role,
+				})
+			}
+		}
+	}
+
+	sortImpacted(impact.SoleAdministrator)
+	sortImpacted(impact.SoleImpersonator)
+	sort.Slice(impact.SoleMemberBindings, func(i, j int) bool {
+		a, b := impact.SoleMemberBindings[i], impact.SoleMemberBindings[j]
+		if a.ResourceID != b.ResourceID {
+			return a.ResourceID < b.ResourceID
+		}
+		return a.Role < b.Role
+	})
+	return impact
+}
+
+// grantScope returns the resources a binding of role on target reaches: the
+// target and, for a project, the child resources the role applies to
+func grantScope(matrix *gcp.AccessMatrix, target gcp.Resource, role string) []gcp.Resource {
+	scope := []gcp.Resource{target}
+	if target.Type == "project" {
+		for _, res := range matrix.Resources {
+			if res.ID != target.ID && gcp.RoleAppliesTo(role, res.Type) {
+				scope = append(scope, res)
+			}
+		}
+	}
+	return scope
+}
+
+// hasAnyRole reports whether roles contains any of candidates
+func hasAnyRole(roles, candidates []string) bool {
+	for _, role := range roles {
+		if contains(candidates, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortImpacted orders impacted resources by ID
+func sortImpacted(resources []ImpactedResource) {
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].ResourceID < resources[j].ResourceID
+	})
+}
+
+// BindingRemoval describes the effect of removing one member from one role binding
+type BindingRemoval struct {
+	Binding Binding `json:"binding"`
+	// Exists is false when the binding is not present in the scanned policies
+	Exists bool `json:"exists"`
+	// StillReaches reports whether the principal reaches the resource via another path
+	StillReaches   bool         `json:"stillReaches"`
+	RemovedPaths   []AccessPath `json:"removedPaths"`
+	RemainingPaths []AccessPath `json:"remainingPaths"`
+	// LostResources lists resources the principal can no longer reach at all,
+	// including children that inherited a removed project-level binding
+	LostResources []ImpactedResource `json:"lostResources"`
+}
+
+// SimulateRemoveBinding recomputes effective access as if the binding were removed
+func SimulateRemoveBinding(matrix *gcp.AccessMatrix, index *PathIndex, binding Binding) BindingRemoval {
+	result := BindingRemoval{
+		Binding:        binding,
+		RemovedPaths:   []AccessPath{},
+		RemainingPaths: []AccessPath{},
+		LostResources:  []ImpactedResource{},
+	}
+
+	target, ok := index.resources[binding.ResourceID]
+	if !ok {
+		return result
+	}
+	result.Exists = contains(boundRoles(target, binding.Principal, nil), binding.Role)
+
+	before := index.Paths(binding.Principal, binding.ResourceID, nil)
+	after := index.Paths(binding.Principal, binding.ResourceID, &binding)
+	remaining := make(map[string]bool, len(after))
+	for _, path := range after {
+		remaining[path.key()] = true
+	}
+	for _, path := range before {
+		if !remaining[path.key()] {
+			result.RemovedPaths = append(result.RemovedPaths, path)
+		}
+	}
+	result.RemainingPaths = after
+	result.StillReaches = len(after) > 0
+
+	// A project-level binding is inherited by every resource type the role applies to
+	candidates := []*gcp.Resource{target}
+	if target.Type == "project" {
+		for _, id := range index.order {
+			res := index.resources[id]
+			if res.ID != target.ID && gcp.RoleAppliesTo(binding.Role, res.Type) {
+				candidates = append(candidates, res)
+			}
+		}
+	}
+
+	roles := make(map[string][]string)
+	for _, entry := range matrix.Access {
+		if entry.UserEmail == binding.Principal {
+			roles[entry.ResourceID] = entry.Roles
+		}
+	}
+	for _, res := range candidates {
+		if index.Reaches(binding.Principal, res.ID, nil) && !index.Reaches(binding.Principal, res.ID, &binding) {
+			result.LostResources = append(result.LostResources, ImpactedResource{
+				ResourceID:   res.ID,
+				ResourceName: res.Name,
+				ResourceType: res.Type,
+				Roles:        roles[res.ID],
+			})
+		}
+	}
+
+	return result
+}
+
+// GainedResource is a resource a proposed grant would give or widen access to
+type GainedResource struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	CurrentTier  string `json:"currentTier,omitempty"` // empty when the principal has no access today
+	NewTier      string `json:"newTier"`
+	NewAccess    bool   `json:"newAccess"`
+	Escalates    bool   `json:"escalates"` // the grant raises the privilege tier
+}
+
+// GrantImpact describes the blast radius of a proposed binding
+type GrantImpact struct {
+	Binding  Binding `json:"binding"`
+	RoleTier string  `json:"roleTier"`
+	// Watchlisted is true when the role is on the high-risk watchlist
+	Watchlisted bool             `json:"watchlisted"`
+	Resources   []GainedResource `json:"resources"`
+	// SoDViolations lists separation-of-duties violations the grant would introduce
+	SoDViolations []SoDViolation `json:"sodViolations"`
+}
+
+// SimulateGrant computes what a principal would gain from a new binding of role on
+// the resource (a project-level binding is inherited by matching child resources)
+// and which rules the resulting access would violate
+func SimulateGrant(matrix *gcp.AccessMatrix, binding Binding, roleTier string, sodRules []rules.SoDRule, watchlist []string) GrantImpact {
+	impact := GrantImpact{
+		Binding:       binding,
+		RoleTier:      roleTier,
+		Watchlisted:   contains(watchlist, binding.Role),
+		Resources:     []GainedResource{},
+		SoDViolations: []SoDViolation{},
+	}
+
+	var target *gcp.Resource
+	for i := range matrix.Resources {
+		if matrix.Resources[i].ID == binding.ResourceID {
+			target = &matrix.Resources[i]
+		}
+	}
+	if target == nil {
+		return impact
+	}
+
+	scope := grantScope(matrix, *target, binding.Role)
+
+	current := make(map[string]gcp.AccessEntry)
+	for _, entry := range matrix.Access {
+		if entry.UserEmail == binding.Principal {
+			current[entry.ResourceID] = entry
+		}
+	}
+
+	// Build the hypothetical matrix alongside the list of gained resources
+	after := &gcp.AccessMatrix{
+		Users:     matrix.Users,
+		Resources: matrix.Resources,
+		Access:    make([]gcp.AccessEntry, 0, len(matrix.Access)+len(scope)),
+	}
+	for _, entry := range matrix.Access {
+		if entry.UserEmail != binding.Principal {
+			after.Access = append(after.Access, entry)
+		}
+	}
+	granted := make(map[string]bool, len(scope))
+	for _, res := range scope {
+		granted[res.ID] = true
+		entry, exists := current[res.ID]
+		if exists && contains(entry.Roles, binding.Role) {
+			continue
+		}
+
+		newTier := gcp.MaxTier(entry.Tier, roleTier)
+		impact.Resources = append(impact.Resources, GainedResource{
+			ResourceID:   res.ID,
+			ResourceName: res.Name,
+			ResourceType: res.Type,
+			CurrentTier:  entry.Tier,
+			NewTier:      newTier,
+			NewAccess:    !exists,
+			Escalates:    gcp.TierRank(newTier) > gcp.TierRank(entry.Tier),
+		})
+		after.Access = append(after.Access, gcp.AccessEntry{
+			UserEmail:    binding.Principal,
+			ResourceID:   res.ID,
+			ResourceName: res.Name,
+			ResourceType: res.Type,
+			Roles:        append(append([]string{}, entry.Roles...), binding.Role),
+			Tier:         newTier,
+		})
+	}
+	for id, entry := range current {
+		if !granted[id] || contains(entry.Roles, binding.Role) {
+			after.Access = append(after.Access, entry)
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, violation := range EvaluateSoD(matrix, sodRules) {
+		if violation.Principal == binding.Principal {
+			existing[violation.RuleID] = true
+		}
+	}
+	for _, violation := range EvaluateSoD(after, sodRules) {
+		if violation.Principal == binding.Principal && !existing[violation.RuleID] {
+			impact.SoDViolations = append(impact.SoDViolations, violation)
+		}
+	}
+
+	sort.Slice(impact.Resources, func(i, j int) bool {
+		return impact.Resources[i].ResourceID < impact.Resources[j].ResourceID
+	})
+	return impact
+}
@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/rules"
+)
+
+// RoleHolding is a role a principal holds and the resources it holds it on
+type RoleHolding struct {
+	Role      string   `json:"role"`
+	Resources []string `json:"resources"`
+}
+
+// SoDViolation reports a principal holding conflicting roles
+type SoDViolation struct {
+	RuleID    string        `json:"ruleId"`
+	RuleName  string        `json:"ruleName"`
+	Severity  string        `json:"severity"`
+	Principal string        `json:"principal"`
+	SideA     []RoleHolding `json:"sideA"`
+	SideB     []RoleHolding `json:"sideB"`
+}
+
+// EvaluateSoD checks every principal against the separation-of-duties rules
+func EvaluateSoD(matrix *gcp.AccessMatrix, sodRules []rules.SoDRule) []SoDViolation {
+	holdings := principalRoleResources(matrix)
+
+	principals := make([]string, 0, len(holdings))
+	for principal := range holdings {
+		principals = append(principals, principal)
+	}
+	sort.Strings(principals)
+
+	violations := []SoDViolation{}
+	for _, rule := range sodRules {
+		for _, principal := range principals {
+			roles := holdings[principal]
+			sideA := collectHoldings(roles, rule.RolesA)
+			sideB := collectHoldings(roles, rule.RolesB)
+			if len(sideA) == 0 || len(sideB) == 0 {
+				continue
+			}
+			violations = append(violations, SoDViolation{
+				RuleID:    rule.ID,
+				RuleName:  rule.Name,
+				Severity:  rule.Severity,
+				Principal: principal,
+				SideA:     sideA,
+				SideB:     sideB,
+			})
+		}
+	}
+	return violations
+}
+
+// principalRoleResources indexes principal -> role -> resource IDs
+func principalRoleResources(matrix *gcp.AccessMatrix) map[string]map[string][]string {
+	holdings := make(map[string]map[string][]string)
+	for _, entry := range matrix.Access {
+		roles, ok := holdings[entry.UserEmail]
+		if !ok {
+			roles = make(map[string][]string)
+			holdings[entry.UserEmail] = roles
+		}
+		for _, role := range entry.Roles {
+			roles[role] = append(roles[role], entry.ResourceID)
+		}
+	}
+	return holdings
+}
+
+// collectHoldings returns the holdings for each of the wanted roles the principal has
+func collectHoldings(roles map[string][]string, wanted []string) []RoleHolding {
+	var result []RoleHolding
+	for _, role := range wanted {
+		if resources, ok := roles[role]; ok {
+			sorted := append([]string(nil), resources...)
+			sort.Strings(sorted)
+			result = append(result, RoleHolding{Role: role, Resources: sorted})
+		}
+	}
+	return result
+}
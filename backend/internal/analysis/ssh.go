@@ -0,0 +1,185 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// Permissions that let a principal log in to a VM or reach it
+const (
+	permOSLogin        = "compute.instances.osLogin"
+	permOSAdminLogin   = "compute.instances.osAdminLogin"
+	permSetMetadata    = "compute.instances.setMetadata"
+	permSetProjectKeys = "compute.projects.setCommonInstanceMetadata"
+	permIAPTunnel      = "iap.tunnelInstances.accessViaIAP"
+	sshViaOSLogin      = "os-login"
+	sshViaOSAdminLogin = "os-admin-login"
+	sshViaInstanceKeys = "instance-metadata"
+	sshViaProjectKeys  = "project-metadata"
+)
+
+// SSHGrant is a principal that can log in to a VM
+type SSHGrant struct {
+	Principal string `json:"principal"`
+	// Via lists how the principal gets a login: "os-login", "os-admin-login",
+	// or, without OS Login, "instance-metadata" and "project-metadata" for
+	// principals that can add their own SSH keys
+	Via []string `json:"via"`
+	// Admin is set when the login has sudo: OS admin login, or any key added
+	// through metadata
+	Admin bool     `json:"admin"`
+	Roles []string `json:"roles"`
+	// IAP is set when the principal may tunnel to the VM through IAP TCP forwarding
+	IAP bool `json:"iap"`
+	// CanSSH is set when the principal can both log in and reach the VM: it has
+	// an external IP (subject to firewall rules) or the principal may use IAP
+	CanSSH bool `json:"canSsh"`
+}
+
+// VMSSHAccess lists who can SSH to a VM
+type VMSSHAccess struct {
+	ResourceID string         `json:"resourceId"`
+	Name       string         `json:"name"`
+	Location   string         `json:"location"`
+	Config     *gcp.SSHConfig `json:"config"` // nil when the metadata could not be read
+	Principals []SSHGrant     `json:"principals"`
+}
+
+// SSHAccess derives, for every VM, which principals can SSH to it: through
+// OS Login roles when OS Login is enabled, otherwise by adding SSH keys to the
+// instance or project metadata. IAP TCP forwarding permission on the project
+// decides whether principals can reach VMs without an external IP.
+// permissions returns a role's permissions.
+func SSHAccess(matrix *gcp.AccessMatrix, permissions func(role string) []string) []VMSSHAccess {
+	holds := permissionChecker(permissions)
+
+	// Project-level roles cover project metadata and IAP tunnels to every instance
+	projectRoles := make(map[string][]string)
+	for _, entry := range matrix.Access {
+		if entry.ResourceType == "project" {
+			projectRoles[entry.UserEmail] = append(projectRoles[entry.UserEmail], entry.Roles...)
+		}
+	}
+
+	vmRoles := make(map[string]map[string][]string) // resource -> principal -> roles
+	for _, entry := range matrix.Access {
+		if entry.ResourceType != "vm" {
+			continue
+		}
+		if vmRoles[entry.ResourceID] == nil {
+			vmRoles[entry.ResourceID] = make(map[string][]string)
+		}
+		vmRoles[entry.ResourceID][entry.UserEmail] = entry.Roles
+	}
+
+	results := []VMSSHAccess{}
+	for _, resource := range matrix.Resources {
+		if resource.Type != "vm" {
+			continue
+		}
+		access := VMSSHAccess{
+			ResourceID: resource.ID,
+			Name:       resource.Name,
+			Location:   resource.Location,
+			Config:     resource.SSH,
+			Principals: []SSHGrant{},
+		}
+		if resource.SSH == nil {
+			results = append(results, access)
+			continue
+		}
+
+		principals := make(map[string]bool)
+		for principal := range vmRoles[resource.ID] {
+			principals[principal] = true
+		}
+		for principal := range projectRoles {
+			principals[principal] = true
+		}
+
+		for principal := range principals {
+			grant := sshGrant(principal, resource.SSH, vmRoles[resource.ID][principal], projectRoles[principal], holds)
+			if len(grant.Via) > 0 {
+				access.Principals = append(access.Principals, grant)
+			}
+		}
+		sort.Slice(access.Principals, func(i, j int) bool {
+			return access.Principals[i].Principal < access.Principals[j].Principal
+		})
+		results = append(results, access)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ResourceID < results[j].ResourceID })
+	return results
+}
+
+// sshGrant works out how one principal can log in to a VM given its roles on
+// the instance (including those inherited from the project) and on the project
+func sshGrant(principal string, config *gcp.SSHConfig, instanceRoles, projectRoles []string, holds func(roles []string, permission string) []string) SSHGrant {
+	grant := SSHGrant{Principal: principal, Via: []string{}, Roles: []string{}}
+	add := func(via string, roles []string, admin bool) {
+		grant.Via = append(grant.Via, via)
+		grant.Roles = appendUnique(grant.Roles, roles...)
+		grant.Admin = grant.Admin || admin
+	}
+
+	if config.OSLogin {
+		if roles := holds(instanceRoles, permOSAdminLogin); len(roles) > 0 {
+			add(sshViaOSAdminLogin, roles, true)
+		} else if roles := holds(instanceRoles, permOSLogin); len(roles) > 0 {
+			add(sshViaOSLogin, roles, false)
+		}
+	} else {
+		if roles := holds(instanceRoles, permSetMetadata); len(roles) > 0 {
+			add(sshViaInstanceKeys, roles, true)
+		}
+		if !config.BlockProjectKeys {
+			if roles := holds(projectRoles, permSetProjectKeys); len(roles) > 0 {
+				add(sshViaProjectKeys, roles, true)
+			}
+		}
+	}
+	if len(grant.Via) == 0 {
+		return grant
+	}
+
+	if roles := holds(append(append([]string{}, projectRoles...), instanceRoles...), permIAPTunnel); len(roles) > 0 {
+		grant.IAP = true
+		grant.Roles = appendUnique(grant.Roles, roles...)
+	}
+	grant.CanSSH = config.ExternalIP || grant.IAP
+	return grant
+}
+
+// permissionChecker returns a function listing which of the given roles grant
+// a permission, resolving each role's permissions once
+func permissionChecker(permissions func(role string) []string) func(roles []string, permission string) []string {
+	resolved := make(map[string]map[string]bool)
+	return func(roles []string, permission string) []string {
+		var granting []string
+		for _, role := range roles {
+			perms, ok := resolved[role]
+			if !ok {
+				perms = make(map[string]bool)
+				for _, p := range permissions(role) {
+					perms[p] = true
+				}
+				resolved[role] = perms
+			}
+			if perms[permission] && !contains(granting, role) {
+				granting = append(granting, role)
+			}
+		}
+		return granting
+	}
+}
+
+// appendUnique appends the values not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if !contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
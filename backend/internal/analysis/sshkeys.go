@@ -0,0 +1,190 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// emailPattern finds an email address in an SSH key comment
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// SSHKeyHolding is a metadata SSH key with the VMs it logs in to and the
+// principal it is attributed to
+type SSHKeyHolding struct {
+	gcp.SSHKey
+	// Principal is the principal the key is attributed to, "" when unknown
+	Principal string `json:"principal,omitempty"`
+	// MappedBy is how the key was attributed: "google-ssh" (the email gcloud
+	// records), "comment", or "username"
+	MappedBy string `json:"mappedBy,omitempty"`
+	// HasIAMAccess is set when the attributed principal holds any role in the
+	// matrix; a key whose owner lost all roles still logs in
+	HasIAMAccess bool `json:"hasIamAccess"`
+	Expired      bool `json:"expired"`
+	// Instances are the VMs the key logs in to: OS Login is off and, for
+	// project keys, project keys are not blocked
+	Instances []string `json:"instances"`
+	// Inert lists VMs holding the key that ignore it because of OS Login
+	Inert []string `json:"inert,omitempty"`
+}
+
+// SSHKeyInventory collects the metadata SSH keys of every VM, one entry per
+// key and scope, and attributes each to a principal where possible
+func SSHKeyInventory(matrix *gcp.AccessMatrix, now time.Time) []SSHKeyHolding {
+	principals := sshKeyPrincipals(matrix)
+
+	byKey := make(map[string]*SSHKeyHolding)
+	for _, resource := range matrix.Resources {
+		if resource.Type != "vm" || resource.SSH == nil {
+			continue
+		}
+		for _, key := range resource.SSH.Keys {
+			id := key.Scope + "|" + key.Username + "|" + key.Fingerprint
+			holding := byKey[id]
+			if holding == nil {
+				holding = &SSHKeyHolding{SSHKey: key, Instances: []string{}}
+				holding.Expired = !key.ExpireOn.IsZero() && key.ExpireOn.Before(now)
+				holding.Principal, holding.MappedBy = principals.attribute(key)
+				holding.HasIAMAccess = holding.Principal != "" && principals.withAccess[holding.Principal]
+				byKey[id] = holding
+			}
+			if resource.SSH.OSLogin {
+				holding.Inert = append(holding.Inert, resource.ID)
+			} else {
+				holding.Instances = append(holding.Instances, resource.ID)
+			}
+		}
+	}
+
+	inventory := make([]SSHKeyHolding, 0, len(byKey))
+	for _, holding := range byKey {
+		sort.Strings(holding.Instances)
+		sort.Strings(holding.Inert)
+		inventory = append(inventory, *holding)
+	}
+	sort.Slice(inventory, func(i, j int) bool {
+		a, b := inventory[i], inventory[j]
+		if a.Scope != b.Scope {
+			return a.Scope > b.Scope // project keys first
+		}
+		if a.Username != b.Username {
+			return a.Username < b.Username
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+	return inventory
+}
+
+// SSHKeyFindings reports metadata SSH keys that log in to VMs. Such keys are
+// checked by the VM, not IAM: removing the owner's roles does not revoke them.
+func SSHKeyFindings(inventory []SSHKeyHolding) []Finding {
+	findings := []Finding{}
+	for _, holding := range inventory {
+		if len(holding.Instances) == 0 || holding.Expired {
+			continue
+		}
+
+		severity := SeverityMedium
+		owner := "The key belongs to " + holding.Principal + "."
+		switch {
+		case holding.Principal == "":
+			severity = SeverityHigh
+			owner = "No principal could be attributed to the key."
+		case !holding.HasIAMAccess:
+			severity = SeverityHigh
+			owner = "The key belongs to " + holding.Principal + ", who holds no IAM role."
+		}
+		subject := holding.Scope + " key " + holding.Username + " " + holding.Fingerprint
+		finding := newFinding("metadata-ssh-key", severity, subject,
+			fmt.Sprintf("SSH key for %s in %s metadata bypasses IAM on %d VMs", holding.Username, holding.Scope, len(holding.Instances)),
+			fmt.Sprintf("%s It logs in as %s with sudo wherever OS Login is off, whatever IAM grants. Enable OS Login or remove the key from the %s metadata.", owner, holding.Username, holding.Scope))
+		finding.Details = map[string]string{
+			"scope":       holding.Scope,
+			"username":    holding.Username,
+			"fingerprint": holding.Fingerprint,
+			"instances":   strconv.Itoa(len(holding.Instances)),
+		}
+		if holding.Principal != "" {
+			finding.Details["principal"] = holding.Principal
+			finding.Details["mappedBy"] = holding.MappedBy
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// keyPrincipals indexes the user and service account principals of a matrix
+// for attributing SSH keys
+type keyPrincipals struct {
+	emails     map[string]string   // lowercased email -> email
+	usernames  map[string][]string // POSIX-style username -> emails
+	withAccess map[string]bool
+}
+
+func sshKeyPrincipals(matrix *gcp.AccessMatrix) keyPrincipals {
+	principals := keyPrincipals{
+		emails:     make(map[string]string),
+		usernames:  make(map[string][]string),
+		withAccess: make(map[string]bool),
+	}
+	for _, user := range matrix.Users {
+		if user.Type != "user" && user.Type != "serviceAccount" {
+			continue
+		}
+		email := strings.ToLower(user.Email)
+		principals.emails[email] = user.Email
+
+		// OS Login and gcloud derive usernames from the email, e.g.
+		// "alice_example_com" or the local part "alice"
+		local, _, _ := strings.Cut(email, "@")
+		for _, username := range []string{posixUsername(email), posixUsername(local)} {
+			if !contains(principals.usernames[username], user.Email) {
+				principals.usernames[username] = append(principals.usernames[username], user.Email)
+			}
+		}
+	}
+	for _, entry := range matrix.Access {
+		principals.withAccess[entry.UserEmail] = true
+	}
+	return principals
+}
+
+// attribute finds the principal a key belongs to: the google-ssh email, an
+// email in the comment, or a username matching exactly one principal.
+// Emails of principals outside the matrix are returned as they are.
+func (p keyPrincipals) attribute(key gcp.SSHKey) (string, string) {
+	if key.Email != "" {
+		if email, ok := p.emails[strings.ToLower(key.Email)]; ok {
+			return email, "google-ssh"
+		}
+		return key.Email, "google-ssh"
+	}
+	for _, word := range strings.Fields(key.Comment) {
+		if email, ok := p.emails[strings.ToLower(word)]; ok {
+			return email, "comment"
+		}
+	}
+	if email := emailPattern.FindString(key.Comment); email != "" {
+		return email, "comment" // an email outside the matrix
+	}
+	if matches := p.usernames[strings.ToLower(key.Username)]; len(matches) == 1 {
+		return matches[0], "username"
+	}
+	return "", ""
+}
+
+// posixUsername replaces the characters Linux usernames cannot hold with "_"
+func posixUsername(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(s))
+}
@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// TerminatedBinding is a role binding a terminated user still holds
+type TerminatedBinding struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+	Role         string `json:"role"`
+	Member       string `json:"member"`
+	// Command removes the binding with gcloud; empty for resource types
+	// gcloud cannot edit directly
+	Command string `json:"command,omitempty"`
+}
+
+// TerminatedUser is what a terminated employee can still reach: their own
+// bindings, the groups they are still a member of, and the service accounts
+// attributed to them with their user-managed keys
+type TerminatedUser struct {
+	Email    string              `json:"email"`
+	Bindings []TerminatedBinding `json:"bindings"`
+	// Groups are the bound groups the user is a direct member of; empty when
+	// group membership was not expanded
+	Groups          []string                `json:"groups"`
+	ServiceAccounts []string                `json:"serviceAccounts"`
+	Keys            []gcp.ServiceAccountKey `json:"keys"`
+	// Plan lists the remediation steps in order
+	Plan []string `json:"plan"`
+}
+
+// TerminatedUsers reconciles a list of terminated employees against the
+// matrix. memberships maps groups to their direct members as returned by
+// gcp.Client.GetGroupMemberships and may be nil; keys are the service account
+// keys of the project. Only users with remaining access are returned.
+func TerminatedUsers(matrix *gcp.AccessMatrix, emails []string, memberships map[string][]string, keys []gcp.ServiceAccountKey) []TerminatedUser {
+	byEmail := make(map[string]*TerminatedUser, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(email)
+		byEmail[email] = &TerminatedUser{
+			Email:           email,
+			Bindings:        []TerminatedBinding{},
+			Groups:          []string{},
+			ServiceAccounts: []string{},
+			Keys:            []gcp.ServiceAccountKey{},
+		}
+	}
+
+	for _, resource := range matrix.Resources {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				user := byEmail[strings.ToLower(gcp.ParseMember(member).Email)]
+				if user == nil {
+					continue
+				}
+				user.Bindings = append(user.Bindings, TerminatedBinding{
+					ResourceID:   resource.ID,
+					ResourceName: resource.Name,
+					ResourceType: resource.Type,
+					Role:         role,
+					Member:       member,
+					Command:      RemoveBindingCommand(resource.ID, role, member),
+				})
+			}
+		}
+	}
+
+	for group, members := range memberships {
+		for _, member := range members {
+			if user := byEmail[strings.ToLower(member)]; user != nil {
+				user.Groups = append(user.Groups, group)
+			}
+		}
+	}
+
+	owned := make(map[string]*TerminatedUser)
+	for _, account := range matrix.Users {
+		if account.Type != "serviceAccount" {
+			continue
+		}
+		if user := byEmail[strings.ToLower(account.Owner)]; user != nil {
+			user.ServiceAccounts = append(user.ServiceAccounts, account.Email)
+			owned[account.Email] = user
+		}
+	}
+	for _, key := range keys {
+		if user := owned[key.ServiceAccount]; user != nil && !key.Disabled {
+			user.Keys = append(user.Keys, key)
+		}
+	}
+
+	terminated := []TerminatedUser{}
+	for _, user := range byEmail {
+		if len(user.Bindings) == 0 && len(user.Groups) == 0 && len(user.ServiceAccounts) == 0 {
+			continue
+		}
+		sort.Slice(user.Bindings, func(i, j int) bool {
+			a, b := user.Bindings[i], user.Bindings[j]
+			if a.ResourceID != b.ResourceID {
+				return a.ResourceID < b.ResourceID
+			}
+			return a.Role < b.Role
+		})
+		sort.Strings(user.Groups)
+		sort.Strings(user.ServiceAccounts)
+		user.Plan = terminationPlan(user)
+		terminated = append(terminated, *user)
+	}
+	sort.Slice(terminated, func(i, j int) bool { return terminated[i].Email < terminated[j].Email })
+	return terminated
+}
+
+// terminationPlan orders the remediation of a terminated user: first cut off
+// credentials that outlive the account, then hand over what they owned, then
+// remove their memberships and bindings
+func terminationPlan(user *TerminatedUser) []string {
+	var plan []string
+	for _, key := range user.Keys {
+		plan = append(plan, fmt.Sprintf("gcloud iam service-accounts keys disable %s --iam-account='%s'", key.KeyID, key.ServiceAccount))
+	}
+	for _, account := range user.ServiceAccounts {
+		plan = append(plan, fmt.Sprintf("# Assign a new owner to %s: PUT /api/admin/service-accounts/%s/owner", account, account))
+	}
+	for _, group := range user.Groups {
+		plan = append(plan, fmt.Sprintf("gcloud identity groups memberships delete --group-email='%s' --member-email='%s'", group, user.Email))
+	}
+	for _, binding := range user.Bindings {
+		if binding.Command == "" {
+			plan = append(plan, fmt.Sprintf("# Edit the IAM policy of %s to remove %s from %s", binding.ResourceID, binding.Member, binding.Role))
+			continue
+		}
+		plan = append(plan, binding.Command)
+	}
+	return plan
+}
@@ -0,0 +1,104 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/rules"
+)
+
+// ToxicMatch is a condition of a toxic rule that a principal meets
+type ToxicMatch struct {
+	Condition   string   `json:"condition"`
+	Permissions []string `json:"permissions"` // the condition's permissions the principal holds
+	Roles       []string `json:"roles"`       // the roles contributing those permissions
+}
+
+// ToxicCombination reports a principal whose effective permissions meet every
+// condition of a toxic rule
+type ToxicCombination struct {
+	RuleID    string       `json:"ruleId"`
+	RuleName  string       `json:"ruleName"`
+	Severity  string       `json:"severity"`
+	Principal string       `json:"principal"`
+	Matches   []ToxicMatch `json:"matches"`
+}
+
+// EvaluateToxic checks every principal's effective permission set, the union of
+// the permissions of all roles it holds anywhere, against the toxic rules.
+// permissions returns a role's permissions; roles it cannot resolve contribute nothing.
+func EvaluateToxic(matrix *gcp.AccessMatrix, toxicRules []rules.ToxicRule, permissions func(role string) []string) []ToxicCombination {
+	holdings := principalRoleResources(matrix)
+
+	principals := make([]string, 0, len(holdings))
+	for principal := range holdings {
+		principals = append(principals, principal)
+	}
+	sort.Strings(principals)
+
+	// permission -> roles granting it, resolved once per role
+	granted := make(map[string][]string)
+	resolved := make(map[string]bool)
+
+	combinations := []ToxicCombination{}
+	for _, principal := range principals {
+		for role := range holdings[principal] {
+			if !resolved[role] {
+				resolved[role] = true
+				for _, permission := range permissions(role) {
+					granted[permission] = append(granted[permission], role)
+				}
+			}
+		}
+
+		for _, rule := range toxicRules {
+			var matches []ToxicMatch
+			for _, condition := range rule.Conditions {
+				match := ToxicMatch{Condition: condition.Name}
+				for _, permission := range condition.Permissions {
+					var from []string
+					for _, role := range granted[permission] {
+						if _, ok := holdings[principal][role]; ok {
+							from = append(from, role)
+						}
+					}
+					if len(from) > 0 {
+						match.Permissions = append(match.Permissions, permission)
+						match.Roles = mergeSorted(match.Roles, from)
+					}
+				}
+				if len(match.Permissions) == 0 {
+					break
+				}
+				matches = append(matches, match)
+			}
+			if len(matches) != len(rule.Conditions) {
+				continue
+			}
+
+			combinations = append(combinations, ToxicCombination{
+				RuleID:    rule.ID,
+				RuleName:  rule.Name,
+				Severity:  rule.Severity,
+				Principal: principal,
+				Matches:   matches,
+			})
+		}
+	}
+
+	sort.SliceStable(combinations, func(i, j int) bool {
+		return combinations[i].RuleID < combinations[j].RuleID
+	})
+	return combinations
+}
+
+// mergeSorted adds the values not yet present and keeps the result sorted
+func mergeSorted(values, add []string) []string {
+	for _, value := range add {
+		if !contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
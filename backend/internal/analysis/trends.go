@@ -0,0 +1,57 @@
+package analysis
+
+import "gcp-access-visualizer/internal/gcp"
+
+// PostureMetrics are the headline counts tracked over time
+type PostureMetrics struct {
+	Principals         int `json:"principals"`
+	Owners             int `json:"owners"`          // principals holding roles/owner anywhere
+	PublicResources    int `json:"publicResources"` // resources granting access to allUsers or allAuthenticatedUsers
+	ExternalPrincipals int `json:"externalPrincipals"`
+}
+
+// ComputeMetrics counts the posture metrics of a matrix
+func ComputeMetrics(matrix *gcp.AccessMatrix, trustedDomains []string) PostureMetrics {
+	userTypes := make(map[string]string, len(matrix.Users))
+	for _, user := range matrix.Users {
+		userTypes[user.Email] = user.Type
+	}
+
+	principals := make(map[string]bool)
+	owners := make(map[string]bool)
+	public := make(map[string]bool)
+	external := make(map[string]bool)
+	for _, entry := range matrix.Access {
+		principals[entry.UserEmail] = true
+		if contains(entry.Roles, "roles/owner") {
+			owners[entry.UserEmail] = true
+		}
+		if IsPublicPrincipal(entry.UserEmail) {
+			public[entry.ResourceID] = true
+		}
+		if IsExternalPrincipal(entry.UserEmail, userTypes[entry.UserEmail], trustedDomains) {
+			external[entry.UserEmail] = true
+		}
+	}
+
+	return PostureMetrics{
+		Principals:         len(principals),
+		Owners:             len(owners),
+		PublicResources:    len(public),
+		ExternalPrincipals: len(external),
+	}
+}
+
+// CountBySeverity counts findings per severity, including severities with none
+func CountBySeverity(findings []Finding) map[string]int {
+	counts := map[string]int{
+		SeverityLow:      0,
+		SeverityMedium:   0,
+		SeverityHigh:     0,
+		SeverityCritical: 0,
+	}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	return counts
+}
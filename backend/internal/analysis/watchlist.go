@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"sort"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// WatchlistGrant is a grant of a watchlisted role to a principal on a resource
+type WatchlistGrant struct {
+	Principal     string `json:"principal"`
+	PrincipalType string `json:"principalType"`
+	Role          string `json:"role"`
+	ResourceID    string `json:"resourceId"`
+	ResourceName  string `json:"resourceName"`
+	ResourceType  string `json:"resourceType"`
+}
+
+// key uniquely identifies a watchlist grant
+func (g WatchlistGrant) key() string {
+	return g.Principal + "::" + g.Role + "::" + g.ResourceID
+}
+
+// Watchlist returns every grant of one of the given roles in the matrix,
+// ordered by role, principal, and resource
+func Watchlist(matrix *gcp.AccessMatrix, roles []string) []WatchlistGrant {
+	watched := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		watched[role] = true
+	}
+
+	userTypes := make(map[string]string, len(matrix.Users))
+	for _, user := range matrix.Users {
+		userTypes[user.Email] = user.Type
+	}
+
+	grants := []WatchlistGrant{}
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			if !watched[role] {
+				continue
+			}
+			grants = append(grants, WatchlistGrant{
+				Principal:     entry.UserEmail,
+				PrincipalType: userTypes[entry.UserEmail],
+				Role:          role,
+				ResourceID:    entry.ResourceID,
+				ResourceName:  entry.ResourceName,
+				ResourceType:  entry.ResourceType,
+			})
+		}
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		return a.ResourceID < b.ResourceID
+	})
+	return grants
+}
+
+// NewWatchlistGrants returns watchlist grants present in current but not in previous
+func NewWatchlistGrants(previous, current *gcp.AccessMatrix, roles []string) []WatchlistGrant {
+	existing := make(map[string]bool)
+	for _, grant := range Watchlist(previous, roles) {
+		existing[grant.key()] = true
+	}
+
+	added := []WatchlistGrant{}
+	for _, grant := range Watchlist(current, roles) {
+		if !existing[grant.key()] {
+			added = append(added, grant)
+		}
+	}
+	return added
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockTTL bounds how long a scan lock survives a replica that dies mid-scan.
+// The replica holding a lock renews it every lockRenewal, so scans may run
+// longer than lockTTL.
+const (
+	lockTTL     = 2 * time.Minute
+	lockRenewal = lockTTL / 3
+)
+
+// keyPrefix namespaces every key so a Redis instance can be shared
+const keyPrefix = "gcp-access-visualizer:"
+
+// unlockScript deletes a lock only if this replica still owns it
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// renewScript extends a lock only if this replica still owns it
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// Redis is a shared cache for role definitions and the scanned matrix. It
+// implements gcp.SharedCache and store.Coordinator, so replicas pointed at the
+// same instance share one warm cache and restarts don't trigger cold rescans.
+type Redis struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+// NewRedis connects to the Redis server at url, e.g. redis://:password@host:6379/0
+func NewRedis(ctx context.Context, url string) (*Redis, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return &Redis{client: client, ctx: ctx}, nil
+}
+
+// Get returns a cached value; misses and errors both report false
+func (r *Redis) Get(key string) ([]byte, bool) {
+	value, err := r.client.Get(r.ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set caches a value for ttl; failures only cost a later cache miss
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) {
+	r.client.Set(r.ctx, keyPrefix+key, value, ttl)
+}
+
+// TryLock acquires the named lock with SET NX, expiring after lockTTL unless
+// renewed; it is renewed until unlocked
+func (r *Redis) TryLock(name string) (func(), bool, error) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	key := keyPrefix + "lock:" + name
+
+	ok, err := r.client.SetNX(r.ctx, key, token, lockTTL).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %q: %w", name, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	done := make(chan struct{})
+	go r.renew(name, key, token, done)
+	return func() {
+		close(done)
+		unlockScript.Run(r.ctx, r.client, []string{key}, token)
+	}, true, nil
+}
+
+// renew extends a held lock every lockRenewal until done is closed or the
+// lock was lost, e.g. because Redis was unreachable for longer than lockTTL
+func (r *Redis) renew(name, key, token string, done <-chan struct{}) {
+	ticker := time.NewTicker(lockRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(r.ctx, r.client, []string{key}, token, lockTTL.Milliseconds()).Int()
+		if err != nil {
+			log.Printf("Warning: failed to renew lock %q: %v", name, err)
+			continue
+		}
+		if renewed == 0 {
+			log.Printf("Warning: lost lock %q; another replica may scan concurrently", name)
+			return
+		}
+	}
+}
+
+// PublishSnapshot replaces the project's shared snapshot
+func (r *Redis) PublishSnapshot(record store.SnapshotRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := r.client.Set(r.ctx, keyPrefix+"snapshot:"+record.Project, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to publish snapshot: %w", err)
+	}
+	return nil
+}
+
+// LatestSnapshot returns the project's shared snapshot, or store.ErrNotFound
+func (r *Redis) LatestSnapshot(project string) (*store.SnapshotRecord, error) {
+	data, err := r.client.Get(r.ctx, keyPrefix+"snapshot:"+project).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var record store.SnapshotRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return &record, nil
+}
+
+// Close closes the connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
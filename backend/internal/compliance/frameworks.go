@@ -0,0 +1,56 @@
+// Package compliance maps the collected access data to the evidence auditors
+// ask for under common frameworks
+package compliance
+
+import "errors"
+
+// ErrUnknownFramework is returned for a framework without a report template
+var ErrUnknownFramework = errors.New("unknown compliance framework")
+
+// Report sections, one per common audit request
+const (
+	SectionUserAccess       = "user-access"
+	SectionPrivilegedAccess = "privileged-access"
+	SectionTerminatedUsers  = "terminated-users"
+	SectionReviews          = "review-attestations"
+)
+
+// Control maps a report section to the control of a framework it evidences
+type Control struct {
+	Section string `json:"section"`
+	Control string `json:"control"`
+	Title   string `json:"title"`
+	// Request is the audit request the section answers
+	Request string `json:"request"`
+}
+
+// Framework is the report template of a compliance framework
+type Framework struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Controls []Control `json:"controls"`
+}
+
+// Frameworks are the supported report templates by ID
+var Frameworks = map[string]Framework{
+	"soc2": {
+		ID:   "soc2",
+		Name: "SOC 2 (Trust Services Criteria)",
+		Controls: []Control{
+			{SectionUserAccess, "CC6.1", "User access listing", "Provide a listing of users with access to in-scope systems and their permissions."},
+			{SectionPrivilegedAccess, "CC6.1", "Privileged access listing", "Provide a listing of users and service accounts with administrative access."},
+			{SectionTerminatedUsers, "CC6.2", "Terminated user access check", "Demonstrate that access of terminated personnel was removed."},
+			{SectionReviews, "CC6.3", "Access review attestations", "Provide evidence that access and exceptions were reviewed and approved."},
+		},
+	},
+	"iso27001": {
+		ID:   "iso27001",
+		Name: "ISO/IEC 27001:2022 Annex A",
+		Controls: []Control{
+			{SectionUserAccess, "A.5.15", "Access control: user access listing", "Provide the access rights granted to users of in-scope systems."},
+			{SectionPrivilegedAccess, "A.8.2", "Privileged access rights", "Provide the allocation of privileged access rights."},
+			{SectionTerminatedUsers, "A.6.5", "Responsibilities after termination", "Demonstrate that access rights were removed upon termination of employment."},
+			{SectionReviews, "A.5.18", "Review of access rights", "Provide records of access rights reviews and approved exceptions."},
+		},
+	},
+}
@@ -0,0 +1,280 @@
+package compliance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Section statuses
+const (
+	StatusCompliant    = "compliant"
+	StatusAttention    = "attention" // rows need follow-up
+	StatusNotPerformed = "not-performed"
+	StatusInformative  = "informative" // a listing for the auditor to sample
+)
+
+// Inputs is the collected data a report is built from
+type Inputs struct {
+	SnapshotID string
+	TakenAt    time.Time
+	Matrix     *gcp.AccessMatrix
+	// Profiles is the HR feed; without it the terminated user check is not performed
+	Profiles       []store.PrincipalProfile
+	FindingStates  []store.FindingState
+	Exceptions     []store.Exception
+	TrustedDomains []string
+}
+
+// Report is a framework's report template filled with collected data
+type Report struct {
+	Framework   string    `json:"framework"`
+	Name        string    `json:"name"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	SnapshotID  string    `json:"snapshotId"`
+	TakenAt     time.Time `json:"takenAt"`
+	Sections    []Section `json:"sections"`
+}
+
+// Section answers one audit request
+type Section struct {
+	Control
+	Status  string      `json:"status"`
+	Summary string      `json:"summary"`
+	Rows    interface{} `json:"rows"`
+}
+
+// AccessRow is one principal's access to one resource
+type AccessRow struct {
+	Principal    string   `json:"principal"`
+	Type         string   `json:"type"`
+	DisplayName  string   `json:"displayName,omitempty"`
+	Team         string   `json:"team,omitempty"`
+	Manager      string   `json:"manager,omitempty"`
+	Owner        string   `json:"owner,omitempty"` // owning team of a service account
+	Resource     string   `json:"resource"`
+	ResourceName string   `json:"resourceName"`
+	ResourceType string   `json:"resourceType"`
+	Roles        []string `json:"roles"`
+	Tier         string   `json:"tier"`
+}
+
+// TerminationRow is a principal with access but without a record in the HR feed
+type TerminationRow struct {
+	Principal string `json:"principal"`
+	Resources int    `json:"resources"`
+	// HighestTier is the most privileged access the principal still holds
+	HighestTier string `json:"highestTier"`
+}
+
+// ReviewRow records one review decision
+type ReviewRow struct {
+	Kind          string     `json:"kind"` // "finding" or "exception"
+	Subject       string     `json:"subject"`
+	Decision      string     `json:"decision"`
+	Reviewer      string     `json:"reviewer"`
+	ReviewedAt    time.Time  `json:"reviewedAt"`
+	Justification string     `json:"justification,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+}
+
+// humanTypes are the principal types a user access listing covers
+var humanTypes = map[string]bool{"user": true, "group": true, "domain": true}
+
+// Build fills the report template of a framework
+func Build(framework string, inputs Inputs, now time.Time) (*Report, error) {
+	template, ok := Frameworks[framework]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFramework, framework)
+	}
+
+	report := &Report{
+		Framework:   template.ID,
+		Name:        template.Name,
+		GeneratedAt: now.UTC(),
+		SnapshotID:  inputs.SnapshotID,
+		TakenAt:     inputs.TakenAt,
+		Sections:    make([]Section, 0, len(template.Controls)),
+	}
+	for _, control := range template.Controls {
+		section := Section{Control: control}
+		switch control.Section {
+		case SectionUserAccess:
+			rows := accessRows(inputs.Matrix, func(user gcp.User, entry gcp.AccessEntry) bool {
+				return humanTypes[user.Type]
+			})
+			section.Status = StatusInformative
+			section.Summary = fmt.Sprintf("%d grants to %d users, groups, and domains", len(rows), countPrincipals(rows))
+			section.Rows = rows
+		case SectionPrivilegedAccess:
+			rows := accessRows(inputs.Matrix, func(user gcp.User, entry gcp.AccessEntry) bool {
+				return gcp.TierRank(entry.Tier) >= gcp.TierRank(gcp.TierAdmin)
+			})
+			section.Status = StatusInformative
+			section.Summary = fmt.Sprintf("%d admin or owner grants to %d principals", len(rows), countPrincipals(rows))
+			section.Rows = rows
+		case SectionTerminatedUsers:
+			terminationSection(&section, inputs)
+		case SectionReviews:
+			rows := reviewRows(inputs.FindingStates, inputs.Exceptions)
+			section.Status = StatusInformative
+			if len(rows) == 0 {
+				section.Status = StatusAttention
+			}
+			section.Summary = fmt.Sprintf("%d recorded review decisions on findings and exceptions", len(rows))
+			section.Rows = rows
+		}
+		report.Sections = append(report.Sections, section)
+	}
+	return report, nil
+}
+
+// accessRows lists the live grants that include selects, with HR metadata
+func accessRows(matrix *gcp.AccessMatrix, include func(gcp.User, gcp.AccessEntry) bool) []AccessRow {
+	users := make(map[string]gcp.User, len(matrix.Users))
+	for _, user := range matrix.Users {
+		users[user.Email] = user
+	}
+
+	rows := []AccessRow{}
+	for _, entry := range matrix.Access {
+		user, ok := users[entry.UserEmail]
+		if !ok {
+			user = gcp.ParseMember(entry.UserEmail)
+		}
+		if user.Deleted || !include(user, entry) {
+			continue
+		}
+		rows = append(rows, AccessRow{
+			Principal:    entry.UserEmail,
+			Type:         user.Type,
+			DisplayName:  user.DisplayName,
+			Team:         user.Team,
+			Manager:      user.Manager,
+			Owner:        user.Owner,
+			Resource:     entry.ResourceID,
+			ResourceName: entry.ResourceName,
+			ResourceType: entry.ResourceType,
+			Roles:        entry.Roles,
+			Tier:         entry.Tier,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Principal != rows[j].Principal {
+			return rows[i].Principal < rows[j].Principal
+		}
+		return rows[i].Resource < rows[j].Resource
+	})
+	return rows
+}
+
+// countPrincipals counts the distinct principals of access rows
+func countPrincipals(rows []AccessRow) int {
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		seen[row.Principal] = true
+	}
+	return len(seen)
+}
+
+// terminationSection checks the users of your own domains holding access
+// against the HR feed: a user without an HR record has left or was never
+// onboarded through HR, and either way needs follow-up
+func terminationSection(section *Section, inputs Inputs) {
+	section.Rows = []TerminationRow{}
+	if len(inputs.Profiles) == 0 {
+		section.Status = StatusNotPerformed
+		section.Summary = "No HR feed loaded; upload principal profiles or configure a directory connector"
+		return
+	}
+
+	known := make(map[string]bool, len(inputs.Profiles))
+	for _, profile := range inputs.Profiles {
+		known[strings.ToLower(profile.Email)] = true
+	}
+	types := make(map[string]string, len(inputs.Matrix.Users))
+	for _, user := range inputs.Matrix.Users {
+		types[user.Email] = user.Type
+	}
+
+	byPrincipal := make(map[string]*TerminationRow)
+	for _, entry := range inputs.Matrix.Access {
+		email := entry.UserEmail
+		if types[email] != "user" || known[strings.ToLower(email)] {
+			continue
+		}
+		if analysis.IsExternalPrincipal(email, "user", inputs.TrustedDomains) {
+			continue // outsiders are not in the HR feed
+		}
+		row := byPrincipal[email]
+		if row == nil {
+			row = &TerminationRow{Principal: email}
+			byPrincipal[email] = row
+		}
+		row.Resources++
+		row.HighestTier = gcp.MaxTier(row.HighestTier, entry.Tier)
+	}
+
+	rows := make([]TerminationRow, 0, len(byPrincipal))
+	for _, row := range byPrincipal {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Principal < rows[j].Principal })
+
+	section.Rows = rows
+	section.Status = StatusCompliant
+	if len(rows) > 0 {
+		section.Status = StatusAttention
+	}
+	section.Summary = fmt.Sprintf("%d users holding access have no record among %d HR profiles", len(rows), len(inputs.Profiles))
+}
+
+// reviewRows lists the triage decisions on findings and the exceptions
+// granted, newest first
+func reviewRows(states []store.FindingState, exceptions []store.Exception) []ReviewRow {
+	rows := []ReviewRow{}
+	for _, state := range states {
+		if state.Status == store.FindingOpen || state.UpdatedBy == "scanner" {
+			continue // not a review decision
+		}
+		rows = append(rows, ReviewRow{
+			Kind:          "finding",
+			Subject:       state.ID,
+			Decision:      state.Status,
+			Reviewer:      state.UpdatedBy,
+			ReviewedAt:    state.UpdatedAt,
+			Justification: firstNonEmpty(state.Justification, state.Note),
+			ExpiresAt:     state.SnoozedUntil,
+		})
+	}
+	for _, exception := range exceptions {
+		expires := exception.ExpiresAt
+		subject := strings.TrimSpace(exception.Principal + " " + exception.Resource + " " + exception.Role)
+		rows = append(rows, ReviewRow{
+			Kind:          "exception",
+			Subject:       exception.Rule + ": " + subject,
+			Decision:      "excepted",
+			Reviewer:      exception.CreatedBy,
+			ReviewedAt:    exception.CreatedAt,
+			Justification: exception.Justification,
+			ExpiresAt:     &expires,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].ReviewedAt.After(rows[j].ReviewedAt) })
+	return rows
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
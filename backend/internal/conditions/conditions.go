@@ -0,0 +1,91 @@
+// Package conditions evaluates IAM condition expressions. It implements the
+// subset of CEL that IAM conditions commonly use: resource.name, resource.type,
+// resource.service, principal.type, and principal.subject with string
+// functions, and request.time with timestamp comparisons and date/time
+// accessors. Attributes it cannot know offline, such as request.auth or
+// resource tags, evaluate to unknown rather than failing.
+package conditions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of evaluating a condition
+type Result string
+
+// Condition results. Unknown means the expression depends on attributes that
+// are not part of the Context; the binding may or may not apply.
+const (
+	True    Result = "true"
+	False   Result = "false"
+	Unknown Result = "unknown"
+)
+
+// Context holds the request attributes a condition is evaluated against
+type Context struct {
+	// ResourceName is the relative resource name, e.g. "projects/_/buckets/logs"
+	ResourceName string `json:"resourceName"`
+	// ResourceType is the asset type, e.g. "storage.googleapis.com/Bucket"
+	ResourceType string `json:"resourceType"`
+	// ResourceService is the service name, e.g. "storage.googleapis.com"
+	ResourceService string `json:"resourceService"`
+	// RequestTime is the time of the simulated request; zero leaves request.time unknown
+	RequestTime time.Time `json:"requestTime"`
+	// PrincipalType and PrincipalSubject describe the caller for policy binding
+	// conditions, e.g. "iam.googleapis.com/ServiceAccount"; empty means unknown
+	PrincipalType    string `json:"principalType,omitempty"`
+	PrincipalSubject string `json:"principalSubject,omitempty"`
+}
+
+// ContextFor builds a context for a request on a resource identified by its
+// full resource name, e.g. "//storage.googleapis.com/projects/_/buckets/logs"
+func ContextFor(resourceID, assetType string, at time.Time) Context {
+	service, name, _ := strings.Cut(strings.TrimPrefix(resourceID, "//"), "/")
+	return Context{
+		ResourceName:    name,
+		ResourceType:    assetType,
+		ResourceService: service,
+		RequestTime:     at,
+	}
+}
+
+// Evaluate evaluates a condition expression against ctx. It returns an error
+// for expressions that do not parse or use unsupported syntax, and Unknown for
+// valid expressions whose outcome depends on attributes ctx does not hold.
+func Evaluate(expression string, ctx Context) (Result, error) {
+	node, err := Parse(expression)
+	if err != nil {
+		return "", err
+	}
+	value, err := node.eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case unknown:
+		return Unknown, nil
+	case bool:
+		if v {
+			return True, nil
+		}
+		return False, nil
+	}
+	return "", fmt.Errorf("condition evaluates to %s, not a bool", typeName(value))
+}
+
+// Combine folds the results of several conditions on one binding path: the
+// path applies only if all of them hold
+func Combine(results ...Result) Result {
+	combined := True
+	for _, result := range results {
+		switch result {
+		case False:
+			return False
+		case Unknown:
+			combined = Unknown
+		}
+	}
+	return combined
+}
@@ -0,0 +1,423 @@
+package conditions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// unknown is the value of attributes the context does not hold. It spreads
+// through operators, except where the other operand decides the result
+// (false && x, true || x).
+type unknown struct{}
+
+// attribute is a partially selected attribute such as "resource", "request", or "principal"
+type attribute string
+
+type (
+	literalNode struct{ value any }
+	identNode   struct{ name string }
+	listNode    struct{ items []Node }
+	selectNode  struct {
+		operand Node
+		field   string
+	}
+	callNode struct {
+		target Node // nil for global functions
+		name   string
+		args   []Node
+	}
+	notNode     struct{ operand Node }
+	andNode     struct{ left, right Node }
+	orNode      struct{ left, right Node }
+	compareNode struct {
+		op          string
+		left, right Node
+	}
+)
+
+func (n literalNode) eval(Context) (any, error) { return n.value, nil }
+
+func (n identNode) eval(Context) (any, error) {
+	switch n.name {
+	case "resource", "request", "principal":
+		return attribute(n.name), nil
+	}
+	// api, origin, destination, and other attributes are not modeled
+	return unknown{}, nil
+}
+
+func (n listNode) eval(ctx Context) (any, error) {
+	items := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+func (n selectNode) eval(ctx Context) (any, error) {
+	operand, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch operand {
+	case attribute("resource"):
+		switch n.field {
+		case "name":
+			return ctx.ResourceName, nil
+		case "type":
+			return ctx.ResourceType, nil
+		case "service":
+			return ctx.ResourceService, nil
+		}
+	case attribute("request"):
+		if n.field == "time" && !ctx.RequestTime.IsZero() {
+			return ctx.RequestTime, nil
+		}
+	case attribute("principal"):
+		switch {
+		case n.field == "type" && ctx.PrincipalType != "":
+			return ctx.PrincipalType, nil
+		case n.field == "subject" && ctx.PrincipalSubject != "":
+			return ctx.PrincipalSubject, nil
+		}
+	}
+	return unknown{}, nil
+}
+
+func (n notNode) eval(ctx Context) (any, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case unknown:
+		return v, nil
+	case bool:
+		return !v, nil
+	}
+	return nil, fmt.Errorf("! applied to %s", typeName(value))
+}
+
+func (n andNode) eval(ctx Context) (any, error) {
+	return logical(ctx, n.left, n.right, false)
+}
+
+func (n orNode) eval(ctx Context) (any, error) {
+	return logical(ctx, n.left, n.right, true)
+}
+
+// logical evaluates && (decisive false) or || (decisive true) with unknowns
+func logical(ctx Context, left, right Node, decisive bool) (any, error) {
+	isUnknown := false
+	for _, operand := range []Node{left, right} {
+		value, err := operand.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case unknown:
+			isUnknown = true
+		case bool:
+			if v == decisive {
+				return decisive, nil
+			}
+		default:
+			return nil, fmt.Errorf("logical operator applied to %s", typeName(value))
+		}
+	}
+	if isUnknown {
+		return unknown{}, nil
+	}
+	return !decisive, nil
+}
+
+func (n compareNode) eval(ctx Context) (any, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if isUnknown(left) || isUnknown(right) {
+		return unknown{}, nil
+	}
+
+	if n.op == "in" {
+		items, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("in requires a list, got %s", typeName(right))
+		}
+		for _, item := range items {
+			if isUnknown(item) {
+				return unknown{}, nil
+			}
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	cmp, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+func (n callNode) eval(ctx Context) (any, error) {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	if n.target == nil {
+		return callGlobal(n.name, args)
+	}
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if isUnknown(target) {
+		return unknown{}, nil
+	}
+	for _, arg := range args {
+		if isUnknown(arg) {
+			return unknown{}, nil
+		}
+	}
+
+	switch t := target.(type) {
+	case string:
+		return callString(t, n.name, args)
+	case time.Time:
+		return callTime(t, n.name, args)
+	case attribute:
+		// resource.matchTag(...), resource.hasTagKey(...), and similar
+		// functions depend on tags, which are not modeled
+		return unknown{}, nil
+	}
+	return nil, fmt.Errorf("%s() is not supported on %s", n.name, typeName(target))
+}
+
+// callGlobal evaluates timestamp(), duration(), and other global functions
+func callGlobal(name string, args []any) (any, error) {
+	switch name {
+	case "timestamp":
+		s, err := stringArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp(%q): %w", s, err)
+		}
+		return t, nil
+	case "duration":
+		s, err := stringArg(name, args)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("duration(%q): %w", s, err)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("function %s() is not supported", name)
+}
+
+// callString evaluates the string methods IAM conditions use on resource attributes
+func callString(s, name string, args []any) (any, error) {
+	arg, err := stringArg(name, args)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "matches":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("matches(%q): %w", arg, err)
+		}
+		return re.MatchString(s), nil
+	case "extract":
+		return extract(s, arg), nil
+	}
+	return nil, fmt.Errorf("%s() is not supported on strings", name)
+}
+
+// extract implements resource.name.extract("projects/{project}/..."): the text
+// matched by the single {placeholder}, or "" if the name does not match
+func extract(s, template string) string {
+	open := strings.Index(template, "{")
+	end := strings.Index(template, "}")
+	if open < 0 || end < open {
+		return ""
+	}
+	prefix, suffix := template[:open], template[end+1:]
+	start := strings.Index(s, prefix)
+	if start < 0 {
+		return ""
+	}
+	rest := s[start+len(prefix):]
+	if suffix == "" {
+		return rest
+	}
+	if stop := strings.Index(rest, suffix); stop >= 0 {
+		return rest[:stop]
+	}
+	return ""
+}
+
+// callTime evaluates the request.time accessors, optionally in a time zone.
+// Like CEL, getMonth, getDayOfMonth, and getDayOfYear are zero-based.
+func callTime(t time.Time, name string, args []any) (any, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%s() takes at most one argument", name)
+	}
+	if len(args) == 1 {
+		zone, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects a time zone name", name)
+		}
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("%s(%q): %w", name, zone, err)
+		}
+		t = t.In(location)
+	} else {
+		t = t.UTC()
+	}
+
+	switch name {
+	case "getFullYear":
+		return int64(t.Year()), nil
+	case "getMonth":
+		return int64(t.Month()) - 1, nil
+	case "getDayOfMonth":
+		return int64(t.Day()) - 1, nil
+	case "getDate":
+		return int64(t.Day()), nil
+	case "getDayOfWeek":
+		return int64(t.Weekday()), nil
+	case "getDayOfYear":
+		return int64(t.YearDay()) - 1, nil
+	case "getHours":
+		return int64(t.Hour()), nil
+	case "getMinutes":
+		return int64(t.Minute()), nil
+	case "getSeconds":
+		return int64(t.Second()), nil
+	}
+	return nil, fmt.Errorf("%s() is not supported on timestamps", name)
+}
+
+func stringArg(name string, args []any) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s() takes one argument", name)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s() expects a string, got %s", name, typeName(args[0]))
+	}
+	return s, nil
+}
+
+func isUnknown(value any) bool {
+	_, ok := value.(unknown)
+	return ok
+}
+
+func equal(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return a == b
+}
+
+// compare orders two values of the same type
+func compare(a, b any) (int, error) {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return sign(x - y), nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	case time.Duration:
+		if y, ok := b.(time.Duration); ok {
+			return sign(int64(x - y)), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+func sign(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "int"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "timestamp"
+	case time.Duration:
+		return "duration"
+	case []any:
+		return "list"
+	case attribute:
+		return "attribute"
+	}
+	return fmt.Sprintf("%T", value)
+}
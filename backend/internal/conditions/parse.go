@@ -0,0 +1,299 @@
+package conditions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Node is a parsed condition expression
+type Node interface {
+	eval(ctx Context) (any, error)
+}
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokInt
+	tokOp
+)
+
+type token struct {
+	kind  int
+	text  string
+	value any // decoded literal for strings and ints
+	pos   int
+}
+
+// lex splits an expression into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(input) && (input[i] == '_' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], pos: start})
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(input) && unicode.IsDigit(rune(input[i])) {
+				i++
+			}
+			n, err := strconv.ParseInt(input[start:i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number at %d: %w", start, err)
+			}
+			tokens = append(tokens, token{kind: tokInt, text: input[start:i], value: n, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for {
+				if i >= len(input) {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				if rune(input[i]) == c {
+					i++
+					break
+				}
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+					switch input[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(input[i])
+					}
+					i++
+					continue
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: input[start:i], value: b.String(), pos: start})
+		default:
+			start := i
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+// parser is a recursive-descent parser over the token stream
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a condition expression
+func Parse(expression string) (Node, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", next.text, next.pos)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d, found %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) or() (Node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Node, error) {
+	left, err := p.member()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="),
+		t.kind == tokIdent && t.text == "in":
+		p.next()
+		right, err := p.member()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: t.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// member parses a primary followed by field selections and method calls
+func (p *parser) member() (Node, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(".") {
+		name := p.next()
+		if name.kind != tokIdent {
+			return nil, fmt.Errorf("expected a field or method name at %d", name.pos)
+		}
+		if p.accept("(") {
+			args, err := p.arguments(")")
+			if err != nil {
+				return nil, err
+			}
+			node = callNode{target: node, name: name.text, args: args}
+			continue
+		}
+		node = selectNode{operand: node, field: name.text}
+	}
+	return node, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString, tokInt:
+		return literalNode{t.value}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		}
+		if p.accept("(") {
+			args, err := p.arguments(")")
+			if err != nil {
+				return nil, err
+			}
+			return callNode{name: t.text, args: args}, nil
+		}
+		return identNode{t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			node, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			items, err := p.arguments("]")
+			if err != nil {
+				return nil, err
+			}
+			return listNode{items}, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// arguments parses a comma-separated list up to the closing token
+func (p *parser) arguments(closing string) ([]Node, error) {
+	var args []Node
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
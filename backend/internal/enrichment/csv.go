@@ -0,0 +1,111 @@
+package enrichment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"gcp-access-visualizer/internal/store"
+)
+
+// ParseCSV reads principal profiles from CSV with a header row.
+// Recognized columns: email (required), displayName, team, manager.
+func ParseCSV(r io.Reader) ([]store.PrincipalProfile, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	emailCol, ok := columns["email"]
+	if !ok {
+		return nil, fmt.Errorf("CSV header must include an email column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[strings.ToLower(name)]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var profiles []store.PrincipalProfile
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		email := strings.TrimSpace(record[emailCol])
+		if email == "" {
+			continue
+		}
+		profiles = append(profiles, store.PrincipalProfile{
+			Email:       email,
+			DisplayName: field(record, "displayName"),
+			Team:        field(record, "team"),
+			Manager:     field(record, "manager"),
+		})
+	}
+
+	return profiles, nil
+}
+
+// ParseEmailList reads a list of emails from CSV: the email column when the
+// first row is a header naming one, otherwise the first column of every row.
+// Emails are lowercased and deduplicated.
+func ParseEmailList(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	emailCol := 0
+	seen := make(map[string]bool)
+	emails := []string{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if line == 1 && !strings.Contains(record[0], "@") {
+			found := false
+			for i, name := range record {
+				if strings.EqualFold(strings.TrimSpace(name), "email") {
+					emailCol, found = i, true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("CSV header must include an email column")
+			}
+			continue
+		}
+
+		if emailCol >= len(record) {
+			continue
+		}
+		email := strings.ToLower(strings.TrimSpace(record[emailCol]))
+		if email == "" || seen[email] {
+			continue
+		}
+		if !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("line %d: %q is not an email", line, email)
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
@@ -0,0 +1,64 @@
+package enrichment
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Source is a connector that supplies HR metadata for principals
+type Source interface {
+	// Name identifies the source; profiles are replaced per source on sync
+	Name() string
+	Fetch(ctx context.Context) ([]store.PrincipalProfile, error)
+}
+
+// Apply attaches display name, team, and manager to users with a matching profile
+func Apply(users []gcp.User, profiles []store.PrincipalProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+
+	byEmail := make(map[string]store.PrincipalProfile, len(profiles))
+	for _, profile := range profiles {
+		byEmail[strings.ToLower(profile.Email)] = profile
+	}
+
+	for i := range users {
+		profile, ok := byEmail[strings.ToLower(users[i].Email)]
+		if !ok {
+			continue
+		}
+		users[i].DisplayName = profile.DisplayName
+		users[i].Team = profile.Team
+		users[i].Manager = profile.Manager
+	}
+}
+
+// Hook returns a scanner hook that enriches every snapshot from the stored profiles
+func Hook(st store.Store) scanner.Hook {
+	return func(matrix *gcp.AccessMatrix) {
+		profiles, err := st.ListProfiles()
+		if err != nil {
+			log.Printf("Warning: failed to load principal profiles: %v", err)
+			return
+		}
+		Apply(matrix.Users, profiles)
+	}
+}
+
+// Sync fetches profiles from a source and stores them, replacing earlier ones from it
+func Sync(ctx context.Context, source Source, st store.Store) (int, error) {
+	profiles, err := source.Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := st.ReplaceProfiles(source.Name(), profiles); err != nil {
+		return 0, err
+	}
+	return len(profiles), nil
+}
@@ -0,0 +1,96 @@
+package enrichment
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// ownerPattern matches ownership hints such as "owner: payments" or
+// "team=data-eng" in service account descriptions and display names. The
+// delimiter is required: prose such as "used by the data team for ETL" names
+// no owner.
+var ownerPattern = regexp.MustCompile(`(?i)\b(?:owner|team)\s*[:=]\s*([A-Za-z0-9][\w.@-]*)`)
+
+// serviceAccountsTTL is how long OwnershipHook reuses the service account
+// list: hooks rerun on every rescan, patch, and reapply, which would otherwise
+// list the accounts each time
+const serviceAccountsTTL = 10 * time.Minute
+
+// ParseOwner extracts an owning team or system from free text, if present
+func ParseOwner(text string) string {
+	match := ownerPattern.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimRight(match[1], ".")
+}
+
+// AttributeOwners sets Owner on service account users. Manual mappings win over
+// hints parsed from the service account description, then its display name.
+func AttributeOwners(users []gcp.User, accounts []gcp.ServiceAccount, manual []store.ServiceAccountOwner) {
+	manualByEmail := make(map[string]string, len(manual))
+	for _, owner := range manual {
+		manualByEmail[strings.ToLower(owner.Email)] = owner.Owner
+	}
+	accountsByEmail := make(map[string]gcp.ServiceAccount, len(accounts))
+	for _, account := range accounts {
+		accountsByEmail[strings.ToLower(account.Email)] = account
+	}
+
+	for i := range users {
+		if users[i].Type != "serviceAccount" {
+			continue
+		}
+		email := strings.ToLower(users[i].Email)
+
+		if owner, ok := manualByEmail[email]; ok {
+			users[i].Owner, users[i].OwnerSource = owner, "manual"
+			continue
+		}
+		account, ok := accountsByEmail[email]
+		if !ok {
+			continue
+		}
+		if owner := ParseOwner(account.Description); owner != "" {
+			users[i].Owner, users[i].OwnerSource = owner, "description"
+		} else if owner := ParseOwner(account.DisplayName); owner != "" {
+			users[i].Owner, users[i].OwnerSource = owner, "displayName"
+		}
+	}
+}
+
+// OwnershipHook returns a scanner hook that attributes service accounts to owners
+func OwnershipHook(client *gcp.Client, st store.Store) scanner.Hook {
+	var (
+		mu        sync.Mutex
+		accounts  []gcp.ServiceAccount
+		fetchedAt time.Time
+	)
+	return func(matrix *gcp.AccessMatrix) {
+		mu.Lock()
+		if time.Since(fetchedAt) >= serviceAccountsTTL {
+			// A failed listing keeps the previous accounts until the next attempt
+			if listed, err := client.GetServiceAccounts(); err != nil {
+				log.Printf("Warning: failed to list service accounts for ownership: %v", err)
+			} else {
+				accounts = listed
+			}
+			fetchedAt = time.Now()
+		}
+		cached := accounts
+		mu.Unlock()
+
+		manual, err := st.ListOwners()
+		if err != nil {
+			log.Printf("Warning: failed to load service account owners: %v", err)
+		}
+		AttributeOwners(matrix.Users, cached, manual)
+	}
+}
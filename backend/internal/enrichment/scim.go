@@ -0,0 +1,136 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gcp-access-visualizer/internal/store"
+)
+
+// SCIMSource reads user profiles from a SCIM 2.0 /Users endpoint
+type SCIMSource struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+// scimUser is the subset of a SCIM user resource used for enrichment
+type scimUser struct {
+	UserName    string `json:"userName"`
+	DisplayName string `json:"displayName"`
+	Emails      []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Enterprise struct {
+		Department string `json:"department"`
+		Manager    struct {
+			DisplayName string `json:"displayName"`
+			Value       string `json:"value"`
+		} `json:"manager"`
+	} `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+// scimListResponse is a page of SCIM users
+type scimListResponse struct {
+	TotalResults int        `json:"totalResults"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+// Name implements Source
+func (s *SCIMSource) Name() string {
+	return "scim"
+}
+
+// Fetch implements Source by paging through all SCIM users
+func (s *SCIMSource) Fetch(ctx context.Context) ([]store.PrincipalProfile, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var profiles []store.PrincipalProfile
+	startIndex := 1
+	for {
+		page, err := s.fetchPage(ctx, client, startIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range page.Resources {
+			email := primaryEmail(user)
+			if email == "" {
+				continue
+			}
+			manager := user.Enterprise.Manager.DisplayName
+			if manager == "" {
+				manager = user.Enterprise.Manager.Value
+			}
+			profiles = append(profiles, store.PrincipalProfile{
+				Email:       email,
+				DisplayName: user.DisplayName,
+				Team:        user.Enterprise.Department,
+				Manager:     manager,
+			})
+		}
+
+		startIndex += len(page.Resources)
+		if len(page.Resources) == 0 || startIndex > page.TotalResults {
+			break
+		}
+	}
+
+	return profiles, nil
+}
+
+func (s *SCIMSource) fetchPage(ctx context.Context, client *http.Client, startIndex int) (*scimListResponse, error) {
+	query := url.Values{}
+	query.Set("startIndex", fmt.Sprintf("%d", startIndex))
+	query.Set("count", "100")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(s.BaseURL, "/")+"/Users?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/scim+json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SCIM users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SCIM users request failed: %s", resp.Status)
+	}
+
+	var page scimListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode SCIM users: %w", err)
+	}
+	return &page, nil
+}
+
+// primaryEmail returns the primary email of a SCIM user, falling back to userName
+func primaryEmail(user scimUser) string {
+	for _, email := range user.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(user.Emails) > 0 {
+		return user.Emails[0].Value
+	}
+	if strings.Contains(user.UserName, "@") {
+		return user.UserName
+	}
+	return ""
+}
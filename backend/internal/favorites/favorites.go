@@ -0,0 +1,228 @@
+// Package favorites keeps each user's starred principals and resources and
+// reports the access changes that affect them since the user last looked.
+package favorites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Kinds of starred items
+const (
+	KindPrincipal = "principal"
+	KindResource  = "resource"
+)
+
+// settingPrefix prefixes the store setting holding a user's favorites
+const settingPrefix = "favorites:"
+
+// userLocks holds a mutex per user that serializes the load and save of the
+// user's favorites, so concurrent requests do not drop each other's changes
+var userLocks sync.Map
+
+// lock locks the favorites of user and returns the function unlocking them
+func lock(user string) func() {
+	value, _ := userLocks.LoadOrStore(user, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Item is a starred principal or resource
+type Item struct {
+	Kind    string    `json:"kind"`
+	ID      string    `json:"id"` // principal email or resource ID
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Grant is a role a principal holds on a resource, directly or inherited
+type Grant struct {
+	Principal    string `json:"principal"`
+	Role         string `json:"role"`
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+}
+
+// key uniquely identifies a grant
+func (g Grant) key() string {
+	return g.Principal + "::" + g.Role + "::" + g.ResourceID
+}
+
+// Feed is the access changes affecting a user's favorites
+type Feed struct {
+	Items []Item `json:"items"`
+	// Since is the snapshot time of the last visit; zero on the first visit,
+	// which sets the baseline
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until"`
+	Added   []Grant   `json:"added"`
+	Removed []Grant   `json:"removed"`
+}
+
+// state is what is persisted per user
+type state struct {
+	Items  []Item    `json:"items"`
+	SeenAt time.Time `json:"seenAt,omitempty"`
+	Grants []Grant   `json:"grants,omitempty"` // grants on the items at the last visit
+}
+
+// Load returns the user's starred items
+func Load(st store.Store, user string) ([]Item, error) {
+	s, err := load(st, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.Items, nil
+}
+
+// Star adds an item to the user's favorites; starring it again is a no-op
+func Star(st store.Store, user string, item Item) error {
+	defer lock(user)()
+	s, err := load(st, user)
+	if err != nil {
+		return err
+	}
+	for _, existing := range s.Items {
+		if existing.Kind == item.Kind && strings.EqualFold(existing.ID, item.ID) {
+			return nil
+		}
+	}
+	s.Items = append(s.Items, item)
+	return save(st, user, s)
+}
+
+// Unstar removes an item from the user's favorites, or returns store.ErrNotFound
+func Unstar(st store.Store, user, kind, id string) error {
+	defer lock(user)()
+	s, err := load(st, user)
+	if err != nil {
+		return err
+	}
+	for i, existing := range s.Items {
+		if existing.Kind == kind && strings.EqualFold(existing.ID, id) {
+			s.Items = append(s.Items[:i], s.Items[i+1:]...)
+			return save(st, user, s)
+		}
+	}
+	return store.ErrNotFound
+}
+
+// Visit reports the grants on the user's favorites added and removed since the
+// last visit, then records the matrix as the baseline of the next one. Grants
+// on items starred since the last visit count as added.
+func Visit(st store.Store, user string, matrix *gcp.AccessMatrix, takenAt time.Time) (*Feed, error) {
+	defer lock(user)()
+	s, err := load(st, user)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := itemGrants(matrix, s.Items)
+	feed := &Feed{Items: s.Items, Since: s.SeenAt, Until: takenAt, Added: []Grant{}, Removed: []Grant{}}
+	if !s.SeenAt.IsZero() {
+		seen := make(map[string]bool, len(s.Grants))
+		for _, grant := range s.Grants {
+			seen[grant.key()] = true
+		}
+		current := make(map[string]bool, len(grants))
+		for _, grant := range grants {
+			current[grant.key()] = true
+			if !seen[grant.key()] {
+				feed.Added = append(feed.Added, grant)
+			}
+		}
+		// Grants of items unstarred since are no longer of interest
+		starred := itemMatcher(s.Items)
+		for _, grant := range s.Grants {
+			if !current[grant.key()] && starred(grant) {
+				feed.Removed = append(feed.Removed, grant)
+			}
+		}
+	}
+
+	s.SeenAt = takenAt
+	s.Grants = grants
+	if err := save(st, user, s); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// itemGrants lists the grants in the matrix on any of the items, ordered by
+// principal, role, and resource
+func itemGrants(matrix *gcp.AccessMatrix, items []Item) []Grant {
+	starred := itemMatcher(items)
+	grants := []Grant{}
+	for _, entry := range matrix.Access {
+		for _, role := range entry.Roles {
+			grant := Grant{Principal: entry.UserEmail, Role: role, ResourceID: entry.ResourceID, ResourceName: entry.ResourceName}
+			if starred(grant) {
+				grants = append(grants, grant)
+			}
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.ResourceID < b.ResourceID
+	})
+	return grants
+}
+
+// itemMatcher returns whether a grant involves any of the items
+func itemMatcher(items []Item) func(Grant) bool {
+	principals := make(map[string]bool)
+	resources := make(map[string]bool)
+	for _, item := range items {
+		switch item.Kind {
+		case KindPrincipal:
+			principals[strings.ToLower(item.ID)] = true
+		case KindResource:
+			resources[item.ID] = true
+		}
+	}
+	return func(grant Grant) bool {
+		return principals[strings.ToLower(grant.Principal)] || resources[grant.ResourceID]
+	}
+}
+
+// load reads the user's favorites, empty when none were saved
+func load(st store.Store, user string) (*state, error) {
+	data, err := st.GetSetting(settingPrefix + user)
+	if errors.Is(err, store.ErrNotFound) {
+		return &state{Items: []Item{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode favorites of %s: %w", user, err)
+	}
+	if s.Items == nil {
+		s.Items = []Item{}
+	}
+	return &s, nil
+}
+
+// save persists the user's favorites
+func save(st store.Store, user string, s *state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode favorites of %s: %w", user, err)
+	}
+	return st.PutSetting(settingPrefix+user, data)
+}
@@ -0,0 +1,105 @@
+package findings
+
+import (
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/store"
+)
+
+// principalDetails are the finding details that name the principal a finding
+// is about, depending on the rule
+var principalDetails = []string{"principal", "member", "serviceAccount", "email", "domain"}
+
+// Excepts reports whether an exception covers a finding. Expiry is not
+// checked; see Active.
+func Excepts(exception store.Exception, finding analysis.Finding) bool {
+	if finding.Kind != exception.Rule {
+		return false
+	}
+	if exception.Principal != "" && !findingPrincipal(finding, exception.Principal) {
+		return false
+	}
+	if exception.Resource != "" && !findingResource(finding, exception.Resource) {
+		return false
+	}
+	if exception.Role != "" && !findingRole(finding, exception.Role) {
+		return false
+	}
+	return true
+}
+
+// Active returns the exceptions that have not expired at now
+func Active(exceptions []store.Exception, now time.Time) []store.Exception {
+	active := []store.Exception{}
+	for _, exception := range exceptions {
+		if now.Before(exception.ExpiresAt) {
+			active = append(active, exception)
+		}
+	}
+	return active
+}
+
+// excepting returns the first exception that covers a finding, or nil
+func excepting(exceptions []store.Exception, finding analysis.Finding) *store.Exception {
+	for i := range exceptions {
+		if Excepts(exceptions[i], finding) {
+			return &exceptions[i]
+		}
+	}
+	return nil
+}
+
+// Principal returns the principal a finding is about, or "" for findings
+// about something else, such as an API key
+func Principal(finding analysis.Finding) string {
+	for _, key := range principalDetails {
+		if principal := finding.Details[key]; principal != "" {
+			return principal
+		}
+	}
+	if subject, _, ok := strings.Cut(finding.Subject, " on "); ok {
+		return subject
+	}
+	return ""
+}
+
+// ResourceID returns the ID of the resource a finding is about, or ""
+func ResourceID(finding analysis.Finding) string {
+	if resource := finding.Details["resourceId"]; resource != "" {
+		return resource
+	}
+	_, resource, _ := strings.Cut(finding.Subject, " on ")
+	return resource
+}
+
+// findingPrincipal reports whether a finding is about principal
+func findingPrincipal(finding analysis.Finding, principal string) bool {
+	for _, key := range principalDetails {
+		if strings.EqualFold(finding.Details[key], principal) {
+			return true
+		}
+	}
+	subject, _, _ := strings.Cut(finding.Subject, " on ")
+	return strings.EqualFold(subject, principal)
+}
+
+// findingResource reports whether a finding is about resource
+func findingResource(finding analysis.Finding, resource string) bool {
+	if finding.Details["resourceId"] == resource {
+		return true
+	}
+	_, subject, ok := strings.Cut(finding.Subject, " on ")
+	return ok && subject == resource
+}
+
+// findingRole reports whether a finding involves role
+func findingRole(finding analysis.Finding, role string) bool {
+	for _, r := range strings.Split(finding.Details["roles"], ",") {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
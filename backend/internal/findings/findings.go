@@ -0,0 +1,115 @@
+package findings
+
+import (
+	"log"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/inventory"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Options configures the finding rules
+type Options struct {
+	KeyRotation analysis.KeyRotationPolicy
+	// DormantAfter flags service accounts that have not authenticated for this long
+	DormantAfter time.Duration
+	// TrustedDomains separates external principals from your own
+	TrustedDomains []string
+}
+
+// Engine gathers the live inventories that findings need beyond the access
+// matrix (API keys, service account keys, ...) and runs every finding rule
+type Engine struct {
+	client      *gcp.Client
+	store       store.Store
+	inventories *inventory.Cache
+	options     func() Options
+}
+
+// NewEngine creates a findings engine that reads live inventories through
+// inventories. options is read on every evaluation; exceptions are read from st.
+func NewEngine(client *gcp.Client, st store.Store, inventories *inventory.Cache, options func() Options) *Engine {
+	return &Engine{client: client, store: st, inventories: inventories, options: options}
+}
+
+// Evaluate returns the findings for a snapshot that no active exception
+// covers, most severe first
+func (e *Engine) Evaluate(snapshot *scanner.Snapshot) []analysis.Finding {
+	all, exceptions := e.EvaluateAll(snapshot)
+	findings := []analysis.Finding{}
+	for _, finding := range all {
+		if excepting(exceptions, finding) == nil {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// EvaluateAll returns every finding for a snapshot, excepted ones included,
+// with the active exceptions. Inventories that cannot be read (e.g. a
+// disabled API) are skipped with a warning.
+func (e *Engine) EvaluateAll(snapshot *scanner.Snapshot) ([]analysis.Finding, []store.Exception) {
+	exceptions, err := e.store.ListExceptions()
+	if err != nil {
+		log.Printf("Warning: failed to list finding exceptions: %v", err)
+	}
+	return e.evaluate(snapshot), Active(exceptions, time.Now())
+}
+
+// evaluate runs every finding rule on a snapshot
+func (e *Engine) evaluate(snapshot *scanner.Snapshot) []analysis.Finding {
+	options := e.options()
+	findings := analysis.DomainFindings(analysis.DomainExposures(snapshot.Matrix))
+	findings = append(findings, analysis.SpecialPrincipalFindings(snapshot.Matrix)...)
+	findings = append(findings, analysis.ExternalOwnerFindings(snapshot.Matrix, options.TrustedDomains)...)
+	findings = append(findings, analysis.DeletedPrincipalFindings(analysis.DeletedPrincipals(snapshot.Matrix))...)
+	findings = append(findings, analysis.SSHKeyFindings(analysis.SSHKeyInventory(snapshot.Matrix, time.Now()))...)
+	findings = append(findings, analysis.ExposureFindings(analysis.ExposedVMs(snapshot.Matrix))...)
+
+	keys, err := e.inventories.APIKeys(snapshot)
+	if err != nil {
+		log.Printf("Warning: failed to list API keys for findings: %v", err)
+	} else {
+		findings = append(findings, analysis.APIKeyFindings(keys)...)
+	}
+
+	saKeys, err := e.inventories.ServiceAccountKeys(snapshot)
+	if err != nil {
+		log.Printf("Warning: failed to list service account keys for findings: %v", err)
+	} else {
+		findings = append(findings, analysis.ServiceAccountKeyFindings(saKeys, options.KeyRotation, time.Now())...)
+	}
+
+	dormant, err := e.DormantServiceAccounts(snapshot)
+	if err != nil {
+		log.Printf("Warning: failed to detect dormant service accounts: %v", err)
+	} else {
+		findings = append(findings, analysis.DormantServiceAccountFindings(dormant)...)
+	}
+
+	analysis.SortFindings(findings)
+	return findings
+}
+
+// DormantServiceAccounts combines the service account inventory with Policy
+// Intelligence authentication activity to find grant-holding accounts that are unused
+func (e *Engine) DormantServiceAccounts(snapshot *scanner.Snapshot) ([]analysis.DormantServiceAccount, error) {
+	accounts, err := e.inventories.ServiceAccounts(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := e.inventories.ServiceAccountActivity(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	lastAuth := make(map[string]time.Time, len(activity))
+	for _, a := range activity {
+		lastAuth[a.Email] = a.LastAuthenticated
+	}
+
+	return analysis.DormantServiceAccounts(e.client.ProjectID, snapshot.Matrix, accounts, lastAuth, e.options().DormantAfter, time.Now()), nil
+}
@@ -0,0 +1,103 @@
+package findings
+
+import (
+	"log"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// StatusExcepted is the status of a finding an active exception covers
+const StatusExcepted = "excepted"
+
+// Tracked is a finding with its triage status
+type Tracked struct {
+	analysis.Finding
+	Status    string              `json:"status"`
+	State     *store.FindingState `json:"state,omitempty"`
+	Exception *store.Exception    `json:"exception,omitempty"`
+}
+
+// Track pairs findings with their triage states and the active exceptions
+// covering them. A snooze that has run out and a resolved finding that came
+// back both count as open again; an exception overrides any triage status.
+func Track(findings []analysis.Finding, states []store.FindingState, exceptions []store.Exception, now time.Time) []Tracked {
+	byID := make(map[string]store.FindingState, len(states))
+	for _, state := range states {
+		byID[state.ID] = state
+	}
+
+	tracked := make([]Tracked, 0, len(findings))
+	for _, finding := range findings {
+		t := Tracked{Finding: finding, Status: store.FindingOpen}
+		if state, ok := byID[finding.ID]; ok {
+			t.State = &state
+			t.Status = Status(state, now)
+		}
+		if exception := excepting(exceptions, finding); exception != nil {
+			t.Exception = exception
+			t.Status = StatusExcepted
+		}
+		tracked = append(tracked, t)
+	}
+	return tracked
+}
+
+// Status returns the status a finding's state has at now
+func Status(state store.FindingState, now time.Time) string {
+	switch state.Status {
+	case store.FindingSnoozed:
+		if state.SnoozedUntil == nil || !now.Before(*state.SnoozedUntil) {
+			return store.FindingOpen
+		}
+	case store.FindingResolved:
+		return store.FindingOpen
+	}
+	return state.Status
+}
+
+// LifecycleListener returns a scanner listener that resolves tracked findings
+// a scan no longer finds and reopens resolved ones that came back. False
+// positives keep their status either way, and nothing is resolved after a scan
+// with collector warnings, whose findings may be incomplete.
+func LifecycleListener(engine *Engine, st store.Store) scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		states, err := st.ListFindingStates()
+		if err != nil {
+			log.Printf("Warning: failed to list finding states: %v", err)
+			return
+		}
+		if len(states) == 0 {
+			return
+		}
+
+		present := make(map[string]bool)
+		for _, finding := range engine.Evaluate(current) {
+			present[finding.ID] = true
+		}
+		complete := len(current.Matrix.Warnings) == 0
+
+		for _, state := range states {
+			switch {
+			case state.Status == store.FindingFalsePositive:
+				continue
+			case state.Status == store.FindingResolved && present[state.ID]:
+				state.Status = store.FindingOpen
+				state.SnoozedUntil = nil
+				state.ResolvedAt = nil
+			case state.Status != store.FindingResolved && !present[state.ID] && complete:
+				resolvedAt := current.TakenAt
+				state.Status = store.FindingResolved
+				state.ResolvedAt = &resolvedAt
+			default:
+				continue
+			}
+			state.UpdatedBy = "scanner"
+			if err := st.SetFindingState(&state); err != nil {
+				log.Printf("Warning: failed to update finding %s: %v", state.ID, err)
+			}
+		}
+	}
+}
@@ -3,7 +3,11 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp/resourcename"
 
 	asset "cloud.google.com/go/asset/apiv1"
 	"cloud.google.com/go/asset/apiv1/assetpb"
@@ -17,6 +21,7 @@ type AccessEntry struct {
 	ResourceName string   `json:"resourceName"`
 	ResourceType string   `json:"resourceType"`
 	Roles        []string `json:"roles"`
+	Tier         string   `json:"tier"` // highest privilege tier among Roles
 }
 
 // AccessMatrix represents the complete access matrix
@@ -24,30 +29,78 @@ type AccessMatrix struct {
 	Users     []User        `json:"users"`
 	Resources []Resource    `json:"resources"`
 	Access    []AccessEntry `json:"access"`
+	// Eligible is access principals may request through Privileged Access
+	// Manager entitlements; Access holds only standing access
+	Eligible []EligibleAccess `json:"eligible,omitempty"`
+	// Warnings lists collectors that failed; the matrix holds everything else
+	Warnings []ScanWarning `json:"warnings"`
+	// Projects reports how the scan of each project went
+	Projects []ProjectScan `json:"projects,omitempty"`
+	// History is set on matrices rebuilt as of a past point in time
+	History *HistoryResult `json:"history,omitempty"`
+}
+
+// ProjectScan is the outcome of scanning one project
+type ProjectScan struct {
+	Project   string        `json:"project"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	// Error is set when the project could not be scanned at all
+	Error     string `json:"error,omitempty"`
+	Resources int    `json:"resources"`
+	Entries   int    `json:"entries"`
+	Warnings  int    `json:"warnings"`
+}
+
+// scanProject builds the access matrix of one project and reports the outcome
+func (c *Client) scanProject() (*AccessMatrix, ProjectScan, error) {
+	scan := ProjectScan{Project: c.ProjectID, StartedAt: time.Now()}
+	matrix, err := c.projectAccessMatrix()
+	scan.Duration = time.Since(scan.StartedAt)
+	if err != nil {
+		scan.Error = err.Error()
+		return nil, scan, err
+	}
+	scan.Resources = len(matrix.Resources)
+	scan.Entries = len(matrix.Access)
+	scan.Warnings = len(matrix.Warnings)
+	return matrix, scan, nil
 }
 
-// GetAccessMatrix aggregates all access data using Asset Inventory API
+// GetAccessMatrix aggregates all access data using Asset Inventory API.
+// A failing collector or Asset Inventory search does not fail the scan; the
+// matrix is built from what was gathered and the failures are listed as warnings.
+// With project discovery, every discovered project is scanned and merged.
 func (c *Client) GetAccessMatrix() (*AccessMatrix, error) {
-	// Get users from project IAM
-	users, err := c.GetUsers()
+	if c.Discovery != nil {
+		return c.discoveredAccessMatrix()
+	}
+	matrix, scan, err := c.scanProject()
 	if err != nil {
 		return nil, err
 	}
+	matrix.Projects = []ProjectScan{scan}
+	return matrix, nil
+}
 
-	// Create a set of valid user emails
-	validUsers := make(map[string]bool)
-	for _, user := range users {
-		validUsers[user.Email] = true
+// projectAccessMatrix builds the access matrix of the client's project
+func (c *Client) projectAccessMatrix() (*AccessMatrix, error) {
+	// Get users from project IAM
+	users, err := c.GetUsers()
+	if err != nil {
+		return nil, err
 	}
 
 	// Use Asset Inventory API to search all IAM policies
 	ctx := context.Background()
-	assetClient, err := asset.NewClient(ctx)
+	assetClient, err := asset.NewClient(ctx, meteredGRPCOptions(c.usage, serviceAsset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create asset client: %w", err)
 	}
 	defer assetClient.Close()
 
+	scanScope := c.currentScope()
+
 	// Search all IAM policies in the project
 	scope := fmt.Sprintf("projects/%s", c.ProjectID)
 	req := &assetpb.SearchAllIamPoliciesRequest{
@@ -56,21 +109,16 @@ func (c *Client) GetAccessMatrix() (*AccessMatrix, error) {
 
 	it := assetClient.SearchAllIamPolicies(ctx, req)
 
-	// Maps to track unique resources and access entries
+	// Resources by ID, each holding its IAM bindings
 	resourcesMap := make(map[string]*Resource)
-	accessMap := make(map[string]*AccessEntry) // key: userEmail::resourceID::role
 
 	// 1. Pre-populate with known resources (GKE, VM, Cloud Run)
-	knownResources, err := c.GetResources()
-	if err == nil {
-		for _, res := range knownResources {
-			// Create a copy to avoid pointer issues
-			r := res
-			resourcesMap[res.ID] = &r
-		}
-	} else {
-		// Log error but continue with IAM search
-		fmt.Printf("Warning: failed to fetch known resources: %v\n", err)
+	knownResources, warnings := c.collectResources(ctx, assetClient)
+	for _, res := range knownResources {
+		// Create a copy to avoid pointer issues
+		r := res
+		r.ID = c.normalizeResourceName(r.ID)
+		resourcesMap[r.ID] = &r
 	}
 
 	for {
@@ -79,181 +127,216 @@ func (c *Client) GetAccessMatrix() (*AccessMatrix, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate policies: %w", err)
+			// Keep the policies read so far alongside the collector results
+			log.Printf("Warning: Asset Inventory IAM search failed: %v", err)
+			warnings = append(warnings, newScanWarning(AssetInventoryCollector, err, "cloudasset.assets.searchAllIamPolicies"))
+			break
 		}
 
-		resourceID := policy.Resource
-		resourceName := extractResourceName(resourceID)
-		resourceType := extractResourceType(resourceID)
-
-		// Add resource if not already tracked
-		if _, exists := resourcesMap[resourceID]; !exists {
-			resourcesMap[resourceID] = &Resource{
-				ID:       resourceID,
-				Name:     resourceName,
-				Type:     resourceType,
-				Location: "global", // Asset API doesn't provide location directly
-				IAM:      make(map[string][]string),
-			}
-		}
+		c.applyPolicy(resourcesMap, policy, scanScope)
+	}
 
-		// Process IAM bindings
-		for _, binding := range policy.Policy.Bindings {
-			role := binding.Role
-			for _, member := range binding.Members {
-				user := parseUser(member)
+	// This ensures we capture users with only resource-level permissions
+	users = appendBoundUsers(users, resourcesMap)
 
-				// Add user to validUsers if not already present
-				// This ensures we capture users with only resource-level permissions
-				if !validUsers[user.Email] {
-					validUsers[user.Email] = true
-					users = append(users, user)
-				}
+	// Resources are attributed to the project so access can be rebuilt per project
+	for _, resource := range resourcesMap {
+		resource.Project = c.projectName()
+	}
+	accessEntries := c.buildAccess(resourcesMap)
 
-				// Add to access entries
-				key := fmt.Sprintf("%s::%s::%s", user.Email, resourceID, role)
-				if _, exists := accessMap[key]; !exists {
-					accessMap[key] = &AccessEntry{
-						UserEmail:    user.Email,
-						ResourceID:   resourceID,
-						ResourceName: resourceName,
-						ResourceType: resourceType,
-						Roles:        []string{role},
-					}
-				}
-			}
+	// Convert maps to slices
+	resources := make([]Resource, 0, len(resourcesMap))
+	for _, res := range resourcesMap {
+		resources = append(resources, *res)
+	}
+
+	// Entitlements are optional: without them the matrix holds standing access only
+	var eligible []EligibleAccess
+	if scanScope.CollectorEnabled(PAMCollector) {
+		entitlements, err := c.GetEntitlements()
+		if err != nil {
+			warnings = append(warnings, newScanWarning(PAMCollector, err, "privilegedaccessmanager.entitlements.list"))
+		} else {
+			eligible = c.eligibleAccess(entitlements, resourcesMap)
 		}
 	}
 
-	// Step 2: Resolve inherited permissions from project-level IAM
-	// Find the project resource and propagate its IAM bindings to child resources
-	projectResourceID := fmt.Sprintf("//cloudresourcemanager.googleapis.com/projects/%s", c.ProjectID)
+	return &AccessMatrix{
+		Users:     users,
+		Resources: resources,
+		Access:    accessEntries,
+		Eligible:  eligible,
+		Warnings:  warnings,
+	}, nil
+}
 
-	// Collect all project-level access entries
-	projectAccessByUser := make(map[string][]string) // userEmail -> []roles
-	for _, entry := range accessMap {
-		if entry.ResourceID == projectResourceID {
-			projectAccessByUser[entry.UserEmail] = append(projectAccessByUser[entry.UserEmail], entry.Roles[0])
-		}
+// applyPolicy records the bindings of an Asset Inventory IAM policy on its
+// resource, adding the resource when it is not tracked yet. Resources of
+// disabled collectors and outside the allowed locations are skipped.
+func (c *Client) applyPolicy(resourcesMap map[string]*Resource, policy *assetpb.IamPolicySearchResult, scanScope ScanScope) {
+	// The project and some resources are named by project number
+	resourceID := c.normalizeResourceName(policy.Resource)
+	parsedName := resourcename.Parse(resourceID)
+	resourceType := parsedName.FriendlyType
+
+	// Prefer the official asset type over name parsing
+	assetType := policy.GetAssetType()
+	if assetType == "" {
+		assetType = parsedName.AssetType
+	} else {
+		resourceType = resourcename.FriendlyTypeForAssetType(assetType)
 	}
 
-	fmt.Printf("Found %d users with project-level permissions\n", len(projectAccessByUser))
+	// Skip resource types whose collector is disabled (the project itself is always kept)
+	if resourceType != "project" && !scanScope.CollectorEnabled(resourceType) {
+		return
+	}
 
-	// For each user with project-level permissions, create inherited access entries
-	for userEmail, roles := range projectAccessByUser {
-		for _, role := range roles {
-			// Determine which resource types this role applies to
-			applicableTypes := getApplicableResourceTypes(role)
+	// Add resource if not already tracked
+	if _, exists := resourcesMap[resourceID]; !exists {
+		resource := &Resource{
+			ID:        resourceID,
+			Name:      parsedName.DisplayName,
+			Type:      resourceType,
+			Location:  parsedName.Location,
+			AssetType: assetType,
+			Project:   policy.GetProject(),
+			IAM:       make(map[string][]string),
+		}
+		resource.normalizeLocation()
+		if !scanScope.LocationAllowed(resource.Location) {
+			return
+		}
+		resourcesMap[resourceID] = resource
+	}
 
-			// Create access entries for all matching resources
-			for resourceID, resource := range resourcesMap {
-				// Skip the project itself
-				if resourceID == projectResourceID {
-					continue
-				}
+	// Keep the raw bindings on the resource, conditional ones also separately
+	resource := resourcesMap[resourceID]
+	unconditional := make(map[string][]string)
+	var conditional []ConditionalBinding
+	for _, binding := range policy.Policy.Bindings {
+		role := binding.Role
+		resource.addBinding(role, binding.Members)
+		if cond := binding.GetCondition(); cond.GetExpression() != "" {
+			conditional = append(conditional, ConditionalBinding{
+				Role:      role,
+				Members:   binding.Members,
+				Condition: Condition{Title: cond.GetTitle(), Description: cond.GetDescription(), Expression: cond.GetExpression()},
+			})
+		} else {
+			unconditional[role] = append(unconditional[role], binding.Members...)
+		}
+	}
+	resource.addConditionalBindings(conditional, unconditional)
+}
 
-				// Check if this role applies to this resource type
-				if contains(applicableTypes, resource.Type) {
-					key := fmt.Sprintf("%s::%s::%s", userEmail, resourceID, role)
-					// Only add if not already exists (don't override direct permissions)
-					if _, exists := accessMap[key]; !exists {
-						accessMap[key] = &AccessEntry{
-							UserEmail:    userEmail,
-							ResourceID:   resourceID,
-							ResourceName: resource.Name,
-							ResourceType: resource.Type,
-							Roles:        []string{role},
-						}
-					}
+// appendBoundUsers appends every member bound on the resources that is not
+// among users yet
+func appendBoundUsers(users []User, resourcesMap map[string]*Resource) []User {
+	known := make(map[string]bool, len(users))
+	for _, user := range users {
+		known[user.Email] = true
+	}
+	for _, resource := range resourcesMap {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				user := ParseMember(member)
+				if !known[user.Email] {
+					known[user.Email] = true
+					users = append(users, user)
 				}
 			}
 		}
 	}
+	return users
+}
 
-	// Convert maps to slices
-	var resources []Resource
-	for _, res := range resourcesMap {
-		resources = append(resources, *res)
+// projectName returns the "projects/NUMBER" name of the client's project, or
+// "projects/ID" when the number is unknown
+func (c *Client) projectName() string {
+	if c.ProjectNumber != "" {
+		return "projects/" + c.ProjectNumber
 	}
+	return "projects/" + c.ProjectID
+}
 
-	var accessEntries []AccessEntry
-	// Group roles by user-resource combination
-	userResourceRoles := make(map[string][]string) // key: userEmail::resourceID
-	for _, entry := range accessMap {
-		key := fmt.Sprintf("%s::%s", entry.UserEmail, entry.ResourceID)
-		userResourceRoles[key] = append(userResourceRoles[key], entry.Roles[0])
-	}
+// buildAccess derives the access entries of the project's resources from their
+// IAM bindings: direct grants, convenience members expanded to the holders of
+// the basic role they stand for, and project-level roles inherited by the
+// resources they apply to. Roles are grouped per principal and resource.
+func (c *Client) buildAccess(resourcesMap map[string]*Resource) []AccessEntry {
+	builder := c.accessBuilder(resourcesMap)
+	accessEntries := make([]AccessEntry, 0, len(builder.order))
+	builder.each(c.RoleTier, func(entry AccessEntry) {
+		accessEntries = append(accessEntries, entry)
+	})
+	return accessEntries
+}
 
-	for key, roles := range userResourceRoles {
-		parts := strings.Split(key, "::")
-		if len(parts) != 2 {
-			continue
-		}
-		userEmail := parts[0]
-		resourceID := parts[1]
-
-		resource := resourcesMap[resourceID]
-		if resource != nil {
-			accessEntries = append(accessEntries, AccessEntry{
-				UserEmail:    userEmail,
-				ResourceID:   resourceID,
-				ResourceName: resource.Name,
-				ResourceType: resource.Type,
-				Roles:        roles,
-			})
+// accessBuilder records the grants of buildAccess
+func (c *Client) accessBuilder(resourcesMap map[string]*Resource) *accessBuilder {
+	builder := newAccessBuilder(resourcesMap)
+
+	// Bindings come from Asset Inventory and from policies collectors read
+	// directly (e.g. Spanner databases) in case Asset Inventory has not indexed them yet
+	for r, resource := range builder.resources {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			roleIndex := builder.role(role)
+			for _, member := range members {
+				builder.grant(builder.user(ParseMember(member).Email), int32(r), roleIndex)
+			}
 		}
 	}
 
-	return &AccessMatrix{
-		Users:     users,
-		Resources: resources,
-		Access:    accessEntries,
-	}, nil
-}
+	projectResourceID := fmt.Sprintf("//cloudresourcemanager.googleapis.com/projects/%s", c.ProjectID)
+	project, ok := builder.resource(projectResourceID)
+	if !ok {
+		return builder
+	}
 
-// extractResourceName extracts a human-readable name from resource ID
-func extractResourceName(resourceID string) string {
-	// Resource ID format: //service.googleapis.com/projects/PROJECT/...
-	parts := strings.Split(resourceID, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+	// Expand convenience members (projectEditor:my-project, ...) to the project
+	// members holding the basic role they stand for, so access granted through
+	// legacy bucket policies is attributed to the people who actually have it
+	expandConvenienceMembers(builder, builder.resources[project], c.IsScannedProject)
+
+	// Step 2: Resolve inherited permissions from project-level IAM: every
+	// project-level role is granted on the resources it applies to, unless
+	// the principal holds it there already
+	var projectGrants []grantKey
+	for _, key := range builder.order {
+		if key.resource == project {
+			projectGrants = append(projectGrants, key)
+		}
 	}
-	return resourceID
-}
 
-// extractResourceType extracts the resource type from resource ID
-func extractResourceType(resourceID string) string {
-	// Extract service name from URL
-	if strings.Contains(resourceID, "cloudresourcemanager.googleapis.com/projects") {
-		return "project"
-	} else if strings.Contains(resourceID, "cloudresourcemanager.googleapis.com/organizations") {
-		return "organization"
-	} else if strings.Contains(resourceID, "compute.googleapis.com") {
-		return "vm"
-	} else if strings.Contains(resourceID, "container.googleapis.com") {
-		return "gke"
-	} else if strings.Contains(resourceID, "run.googleapis.com") {
-		return "cloudrun"
-	} else if strings.Contains(resourceID, "storage.googleapis.com") {
-		return "storage"
-	} else if strings.Contains(resourceID, "bigquery.googleapis.com") {
-		return "bigquery"
-	} else if strings.Contains(resourceID, "iam.googleapis.com") {
-		return "serviceaccount"
-	}
-
-	// Default: extract service name
-	if strings.HasPrefix(resourceID, "//") {
-		parts := strings.Split(resourceID[2:], "/")
-		if len(parts) > 0 {
-			serviceParts := strings.Split(parts[0], ".")
-			if len(serviceParts) > 0 {
-				return serviceParts[0]
+	fmt.Printf("Found %d users with project-level permissions\n", len(projectGrants))
+
+	for _, key := range projectGrants {
+		for _, role := range builder.grants[key] {
+			for _, resources := range builder.applicableResources(role) {
+				for _, resource := range resources {
+					// Skip the project itself
+					if resource != project {
+						builder.grant(key.user, resource, role)
+					}
+				}
 			}
 		}
 	}
+	return builder
+}
 
-	return "other"
+// RoleAppliesTo reports whether a project-level grant of role is inherited by
+// resources of the given type
+func RoleAppliesTo(role, resourceType string) bool {
+	return contains(getApplicableResourceTypes(role), resourceType)
 }
 
 // getApplicableResourceTypes returns the resource types that a given role applies to
@@ -261,15 +344,39 @@ func extractResourceType(resourceID string) string {
 func getApplicableResourceTypes(role string) []string {
 	// Owner, Editor, and Viewer roles apply to all resource types
 	if strings.Contains(role, "roles/owner") || strings.Contains(role, "roles/editor") || strings.Contains(role, "roles/viewer") {
-		return []string{"storage", "vm", "gke", "cloudrun", "bigquery", "project", "serviceaccount"}
+		return allResourceTypes()
 	}
 
-	// Storage roles apply to storage buckets
+	// Storage roles apply to storage buckets, including legacy Container Registry buckets
 	if strings.Contains(role, "roles/storage.") {
-		return []string{"storage"}
+		return []string{"storage", "containerregistry"}
+	}
+
+	// Artifact Registry roles apply to repositories
+	if strings.Contains(role, "roles/artifactregistry.") {
+		return []string{"artifactregistry"}
+	}
+
+	// Network roles apply to VPC networks and subnets; firewall rules are
+	// modified through the security admin role
+	if strings.Contains(role, "roles/compute.networkAdmin") || strings.Contains(role, "roles/compute.networkUser") || strings.Contains(role, "roles/compute.networkViewer") {
+		return []string{"network", "subnet"}
+	}
+	if strings.Contains(role, "roles/compute.securityAdmin") {
+		return []string{"firewall"}
+	}
+
+	// IAP roles decide who can reach IAP-protected backend services
+	if strings.Contains(role, "roles/iap.") {
+		return []string{"backendservice"}
 	}
 
-	// Compute roles apply to VMs
+	// Compute admin and viewer span instances and networking
+	if strings.Contains(role, "roles/compute.admin") || strings.Contains(role, "roles/compute.viewer") {
+		return []string{"vm", "network", "subnet", "firewall"}
+	}
+
+	// Other compute roles apply to VMs
 	if strings.Contains(role, "roles/compute.") {
 		return []string{"vm"}
 	}
@@ -289,6 +396,48 @@ func getApplicableResourceTypes(role string) []string {
 		return []string{"bigquery"}
 	}
 
+	// Spanner roles apply to instances and databases
+	if strings.Contains(role, "roles/spanner.") {
+		return []string{"spanner"}
+	}
+
+	// Firestore is governed by the Datastore roles
+	if strings.Contains(role, "roles/datastore.") {
+		return []string{"firestore"}
+	}
+
+	// Bigtable roles apply to instances
+	if strings.Contains(role, "roles/bigtable.") {
+		return []string{"bigtable"}
+	}
+
+	// Data platform roles apply to their jobs, clusters, and environments
+	if strings.Contains(role, "roles/dataflow.") {
+		return []string{"dataflow"}
+	}
+	if strings.Contains(role, "roles/dataproc.") {
+		return []string{"dataproc"}
+	}
+	if strings.Contains(role, "roles/composer.") {
+		return []string{"composer"}
+	}
+
+	// Scheduler, Tasks, and Eventarc roles apply to their jobs, queues, and triggers
+	if strings.Contains(role, "roles/cloudscheduler.") {
+		return []string{"scheduler"}
+	}
+	if strings.Contains(role, "roles/cloudtasks.") {
+		return []string{"tasks"}
+	}
+	if strings.Contains(role, "roles/eventarc.") {
+		return []string{"eventarc"}
+	}
+
+	// API key admin/viewer roles apply to API keys
+	if strings.Contains(role, "roles/serviceusage.apiKeys") {
+		return []string{"apikey"}
+	}
+
 	// IAM roles apply to service accounts
 	if strings.Contains(role, "roles/iam.") {
 		return []string{"serviceaccount"}
@@ -298,6 +447,27 @@ func getApplicableResourceTypes(role string) []string {
 	return []string{}
 }
 
+// outsideProjectTypes are resource types that live outside the project hierarchy,
+// so project-level roles never grant access to them
+var outsideProjectTypes = []string{"billing"}
+
+// allResourceTypes returns every friendly resource type known to the parser or a collector
+// that can inherit project-level grants
+func allResourceTypes() []string {
+	var types []string
+	for _, name := range CollectorNames() {
+		if !contains(outsideProjectTypes, name) {
+			types = append(types, name)
+		}
+	}
+	for _, assetType := range resourcename.KnownAssetTypes() {
+		if !contains(types, assetType.FriendlyType) && !contains(outsideProjectTypes, assetType.FriendlyType) {
+			types = append(types, assetType.FriendlyType)
+		}
+	}
+	return types
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -307,3 +477,36 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// expandConvenienceMembers grants the role of every convenience member bound
+// in builder to each project member holding the basic role behind it.
+// Convenience members of other projects, by ID or number, are left
+// unexpanded: their project's policy is not part of the scan.
+func expandConvenienceMembers(builder *accessBuilder, project *Resource, scanned func(project string) bool) {
+	type expansion struct {
+		holder string
+		key    grantKey
+	}
+	var expanded []expansion
+	for _, key := range builder.order {
+		email := builder.users[key.user]
+		basicRole, ok := ConvenienceRole(ParseMember(email).Type)
+		if !ok {
+			continue
+		}
+		if !scanned(ConvenienceProject(email)) {
+			continue
+		}
+		for _, holder := range project.IAM[basicRole] {
+			expanded = append(expanded, expansion{holder: holder, key: key})
+		}
+	}
+
+	// Expansions are granted after the scan so they are not expanded again
+	for _, expansion := range expanded {
+		user := builder.user(ParseMember(expansion.holder).Email)
+		for _, role := range builder.grants[expansion.key] {
+			builder.grant(user, expansion.key.resource, role)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package gcp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	accesscontextmanager "google.golang.org/api/accesscontextmanager/v1"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// accessLevelPattern matches access level names in condition expressions, e.g.
+// 'accessPolicies/123/accessLevels/corp_network' in request.auth.access_levels
+var accessLevelPattern = regexp.MustCompile(`accessPolicies/[0-9]+/accessLevels/[A-Za-z][A-Za-z0-9_]*`)
+
+// AccessLevel is an Access Context Manager access level with the constraints a
+// request must meet to be granted it
+type AccessLevel struct {
+	Name        string `json:"name"` // accessPolicies/P/accessLevels/L
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Combining is "AND" or "OR": whether every condition or any one of them
+	// must hold. Custom levels have an Expression instead of conditions.
+	Combining  string                 `json:"combining,omitempty"`
+	Conditions []AccessLevelCondition `json:"conditions,omitempty"`
+	Expression string                 `json:"expression,omitempty"`
+	// Perimeters lists the service perimeters around the project that admit
+	// requests meeting this level
+	Perimeters []string `json:"perimeters,omitempty"`
+}
+
+// AccessLevelCondition is one condition of a basic access level. Every
+// non-empty constraint must hold, or, when Negate is set, at least one must not.
+type AccessLevelCondition struct {
+	IPSubnetworks        []string           `json:"ipSubnetworks,omitempty"`
+	VPCNetworks          []string           `json:"vpcNetworks,omitempty"` // network, optionally "network ranges"
+	Regions              []string           `json:"regions,omitempty"`     // ISO 3166-1 alpha-2 codes
+	Members              []string           `json:"members,omitempty"`
+	RequiredAccessLevels []string           `json:"requiredAccessLevels,omitempty"`
+	Device               *DeviceConstraints `json:"device,omitempty"`
+	Negate               bool               `json:"negate,omitempty"`
+}
+
+// DeviceConstraints is the device policy of an access level condition
+type DeviceConstraints struct {
+	RequireScreenlock    bool     `json:"requireScreenlock,omitempty"`
+	RequireCorpOwned     bool     `json:"requireCorpOwned,omitempty"`
+	RequireAdminApproval bool     `json:"requireAdminApproval,omitempty"`
+	EncryptionStatuses   []string `json:"encryptionStatuses,omitempty"` // e.g. "ENCRYPTED"
+	ManagementLevels     []string `json:"managementLevels,omitempty"`   // e.g. "COMPLETE"
+	// OSConstraints are allowed operating systems, e.g. "DESKTOP_MAC 10.15.0"
+	OSConstraints []string `json:"osConstraints,omitempty"`
+}
+
+// ServicePerimeter is a VPC Service Controls perimeter that includes the
+// scanned project
+type ServicePerimeter struct {
+	Name  string `json:"name"` // accessPolicies/P/servicePerimeters/S
+	Title string `json:"title"`
+	// DryRun is set when only the dry-run configuration includes the project
+	DryRun             bool     `json:"dryRun,omitempty"`
+	RestrictedServices []string `json:"restrictedServices"`
+	AccessLevels       []string `json:"accessLevels"`
+}
+
+// AccessContext holds the access levels referenced by the project's IAM
+// conditions and VPC Service Controls perimeters, resolved to their constraints
+type AccessContext struct {
+	Organization string             `json:"organization,omitempty"` // "organizations/N"
+	Levels       []AccessLevel      `json:"levels"`
+	Perimeters   []ServicePerimeter `json:"perimeters"`
+	// Missing lists referenced levels that could not be found
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Level returns the access level with the given name, or nil
+func (a *AccessContext) Level(name string) *AccessLevel {
+	if a == nil {
+		return nil
+	}
+	for i := range a.Levels {
+		if a.Levels[i].Name == name {
+			return &a.Levels[i]
+		}
+	}
+	return nil
+}
+
+// AccessLevelReferences returns the access level names an IAM condition
+// expression refers to, in order of appearance
+func AccessLevelReferences(expression string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, name := range accessLevelPattern.FindAllString(expression, -1) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetAccessContext resolves the access levels named in referenced (typically
+// from IAM conditions) and those of the service perimeters that include the
+// project. Perimeters are found in the access policies of the project's
+// organization; levels of other policies are looked up by name.
+func (c *Client) GetAccessContext(referenced []string) (*AccessContext, error) {
+	project, err := c.GetProject(c.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	organization, folders, err := c.ancestors()
+	if err != nil {
+		return nil, err
+	}
+
+	accessContext := &AccessContext{Levels: []AccessLevel{}, Perimeters: []ServicePerimeter{}}
+	policies := []string{}
+	if organization != "" {
+		accessContext.Organization = organization
+		err := c.AccessContext.AccessPolicies.List().Parent(organization).
+			Pages(c.ctx, func(page *accesscontextmanager.ListAccessPoliciesResponse) error {
+				for _, policy := range page.AccessPolicies {
+					if policyInScope(policy.Scopes, project.Number, folders) {
+						policies = append(policies, policy.Name)
+					}
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list access policies of %s: %w", organization, err)
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range referenced {
+		wanted[name] = true
+	}
+	levelPerimeters := make(map[string][]string)
+	for _, policy := range policies {
+		err := c.AccessContext.AccessPolicies.ServicePerimeters.List(policy).
+			Pages(c.ctx, func(page *accesscontextmanager.ListServicePerimetersResponse) error {
+				for _, perimeter := range page.ServicePerimeters {
+					config, dryRun := perimeterConfig(perimeter, project.Number)
+					if config == nil {
+						continue
+					}
+					accessContext.Perimeters = append(accessContext.Perimeters, ServicePerimeter{
+						Name:               perimeter.Name,
+						Title:              perimeter.Title,
+						DryRun:             dryRun,
+						RestrictedServices: nonNil(config.RestrictedServices),
+						AccessLevels:       nonNil(config.AccessLevels),
+					})
+					for _, level := range config.AccessLevels {
+						wanted[level] = true
+						levelPerimeters[level] = append(levelPerimeters[level], perimeter.Name)
+					}
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list service perimeters of %s: %w", policy, err)
+		}
+	}
+
+	// Resolve the wanted levels policy by policy, following required levels
+	// of basic conditions within the same policy
+	resolved := make(map[string]bool)
+	for len(wanted) > 0 {
+		byPolicy := make(map[string]bool)
+		for name := range wanted {
+			policy, _, _ := strings.Cut(strings.TrimPrefix(name, "accessPolicies/"), "/")
+			byPolicy["accessPolicies/"+policy] = true
+		}
+		next := make(map[string]bool)
+		for policy := range byPolicy {
+			err := c.AccessContext.AccessPolicies.AccessLevels.List(policy).AccessLevelFormat("AS_DEFINED").
+				Pages(c.ctx, func(page *accesscontextmanager.ListAccessLevelsResponse) error {
+					for _, level := range page.AccessLevels {
+						if !wanted[level.Name] || resolved[level.Name] {
+							continue
+						}
+						resolved[level.Name] = true
+						converted := convertAccessLevel(level)
+						converted.Perimeters = levelPerimeters[level.Name]
+						accessContext.Levels = append(accessContext.Levels, converted)
+						for _, condition := range converted.Conditions {
+							for _, required := range condition.RequiredAccessLevels {
+								if !resolved[required] {
+									next[required] = true
+								}
+							}
+						}
+					}
+					return nil
+				})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list access levels of %s: %w", policy, err)
+			}
+		}
+		for name := range wanted {
+			if !resolved[name] {
+				accessContext.Missing = append(accessContext.Missing, name)
+			}
+		}
+		wanted = next
+	}
+
+	sort.Slice(accessContext.Levels, func(i, j int) bool { return accessContext.Levels[i].Name < accessContext.Levels[j].Name })
+	sort.Strings(accessContext.Missing)
+	return accessContext, nil
+}
+
+// ancestors returns the organization above the scanned project ("" if none)
+// and its ancestor folders as "folders/N"
+func (c *Client) ancestors() (string, map[string]bool, error) {
+	response, err := c.Ancestry.Projects.GetAncestry(c.ProjectID, &cloudresourcemanager.GetAncestryRequest{}).Context(c.ctx).Do()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get ancestry of %s: %w", c.ProjectID, err)
+	}
+	organization := ""
+	folders := make(map[string]bool)
+	for _, ancestor := range response.Ancestor {
+		switch id := ancestor.ResourceId; {
+		case id == nil:
+		case id.Type == "folder":
+			folders["folders/"+id.Id] = true
+		case id.Type == "organization":
+			organization = "organizations/" + id.Id
+		}
+	}
+	return organization, folders, nil
+}
+
+// policyInScope reports whether an access policy applies to the project: an
+// organization-wide policy, or one scoped to the project or a folder above it
+func policyInScope(scopes []string, projectNumber string, folders map[string]bool) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == "projects/"+projectNumber || folders[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// perimeterConfig returns the configuration of a perimeter that includes the
+// project: the enforced one, else the dry-run spec (dryRun set), else nil.
+// Perimeter bridges carry no access levels and are skipped.
+func perimeterConfig(perimeter *accesscontextmanager.ServicePerimeter, projectNumber string) (*accesscontextmanager.ServicePerimeterConfig, bool) {
+	if perimeter.PerimeterType == "PERIMETER_TYPE_BRIDGE" {
+		return nil, false
+	}
+	includes := func(config *accesscontextmanager.ServicePerimeterConfig) bool {
+		if config == nil {
+			return false
+		}
+		for _, resource := range config.Resources {
+			if resource == "projects/"+projectNumber {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case includes(perimeter.Status):
+		return perimeter.Status, false
+	case includes(perimeter.Spec):
+		return perimeter.Spec, true
+	}
+	return nil, false
+}
+
+func convertAccessLevel(level *accesscontextmanager.AccessLevel) AccessLevel {
+	converted := AccessLevel{
+		Name:        level.Name,
+		Title:       level.Title,
+		Description: level.Description,
+	}
+	if level.Custom != nil && level.Custom.Expr != nil {
+		converted.Expression = level.Custom.Expr.Expression
+	}
+	if level.Basic == nil {
+		return converted
+	}
+
+	converted.Combining = level.Basic.CombiningFunction
+	if converted.Combining == "" {
+		converted.Combining = "AND"
+	}
+	for _, condition := range level.Basic.Conditions {
+		c := AccessLevelCondition{
+			IPSubnetworks:        condition.IpSubnetworks,
+			Regions:              condition.Regions,
+			Members:              condition.Members,
+			RequiredAccessLevels: condition.RequiredAccessLevels,
+			Negate:               condition.Negate,
+		}
+		for _, source := range condition.VpcNetworkSources {
+			if subnet := source.VpcSubnetwork; subnet != nil {
+				network := subnet.Network
+				if len(subnet.VpcIpSubnetworks) > 0 {
+					network += " " + strings.Join(subnet.VpcIpSubnetworks, ",")
+				}
+				c.VPCNetworks = append(c.VPCNetworks, network)
+			}
+		}
+		if policy := condition.DevicePolicy; policy != nil {
+			device := &DeviceConstraints{
+				RequireScreenlock:    policy.RequireScreenlock,
+				RequireCorpOwned:     policy.RequireCorpOwned,
+				RequireAdminApproval: policy.RequireAdminApproval,
+				EncryptionStatuses:   policy.AllowedEncryptionStatuses,
+				ManagementLevels:     policy.AllowedDeviceManagementLevels,
+			}
+			for _, os := range policy.OsConstraints {
+				constraint := os.OsType
+				if os.MinimumVersion != "" {
+					constraint += " " + os.MinimumVersion
+				}
+				device.OSConstraints = append(device.OSConstraints, constraint)
+			}
+			c.Device = device
+		}
+		converted.Conditions = append(converted.Conditions, c)
+	}
+	return converted
+}
+
+// nonNil returns an empty slice for nil, so it renders as [] in JSON
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,59 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	policyanalyzer "google.golang.org/api/policyanalyzer/v1"
+)
+
+// ServiceAccountActivity is the last authentication of a service account as
+// observed by Policy Intelligence
+type ServiceAccountActivity struct {
+	Email             string    `json:"email"`
+	UniqueID          string    `json:"uniqueId"`
+	LastAuthenticated time.Time `json:"lastAuthenticated"`
+}
+
+// serviceAccountAuthentication is the payload of a serviceAccountLastAuthentication activity
+type serviceAccountAuthentication struct {
+	LastAuthenticatedTime time.Time `json:"lastAuthenticatedTime"`
+	ServiceAccount        struct {
+		ServiceAccountID string `json:"serviceAccountId"`
+		FullResourceName string `json:"fullResourceName"`
+	} `json:"serviceAccount"`
+}
+
+// GetServiceAccountActivity returns the last authentication time of every service
+// account that authenticated within the Policy Intelligence observation window.
+// Accounts missing from the result have not authenticated in that window.
+func (c *Client) GetServiceAccountActivity() ([]ServiceAccountActivity, error) {
+	parent := fmt.Sprintf("projects/%s/locations/global/activityTypes/serviceAccountLastAuthentication", c.ProjectID)
+
+	var activities []ServiceAccountActivity
+	err := c.PolicyAnalyzer.Projects.Locations.ActivityTypes.Activities.Query(parent).
+		Pages(c.ctx, func(page *policyanalyzer.GoogleCloudPolicyanalyzerV1QueryActivityResponse) error {
+			for _, activity := range page.Activities {
+				var payload serviceAccountAuthentication
+				if err := json.Unmarshal(activity.Activity, &payload); err != nil {
+					return fmt.Errorf("failed to decode activity for %s: %w", activity.FullResourceName, err)
+				}
+
+				// Full resource names end in serviceAccounts/EMAIL
+				name := payload.ServiceAccount.FullResourceName
+				activities = append(activities, ServiceAccountActivity{
+					Email:             name[strings.LastIndex(name, "/")+1:],
+					UniqueID:          payload.ServiceAccount.ServiceAccountID,
+					LastAuthenticated: payload.LastAuthenticatedTime,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service account activity: %w", err)
+	}
+
+	return activities, nil
+}
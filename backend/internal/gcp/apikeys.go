@@ -0,0 +1,160 @@
+package gcp
+
+import (
+	"fmt"
+	"time"
+
+	apikeys "google.golang.org/api/apikeys/v2"
+	iam "google.golang.org/api/iam/v1"
+)
+
+func init() {
+	RegisterCollector(Collector{Name: "apikey", Collect: (*Client).getAPIKeyResources, Services: []string{"apikeys.googleapis.com"}, Permission: "apikeys.keys.list"})
+}
+
+// APIKeyRestrictions summarizes how an API key is restricted. A key with no API
+// targets can call every enabled API; one with no application restrictions can
+// be used from anywhere.
+type APIKeyRestrictions struct {
+	APITargets       []string `json:"apiTargets"`
+	BrowserReferrers []string `json:"browserReferrers,omitempty"`
+	ServerIPs        []string `json:"serverIps,omitempty"`
+	AndroidApps      []string `json:"androidApps,omitempty"`
+	IOSBundleIDs     []string `json:"iosBundleIds,omitempty"`
+}
+
+// HasApplicationRestriction reports whether the key is bound to referrers, IPs, or apps
+func (r APIKeyRestrictions) HasApplicationRestriction() bool {
+	return len(r.BrowserReferrers) > 0 || len(r.ServerIPs) > 0 || len(r.AndroidApps) > 0 || len(r.IOSBundleIDs) > 0
+}
+
+// APIKey describes an API key of the project. The key string itself is never read.
+type APIKey struct {
+	Name           string             `json:"name"` // projects/P/locations/global/keys/UID
+	UID            string             `json:"uid"`
+	DisplayName    string             `json:"displayName"`
+	CreateTime     time.Time          `json:"createTime"`
+	ServiceAccount string             `json:"serviceAccount,omitempty"` // key bound to a service account
+	Restrictions   APIKeyRestrictions `json:"restrictions"`
+}
+
+// OAuthClient describes an IAM OAuth client (workforce identity federation apps)
+type OAuthClient struct {
+	Name         string   `json:"name"`
+	ClientID     string   `json:"clientId"`
+	DisplayName  string   `json:"displayName"`
+	ClientType   string   `json:"clientType"` // "PUBLIC_CLIENT", "CONFIDENTIAL_CLIENT"
+	GrantTypes   []string `json:"grantTypes"`
+	Scopes       []string `json:"scopes"`
+	RedirectURIs []string `json:"redirectUris"`
+	Disabled     bool     `json:"disabled"`
+}
+
+// GetAPIKeys lists the API keys of the project with their restrictions
+func (c *Client) GetAPIKeys() ([]APIKey, error) {
+	keys := []APIKey{}
+	err := c.APIKeys.Projects.Locations.Keys.List(fmt.Sprintf("projects/%s/locations/global", c.ProjectID)).
+		Pages(c.ctx, func(page *apikeys.V2ListKeysResponse) error {
+			for _, key := range page.Keys {
+				created, _ := time.Parse(time.RFC3339, key.CreateTime)
+				keys = append(keys, APIKey{
+					Name:           key.Name,
+					UID:            key.Uid,
+					DisplayName:    key.DisplayName,
+					CreateTime:     created,
+					ServiceAccount: key.ServiceAccountEmail,
+					Restrictions:   apiKeyRestrictions(key.Restrictions),
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetOAuthClients lists the IAM OAuth clients of the project
+func (c *Client) GetOAuthClients() ([]OAuthClient, error) {
+	clients := []OAuthClient{}
+	err := c.IAM.Projects.Locations.OauthClients.List(fmt.Sprintf("projects/%s/locations/global", c.ProjectID)).
+		Pages(c.ctx, func(page *iam.ListOauthClientsResponse) error {
+			for _, client := range page.OauthClients {
+				clients = append(clients, OAuthClient{
+					Name:         client.Name,
+					ClientID:     client.ClientId,
+					DisplayName:  client.DisplayName,
+					ClientType:   client.ClientType,
+					GrantTypes:   client.AllowedGrantTypes,
+					Scopes:       client.AllowedScopes,
+					RedirectURIs: client.AllowedRedirectUris,
+					Disabled:     client.Disabled,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OAuth clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+// getAPIKeyResources exposes API keys as resources; who can read or rotate them
+// follows from project-level API key roles
+func (c *Client) getAPIKeyResources() ([]Resource, error) {
+	keys, err := c.GetAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, key := range keys {
+		name := key.DisplayName
+		if name == "" {
+			name = key.UID
+		}
+		resource := Resource{
+			ID:        "//apikeys.googleapis.com/" + key.Name,
+			Name:      name,
+			Type:      "apikey",
+			Location:  "global",
+			AssetType: "apikeys.googleapis.com/Key",
+			RunAs:     key.ServiceAccount,
+			IAM:       make(map[string][]string),
+		}
+		resource.IAM["inherited"] = []string{"project-level"}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// apiKeyRestrictions flattens the API Keys API restriction message
+func apiKeyRestrictions(restrictions *apikeys.V2Restrictions) APIKeyRestrictions {
+	result := APIKeyRestrictions{APITargets: []string{}}
+	if restrictions == nil {
+		return result
+	}
+
+	for _, target := range restrictions.ApiTargets {
+		result.APITargets = append(result.APITargets, target.Service)
+	}
+	if r := restrictions.BrowserKeyRestrictions; r != nil {
+		result.BrowserReferrers = r.AllowedReferrers
+	}
+	if r := restrictions.ServerKeyRestrictions; r != nil {
+		result.ServerIPs = r.AllowedIps
+	}
+	if r := restrictions.AndroidKeyRestrictions; r != nil {
+		for _, app := range r.AllowedApplications {
+			result.AndroidApps = append(result.AndroidApps, app.PackageName)
+		}
+	}
+	if r := restrictions.IosKeyRestrictions; r != nil {
+		result.IOSBundleIDs = r.AllowedBundleIds
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package gcp
+
+import "fmt"
+
+func init() {
+	RegisterCollector(Collector{Name: "billing", Collect: (*Client).getBillingAccounts, Services: []string{"cloudbilling.googleapis.com"}, Permission: "billing.resourceAssociations.list", OptIn: true})
+}
+
+// getBillingAccounts reads the billing account linked to the project and who holds
+// billing roles on it. Billing accounts sit outside the project hierarchy, so the
+// collector is opt-in and needs billing.accounts.getIamPolicy on the account.
+func (c *Client) getBillingAccounts() ([]Resource, error) {
+	info, err := c.Billing.Projects.GetBillingInfo(fmt.Sprintf("projects/%s", c.ProjectID)).Context(c.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if !info.BillingEnabled || info.BillingAccountName == "" {
+		return nil, nil
+	}
+
+	resource := Resource{
+		ID:        "//cloudbilling.googleapis.com/" + info.BillingAccountName,
+		Name:      info.BillingAccountName,
+		Type:      "billing",
+		Location:  "global",
+		AssetType: "cloudbilling.googleapis.com/BillingAccount",
+		IAM:       make(map[string][]string),
+	}
+
+	// The display name needs billing.accounts.get; fall back to the account ID
+	if account, err := c.Billing.BillingAccounts.Get(info.BillingAccountName).Context(c.ctx).Do(); err == nil && account.DisplayName != "" {
+		resource.Name = account.DisplayName
+	}
+
+	policy, err := c.Billing.BillingAccounts.GetIamPolicy(info.BillingAccountName).OptionsRequestedPolicyVersion(PolicyVersion).Context(c.ctx).Do()
+	if err == nil && policy != nil {
+		for _, binding := range policy.Bindings {
+			resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+		}
+	}
+
+	return []Resource{resource}, nil
+}
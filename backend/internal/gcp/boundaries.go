@@ -0,0 +1,132 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+
+	iamv3 "cloud.google.com/go/iam/apiv3"
+	"cloud.google.com/go/iam/apiv3/iampb"
+	"google.golang.org/api/iterator"
+)
+
+// AccessBoundary is a Principal Access Boundary (PAB) policy as bound to a set
+// of principals. Principals in the set can only use their roles on resources
+// within the rules of the boundaries bound to them, whatever their role
+// bindings grant elsewhere.
+type AccessBoundary struct {
+	Policy      string `json:"policy"` // organizations/O/locations/global/principalAccessBoundaryPolicies/ID
+	DisplayName string `json:"displayName,omitempty"`
+	Binding     string `json:"binding"` // name of the policy binding
+	// PrincipalSet is the binding target, e.g.
+	// "//cloudresourcemanager.googleapis.com/projects/my-project"
+	PrincipalSet string `json:"principalSet"`
+	// Condition narrows the principals in the set, e.g. by principal.type
+	Condition *Condition `json:"condition,omitempty"`
+	// Resources are the projects, folders, and organizations the principals may
+	// access, as full resource names
+	Resources          []string `json:"resources"`
+	EnforcementVersion string   `json:"enforcementVersion,omitempty"`
+}
+
+// BoundaryScope places the scanned project in the resource hierarchy, which
+// boundary rules and principal sets refer to
+type BoundaryScope struct {
+	ProjectID     string `json:"projectId"`
+	ProjectNumber string `json:"projectNumber"`
+	Parent        string `json:"parent,omitempty"` // "folders/N" or "organizations/N"
+}
+
+// GetAccessBoundaries lists the Principal Access Boundary policies bound at the
+// scanned project and at its parent folder or organization. Bindings on higher
+// ancestors are not listed.
+func (c *Client) GetAccessBoundaries() ([]AccessBoundary, *BoundaryScope, error) {
+	project, err := c.GetProject(c.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scope := &BoundaryScope{ProjectID: project.ID, ProjectNumber: project.Number, Parent: project.Parent}
+
+	opts, err := meteredOptions(c.ctx, c.usage)
+	if err != nil {
+		return nil, nil, err
+	}
+	bindingsClient, err := iamv3.NewPolicyBindingsRESTClient(c.ctx, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create policy bindings client: %w", err)
+	}
+	defer bindingsClient.Close()
+	policiesClient, err := iamv3.NewPrincipalAccessBoundaryPoliciesRESTClient(c.ctx, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create access boundary client: %w", err)
+	}
+	defer policiesClient.Close()
+
+	parents := []string{"projects/" + project.ID}
+	if project.Parent != "" {
+		parents = append(parents, project.Parent)
+	}
+
+	policies := make(map[string]*iampb.PrincipalAccessBoundaryPolicy)
+	boundaries := []AccessBoundary{}
+	for _, parent := range parents {
+		it := bindingsClient.ListPolicyBindings(c.ctx, &iampb.ListPolicyBindingsRequest{
+			Parent: parent + "/locations/global",
+		})
+		for {
+			binding, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list policy bindings of %s: %w", parent, err)
+			}
+			if binding.GetPolicyKind() != iampb.PolicyBinding_PRINCIPAL_ACCESS_BOUNDARY {
+				continue
+			}
+
+			policy, ok := policies[binding.GetPolicy()]
+			if !ok {
+				policy, err = policiesClient.GetPrincipalAccessBoundaryPolicy(c.ctx, &iampb.GetPrincipalAccessBoundaryPolicyRequest{
+					Name: binding.GetPolicy(),
+				})
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to get access boundary %s: %w", binding.GetPolicy(), err)
+				}
+				policies[binding.GetPolicy()] = policy
+			}
+
+			boundary := AccessBoundary{
+				Policy:             binding.GetPolicy(),
+				DisplayName:        policy.GetDisplayName(),
+				Binding:            binding.GetName(),
+				PrincipalSet:       binding.GetTarget().GetPrincipalSet(),
+				Resources:          []string{},
+				EnforcementVersion: policy.GetDetails().GetEnforcementVersion(),
+			}
+			if cond := binding.GetCondition(); cond.GetExpression() != "" {
+				boundary.Condition = &Condition{Title: cond.GetTitle(), Description: cond.GetDescription(), Expression: cond.GetExpression()}
+			}
+			for _, rule := range policy.GetDetails().GetRules() {
+				if rule.GetEffect() == iampb.PrincipalAccessBoundaryPolicyRule_ALLOW {
+					boundary.Resources = append(boundary.Resources, rule.GetResources()...)
+				}
+			}
+			boundaries = append(boundaries, boundary)
+		}
+	}
+	return boundaries, scope, nil
+}
+
+// ServiceAccountProject returns the project ID in a service account email
+// such as "sa@my-project.iam.gserviceaccount.com", or ""
+func ServiceAccountProject(email string) string {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return ""
+	}
+	project, found := strings.CutSuffix(domain, ".iam.gserviceaccount.com")
+	if !found {
+		return ""
+	}
+	return project
+}
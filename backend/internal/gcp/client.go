@@ -2,40 +2,115 @@ package gcp
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	container "cloud.google.com/go/container/apiv1"
+	admin "cloud.google.com/go/iam/admin/apiv1"
 	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
 	run "cloud.google.com/go/run/apiv2"
 )
 
 // Client holds all GCP API clients
 type Client struct {
-	ProjectID       string
+	ProjectID string
+	// ProjectNumber is set by ResolveProject; Asset Inventory names some
+	// resources by number rather than ID
+	ProjectNumber string
+	Scope         ScanScope
+	// Cache, when set, shares role definitions between replicas and across restarts
+	Cache           SharedCache
 	ComputeClient   *compute.InstancesClient
 	ContainerClient *container.ClusterManagerClient
 	RunClient       *run.ServicesClient
 	ResourceManager *resourcemanager.ProjectsClient
-	ctx             context.Context
+	IAMAdminClient  *admin.IamClient
+
+	// PolicyWorkers bounds concurrent GetIamPolicy calls per collector;
+	// zero uses DefaultPolicyWorkers
+	PolicyWorkers int
+
+	// Incremental scans rerun only collectors whose asset types changed since
+	// the previous scan; all collectors run at least every FullScanInterval
+	Incremental      bool
+	FullScanInterval time.Duration
+
+	// GroupCacheTTL is how long direct group memberships are cached and
+	// GroupMaxDepth how many levels of nested groups are expanded; zero uses
+	// DefaultGroupCacheTTL and DefaultGroupMaxDepth
+	GroupCacheTTL time.Duration
+	GroupMaxDepth int
+	// GroupExpansion, when set and returning false, stops group memberships
+	// from being read, so access through groups is not followed
+	GroupExpansion func() bool
+
+	// Discovery, when set, scans every project under an organization or
+	// folder; ProjectID then remains the home project of project-level reads
+	Discovery *ProjectDiscovery
+	// ProjectWorkers is how many discovered projects are scanned at once;
+	// zero scans them one at a time. Dispatcher, when set, takes over and
+	// hands the projects to workers elsewhere, e.g. other processes.
+	ProjectWorkers int
+	Dispatcher     ProjectDispatcher
+
+	// REST services for collectors without a dedicated Cloud Client library
+	RESTServices
+
+	ctx    context.Context
+	roles  *roleCache
+	groups *groupCache
+	// usage counts API calls against the meter of the running scan
+	usage *usageTracker
+
+	// scopeMu guards Scope.Enabled, which can change at runtime
+	scopeMu sync.RWMutex
+
+	incrementalMu sync.Mutex
+	incremental   *incrementalState
+
+	// projects holds the clients of discovered projects
+	projects projectClients
+}
+
+// SharedCache is an external key-value cache such as Redis
+type SharedCache interface {
+	// Get returns the cached value, or false on a miss or error
+	Get(key string) ([]byte, bool)
+	// Set caches a value for ttl; failures are ignored
+	Set(key string, value []byte, ttl time.Duration)
 }
 
 // NewClient creates a new GCP client with all necessary API clients
 func NewClient(ctx context.Context, projectID string) (*Client, error) {
+	// Every client reports its API calls to the tracker so scans can be metered
+	usage := &usageTracker{}
+	opts, err := meteredOptions(ctx, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize REST services first; they hold nothing that needs closing
+	restServices, err := newRESTServices(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize Compute Engine client
-	computeClient, err := compute.NewInstancesRESTClient(ctx)
+	computeClient, err := compute.NewInstancesRESTClient(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize GKE client
-	containerClient, err := container.NewClusterManagerRESTClient(ctx)
+	containerClient, err := container.NewClusterManagerRESTClient(ctx, opts...)
 	if err != nil {
 		computeClient.Close()
 		return nil, err
 	}
 
 	// Initialize Cloud Run client
-	runClient, err := run.NewServicesRESTClient(ctx)
+	runClient, err := run.NewServicesRESTClient(ctx, opts...)
 	if err != nil {
 		computeClient.Close()
 		containerClient.Close()
@@ -43,11 +118,21 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 	}
 
 	// Initialize Resource Manager client
-	resourceManagerClient, err := resourcemanager.NewProjectsRESTClient(ctx)
+	resourceManagerClient, err := resourcemanager.NewProjectsRESTClient(ctx, opts...)
+	if err != nil {
+		computeClient.Close()
+		containerClient.Close()
+		runClient.Close()
+		return nil, err
+	}
+
+	// Initialize IAM admin client (service accounts, keys, roles)
+	iamAdminClient, err := admin.NewIamClient(ctx, meteredGRPCOptions(usage, serviceIAM)...)
 	if err != nil {
 		computeClient.Close()
 		containerClient.Close()
 		runClient.Close()
+		resourceManagerClient.Close()
 		return nil, err
 	}
 
@@ -57,7 +142,14 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 		ContainerClient: containerClient,
 		RunClient:       runClient,
 		ResourceManager: resourceManagerClient,
-		ctx:             ctx,
+		IAMAdminClient:  iamAdminClient,
+
+		RESTServices: *restServices,
+
+		ctx:    ctx,
+		roles:  &roleCache{entries: make(map[string]cachedRole)},
+		groups: &groupCache{entries: make(map[string]cachedGroup)},
+		usage:  usage,
 	}, nil
 }
 
@@ -67,5 +159,6 @@ func (c *Client) Close() error {
 	c.ContainerClient.Close()
 	c.RunClient.Close()
 	c.ResourceManager.Close()
+	c.IAMAdminClient.Close()
 	return nil
 }
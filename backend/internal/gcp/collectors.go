@@ -0,0 +1,164 @@
+package gcp
+
+import (
+	"log"
+
+	"gcp-access-visualizer/internal/gcp/resourcename"
+)
+
+// Collector lists one kind of resource together with its IAM policy
+type Collector struct {
+	// Name is the resource type the collector produces, e.g. "vm"
+	Name    string
+	Collect func(c *Client) ([]Resource, error)
+	// Services lists the APIs the collector calls, for scan cost estimates
+	Services []string
+	// Permission is the IAM permission its list call needs, reported when the
+	// collector fails with a permission error that does not name one
+	Permission string
+	// OptIn collectors only run when listed in ScanScope.Enabled or ScanScope.Extra
+	OptIn bool
+}
+
+// collectorRegistry holds the registered collectors in registration order
+var collectorRegistry []Collector
+
+// RegisterCollector adds a collector to the registry. Collectors register
+// themselves from init functions.
+func RegisterCollector(collector Collector) {
+	collectorRegistry = append(collectorRegistry, collector)
+}
+
+// CollectorNames returns the names of all registered collectors
+func CollectorNames() []string {
+	names := make([]string, 0, len(collectorRegistry))
+	for _, collector := range collectorRegistry {
+		names = append(names, collector.Name)
+	}
+	return names
+}
+
+// ScanScope restricts which collectors run and which locations they cover.
+// The zero value scans everything everywhere.
+type ScanScope struct {
+	// Enabled lists the only collectors/resource types to scan; empty enables all
+	Enabled []string
+	// Disabled lists collectors/resource types to skip
+	Disabled []string
+	// Extra lists opt-in collectors to run in addition to the enabled ones
+	Extra []string
+	// Regions and Zones restrict located resources; empty allows all
+	Regions []string
+	Zones   []string
+}
+
+// CollectorEnabled reports whether a collector or resource type is in scope
+func (s ScanScope) CollectorEnabled(name string) bool {
+	if contains(s.Disabled, name) {
+		return false
+	}
+	return len(s.Enabled) == 0 || contains(s.Enabled, name) || contains(s.Extra, name)
+}
+
+// LocationAllowed reports whether a zone, region, or multi-region is in scope.
+// Global resources are always allowed.
+func (s ScanScope) LocationAllowed(location string) bool {
+	if len(s.Regions) == 0 && len(s.Zones) == 0 {
+		return true
+	}
+
+	normalized := resourcename.NormalizeLocation(location)
+	switch normalized.Kind {
+	case resourcename.LocationGlobal:
+		return true
+	case resourcename.LocationZone:
+		// A zone such as us-central1-a is in scope if listed or if its region is
+		return contains(s.Zones, normalized.Name) || contains(s.Regions, normalized.Region)
+	}
+	return contains(s.Regions, normalized.Name)
+}
+
+// SetEnabledCollectors changes the enabled collectors for subsequent scans
+func (c *Client) SetEnabledCollectors(enabled []string) {
+	c.scopeMu.Lock()
+	defer c.scopeMu.Unlock()
+
+	c.Scope.Enabled = enabled
+}
+
+// ActiveCollectors returns the names of the collectors the next scan runs
+func (c *Client) ActiveCollectors() []string {
+	scope := c.currentScope()
+	names := []string{}
+	for _, collector := range collectorRegistry {
+		if collectorActive(scope, collector) {
+			names = append(names, collector.Name)
+		}
+	}
+	return names
+}
+
+// currentScope returns a copy of the scan scope safe to use during a scan
+func (c *Client) currentScope() ScanScope {
+	c.scopeMu.RLock()
+	defer c.scopeMu.RUnlock()
+
+	return c.Scope
+}
+
+// collectorActive reports whether a collector runs with the scope; opt-in
+// collectors must be listed explicitly
+func collectorActive(scope ScanScope, collector Collector) bool {
+	if !scope.CollectorEnabled(collector.Name) {
+		return false
+	}
+	return !collector.OptIn || contains(scope.Enabled, collector.Name) || contains(scope.Extra, collector.Name)
+}
+
+// runCollectors runs every in-scope collector and drops resources outside the
+// allowed locations. Failed collectors are skipped and reported as warnings.
+func (c *Client) runCollectors() ([]Resource, []ScanWarning) {
+	resources, _, warnings := c.collect(nil)
+	return resources, warnings
+}
+
+// collect runs every in-scope collector, except those for which reuse returns
+// previous resources, and drops resources outside the allowed locations. It also
+// returns what each collector produced before location filtering; a failed
+// collector produces nothing and adds a warning.
+func (c *Client) collect(reuse func(collector Collector) ([]Resource, bool)) ([]Resource, map[string][]Resource, []ScanWarning) {
+	scope := c.currentScope()
+	var resources []Resource
+	warnings := []ScanWarning{}
+	collected := make(map[string][]Resource)
+	for _, collector := range collectorRegistry {
+		if !collectorActive(scope, collector) {
+			continue
+		}
+
+		var produced []Resource
+		reused := false
+		if reuse != nil {
+			produced, reused = reuse(collector)
+		}
+		if !reused {
+			var err error
+			if produced, err = collector.Collect(c); err != nil {
+				log.Printf("Warning: %s collector failed: %v", collector.Name, err)
+				warnings = append(warnings, newScanWarning(collector.Name, err, collector.Permission))
+				produced = nil
+			}
+			for i := range produced {
+				produced[i].normalizeLocation()
+			}
+		}
+		collected[collector.Name] = produced
+
+		for _, res := range produced {
+			if c.Scope.LocationAllowed(res.Location) {
+				resources = append(resources, res)
+			}
+		}
+	}
+	return resources, collected, warnings
+}
@@ -0,0 +1,93 @@
+package gcp
+
+// CompactResource is a Resource without its raw IAM map
+type CompactResource struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Location string `json:"location"`
+}
+
+// CompactAccessEntry references users, resources, and roles by index
+type CompactAccessEntry struct {
+	User     int    `json:"u"`
+	Resource int    `json:"r"`
+	Roles    []int  `json:"roles"`
+	Tier     string `json:"tier"`
+}
+
+// CompactAccessMatrix is a trimmed AccessMatrix where access entries hold
+// integer indices into the users, resources, and roles arrays instead of
+// repeating emails, resource IDs, and role names on every entry
+type CompactAccessMatrix struct {
+	Users     []User               `json:"users"`
+	Resources []CompactResource    `json:"resources"`
+	Roles     []string             `json:"roles"`
+	Access    []CompactAccessEntry `json:"access"`
+	Eligible  []EligibleAccess     `json:"eligible,omitempty"`
+	Warnings  []ScanWarning        `json:"warnings"`
+	Projects  []ProjectScan        `json:"projects,omitempty"`
+	History   *HistoryResult       `json:"history,omitempty"`
+}
+
+// Compact converts the access matrix into its index-based representation
+func (m *AccessMatrix) Compact() *CompactAccessMatrix {
+	compact := &CompactAccessMatrix{
+		Users:     m.Users,
+		Resources: make([]CompactResource, 0, len(m.Resources)),
+		Roles:     []string{},
+		Access:    make([]CompactAccessEntry, 0, len(m.Access)),
+		Eligible:  m.Eligible,
+		Warnings:  m.Warnings,
+		Projects:  m.Projects,
+		History:   m.History,
+	}
+
+	userIndex := make(map[string]int, len(m.Users))
+	for i, user := range m.Users {
+		userIndex[user.Email] = i
+	}
+
+	resourceIndex := make(map[string]int, len(m.Resources))
+	for i, res := range m.Resources {
+		resourceIndex[res.ID] = i
+		compact.Resources = append(compact.Resources, CompactResource{
+			ID:       res.ID,
+			Name:     res.Name,
+			Type:     res.Type,
+			Location: res.Location,
+		})
+	}
+
+	roleIndex := make(map[string]int)
+	for _, entry := range m.Access {
+		u, ok := userIndex[entry.UserEmail]
+		if !ok {
+			continue
+		}
+		r, ok := resourceIndex[entry.ResourceID]
+		if !ok {
+			continue
+		}
+
+		roles := make([]int, 0, len(entry.Roles))
+		for _, role := range entry.Roles {
+			idx, exists := roleIndex[role]
+			if !exists {
+				idx = len(compact.Roles)
+				roleIndex[role] = idx
+				compact.Roles = append(compact.Roles, role)
+			}
+			roles = append(roles, idx)
+		}
+
+		compact.Access = append(compact.Access, CompactAccessEntry{
+			User:     u,
+			Resource: r,
+			Roles:    roles,
+			Tier:     entry.Tier,
+		})
+	}
+
+	return compact
+}
@@ -0,0 +1,56 @@
+package gcp
+
+// PolicyVersion is requested from every getIamPolicy call. Version 3 returns
+// conditional role bindings as such; older versions rename their roles with a
+// "_withcond_" suffix and drop the condition.
+const PolicyVersion = 3
+
+// Condition is an IAM condition: a CEL expression that must hold for a binding to apply
+type Condition struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"`
+}
+
+// ConditionalBinding is a role binding that only applies while its condition holds.
+// Members are listed only if they do not also hold the role unconditionally.
+type ConditionalBinding struct {
+	Role      string    `json:"role"`
+	Members   []string  `json:"members"`
+	Condition Condition `json:"condition"`
+}
+
+// MemberConditions returns the conditions under which member (as parsed by
+// ParseMember) holds role on the resource, or nil if it holds it unconditionally
+func (r *Resource) MemberConditions(role, member string) []Condition {
+	var conditions []Condition
+	for _, binding := range r.Conditions {
+		if binding.Role != role {
+			continue
+		}
+		for _, m := range binding.Members {
+			if ParseMember(m).Email == member {
+				conditions = append(conditions, binding.Condition)
+				break
+			}
+		}
+	}
+	return conditions
+}
+
+// addConditionalBindings records the conditional bindings of a policy, leaving
+// out members the policy also grants the role without a condition
+func (r *Resource) addConditionalBindings(bindings []ConditionalBinding, unconditional map[string][]string) {
+	for _, binding := range bindings {
+		var members []string
+		for _, member := range binding.Members {
+			if !contains(unconditional[binding.Role], member) {
+				members = append(members, member)
+			}
+		}
+		if len(members) > 0 {
+			binding.Members = members
+			r.Conditions = append(r.Conditions, binding)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+
+	bigtableadmin "google.golang.org/api/bigtableadmin/v2"
+	spanner "google.golang.org/api/spanner/v1"
+)
+
+func init() {
+	RegisterCollector(Collector{Name: "spanner", Collect: (*Client).getSpannerResources, Services: []string{"spanner.googleapis.com"}, Permission: "spanner.instances.list"})
+	RegisterCollector(Collector{Name: "firestore", Collect: (*Client).getFirestoreDatabases, Services: []string{"firestore.googleapis.com"}, Permission: "datastore.databases.list"})
+	RegisterCollector(Collector{Name: "bigtable", Collect: (*Client).getBigtableInstances, Services: []string{"bigtableadmin.googleapis.com"}, Permission: "bigtable.instances.list"})
+}
+
+// getSpannerResources lists Spanner instances and their databases with IAM policies;
+// database-level bindings narrow or extend what the instance grants
+func (c *Client) getSpannerResources() ([]Resource, error) {
+	var resources []Resource
+	err := c.Spanner.Projects.Instances.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *spanner.ListInstancesResponse) error {
+			for _, instance := range page.Instances {
+				location := spannerConfigLocation(instance.Config)
+
+				resource := Resource{
+					ID:        "//spanner.googleapis.com/" + instance.Name,
+					Name:      lastSegment(instance.Name),
+					Type:      "spanner",
+					Location:  location,
+					AssetType: "spanner.googleapis.com/Instance",
+					IAM:       make(map[string][]string),
+				}
+				policy, err := c.Spanner.Projects.Instances.GetIamPolicy(instance.Name, &spanner.GetIamPolicyRequest{Options: &spanner.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+				if err == nil && policy != nil {
+					resource.IAM = spannerPolicyBindings(policy)
+				}
+				resources = append(resources, resource)
+
+				databases, err := c.getSpannerDatabases(instance.Name, location)
+				if err != nil {
+					return err
+				}
+				resources = append(resources, databases...)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// getSpannerDatabases lists the databases of one Spanner instance
+func (c *Client) getSpannerDatabases(instance, location string) ([]Resource, error) {
+	var resources []Resource
+	err := c.Spanner.Projects.Instances.Databases.List(instance).
+		Pages(c.ctx, func(page *spanner.ListDatabasesResponse) error {
+			for _, database := range page.Databases {
+				resource := Resource{
+					ID:        "//spanner.googleapis.com/" + database.Name,
+					Name:      lastSegment(instance) + "/" + lastSegment(database.Name),
+					Type:      "spanner",
+					Location:  location,
+					AssetType: "spanner.googleapis.com/Database",
+					IAM:       make(map[string][]string),
+				}
+				policy, err := c.Spanner.Projects.Instances.Databases.GetIamPolicy(database.Name, &spanner.GetIamPolicyRequest{Options: &spanner.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+				if err == nil && policy != nil {
+					resource.IAM = spannerPolicyBindings(policy)
+				}
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// getFirestoreDatabases lists Firestore databases. Firestore has no resource-level
+// IAM, so access is always inherited from the project.
+func (c *Client) getFirestoreDatabases() ([]Resource, error) {
+	resp, err := c.Firestore.Projects.Databases.List(fmt.Sprintf("projects/%s", c.ProjectID)).Context(c.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, database := range resp.Databases {
+		resource := Resource{
+			ID:        "//firestore.googleapis.com/" + database.Name,
+			Name:      lastSegment(database.Name),
+			Type:      "firestore",
+			Location:  database.LocationId,
+			AssetType: "firestore.googleapis.com/Database",
+			IAM:       make(map[string][]string),
+		}
+		resource.IAM["inherited"] = []string{"project-level"}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// getBigtableInstances lists Bigtable instances with their IAM policies. An instance
+// has no location of its own, so the zone of its first cluster is used.
+func (c *Client) getBigtableInstances() ([]Resource, error) {
+	var resources []Resource
+	err := c.Bigtable.Projects.Instances.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *bigtableadmin.ListInstancesResponse) error {
+			for _, instance := range page.Instances {
+				resource := Resource{
+					ID:        "//bigtableadmin.googleapis.com/" + instance.Name,
+					Name:      lastSegment(instance.Name),
+					Type:      "bigtable",
+					AssetType: "bigtableadmin.googleapis.com/Instance",
+					IAM:       make(map[string][]string),
+				}
+
+				clusters, err := c.Bigtable.Projects.Instances.Clusters.List(instance.Name).Context(c.ctx).Do()
+				if err == nil && len(clusters.Clusters) > 0 {
+					resource.Location = lastSegment(clusters.Clusters[0].Location)
+				}
+
+				policy, err := c.Bigtable.Projects.Instances.GetIamPolicy(instance.Name, &bigtableadmin.GetIamPolicyRequest{Options: &bigtableadmin.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+				if err == nil && policy != nil {
+					for _, binding := range policy.Bindings {
+						resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+					}
+				}
+
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// spannerPolicyBindings converts a Spanner policy into role -> members
+func spannerPolicyBindings(policy *spanner.Policy) map[string][]string {
+	bindings := make(map[string][]string)
+	for _, binding := range policy.Bindings {
+		bindings[binding.Role] = append(bindings[binding.Role], binding.Members...)
+	}
+	return bindings
+}
+
+// spannerConfigLocation derives a location from an instance config name such as
+// "projects/p/instanceConfigs/regional-us-central1" or ".../instanceConfigs/nam3"
+func spannerConfigLocation(config string) string {
+	return strings.TrimPrefix(lastSegment(config), "regional-")
+}
+
+// lastSegment returns the final path segment of a resource name
+func lastSegment(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,151 @@
+package gcp
+
+import (
+	"fmt"
+
+	composer "google.golang.org/api/composer/v1"
+	computev1 "google.golang.org/api/compute/v1"
+	dataflow "google.golang.org/api/dataflow/v1b3"
+	dataproc "google.golang.org/api/dataproc/v1"
+)
+
+func init() {
+	RegisterCollector(Collector{Name: "dataflow", Collect: (*Client).getDataflowJobs, Services: []string{"dataflow.googleapis.com"}, Permission: "dataflow.jobs.list"})
+	RegisterCollector(Collector{Name: "dataproc", Collect: (*Client).getDataprocClusters, Services: []string{"dataproc.googleapis.com"}, Permission: "dataproc.clusters.list"})
+	RegisterCollector(Collector{Name: "composer", Collect: (*Client).getComposerEnvironments, Services: []string{"composer.googleapis.com", "compute.googleapis.com"}, Permission: "composer.environments.list"})
+}
+
+// getDataflowJobs lists active Dataflow jobs in every region. Jobs have no IAM
+// policy of their own; what matters is the service account their workers run as.
+func (c *Client) getDataflowJobs() ([]Resource, error) {
+	var resources []Resource
+	err := c.Dataflow.Projects.Jobs.Aggregated(c.ProjectID).Filter("ACTIVE").View("JOB_VIEW_ALL").
+		Pages(c.ctx, func(page *dataflow.ListJobsResponse) error {
+			for _, job := range page.Jobs {
+				resource := Resource{
+					ID:        fmt.Sprintf("//dataflow.googleapis.com/projects/%s/locations/%s/jobs/%s", c.ProjectID, job.Location, job.Id),
+					Name:      job.Name,
+					Type:      "dataflow",
+					Location:  job.Location,
+					AssetType: "dataflow.googleapis.com/Job",
+					IAM:       make(map[string][]string),
+				}
+				if job.Environment != nil {
+					resource.RunAs = job.Environment.ServiceAccountEmail
+				}
+				resource.IAM["inherited"] = []string{"project-level"}
+
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// getDataprocClusters lists Dataproc clusters in every in-scope region with their
+// IAM policies and the service account of their VMs
+func (c *Client) getDataprocClusters() ([]Resource, error) {
+	regions, err := c.scanRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, region := range regions {
+		err := c.Dataproc.Projects.Regions.Clusters.List(c.ProjectID, region).
+			Pages(c.ctx, func(page *dataproc.ListClustersResponse) error {
+				for _, cluster := range page.Clusters {
+					name := fmt.Sprintf("projects/%s/regions/%s/clusters/%s", c.ProjectID, region, cluster.ClusterName)
+					resource := Resource{
+						ID:        "//dataproc.googleapis.com/" + name,
+						Name:      cluster.ClusterName,
+						Type:      "dataproc",
+						Location:  region,
+						AssetType: "dataproc.googleapis.com/Cluster",
+						IAM:       make(map[string][]string),
+					}
+					if cluster.Config != nil && cluster.Config.GceClusterConfig != nil {
+						resource.RunAs = cluster.Config.GceClusterConfig.ServiceAccount
+					}
+
+					policy, err := c.Dataproc.Projects.Regions.Clusters.GetIamPolicy(name, &dataproc.GetIamPolicyRequest{Options: &dataproc.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+					if err == nil && policy != nil {
+						for _, binding := range policy.Bindings {
+							resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+						}
+					}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// getComposerEnvironments lists Cloud Composer environments in every in-scope
+// region. Access is project-level; the node service account runs the DAGs.
+func (c *Client) getComposerEnvironments() ([]Resource, error) {
+	regions, err := c.scanRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, region := range regions {
+		parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, region)
+		err := c.Composer.Projects.Locations.Environments.List(parent).
+			Pages(c.ctx, func(page *composer.ListEnvironmentsResponse) error {
+				for _, environment := range page.Environments {
+					resource := Resource{
+						ID:        "//composer.googleapis.com/" + environment.Name,
+						Name:      lastSegment(environment.Name),
+						Type:      "composer",
+						Location:  region,
+						AssetType: "composer.googleapis.com/Environment",
+						IAM:       make(map[string][]string),
+					}
+					if environment.Config != nil && environment.Config.NodeConfig != nil {
+						resource.RunAs = environment.Config.NodeConfig.ServiceAccount
+					}
+					resource.IAM["inherited"] = []string{"project-level"}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// scanRegions lists the Compute Engine regions of the project that are in scope,
+// for regional APIs that have no "all locations" wildcard
+func (c *Client) scanRegions() ([]string, error) {
+	var regions []string
+	err := c.Compute.Regions.List(c.ProjectID).
+		Pages(c.ctx, func(page *computev1.RegionList) error {
+			for _, region := range page.Items {
+				if c.Scope.LocationAllowed(region.Name) {
+					regions = append(regions, region.Name)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return regions, nil
+}
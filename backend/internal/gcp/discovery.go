@@ -0,0 +1,214 @@
+package gcp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	resourcemanager "cloud.google.com/go/resourcemanager/apiv3"
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	"google.golang.org/api/iterator"
+)
+
+// ProjectDiscovery scans every active project under an organization or folder
+// instead of the configured project alone. Projects are enumerated again on
+// every scan, so new projects are picked up without a restart.
+type ProjectDiscovery struct {
+	// Parent is "organizations/N" or "folders/N"; nested folders are included
+	Parent string
+	// Exclude lists project IDs or numbers never scanned
+	Exclude []string
+}
+
+// DiscoveredProject is a project found under the discovery parent
+type DiscoveredProject struct {
+	ID          string `json:"id"`
+	Number      string `json:"number"`
+	DisplayName string `json:"displayName"`
+	Parent      string `json:"parent"`
+}
+
+// projectClients holds a client per discovered project, kept across scans so
+// incremental state survives
+type projectClients struct {
+	mu         sync.Mutex
+	clients    map[string]*Client
+	discovered []DiscoveredProject
+}
+
+// DiscoverProjects lists the active projects under the discovery parent,
+// walking nested folders, minus the excluded ones
+func (c *Client) DiscoverProjects() ([]DiscoveredProject, error) {
+	if c.Discovery == nil {
+		return nil, fmt.Errorf("project discovery is not configured")
+	}
+	opts, err := meteredOptions(c.ctx, c.usage)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := resourcemanager.NewFoldersRESTClient(c.ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folders client: %w", err)
+	}
+	defer folders.Close()
+
+	projects := []DiscoveredProject{}
+	parents := []string{c.Discovery.Parent}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+
+		// ListProjects returns active projects unless deleted ones are requested
+		it := c.ResourceManager.ListProjects(c.ctx, &resourcemanagerpb.ListProjectsRequest{Parent: parent})
+		for {
+			project, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list projects under %s: %w", parent, err)
+			}
+			discovered := DiscoveredProject{
+				ID:          project.GetProjectId(),
+				Number:      strings.TrimPrefix(project.GetName(), "projects/"),
+				DisplayName: project.GetDisplayName(),
+				Parent:      project.GetParent(),
+			}
+			if project.GetState() != resourcemanagerpb.Project_ACTIVE ||
+				contains(c.Discovery.Exclude, discovered.ID) || contains(c.Discovery.Exclude, discovered.Number) {
+				continue
+			}
+			projects = append(projects, discovered)
+		}
+
+		folderIt := folders.ListFolders(c.ctx, &resourcemanagerpb.ListFoldersRequest{Parent: parent})
+		for {
+			folder, err := folderIt.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list folders under %s: %w", parent, err)
+			}
+			parents = append(parents, folder.GetName())
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+// ScannedProjects returns the projects the last scan covered: the discovered
+// projects, or the configured project when discovery is off
+func (c *Client) ScannedProjects() []DiscoveredProject {
+	if c.Discovery == nil {
+		return []DiscoveredProject{{ID: c.ProjectID, Number: c.ProjectNumber}}
+	}
+	c.projects.mu.Lock()
+	defer c.projects.mu.Unlock()
+	return append([]DiscoveredProject{}, c.projects.discovered...)
+}
+
+// ScannedProjectID returns the project ID of a scanned project given its ID,
+// number, or "projects/..." name
+func (c *Client) ScannedProjectID(ref string) (string, bool) {
+	ref = strings.TrimPrefix(ref, "projects/")
+	for _, project := range c.ScannedProjects() {
+		if ref == project.ID || (project.Number != "" && ref == project.Number) {
+			return project.ID, true
+		}
+	}
+	return "", false
+}
+
+// forProject returns the client scanning one discovered project. It shares
+// the API clients, caches, and usage meter of c and follows its scan scope.
+func (c *Client) forProject(project DiscoveredProject) *Client {
+	c.projects.mu.Lock()
+	defer c.projects.mu.Unlock()
+
+	if c.projects.clients == nil {
+		c.projects.clients = make(map[string]*Client)
+	}
+	child := c.projects.clients[project.ID]
+	if child == nil {
+		child = c.child(project)
+		c.projects.clients[project.ID] = child
+	}
+	child.PolicyWorkers = c.PolicyWorkers
+	child.Incremental = c.Incremental
+	child.FullScanInterval = c.FullScanInterval
+	child.GroupCacheTTL = c.GroupCacheTTL
+	child.GroupMaxDepth = c.GroupMaxDepth
+	child.GroupExpansion = c.GroupExpansion
+	child.scopeMu.Lock()
+	child.Scope = c.currentScope()
+	child.scopeMu.Unlock()
+	return child
+}
+
+// child returns a new client for another project sharing the API clients,
+// caches, and usage meter of c
+func (c *Client) child(project DiscoveredProject) *Client {
+	return &Client{
+		ProjectID:       project.ID,
+		ProjectNumber:   project.Number,
+		Cache:           c.Cache,
+		ComputeClient:   c.ComputeClient,
+		ContainerClient: c.ContainerClient,
+		RunClient:       c.RunClient,
+		ResourceManager: c.ResourceManager,
+		IAMAdminClient:  c.IAMAdminClient,
+		RESTServices:    c.RESTServices,
+
+		ctx:    c.ctx,
+		roles:  c.roles,
+		groups: c.groups,
+		usage:  c.usage,
+	}
+}
+
+// discoveredAccessMatrix scans every discovered project, concurrently or
+// through the Dispatcher, and merges the matrices in discovery order. A
+// project that cannot be scanned is reported as a warning and does not fail
+// the others.
+func (c *Client) discoveredAccessMatrix() (*AccessMatrix, error) {
+	projects, err := c.DiscoverProjects()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Discovered %d projects under %s", len(projects), c.Discovery.Parent)
+
+	c.projects.mu.Lock()
+	c.projects.discovered = projects
+	c.projects.mu.Unlock()
+
+	merged := &AccessMatrix{Users: []User{}, Resources: []Resource{}, Access: []AccessEntry{}, Warnings: []ScanWarning{}}
+	users := make(map[string]bool)
+	for i, result := range c.scanDiscovered(projects) {
+		project, matrix, err := projects[i], result.Matrix, result.Err
+		merged.Projects = append(merged.Projects, result.Scan)
+		if err != nil {
+			warning := newScanWarning(ProjectCollector, err, "resourcemanager.projects.getIamPolicy")
+			warning.Project = project.ID
+			merged.Warnings = append(merged.Warnings, warning)
+			continue
+		}
+		for _, user := range matrix.Users {
+			if !users[user.Email] {
+				users[user.Email] = true
+				merged.Users = append(merged.Users, user)
+			}
+		}
+		merged.Resources = append(merged.Resources, matrix.Resources...)
+		merged.Access = append(merged.Access, matrix.Access...)
+		merged.Eligible = append(merged.Eligible, matrix.Eligible...)
+		for _, warning := range matrix.Warnings {
+			warning.Project = project.ID
+			merged.Warnings = append(merged.Warnings, warning)
+		}
+	}
+	return merged, nil
+}
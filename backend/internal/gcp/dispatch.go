@@ -0,0 +1,67 @@
+package gcp
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// ProjectResult is the outcome of scanning one discovered project
+type ProjectResult struct {
+	Matrix *AccessMatrix
+	Scan   ProjectScan
+	// Err is set when the project could not be scanned; Matrix is then nil
+	Err error
+}
+
+// ProjectDispatcher scans the projects of a discovery scan on behalf of the
+// client, e.g. by queueing them for worker processes. It returns one result
+// per project, in order, and reports a project it could not get scanned as a
+// failed result rather than failing the scan. Work should stop at deadline
+// (the zero time for none).
+type ProjectDispatcher interface {
+	Dispatch(c *Client, projects []DiscoveredProject, scope ScanScope, deadline time.Time) []ProjectResult
+}
+
+// ScanProject scans one project with the given scope, sharing the API clients
+// and caches of c. Worker processes use it for queued scan tasks.
+func (c *Client) ScanProject(project DiscoveredProject, scope ScanScope) ProjectResult {
+	child := c.forProject(project)
+	child.scopeMu.Lock()
+	child.Scope = scope
+	child.scopeMu.Unlock()
+
+	matrix, scan, err := child.scanProject()
+	if err != nil {
+		log.Printf("Warning: failed to scan project %s: %v", project.ID, err)
+	}
+	return ProjectResult{Matrix: matrix, Scan: scan, Err: err}
+}
+
+// scanDiscovered scans the discovered projects through the Dispatcher, or with
+// ProjectWorkers goroutines when none is set
+func (c *Client) scanDiscovered(projects []DiscoveredProject) []ProjectResult {
+	scope := c.currentScope()
+	if c.Dispatcher != nil {
+		return c.Dispatcher.Dispatch(c, projects, scope, c.usage.deadline())
+	}
+
+	results := make([]ProjectResult, len(projects))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(c.ProjectWorkers, 1), len(projects)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = c.ScanProject(projects[i], scope)
+			}
+		}()
+	}
+	for i := range projects {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
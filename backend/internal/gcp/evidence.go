@@ -0,0 +1,96 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	logging "google.golang.org/api/logging/v2"
+)
+
+// GrantAuditWindow is how far back grant events are looked up, the retention
+// of Admin Activity audit logs
+const GrantAuditWindow = 400 * 24 * time.Hour
+
+// grantAuditEntry is the part of a SetIamPolicy audit log payload that tells
+// which bindings a call added or removed
+type grantAuditEntry struct {
+	ServiceData struct {
+		PolicyDelta policyDelta `json:"policyDelta"`
+	} `json:"serviceData"`
+	Metadata struct {
+		PolicyDelta policyDelta `json:"policyDelta"`
+	} `json:"metadata"`
+}
+
+// policyDelta lists the binding changes of a SetIamPolicy call
+type policyDelta struct {
+	BindingDeltas []struct {
+		Action string `json:"action"` // "ADD" or "REMOVE"
+		Role   string `json:"role"`
+		Member string `json:"member"`
+	} `json:"bindingDeltas"`
+}
+
+// GrantEvents returns the Admin Activity audit log entries of SetIamPolicy
+// calls on a resource that added a binding for principal, oldest first, as
+// the raw log entries. Entries whose payload lists no binding changes are
+// kept when they name the principal.
+func (c *Client) GrantEvents(resource Resource, principal string) ([]json.RawMessage, error) {
+	projectID := c.ProjectID
+	if id, ok := c.ScannedProjectID(resource.Project); ok {
+		projectID = id
+	}
+	name := resource.Name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	request := &logging.ListLogEntriesRequest{
+		ResourceNames: []string{"projects/" + projectID},
+		Filter: fmt.Sprintf(`logName:"cloudaudit.googleapis.com%%2Factivity" AND protoPayload.methodName:"SetIamPolicy" AND protoPayload.resourceName:%q AND %q AND timestamp>=%q`,
+			name, ParseMember(principal).Email, time.Now().Add(-GrantAuditWindow).UTC().Format(time.RFC3339)),
+		OrderBy:  "timestamp asc",
+		PageSize: 1000,
+	}
+
+	events := []json.RawMessage{}
+	err := c.Logging.Entries.List(request).Pages(c.ctx, func(page *logging.ListLogEntriesResponse) error {
+		for _, entry := range page.Entries {
+			var payload grantAuditEntry
+			if err := json.Unmarshal(entry.ProtoPayload, &payload); err == nil && !grantsMember(payload, principal) {
+				continue
+			}
+			raw, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			events = append(events, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IAM audit logs: %w", err)
+	}
+	return events, nil
+}
+
+// grantsMember reports whether a SetIamPolicy payload added a binding for
+// principal, or lists no binding changes to tell
+func grantsMember(payload grantAuditEntry, principal string) bool {
+	deltas := append(payload.ServiceData.PolicyDelta.BindingDeltas, payload.Metadata.PolicyDelta.BindingDeltas...)
+	if len(deltas) == 0 {
+		return true
+	}
+	email := ParseMember(principal).Email
+	for _, delta := range deltas {
+		if delta.Action != "ADD" {
+			continue
+		}
+		if delta.Member == principal || strings.EqualFold(ParseMember(delta.Member).Email, email) {
+			return true
+		}
+	}
+	return false
+}
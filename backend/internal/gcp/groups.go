@@ -0,0 +1,253 @@
+package gcp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cloudidentity "google.golang.org/api/cloudidentity/v1"
+)
+
+// Defaults for Client.GroupCacheTTL and Client.GroupMaxDepth
+const (
+	DefaultGroupCacheTTL = 15 * time.Minute
+	DefaultGroupMaxDepth = 10
+)
+
+// GroupMember is a direct member of a Google group
+type GroupMember struct {
+	Email string `json:"email"`
+	Type  string `json:"type"` // "user", "serviceAccount", "group", "other"
+}
+
+// GroupNode is a group member in an expansion tree. Nested groups carry their
+// own members unless expanding them would loop or exceed the depth limit.
+type GroupNode struct {
+	Email   string      `json:"email"`
+	Type    string      `json:"type"`
+	Members []GroupNode `json:"members,omitempty"`
+	// Cycle marks a group already on the path from the root; it is not expanded again
+	Cycle bool `json:"cycle,omitempty"`
+	// Truncated marks a group at the depth limit whose members were not read
+	Truncated bool `json:"truncated,omitempty"`
+	// Error is set when the group's members could not be read
+	Error string `json:"error,omitempty"`
+}
+
+// GroupExpansion is the transitive membership of a group
+type GroupExpansion struct {
+	Root GroupNode `json:"root"`
+	// Members are the unique non-group principals reachable through any nesting
+	Members []GroupMember `json:"members"`
+	// Groups are the nested groups found below the root
+	Groups []string `json:"groups"`
+	// Cycles lists each membership loop as the chain of groups that closes it
+	Cycles   [][]string `json:"cycles,omitempty"`
+	MaxDepth int        `json:"maxDepth"`
+	// Truncated reports whether the depth limit cut off part of the tree
+	Truncated bool `json:"truncated"`
+}
+
+// groupCache caches direct group memberships, which change more often than
+// role definitions but are expensive to list for every request
+type groupCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedGroup
+}
+
+type cachedGroup struct {
+	members   []GroupMember
+	fetchedAt time.Time
+}
+
+// GetGroupMembers lists the direct members of a Google group via Cloud
+// Identity, served from cache for GroupCacheTTL
+func (c *Client) GetGroupMembers(groupEmail string) ([]GroupMember, error) {
+	key := strings.ToLower(groupEmail)
+	c.groups.mu.Lock()
+	if cached, ok := c.groups.entries[key]; ok && time.Since(cached.fetchedAt) < c.groupCacheTTL() {
+		c.groups.mu.Unlock()
+		return cached.members, nil
+	}
+	c.groups.mu.Unlock()
+
+	lookup, err := c.CloudIdentity.Groups.Lookup().GroupKeyId(groupEmail).Context(c.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up group %s: %w", groupEmail, err)
+	}
+
+	members := []GroupMember{}
+	err = c.CloudIdentity.Groups.Memberships.List(lookup.Name).
+		Pages(c.ctx, func(page *cloudidentity.ListMembershipsResponse) error {
+			for _, membership := range page.Memberships {
+				if membership.PreferredMemberKey == nil {
+					continue
+				}
+				members = append(members, GroupMember{
+					Email: membership.PreferredMemberKey.Id,
+					Type:  membershipType(membership.Type),
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", groupEmail, err)
+	}
+
+	c.groups.mu.Lock()
+	c.groups.entries[key] = cachedGroup{members: members, fetchedAt: time.Now()}
+	c.groups.mu.Unlock()
+	return members, nil
+}
+
+// groupCacheTTL returns the configured membership cache TTL or the default
+func (c *Client) groupCacheTTL() time.Duration {
+	if c.GroupCacheTTL > 0 {
+		return c.GroupCacheTTL
+	}
+	return DefaultGroupCacheTTL
+}
+
+// groupMaxDepth returns the configured nesting limit or the default
+func (c *Client) groupMaxDepth() int {
+	if c.GroupMaxDepth > 0 {
+		return c.GroupMaxDepth
+	}
+	return DefaultGroupMaxDepth
+}
+
+// ExpandGroup resolves a group's members transitively, reading GroupMaxDepth
+// levels of groups including the root. Loops are reported rather than
+// followed, and nested groups that cannot be read are marked with their error;
+// only a failure to read the root group itself is returned as an error.
+func (c *Client) ExpandGroup(groupEmail string) (*GroupExpansion, error) {
+	rootMembers, err := c.GetGroupMembers(groupEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	expansion := &GroupExpansion{MaxDepth: c.groupMaxDepth()}
+	seenMembers := make(map[string]bool)
+	seenGroups := map[string]bool{groupEmail: true}
+
+	var expand func(node *GroupNode, members []GroupMember, path []string)
+	expand = func(node *GroupNode, members []GroupMember, path []string) {
+		for _, member := range members {
+			child := GroupNode{Email: member.Email, Type: member.Type}
+			if member.Type != "group" {
+				if !seenMembers[member.Email] {
+					seenMembers[member.Email] = true
+					expansion.Members = append(expansion.Members, member)
+				}
+				node.Members = append(node.Members, child)
+				continue
+			}
+
+			if !seenGroups[member.Email] {
+				seenGroups[member.Email] = true
+				expansion.Groups = append(expansion.Groups, member.Email)
+			}
+			switch {
+			case containsFold(path, member.Email):
+				child.Cycle = true
+				expansion.Cycles = append(expansion.Cycles, append(append([]string(nil), path...), member.Email))
+			case len(path) >= expansion.MaxDepth:
+				child.Truncated = true
+				expansion.Truncated = true
+			default:
+				nested, err := c.GetGroupMembers(member.Email)
+				if err != nil {
+					child.Error = err.Error()
+					break
+				}
+				expand(&child, nested, append(path, member.Email))
+			}
+			node.Members = append(node.Members, child)
+		}
+	}
+
+	expansion.Root = GroupNode{Email: groupEmail, Type: "group"}
+	expand(&expansion.Root, rootMembers, []string{groupEmail})
+
+	sort.Slice(expansion.Members, func(i, j int) bool { return expansion.Members[i].Email < expansion.Members[j].Email })
+	sort.Strings(expansion.Groups)
+	if expansion.Members == nil {
+		expansion.Members = []GroupMember{}
+	}
+	if expansion.Groups == nil {
+		expansion.Groups = []string{}
+	}
+	return expansion, nil
+}
+
+// GetGroupMemberships returns the direct member emails of every group principal
+// in the matrix and of the groups nested in them, GroupMaxDepth levels deep,
+// so membership can be followed transitively. Each group is read once, which
+// also stops membership loops. Groups that cannot be read are skipped with a warning.
+func (c *Client) GetGroupMemberships(matrix *AccessMatrix) map[string][]string {
+	memberships := make(map[string][]string)
+	if c.GroupExpansion != nil && !c.GroupExpansion() {
+		return memberships
+	}
+	visited := make(map[string]bool)
+
+	type pending struct {
+		email string
+		depth int
+	}
+	var queue []pending
+	for _, user := range matrix.Users {
+		if user.Type == "group" {
+			queue = append(queue, pending{email: user.Email})
+		}
+	}
+
+	for len(queue) > 0 {
+		group := queue[0]
+		queue = queue[1:]
+		key := strings.ToLower(group.email)
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		members, err := c.GetGroupMembers(group.email)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		for _, member := range members {
+			memberships[group.email] = append(memberships[group.email], member.Email)
+			if member.Type == "group" && group.depth+1 < c.groupMaxDepth() {
+				queue = append(queue, pending{email: member.Email, depth: group.depth + 1})
+			}
+		}
+	}
+	return memberships
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// membershipType maps a Cloud Identity membership type to a principal type
+func membershipType(t string) string {
+	switch strings.ToUpper(t) {
+	case "USER":
+		return "user"
+	case "SERVICE_ACCOUNT":
+		return "serviceAccount"
+	case "GROUP":
+		return "group"
+	}
+	return "other"
+}
@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	asset "cloud.google.com/go/asset/apiv1"
+	"cloud.google.com/go/asset/apiv1/assetpb"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
+)
+
+// CheckCredentials reports whether the application default credentials can
+// mint an access token
+func (c *Client) CheckCredentials(ctx context.Context) error {
+	credentials, err := google.FindDefaultCredentials(ctx, cloudPlatformScope)
+	if err != nil {
+		return fmt.Errorf("no application default credentials: %w", err)
+	}
+	if _, err := credentials.TokenSource.Token(); err != nil {
+		return fmt.Errorf("failed to obtain an access token: %w", err)
+	}
+	return nil
+}
+
+// CheckAssetAPI makes one minimal Asset Inventory search in the project. The
+// call is not metered, so health checks never count against a scan's budget.
+func (c *Client) CheckAssetAPI(ctx context.Context) error {
+	client, err := asset.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create asset client: %w", err)
+	}
+	defer client.Close()
+
+	it := client.SearchAllResources(ctx, &assetpb.SearchAllResourcesRequest{
+		Scope:      "projects/" + c.ProjectID,
+		AssetTypes: []string{"cloudresourcemanager.googleapis.com/Project"},
+		PageSize:   1,
+	})
+	if _, err := it.Next(); err != nil && !errors.Is(err, iterator.Done) {
+		return err
+	}
+	return nil
+}
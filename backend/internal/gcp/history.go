@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp/resourcename"
+
+	asset "cloud.google.com/go/asset/apiv1"
+	"cloud.google.com/go/asset/apiv1/assetpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AssetHistoryRetention is how far back Asset Inventory keeps the history of
+// IAM policies
+const AssetHistoryRetention = 35 * 24 * time.Hour
+
+// historyBatchSize is the most asset names one BatchGetAssetsHistory call takes
+const historyBatchSize = 100
+
+// ErrAsOfOutOfRange is returned for points in time Asset Inventory holds no
+// history for: in the future or past its retention
+var ErrAsOfOutOfRange = errors.New("asOf is outside the Asset Inventory history window")
+
+// HistoryResult describes an access matrix rebuilt as of a point in time
+type HistoryResult struct {
+	AsOf time.Time `json:"asOf"`
+	// Resources counts the resources whose policy history was read; Policies
+	// counts those that held a policy at AsOf
+	Resources int `json:"resources"`
+	Policies  int `json:"policies"`
+}
+
+// AccessMatrixAsOf returns a copy of the matrix with the bindings of every
+// resource replaced by its IAM policy at asOf, read from the Asset Inventory
+// history, and access rebuilt. Resources, principals, and collector details
+// are the current ones: resources deleted since asOf are not included, and
+// resources created since hold no bindings.
+func (c *Client) AccessMatrixAsOf(matrix *AccessMatrix, asOf time.Time) (*AccessMatrix, *HistoryResult, error) {
+	now := time.Now()
+	if asOf.After(now) || now.Sub(asOf) > AssetHistoryRetention {
+		return nil, nil, fmt.Errorf("%w: it must be within the last %d days", ErrAsOfOutOfRange, int(AssetHistoryRetention.Hours()/24))
+	}
+
+	result := &HistoryResult{AsOf: asOf.UTC()}
+	patched := matrix
+	for _, project := range c.ScannedProjects() {
+		client := c
+		if c.Discovery != nil {
+			client = c.forProject(project)
+		}
+		var names []string
+		for _, resource := range matrix.Resources {
+			if client.IsScannedProject(resource.Project) {
+				names = append(names, resource.ID)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		policies, err := client.policyHistory(names, asOf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read policy history of %s: %w", project.ID, err)
+		}
+		patched = client.patchPolicies(patched, policies, func(*Resource) bool { return true })
+		result.Resources += len(names)
+		result.Policies += len(policies)
+	}
+	if patched == matrix {
+		copied := *matrix
+		patched = &copied
+	}
+	patched.History = result
+	return patched, result, nil
+}
+
+// policyHistory reads the IAM policies the named resources of the project
+// held at asOf. Resources without a policy or deleted at asOf are left out.
+func (c *Client) policyHistory(resourceIDs []string, asOf time.Time) ([]*assetpb.IamPolicySearchResult, error) {
+	ctx := context.Background()
+	assetClient, err := asset.NewClient(ctx, meteredGRPCOptions(c.usage, serviceAsset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset client: %w", err)
+	}
+	defer assetClient.Close()
+
+	// Asset Inventory may name a resource by project number
+	var names []string
+	for _, id := range resourceIDs {
+		names = append(names, id)
+		if c.ProjectNumber != "" {
+			if byNumber := resourcename.ReplaceProject(id, c.ProjectID, c.ProjectNumber); byNumber != id {
+				names = append(names, byNumber)
+			}
+		}
+	}
+
+	var policies []*assetpb.IamPolicySearchResult
+	for start := 0; start < len(names); start += historyBatchSize {
+		end := min(start+historyBatchSize, len(names))
+		resp, err := assetClient.BatchGetAssetsHistory(ctx, &assetpb.BatchGetAssetsHistoryRequest{
+			Parent:      fmt.Sprintf("projects/%s", c.ProjectID),
+			AssetNames:  names[start:end],
+			ContentType: assetpb.ContentType_IAM_POLICY,
+			ReadTimeWindow: &assetpb.TimeWindow{
+				StartTime: timestamppb.New(asOf),
+				EndTime:   timestamppb.New(asOf),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, temporal := range resp.GetAssets() {
+			if temporal.GetDeleted() || temporal.GetAsset().GetIamPolicy() == nil {
+				continue
+			}
+			policies = append(policies, &assetpb.IamPolicySearchResult{
+				Resource:  temporal.GetAsset().GetName(),
+				AssetType: temporal.GetAsset().GetAssetType(),
+				Project:   c.projectName(),
+				Policy:    temporal.GetAsset().GetIamPolicy(),
+			})
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,80 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+
+	computev1 "google.golang.org/api/compute/v1"
+	iap "google.golang.org/api/iap/v1"
+)
+
+func init() {
+	RegisterCollector(Collector{Name: "backendservice", Collect: (*Client).getBackendServices, Services: []string{"compute.googleapis.com", "iap.googleapis.com"}, Permission: "compute.backendServices.list"})
+}
+
+// getBackendServices lists load balancer backend services. For those protected by
+// Identity-Aware Proxy the IAP policy is read, so roles/iap.httpsResourceAccessor
+// grants show who can reach the internal web app behind the load balancer.
+func (c *Client) getBackendServices() ([]Resource, error) {
+	projectNumber, err := c.projectNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	err = c.Compute.BackendServices.AggregatedList(c.ProjectID).
+		Pages(c.ctx, func(page *computev1.BackendServiceAggregatedList) error {
+			for key, scoped := range page.Items {
+				// Keys look like "global" or "regions/us-central1"
+				location := strings.TrimPrefix(key, "regions/")
+				if !c.Scope.LocationAllowed(location) {
+					continue
+				}
+
+				for _, backend := range scoped.BackendServices {
+					resource := Resource{
+						ID:        computeAssetName(backend.SelfLink),
+						Name:      backend.Name,
+						Type:      "backendservice",
+						Location:  location,
+						AssetType: "compute.googleapis.com/BackendService",
+						IAM:       make(map[string][]string),
+					}
+					if location != "global" {
+						resource.AssetType = "compute.googleapis.com/RegionBackendService"
+					}
+
+					if backend.Iap == nil || !backend.Iap.Enabled {
+						resource.IAM["inherited"] = []string{"project-level"}
+						resources = append(resources, resource)
+						continue
+					}
+
+					policy, err := c.IAP.V1.GetIamPolicy(iapResourceName(projectNumber, location, backend.Id), &iap.GetIamPolicyRequest{Options: &iap.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+					if err == nil && policy != nil {
+						for _, binding := range policy.Bindings {
+							resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+						}
+					}
+
+					resources = append(resources, resource)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// iapResourceName builds the IAP resource of a backend service, e.g.
+// "projects/123/iap_web/compute/services/456" or ".../compute-us-central1/services/456"
+func iapResourceName(projectNumber, location string, backendID uint64) string {
+	service := "compute"
+	if location != "global" {
+		service = "compute-" + location
+	}
+	return fmt.Sprintf("projects/%s/iap_web/%s/services/%d", projectNumber, service, backendID)
+}
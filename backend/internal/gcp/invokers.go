@@ -0,0 +1,200 @@
+package gcp
+
+import (
+	"fmt"
+
+	cloudscheduler "google.golang.org/api/cloudscheduler/v1"
+	cloudtasks "google.golang.org/api/cloudtasks/v2"
+	eventarc "google.golang.org/api/eventarc/v1"
+)
+
+// The collectors in this file cover systems that call other services on a
+// schedule or in response to events. What matters for access is the identity
+// they present (RunAs) and what they call with it (Invokes).
+
+func init() {
+	RegisterCollector(Collector{Name: "scheduler", Collect: (*Client).getSchedulerJobs, Services: []string{"cloudscheduler.googleapis.com"}, Permission: "cloudscheduler.jobs.list"})
+	RegisterCollector(Collector{Name: "tasks", Collect: (*Client).getTaskQueues, Services: []string{"cloudtasks.googleapis.com"}, Permission: "cloudtasks.queues.list"})
+	RegisterCollector(Collector{Name: "eventarc", Collect: (*Client).getEventarcTriggers, Services: []string{"eventarc.googleapis.com"}, Permission: "eventarc.triggers.list"})
+}
+
+// getSchedulerJobs lists Cloud Scheduler jobs with the OIDC/OAuth service account
+// attached to their HTTP targets
+func (c *Client) getSchedulerJobs() ([]Resource, error) {
+	var locations []string
+	err := c.Scheduler.Projects.Locations.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *cloudscheduler.ListLocationsResponse) error {
+			for _, location := range page.Locations {
+				if c.Scope.LocationAllowed(location.LocationId) {
+					locations = append(locations, location.LocationId)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, location := range locations {
+		parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, location)
+		err := c.Scheduler.Projects.Locations.Jobs.List(parent).
+			Pages(c.ctx, func(page *cloudscheduler.ListJobsResponse) error {
+				for _, job := range page.Jobs {
+					resource := Resource{
+						ID:        "//cloudscheduler.googleapis.com/" + job.Name,
+						Name:      lastSegment(job.Name),
+						Type:      "scheduler",
+						Location:  location,
+						AssetType: "cloudscheduler.googleapis.com/Job",
+						IAM:       make(map[string][]string),
+					}
+					if target := job.HttpTarget; target != nil {
+						resource.Invokes = target.Uri
+						switch {
+						case target.OidcToken != nil:
+							resource.RunAs = target.OidcToken.ServiceAccountEmail
+						case target.OauthToken != nil:
+							resource.RunAs = target.OauthToken.ServiceAccountEmail
+						}
+					}
+					resource.IAM["inherited"] = []string{"project-level"}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// getTaskQueues lists Cloud Tasks queues with their IAM policies (who can enqueue)
+// and the identity configured on the queue's HTTP target override
+func (c *Client) getTaskQueues() ([]Resource, error) {
+	var locations []string
+	err := c.Tasks.Projects.Locations.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *cloudtasks.ListLocationsResponse) error {
+			for _, location := range page.Locations {
+				if c.Scope.LocationAllowed(location.LocationId) {
+					locations = append(locations, location.LocationId)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, location := range locations {
+		parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, location)
+		err := c.Tasks.Projects.Locations.Queues.List(parent).
+			Pages(c.ctx, func(page *cloudtasks.ListQueuesResponse) error {
+				for _, queue := range page.Queues {
+					resource := Resource{
+						ID:        "//cloudtasks.googleapis.com/" + queue.Name,
+						Name:      lastSegment(queue.Name),
+						Type:      "tasks",
+						Location:  location,
+						AssetType: "cloudtasks.googleapis.com/Queue",
+						IAM:       make(map[string][]string),
+					}
+					if target := queue.HttpTarget; target != nil {
+						if target.UriOverride != nil {
+							resource.Invokes = target.UriOverride.Host
+						}
+						switch {
+						case target.OidcToken != nil:
+							resource.RunAs = target.OidcToken.ServiceAccountEmail
+						case target.OauthToken != nil:
+							resource.RunAs = target.OauthToken.ServiceAccountEmail
+						}
+					}
+
+					policy, err := c.Tasks.Projects.Locations.Queues.GetIamPolicy(queue.Name, &cloudtasks.GetIamPolicyRequest{Options: &cloudtasks.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion}}).Context(c.ctx).Do()
+					if err == nil && policy != nil {
+						for _, binding := range policy.Bindings {
+							resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+						}
+					}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// getEventarcTriggers lists Eventarc triggers with the service account used to
+// deliver events and their destination
+func (c *Client) getEventarcTriggers() ([]Resource, error) {
+	var locations []string
+	err := c.Eventarc.Projects.Locations.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *eventarc.ListLocationsResponse) error {
+			for _, location := range page.Locations {
+				if c.Scope.LocationAllowed(location.LocationId) {
+					locations = append(locations, location.LocationId)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, location := range locations {
+		parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, location)
+		err := c.Eventarc.Projects.Locations.Triggers.List(parent).
+			Pages(c.ctx, func(page *eventarc.ListTriggersResponse) error {
+				for _, trigger := range page.Triggers {
+					resource := Resource{
+						ID:        "//eventarc.googleapis.com/" + trigger.Name,
+						Name:      lastSegment(trigger.Name),
+						Type:      "eventarc",
+						Location:  location,
+						AssetType: "eventarc.googleapis.com/Trigger",
+						RunAs:     trigger.ServiceAccount,
+						Invokes:   eventarcDestination(c.ProjectID, trigger.Destination),
+						IAM:       make(map[string][]string),
+					}
+					resource.IAM["inherited"] = []string{"project-level"}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// eventarcDestination describes where a trigger delivers events. Cloud Run
+// destinations use the service name, which is the Cloud Run collector's resource ID.
+func eventarcDestination(project string, destination *eventarc.Destination) string {
+	switch {
+	case destination == nil:
+		return ""
+	case destination.CloudRun != nil:
+		return fmt.Sprintf("projects/%s/locations/%s/services/%s", project, destination.CloudRun.Region, destination.CloudRun.Service)
+	case destination.CloudFunction != "":
+		return destination.CloudFunction
+	case destination.Workflow != "":
+		return destination.Workflow
+	case destination.HttpEndpoint != nil:
+		return destination.HttpEndpoint.Uri
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+package gcp
+
+import (
+	"strings"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+func init() {
+	RegisterCollector(Collector{Name: "network", Collect: (*Client).getNetworks, Services: []string{"compute.googleapis.com"}, Permission: "compute.networks.list"})
+	RegisterCollector(Collector{Name: "subnet", Collect: (*Client).getSubnetworks, Services: []string{"compute.googleapis.com"}, Permission: "compute.subnetworks.list"})
+	RegisterCollector(Collector{Name: "firewall", Collect: (*Client).getFirewalls, Services: []string{"compute.googleapis.com"}, Permission: "compute.firewalls.list"})
+}
+
+// getNetworks lists VPC networks. Networks have no IAM policy of their own.
+func (c *Client) getNetworks() ([]Resource, error) {
+	var resources []Resource
+	err := c.Compute.Networks.List(c.ProjectID).
+		Pages(c.ctx, func(page *computev1.NetworkList) error {
+			for _, network := range page.Items {
+				resource := Resource{
+					ID:        computeAssetName(network.SelfLink),
+					Name:      network.Name,
+					Type:      "network",
+					Location:  "global",
+					AssetType: "compute.googleapis.com/Network",
+					IAM:       make(map[string][]string),
+				}
+				resource.IAM["inherited"] = []string{"project-level"}
+
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// getSubnetworks lists subnets in every region with their IAM policies. On a
+// Shared VPC host project, subnet-level compute.networkUser grants decide which
+// service project principals can attach workloads to the subnet.
+func (c *Client) getSubnetworks() ([]Resource, error) {
+	var resources []Resource
+	err := c.Compute.Subnetworks.AggregatedList(c.ProjectID).
+		Pages(c.ctx, func(page *computev1.SubnetworkAggregatedList) error {
+			for key, scoped := range page.Items {
+				// Keys look like "regions/us-central1"
+				region := strings.TrimPrefix(key, "regions/")
+				if !c.Scope.LocationAllowed(region) {
+					continue
+				}
+
+				for _, subnet := range scoped.Subnetworks {
+					resource := Resource{
+						ID:        computeAssetName(subnet.SelfLink),
+						Name:      subnet.Name,
+						Type:      "subnet",
+						Location:  region,
+						AssetType: "compute.googleapis.com/Subnetwork",
+						IAM:       make(map[string][]string),
+					}
+
+					policy, err := c.Compute.Subnetworks.GetIamPolicy(c.ProjectID, region, subnet.Name).OptionsRequestedPolicyVersion(PolicyVersion).Context(c.ctx).Do()
+					if err == nil && policy != nil {
+						for _, binding := range policy.Bindings {
+							resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+						}
+					}
+
+					resources = append(resources, resource)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// getFirewalls lists firewall rules. Rules have no IAM policy of their own; who
+// can modify them follows from project-level compute roles.
+func (c *Client) getFirewalls() ([]Resource, error) {
+	var resources []Resource
+	err := c.Compute.Firewalls.List(c.ProjectID).
+		Pages(c.ctx, func(page *computev1.FirewallList) error {
+			for _, firewall := range page.Items {
+				resource := Resource{
+					ID:        computeAssetName(firewall.SelfLink),
+					Name:      firewall.Name,
+					Type:      "firewall",
+					Location:  "global",
+					AssetType: "compute.googleapis.com/Firewall",
+					IAM:       make(map[string][]string),
+				}
+				resource.IAM["inherited"] = []string{"project-level"}
+				resource.Firewall = firewallRule(firewall)
+
+				resources = append(resources, resource)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// FirewallRule is a VPC firewall rule: which traffic it allows or denies to
+// which instances of a network
+type FirewallRule struct {
+	Network   string `json:"network"`   // full resource name of the VPC network
+	Direction string `json:"direction"` // "INGRESS" or "EGRESS"
+	Priority  int64  `json:"priority"`  // lower numbers take precedence
+	Deny      bool   `json:"deny,omitempty"`
+	Disabled  bool   `json:"disabled,omitempty"`
+	// Ports are protocol and port specs, e.g. "tcp:22", "tcp:8000-8080", "all"
+	Ports        []string `json:"ports"`
+	SourceRanges []string `json:"sourceRanges,omitempty"`
+	// TargetTags and TargetServiceAccounts select instances; both empty
+	// applies the rule to every instance in the network
+	TargetTags            []string `json:"targetTags,omitempty"`
+	TargetServiceAccounts []string `json:"targetServiceAccounts,omitempty"`
+}
+
+// AppliesTo reports whether the rule targets an instance on the network
+func (r *FirewallRule) AppliesTo(instance *InstanceDetails) bool {
+	if !contains(instance.Networks, r.Network) {
+		return false
+	}
+	if len(r.TargetTags) == 0 && len(r.TargetServiceAccounts) == 0 {
+		return true
+	}
+	for _, tag := range r.TargetTags {
+		if contains(instance.Tags, tag) {
+			return true
+		}
+	}
+	for _, account := range r.TargetServiceAccounts {
+		if contains(instance.ServiceAccounts, account) {
+			return true
+		}
+	}
+	return false
+}
+
+// Public reports whether the rule matches traffic from any address
+func (r *FirewallRule) Public() bool {
+	return contains(r.SourceRanges, "0.0.0.0/0") || contains(r.SourceRanges, "::/0")
+}
+
+func firewallRule(firewall *computev1.Firewall) *FirewallRule {
+	rule := &FirewallRule{
+		Network:               computeAssetName(firewall.Network),
+		Direction:             firewall.Direction,
+		Priority:              firewall.Priority,
+		Deny:                  len(firewall.Denied) > 0,
+		Disabled:              firewall.Disabled,
+		Ports:                 []string{},
+		SourceRanges:          firewall.SourceRanges,
+		TargetTags:            firewall.TargetTags,
+		TargetServiceAccounts: firewall.TargetServiceAccounts,
+	}
+	add := func(protocol string, ports []string) {
+		switch {
+		case protocol == "all":
+			rule.Ports = append(rule.Ports, "all")
+		case len(ports) == 0:
+			rule.Ports = append(rule.Ports, protocol)
+		default:
+			for _, port := range ports {
+				rule.Ports = append(rule.Ports, protocol+":"+port)
+			}
+		}
+	}
+	for _, allowed := range firewall.Allowed {
+		add(allowed.IPProtocol, allowed.Ports)
+	}
+	for _, denied := range firewall.Denied {
+		add(denied.IPProtocol, denied.Ports)
+	}
+	return rule
+}
+
+// InstanceDetails is the network placement and identity of a VM
+type InstanceDetails struct {
+	Networks    []string `json:"networks"` // full resource names of the VPC networks
+	Tags        []string `json:"tags,omitempty"`
+	ExternalIPs []string `json:"externalIps,omitempty"`
+	// ServiceAccounts are the accounts the VM runs as, with the OAuth scopes
+	// that limit what their tokens can do on the VM
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+}
+
+// FullAPIAccess reports whether the VM's tokens carry the cloud-platform
+// scope, so its service account can use every role it holds
+func (d *InstanceDetails) FullAPIAccess() bool {
+	return contains(d.Scopes, cloudPlatformScope)
+}
+
+func instanceDetails(instance *computepb.Instance) *InstanceDetails {
+	details := &InstanceDetails{
+		Networks: []string{},
+		Tags:     instance.GetTags().GetItems(),
+	}
+	for _, iface := range instance.GetNetworkInterfaces() {
+		network := computeAssetName(iface.GetNetwork())
+		if !contains(details.Networks, network) {
+			details.Networks = append(details.Networks, network)
+		}
+		for _, access := range iface.GetAccessConfigs() {
+			if ip := access.GetNatIP(); ip != "" {
+				details.ExternalIPs = append(details.ExternalIPs, ip)
+			}
+		}
+		for _, access := range iface.GetIpv6AccessConfigs() {
+			if ip := access.GetExternalIpv6(); ip != "" {
+				details.ExternalIPs = append(details.ExternalIPs, ip)
+			}
+		}
+	}
+	for _, account := range instance.GetServiceAccounts() {
+		details.ServiceAccounts = append(details.ServiceAccounts, account.GetEmail())
+		details.Scopes = append(details.Scopes, account.GetScopes()...)
+	}
+	return details
+}
+
+// computeAssetName converts a Compute Engine self link into the Asset Inventory
+// full resource name, so collector and Asset search results share one ID
+func computeAssetName(selfLink string) string {
+	if i := strings.Index(selfLink, "/projects/"); i >= 0 {
+		return "//compute.googleapis.com" + selfLink[i:]
+	}
+	return selfLink
+}
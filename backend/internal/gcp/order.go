@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Matrices are built from maps, so without sorting the order of users,
+// resources, and access entries changed from scan to scan and broke clients
+// that diff or cache responses. Their canonical order is by principal, then
+// resource, then role. Warnings and project scans keep the order they were
+// recorded in, which follows collector registration and project discovery.
+
+// compareUsers orders users by email
+func compareUsers(a, b User) int {
+	return cmp.Compare(a.Email, b.Email)
+}
+
+// compareResources orders resources by ID
+func compareResources(a, b Resource) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// compareAccess orders access entries by principal, then resource, then roles
+func compareAccess(a, b AccessEntry) int {
+	return cmp.Or(
+		cmp.Compare(a.UserEmail, b.UserEmail),
+		cmp.Compare(a.ResourceID, b.ResourceID),
+		slices.Compare(a.Roles, b.Roles),
+	)
+}
+
+// compareEligible orders eligible access like access entries, then by entitlement
+func compareEligible(a, b EligibleAccess) int {
+	return cmp.Or(
+		cmp.Compare(a.UserEmail, b.UserEmail),
+		cmp.Compare(a.ResourceID, b.ResourceID),
+		slices.Compare(a.Roles, b.Roles),
+		cmp.Compare(a.Entitlement, b.Entitlement),
+	)
+}
+
+// SortUsers sorts users into canonical order, by email
+func SortUsers(users []User) {
+	slices.SortStableFunc(users, compareUsers)
+}
+
+// SortResources sorts resources into canonical order, by ID
+func SortResources(resources []Resource) {
+	slices.SortStableFunc(resources, compareResources)
+}
+
+// Sorted returns the matrix with its users, resources, access, and eligible
+// access in canonical order, and the roles of every entry sorted. Matrices
+// already in order are returned as they are; otherwise the result is a copy,
+// so matrices being served are never reordered underneath their readers.
+func (m *AccessMatrix) Sorted() *AccessMatrix {
+	if m == nil || m.isSorted() {
+		return m
+	}
+
+	sorted := *m
+	sorted.Users = sortedCopy(m.Users, compareUsers)
+	sorted.Resources = sortedCopy(m.Resources, compareResources)
+	if !accessSorted(m.Access) {
+		sorted.Access = make([]AccessEntry, len(m.Access))
+		for i, entry := range m.Access {
+			entry.Roles = sortedRoles(entry.Roles)
+			sorted.Access[i] = entry
+		}
+		slices.SortStableFunc(sorted.Access, compareAccess)
+	}
+	if !eligibleSorted(m.Eligible) {
+		sorted.Eligible = make([]EligibleAccess, len(m.Eligible))
+		for i, entry := range m.Eligible {
+			entry.Roles = sortedRoles(entry.Roles)
+			sorted.Eligible[i] = entry
+		}
+		slices.SortStableFunc(sorted.Eligible, compareEligible)
+	}
+	return &sorted
+}
+
+// isSorted reports whether the matrix is in canonical order
+func (m *AccessMatrix) isSorted() bool {
+	return slices.IsSortedFunc(m.Users, compareUsers) &&
+		slices.IsSortedFunc(m.Resources, compareResources) &&
+		accessSorted(m.Access) &&
+		eligibleSorted(m.Eligible)
+}
+
+// accessSorted reports whether entries and their roles are in canonical order
+func accessSorted(entries []AccessEntry) bool {
+	for _, entry := range entries {
+		if !slices.IsSorted(entry.Roles) {
+			return false
+		}
+	}
+	return slices.IsSortedFunc(entries, compareAccess)
+}
+
+// eligibleSorted reports whether eligible access and its roles are in
+// canonical order
+func eligibleSorted(entries []EligibleAccess) bool {
+	for _, entry := range entries {
+		if !slices.IsSorted(entry.Roles) {
+			return false
+		}
+	}
+	return slices.IsSortedFunc(entries, compareEligible)
+}
+
+// sortedCopy returns items when they are in order, else a sorted copy
+func sortedCopy[T any](items []T, compare func(a, b T) int) []T {
+	if slices.IsSortedFunc(items, compare) {
+		return items
+	}
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, compare)
+	return sorted
+}
+
+// sortedRoles returns roles when they are sorted, else a sorted copy
+func sortedRoles(roles []string) []string {
+	if slices.IsSorted(roles) {
+		return roles
+	}
+	return slices.Sorted(slices.Values(roles))
+}
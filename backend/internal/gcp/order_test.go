@@ -0,0 +1,147 @@
+package gcp
+
+import (
+	"encoding/json"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// orderTestMatrix returns a small matrix in canonical order
+func orderTestMatrix() *AccessMatrix {
+	return &AccessMatrix{
+		Users: []User{
+			{Email: "alice@example.com", Type: "user"},
+			{Email: "bob@example.com", Type: "user"},
+			{Email: "deploy@p.iam.gserviceaccount.com", Type: "serviceAccount"},
+		},
+		Resources: []Resource{
+			{ID: "//cloudresourcemanager.googleapis.com/projects/p", Type: "project"},
+			{ID: "//storage.googleapis.com/projects/_/buckets/a", Type: "storage"},
+			{ID: "//storage.googleapis.com/projects/_/buckets/b", Type: "storage"},
+		},
+		Access: []AccessEntry{
+			{UserEmail: "alice@example.com", ResourceID: "//cloudresourcemanager.googleapis.com/projects/p", Roles: []string{"roles/editor", "roles/viewer"}},
+			{UserEmail: "alice@example.com", ResourceID: "//storage.googleapis.com/projects/_/buckets/a", Roles: []string{"roles/storage.admin"}},
+			{UserEmail: "bob@example.com", ResourceID: "//storage.googleapis.com/projects/_/buckets/a", Roles: []string{"roles/storage.objectViewer"}},
+			{UserEmail: "bob@example.com", ResourceID: "//storage.googleapis.com/projects/_/buckets/b", Roles: []string{"roles/storage.admin", "roles/storage.objectViewer"}},
+			{UserEmail: "deploy@p.iam.gserviceaccount.com", ResourceID: "//storage.googleapis.com/projects/_/buckets/b", Roles: []string{"roles/storage.objectAdmin"}},
+		},
+		Eligible: []EligibleAccess{
+			{UserEmail: "alice@example.com", ResourceID: "//cloudresourcemanager.googleapis.com/projects/p", Roles: []string{"roles/owner"}, Entitlement: "break-glass"},
+			{UserEmail: "bob@example.com", ResourceID: "//cloudresourcemanager.googleapis.com/projects/p", Roles: []string{"roles/editor"}, Entitlement: "oncall"},
+		},
+		Warnings: []ScanWarning{{Collector: "vm", Error: "denied"}, {Collector: "gke", Error: "denied"}},
+	}
+}
+
+// shuffled returns a copy of the matrix with every list and role list shuffled
+func shuffled(m *AccessMatrix, rng *rand.Rand) *AccessMatrix {
+	shuffle := func(n int, swap func(i, j int)) { rng.Shuffle(n, swap) }
+	out := *m
+	out.Users = slices.Clone(m.Users)
+	out.Resources = slices.Clone(m.Resources)
+	out.Access = slices.Clone(m.Access)
+	out.Eligible = slices.Clone(m.Eligible)
+	shuffle(len(out.Users), func(i, j int) { out.Users[i], out.Users[j] = out.Users[j], out.Users[i] })
+	shuffle(len(out.Resources), func(i, j int) { out.Resources[i], out.Resources[j] = out.Resources[j], out.Resources[i] })
+	shuffle(len(out.Access), func(i, j int) { out.Access[i], out.Access[j] = out.Access[j], out.Access[i] })
+	shuffle(len(out.Eligible), func(i, j int) { out.Eligible[i], out.Eligible[j] = out.Eligible[j], out.Eligible[i] })
+	for i := range out.Access {
+		roles := slices.Clone(out.Access[i].Roles)
+		shuffle(len(roles), func(a, b int) { roles[a], roles[b] = roles[b], roles[a] })
+		out.Access[i].Roles = roles
+	}
+	return &out
+}
+
+func encode(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return string(data)
+}
+
+func TestSortedRestoresCanonicalOrder(t *testing.T) {
+	want := orderTestMatrix()
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		got := shuffled(want, rng).Sorted()
+		if encode(t, got) != encode(t, want) {
+			t.Fatalf("permutation %d: Sorted() = %s, want %s", i, encode(t, got), encode(t, want))
+		}
+		if encode(t, got.Compact()) != encode(t, want.Compact()) {
+			t.Fatalf("permutation %d: compact encoding differs", i)
+		}
+	}
+}
+
+func TestSortedKeepsWarningOrder(t *testing.T) {
+	matrix := shuffled(orderTestMatrix(), rand.New(rand.NewSource(2)))
+	got := matrix.Sorted()
+	if got.Warnings[0].Collector != "vm" || got.Warnings[1].Collector != "gke" {
+		t.Errorf("Warnings = %v, want them in the order they were recorded", got.Warnings)
+	}
+}
+
+func TestSortedDoesNotReorderInput(t *testing.T) {
+	matrix := shuffled(orderTestMatrix(), rand.New(rand.NewSource(3)))
+	before := encode(t, matrix)
+
+	if matrix.Sorted() == matrix {
+		t.Fatal("Sorted() returned an unsorted matrix as it is")
+	}
+	if encode(t, matrix) != before {
+		t.Error("Sorted() reordered the matrix it was called on")
+	}
+
+	sorted := orderTestMatrix()
+	if sorted.Sorted() != sorted {
+		t.Error("Sorted() copied a matrix already in canonical order")
+	}
+}
+
+func TestBuildAccessIsDeterministic(t *testing.T) {
+	c := &Client{ProjectID: "p", roles: &roleCache{entries: make(map[string]cachedRole)}}
+	for _, role := range []string{"roles/owner", "roles/viewer", "roles/storage.admin", "roles/storage.objectViewer"} {
+		c.cacheRole(role, RoleInfo{Name: role, Tier: TierFromName(role)})
+	}
+	resources := func() map[string]*Resource {
+		return map[string]*Resource{
+			"//cloudresourcemanager.googleapis.com/projects/p": {
+				ID: "//cloudresourcemanager.googleapis.com/projects/p", Type: "project",
+				IAM: map[string][]string{
+					"roles/owner":  {"user:alice@example.com"},
+					"roles/viewer": {"user:bob@example.com", "group:eng@example.com", "user:carol@example.com"},
+				},
+			},
+			"//storage.googleapis.com/projects/_/buckets/a": {
+				ID: "//storage.googleapis.com/projects/_/buckets/a", Type: "storage",
+				IAM: map[string][]string{
+					"roles/storage.admin":        {"user:carol@example.com"},
+					"roles/storage.objectViewer": {"projectViewer:p", "user:dave@example.com"},
+				},
+			},
+			"//storage.googleapis.com/projects/_/buckets/b": {
+				ID: "//storage.googleapis.com/projects/_/buckets/b", Type: "storage",
+				IAM: map[string][]string{"roles/storage.objectViewer": {"user:alice@example.com"}},
+			},
+		}
+	}
+
+	want := ""
+	for i := 0; i < 20; i++ {
+		matrix := (&AccessMatrix{Access: c.buildAccess(resources())}).Sorted()
+		got := encode(t, matrix.Access)
+		if i == 0 {
+			want = got
+			continue
+		}
+		if got != want {
+			t.Fatalf("build %d: access = %s, want %s", i, got, want)
+		}
+	}
+}
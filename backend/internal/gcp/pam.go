@@ -0,0 +1,311 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	logging "google.golang.org/api/logging/v2"
+)
+
+// servicePAM is the Privileged Access Manager API, called over plain REST as
+// there is no client library for it here
+const servicePAM = "privilegedaccessmanager.googleapis.com"
+
+// PAMCollector names the Privileged Access Manager entitlement listing in scan warnings
+const PAMCollector = "pam"
+
+// Entitlement is a Privileged Access Manager entitlement: the principals that
+// may request temporary roles on a resource, and how such requests are granted
+type Entitlement struct {
+	Name        string            `json:"name"` // projects/P/locations/global/entitlements/E
+	Resource    string            `json:"resource"`
+	Eligible    []string          `json:"eligible"` // members such as "user:a@example.com"
+	Roles       []EntitlementRole `json:"roles"`
+	MaxDuration time.Duration     `json:"maxDuration"`
+	// Approvers are the members that must approve a request; none means
+	// requests are granted without approval
+	Approvers []string `json:"approvers,omitempty"`
+	State     string   `json:"state"` // "AVAILABLE", "CREATING", ...
+}
+
+// EntitlementRole is a role an entitlement grants, optionally conditional
+type EntitlementRole struct {
+	Role      string `json:"role"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// RequiresApproval reports whether requests need a manual approval
+func (e Entitlement) RequiresApproval() bool {
+	return len(e.Approvers) > 0
+}
+
+// EligibleAccess is access a principal does not hold but may request through
+// a Privileged Access Manager entitlement, as opposed to the standing access
+// in AccessMatrix.Access
+type EligibleAccess struct {
+	UserEmail        string        `json:"userEmail"`
+	ResourceID       string        `json:"resourceId"`
+	ResourceName     string        `json:"resourceName"`
+	ResourceType     string        `json:"resourceType"`
+	Roles            []string      `json:"roles"`
+	Tier             string        `json:"tier"`
+	Entitlement      string        `json:"entitlement"`
+	MaxDuration      time.Duration `json:"maxDuration"`
+	RequiresApproval bool          `json:"requiresApproval"`
+}
+
+// Elevation is a Privileged Access Manager grant reconstructed from audit logs
+type Elevation struct {
+	Grant         string    `json:"grant"` // .../entitlements/E/grants/G
+	Entitlement   string    `json:"entitlement"`
+	Requester     string    `json:"requester,omitempty"`
+	Justification string    `json:"justification,omitempty"`
+	Requested     time.Time `json:"requested,omitempty"`
+	Activated     time.Time `json:"activated,omitempty"`
+	// Ends is when the grant ended, or when it expires if it is still active
+	Ends     time.Time     `json:"ends,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Active   bool          `json:"active"`
+}
+
+// pamEntitlement is the REST representation of an entitlement
+type pamEntitlement struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	EligibleUsers []struct {
+		Principals []string `json:"principals"`
+	} `json:"eligibleUsers"`
+	ApprovalWorkflow *struct {
+		ManualApprovals *struct {
+			Steps []struct {
+				Approvers []struct {
+					Principals []string `json:"principals"`
+				} `json:"approvers"`
+			} `json:"steps"`
+		} `json:"manualApprovals"`
+	} `json:"approvalWorkflow"`
+	PrivilegedAccess struct {
+		GcpIamAccess *struct {
+			Resource     string `json:"resource"`
+			RoleBindings []struct {
+				Role                string `json:"role"`
+				ConditionExpression string `json:"conditionExpression"`
+			} `json:"roleBindings"`
+		} `json:"gcpIamAccess"`
+	} `json:"privilegedAccess"`
+	MaxRequestDuration string `json:"maxRequestDuration"`
+}
+
+// GetEntitlements lists the Privileged Access Manager entitlements of the
+// project. Entitlements on folders and the organization are not listed.
+func (c *Client) GetEntitlements() ([]Entitlement, error) {
+	httpClient, err := meteredHTTPClient(c.ctx, c.usage)
+	if err != nil {
+		return nil, err
+	}
+
+	entitlements := []Entitlement{}
+	endpoint := fmt.Sprintf("https://%s/v1/projects/%s/locations/global/entitlements", servicePAM, c.ProjectID)
+	pageToken := ""
+	for {
+		var page struct {
+			Entitlements  []pamEntitlement `json:"entitlements"`
+			NextPageToken string           `json:"nextPageToken"`
+		}
+		query := url.Values{}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		if err := getJSON(httpClient, endpoint+"?"+query.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("failed to list entitlements: %w", err)
+		}
+		for _, raw := range page.Entitlements {
+			entitlements = append(entitlements, convertEntitlement(raw))
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	return entitlements, nil
+}
+
+// getJSON fetches a URL and decodes the JSON response; API errors are
+// returned as *googleapi.Error
+func getJSON(httpClient *http.Client, endpoint string, v any) error {
+	resp, err := httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func convertEntitlement(raw pamEntitlement) Entitlement {
+	entitlement := Entitlement{
+		Name:     raw.Name,
+		Eligible: []string{},
+		Roles:    []EntitlementRole{},
+		State:    raw.State,
+	}
+	entitlement.MaxDuration, _ = time.ParseDuration(strings.TrimSuffix(raw.MaxRequestDuration, "s") + "s")
+	for _, users := range raw.EligibleUsers {
+		entitlement.Eligible = append(entitlement.Eligible, users.Principals...)
+	}
+	if workflow := raw.ApprovalWorkflow; workflow != nil && workflow.ManualApprovals != nil {
+		for _, step := range workflow.ManualApprovals.Steps {
+			for _, approvers := range step.Approvers {
+				entitlement.Approvers = append(entitlement.Approvers, approvers.Principals...)
+			}
+		}
+	}
+	if access := raw.PrivilegedAccess.GcpIamAccess; access != nil {
+		entitlement.Resource = access.Resource
+		for _, binding := range access.RoleBindings {
+			entitlement.Roles = append(entitlement.Roles, EntitlementRole{Role: binding.Role, Condition: binding.ConditionExpression})
+		}
+	}
+	return entitlement
+}
+
+// eligibleAccess turns entitlements into eligible access entries, one per
+// eligible principal and entitlement. Roles on the project are not expanded
+// to the resources below it.
+func (c *Client) eligibleAccess(entitlements []Entitlement, resources map[string]*Resource) []EligibleAccess {
+	eligible := []EligibleAccess{}
+	for _, entitlement := range entitlements {
+		if entitlement.Resource == "" || len(entitlement.Roles) == 0 {
+			continue
+		}
+		roles := make([]string, 0, len(entitlement.Roles))
+		tier := ""
+		for _, binding := range entitlement.Roles {
+			roles = append(roles, binding.Role)
+			tier = MaxTier(tier, c.RoleTier(binding.Role))
+		}
+		resourceID := c.normalizeResourceName(entitlement.Resource)
+		name, resourceType := resourceID, ""
+		if resource := resources[resourceID]; resource != nil {
+			name, resourceType = resource.Name, resource.Type
+		}
+		for _, member := range entitlement.Eligible {
+			eligible = append(eligible, EligibleAccess{
+				UserEmail:        ParseMember(member).Email,
+				ResourceID:       resourceID,
+				ResourceName:     name,
+				ResourceType:     resourceType,
+				Roles:            roles,
+				Tier:             tier,
+				Entitlement:      entitlement.Name,
+				MaxDuration:      entitlement.MaxDuration,
+				RequiresApproval: entitlement.RequiresApproval(),
+			})
+		}
+	}
+	return eligible
+}
+
+// pamAuditEntry is the part of a Privileged Access Manager audit log payload
+// needed to follow a grant
+type pamAuditEntry struct {
+	MethodName         string `json:"methodName"`
+	ResourceName       string `json:"resourceName"`
+	AuthenticationInfo struct {
+		PrincipalEmail string `json:"principalEmail"`
+	} `json:"authenticationInfo"`
+	Request struct {
+		Grant struct {
+			RequestedDuration string `json:"requestedDuration"`
+			Justification     struct {
+				UnstructuredJustification string `json:"unstructuredJustification"`
+			} `json:"justification"`
+		} `json:"grant"`
+	} `json:"request"`
+	Response struct {
+		Name string `json:"name"`
+	} `json:"response"`
+}
+
+// GetElevations follows Privileged Access Manager grants of the project since
+// the given time through the audit logs: who requested which entitlement, when
+// the grant was activated, and when it ended or will expire
+func (c *Client) GetElevations(since time.Time) ([]Elevation, error) {
+	request := &logging.ListLogEntriesRequest{
+		ResourceNames: []string{"projects/" + c.ProjectID},
+		Filter: fmt.Sprintf(`protoPayload.serviceName=%q AND timestamp>=%q`,
+			servicePAM, since.UTC().Format(time.RFC3339)),
+		OrderBy:  "timestamp asc",
+		PageSize: 1000,
+	}
+
+	grants := make(map[string]*Elevation)
+	err := c.Logging.Entries.List(request).Pages(c.ctx, func(page *logging.ListLogEntriesResponse) error {
+		for _, entry := range page.Entries {
+			var payload pamAuditEntry
+			if err := json.Unmarshal(entry.ProtoPayload, &payload); err != nil {
+				continue
+			}
+			name := payload.ResourceName
+			if !strings.Contains(name, "/grants/") {
+				name = payload.Response.Name
+			}
+			if !strings.Contains(name, "/grants/") {
+				continue
+			}
+			timestamp, _ := time.Parse(time.RFC3339Nano, entry.Timestamp)
+
+			elevation := grants[name]
+			if elevation == nil {
+				entitlement, _, _ := strings.Cut(name, "/grants/")
+				elevation = &Elevation{Grant: name, Entitlement: entitlement}
+				grants[name] = elevation
+			}
+			applyGrantEvent(elevation, payload, timestamp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read privileged access audit logs: %w", err)
+	}
+
+	now := time.Now()
+	elevations := make([]Elevation, 0, len(grants))
+	for _, elevation := range grants {
+		if !elevation.Activated.IsZero() && elevation.Ends.IsZero() && elevation.Duration > 0 {
+			elevation.Ends = elevation.Activated.Add(elevation.Duration)
+			elevation.Active = elevation.Ends.After(now)
+		}
+		elevations = append(elevations, *elevation)
+	}
+	sort.Slice(elevations, func(i, j int) bool { return elevations[i].Requested.After(elevations[j].Requested) })
+	return elevations, nil
+}
+
+// applyGrantEvent records a grant request, activation, or end on an elevation
+func applyGrantEvent(elevation *Elevation, payload pamAuditEntry, timestamp time.Time) {
+	method := payload.MethodName
+	switch {
+	case strings.HasSuffix(method, "CreateGrant"):
+		elevation.Requested = timestamp
+		elevation.Requester = payload.AuthenticationInfo.PrincipalEmail
+		elevation.Justification = payload.Request.Grant.Justification.UnstructuredJustification
+		if requested := payload.Request.Grant.RequestedDuration; requested != "" {
+			elevation.Duration, _ = time.ParseDuration(strings.TrimSuffix(requested, "s") + "s")
+		}
+	case strings.Contains(method, "ActivateGrant"):
+		elevation.Activated = timestamp
+	case strings.Contains(method, "RevokeGrant"), strings.Contains(method, "EndGrant"),
+		strings.Contains(method, "ExpireGrant"), strings.Contains(method, "WithdrawGrant"):
+		elevation.Ends = timestamp
+		elevation.Active = false
+	}
+}
@@ -0,0 +1,68 @@
+package gcp
+
+import "sync"
+
+// DefaultPolicyWorkers bounds concurrent GetIamPolicy calls per collector when
+// Client.PolicyWorkers is unset
+const DefaultPolicyWorkers = 16
+
+// policyFetcher issues per-resource GetIamPolicy calls concurrently with a
+// bounded number of workers, calling each key once
+type policyFetcher struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu        sync.Mutex
+	requested map[string]bool
+	policies  map[string]map[string][]string
+}
+
+// newPolicyFetcher creates a fetcher limited to the client's policy workers
+func (c *Client) newPolicyFetcher() *policyFetcher {
+	workers := c.PolicyWorkers
+	if workers <= 0 {
+		workers = DefaultPolicyWorkers
+	}
+	return &policyFetcher{
+		sem:       make(chan struct{}, workers),
+		requested: make(map[string]bool),
+		policies:  make(map[string]map[string][]string),
+	}
+}
+
+// Fetch schedules fetch for key unless the key was already requested. fetch
+// returns the policy bindings as role -> members.
+func (f *policyFetcher) Fetch(key string, fetch func() (map[string][]string, error)) {
+	f.mu.Lock()
+	if f.requested[key] {
+		f.mu.Unlock()
+		return
+	}
+	f.requested[key] = true
+	f.mu.Unlock()
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.sem <- struct{}{}
+		defer func() { <-f.sem }()
+
+		bindings, err := fetch()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.policies[key] = bindings
+		f.mu.Unlock()
+	}()
+}
+
+// Wait blocks until every scheduled fetch finished and returns the bindings by
+// key. Keys whose fetch failed are missing.
+func (f *policyFetcher) Wait() map[string]map[string][]string {
+	f.wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.policies
+}
@@ -0,0 +1,169 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp/resourcename"
+
+	"cloud.google.com/go/resourcemanager/apiv3/resourcemanagerpb"
+	essentialcontacts "google.golang.org/api/essentialcontacts/v1"
+)
+
+// ErrProjectNotScanned is returned for projects outside the scan scope
+var ErrProjectNotScanned = errors.New("project is not scanned")
+
+// ProjectContact is an Essential Contacts entry of a project
+type ProjectContact struct {
+	Email      string   `json:"email"`
+	Categories []string `json:"categories"` // notification categories such as "SECURITY", "BILLING"
+}
+
+// ProjectInfo describes a scanned project: who it belongs to and its lifecycle
+type ProjectInfo struct {
+	ID          string            `json:"id"`
+	Number      string            `json:"number"`
+	DisplayName string            `json:"displayName"`
+	Parent      string            `json:"parent,omitempty"` // "folders/N" or "organizations/N"
+	State       string            `json:"state"`            // "ACTIVE", "DELETE_REQUESTED"
+	Labels      map[string]string `json:"labels"`
+	CreateTime  time.Time         `json:"createTime"`
+	Contacts    []ProjectContact  `json:"contacts"`
+	// ContactsError is set when Essential Contacts could not be read, e.g. the API is disabled
+	ContactsError string `json:"contactsError,omitempty"`
+}
+
+// GetProjects returns metadata for every scanned project
+func (c *Client) GetProjects() ([]ProjectInfo, error) {
+	if c.Discovery == nil {
+		project, err := c.GetProject(c.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		return []ProjectInfo{*project}, nil
+	}
+
+	projects := []ProjectInfo{}
+	for _, discovered := range c.ScannedProjects() {
+		project, err := c.forProject(discovered).GetProject(discovered.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project %s: %w", discovered.ID, err)
+		}
+		projects = append(projects, *project)
+	}
+	return projects, nil
+}
+
+// GetProject returns metadata for a scanned project, looked up by project ID or
+// "projects/NUMBER" name
+func (c *Client) GetProject(id string) (*ProjectInfo, error) {
+	if c.Discovery != nil && !c.IsScannedProject(id) {
+		projectID, ok := c.ScannedProjectID(id)
+		if !ok {
+			return nil, ErrProjectNotScanned
+		}
+		for _, discovered := range c.ScannedProjects() {
+			if discovered.ID == projectID {
+				return c.forProject(discovered).GetProject(projectID)
+			}
+		}
+	}
+
+	project, err := c.ResourceManager.GetProject(c.ctx, &resourcemanagerpb.GetProjectRequest{
+		Name: fmt.Sprintf("projects/%s", c.ProjectID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if id != project.GetProjectId() && id != project.GetName() && id != strings.TrimPrefix(project.GetName(), "projects/") {
+		return nil, ErrProjectNotScanned
+	}
+
+	info := &ProjectInfo{
+		ID:          project.GetProjectId(),
+		Number:      strings.TrimPrefix(project.GetName(), "projects/"),
+		DisplayName: project.GetDisplayName(),
+		Parent:      project.GetParent(),
+		State:       project.GetState().String(),
+		Labels:      project.GetLabels(),
+		CreateTime:  project.GetCreateTime().AsTime(),
+		Contacts:    []ProjectContact{},
+	}
+	if info.Labels == nil {
+		info.Labels = map[string]string{}
+	}
+
+	contacts, err := c.getProjectContacts(project.GetName())
+	if err != nil {
+		info.ContactsError = err.Error()
+	} else {
+		info.Contacts = contacts
+	}
+
+	return info, nil
+}
+
+// ResolveProject looks up the ID and number of the scanned project. ProjectID
+// may be configured as either; it is replaced by the project ID, and resource
+// names carrying the number are rewritten to the ID during scans.
+func (c *Client) ResolveProject() error {
+	project, err := c.ResourceManager.GetProject(c.ctx, &resourcemanagerpb.GetProjectRequest{
+		Name: fmt.Sprintf("projects/%s", c.ProjectID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve project %s: %w", c.ProjectID, err)
+	}
+	c.ProjectID = project.GetProjectId()
+	c.ProjectNumber = strings.TrimPrefix(project.GetName(), "projects/")
+	return nil
+}
+
+// IsScannedProject reports whether a project ID, project number, or
+// "projects/..." name refers to the client's own project
+func (c *Client) IsScannedProject(ref string) bool {
+	ref = strings.TrimPrefix(ref, "projects/")
+	return ref == c.ProjectID || (c.ProjectNumber != "" && ref == c.ProjectNumber)
+}
+
+// normalizeResourceName rewrites the project number in a resource name to the
+// project ID, so names from every source match the project's resource ID
+func (c *Client) normalizeResourceName(name string) string {
+	return resourcename.ReplaceProject(name, c.ProjectNumber, c.ProjectID)
+}
+
+// projectNumber resolves the number of the scanned project, which some APIs
+// (such as IAP) require instead of the project ID
+func (c *Client) projectNumber() (string, error) {
+	if c.ProjectNumber != "" {
+		return c.ProjectNumber, nil
+	}
+	project, err := c.ResourceManager.GetProject(c.ctx, &resourcemanagerpb.GetProjectRequest{
+		Name: fmt.Sprintf("projects/%s", c.ProjectID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(project.GetName(), "projects/"), nil
+}
+
+// getProjectContacts lists the Essential Contacts configured directly on a project
+func (c *Client) getProjectContacts(name string) ([]ProjectContact, error) {
+	contacts := []ProjectContact{}
+	err := c.Contacts.Projects.Contacts.List(name).
+		Pages(c.ctx, func(page *essentialcontacts.GoogleCloudEssentialcontactsV1ListContactsResponse) error {
+			for _, contact := range page.Contacts {
+				contacts = append(contacts, ProjectContact{
+					Email:      contact.Email,
+					Categories: contact.NotificationCategorySubscriptions,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
@@ -0,0 +1,125 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+	"google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// gcrBucketPrefixes maps legacy Container Registry bucket prefixes to their multi-region
+var gcrBucketPrefixes = map[string]string{
+	"":      "us", // gcr.io
+	"us.":   "us",
+	"eu.":   "eu",
+	"asia.": "asia",
+}
+
+func init() {
+	RegisterCollector(Collector{Name: "artifactregistry", Collect: (*Client).getArtifactRegistryRepositories, Services: []string{"artifactregistry.googleapis.com"}, Permission: "artifactregistry.repositories.list"})
+	RegisterCollector(Collector{Name: "containerregistry", Collect: (*Client).getContainerRegistryBuckets, Services: []string{"storage.googleapis.com"}, Permission: "storage.buckets.get"})
+}
+
+// getArtifactRegistryRepositories lists repositories in every Artifact Registry location
+// with their IAM policies, showing who can push (writer) or pull (reader) images
+func (c *Client) getArtifactRegistryRepositories() ([]Resource, error) {
+	var locations []string
+	err := c.ArtifactRegistry.Projects.Locations.List(fmt.Sprintf("projects/%s", c.ProjectID)).
+		Pages(c.ctx, func(page *artifactregistry.ListLocationsResponse) error {
+			for _, location := range page.Locations {
+				if c.Scope.LocationAllowed(location.LocationId) {
+					locations = append(locations, location.LocationId)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, location := range locations {
+		parent := fmt.Sprintf("projects/%s/locations/%s", c.ProjectID, location)
+		err := c.ArtifactRegistry.Projects.Locations.Repositories.List(parent).
+			Pages(c.ctx, func(page *artifactregistry.ListRepositoriesResponse) error {
+				for _, repo := range page.Repositories {
+					resource := Resource{
+						ID:        "//artifactregistry.googleapis.com/" + repo.Name,
+						Name:      repo.Name[strings.LastIndex(repo.Name, "/")+1:],
+						Type:      "artifactregistry",
+						Location:  location,
+						AssetType: "artifactregistry.googleapis.com/Repository",
+						IAM:       make(map[string][]string),
+					}
+
+					policy, err := c.ArtifactRegistry.Projects.Locations.Repositories.GetIamPolicy(repo.Name).OptionsRequestedPolicyVersion(PolicyVersion).Context(c.ctx).Do()
+					if err == nil && policy != nil {
+						for _, binding := range policy.Bindings {
+							resource.IAM[binding.Role] = append(resource.IAM[binding.Role], binding.Members...)
+						}
+					}
+
+					resources = append(resources, resource)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resources, nil
+}
+
+// getContainerRegistryBuckets finds the legacy gcr.io storage buckets of the project
+// and reads their IAM policies; bucket access is image push/pull access
+func (c *Client) getContainerRegistryBuckets() ([]Resource, error) {
+	var resources []Resource
+	for prefix, location := range gcrBucketPrefixes {
+		bucket := fmt.Sprintf("%sartifacts.%s.appspot.com", prefix, c.ProjectID)
+
+		if _, err := c.Storage.Buckets.Get(bucket).Context(c.ctx).Do(); err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		resource := Resource{
+			ID:        "//storage.googleapis.com/" + bucket,
+			Name:      bucket,
+			Type:      "containerregistry",
+			Location:  location,
+			AssetType: "storage.googleapis.com/Bucket",
+			IAM:       make(map[string][]string),
+		}
+
+		policy, err := c.Storage.Buckets.GetIamPolicy(bucket).OptionsRequestedPolicyVersion(PolicyVersion).Context(c.ctx).Do()
+		if err == nil && policy != nil {
+			resource.IAM = storagePolicyBindings(policy)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// storagePolicyBindings converts a storage bucket policy into role -> members
+func storagePolicyBindings(policy *storage.Policy) map[string][]string {
+	bindings := make(map[string][]string)
+	for _, binding := range policy.Bindings {
+		bindings[binding.Role] = append(bindings[binding.Role], binding.Members...)
+	}
+	return bindings
+}
+
+// isNotFound reports whether a REST API error is a 404
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
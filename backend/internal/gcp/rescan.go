@@ -0,0 +1,365 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	asset "cloud.google.com/go/asset/apiv1"
+	"cloud.google.com/go/asset/apiv1/assetpb"
+	"google.golang.org/api/iterator"
+)
+
+// ErrResourceNotScanned is returned when rescanning a resource the matrix does not hold
+var ErrResourceNotScanned = errors.New("resource is not in the access matrix")
+
+// RescanResult describes what a selective rescan refreshed
+type RescanResult struct {
+	// Policies counts the IAM policies read from Asset Inventory
+	Policies int `json:"policies"`
+	// Resources are the resources whose bindings were replaced
+	Resources []string `json:"resources"`
+	// Projects are the projects whose access entries were rebuilt
+	Projects []string `json:"projects"`
+}
+
+// RescanResource refreshes the IAM policy of one resource and returns a copy of
+// the matrix with its access rebuilt. A resource Asset Inventory reports no
+// policy for is left without bindings. Access on other resources changes only
+// when the resource is a project, whose roles they inherit.
+func (c *Client) RescanResource(matrix *AccessMatrix, resourceID string) (*AccessMatrix, *RescanResult, error) {
+	var target *Resource
+	for i := range matrix.Resources {
+		if matrix.Resources[i].ID == resourceID {
+			target = &matrix.Resources[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, nil, ErrResourceNotScanned
+	}
+	client := c.projectClient(target.Project)
+	if client == nil {
+		return nil, nil, ErrResourceNotScanned
+	}
+
+	// Asset Inventory may name the resource by project number
+	queries := []string{fmt.Sprintf("resource:%q", resourceID)}
+	if client.ProjectNumber != "" {
+		if byNumber := strings.Replace(resourceID, "projects/"+client.ProjectID, "projects/"+client.ProjectNumber, 1); byNumber != resourceID {
+			queries = append(queries, fmt.Sprintf("resource:%q", byNumber))
+		}
+	}
+	policies, err := client.searchPolicies(queries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The query matches names containing the resource's; keep its own policy only
+	var own []*assetpb.IamPolicySearchResult
+	for _, policy := range policies {
+		if client.normalizeResourceName(policy.Resource) == resourceID {
+			own = append(own, policy)
+		}
+	}
+	patched := client.patchPolicies(matrix, own, func(resource *Resource) bool { return resource.ID == resourceID })
+	return patched, &RescanResult{Policies: len(own), Resources: []string{resourceID}, Projects: []string{client.ProjectID}}, nil
+}
+
+// RescanPrincipal refreshes every IAM policy that mentions a principal, in
+// every scanned project, and returns a copy of the matrix with access rebuilt.
+// Bindings of the principal on resources whose policy no longer mentions it
+// are dropped.
+func (c *Client) RescanPrincipal(matrix *AccessMatrix, email string) (*AccessMatrix, *RescanResult, error) {
+	result := &RescanResult{Resources: []string{}, Projects: []string{}}
+	patched := matrix
+	for _, project := range c.ScannedProjects() {
+		client := c
+		if c.Discovery != nil {
+			client = c.forProject(project)
+		}
+		policies, err := client.searchPolicies([]string{fmt.Sprintf("policy:%q", email)})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to rescan %s in %s: %w", email, project.ID, err)
+		}
+
+		refreshed := make(map[string]bool)
+		for _, policy := range policies {
+			refreshed[client.normalizeResourceName(policy.Resource)] = true
+		}
+		// Resources that bound the principal before are refreshed too: an
+		// empty result for them means the binding was removed
+		holding := make(map[string]bool)
+		for _, resource := range patched.Resources {
+			if client.IsScannedProject(resource.Project) && resource.binds(email) {
+				holding[resource.ID] = true
+			}
+		}
+		if len(refreshed) == 0 && len(holding) == 0 {
+			continue
+		}
+
+		patched = client.patchPolicies(patched, policies, func(resource *Resource) bool {
+			return holding[resource.ID] || refreshed[resource.ID]
+		})
+		result.Policies += len(policies)
+		for id := range refreshed {
+			holding[id] = true
+		}
+		for id := range holding {
+			result.Resources = append(result.Resources, id)
+		}
+		result.Projects = append(result.Projects, client.ProjectID)
+	}
+	sort.Strings(result.Resources)
+	return patched, result, nil
+}
+
+// RescanProjects scans the given projects (by ID, number, or "projects/..."
+// name) again and returns a copy of the matrix in which their resources,
+// access, eligible access, and warnings are replaced; other projects are kept
+// as they are. A project that cannot be scanned keeps its previous data, and
+// the failure is reported in its scan outcome and as a warning. Without
+// project discovery, this is a full scan of the configured project.
+func (c *Client) RescanProjects(matrix *AccessMatrix, refs []string) (*AccessMatrix, error) {
+	var projects []DiscoveredProject
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		id, ok := c.ScannedProjectID(ref)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrProjectNotScanned, ref)
+		}
+		for _, project := range c.ScannedProjects() {
+			if project.ID == id && !seen[id] {
+				seen[id] = true
+				projects = append(projects, project)
+			}
+		}
+	}
+
+	if c.Discovery == nil {
+		return c.GetAccessMatrix()
+	}
+	for i, result := range c.scanDiscovered(projects) {
+		matrix = c.forProject(projects[i]).replaceProject(matrix, result.Matrix, result.Scan, result.Err)
+	}
+	return matrix, nil
+}
+
+// replaceProject returns a copy of the matrix with the client's project
+// replaced by a fresh scan of it, or only its scan outcome and a warning when
+// the scan failed
+func (c *Client) replaceProject(matrix, scanned *AccessMatrix, scan ProjectScan, err error) *AccessMatrix {
+	replaced := &AccessMatrix{
+		Users:     matrix.Users,
+		Resources: matrix.Resources,
+		Access:    matrix.Access,
+		Eligible:  matrix.Eligible,
+		Warnings:  []ScanWarning{},
+		Projects:  []ProjectScan{},
+	}
+	for _, previous := range matrix.Projects {
+		if previous.Project != c.ProjectID {
+			replaced.Projects = append(replaced.Projects, previous)
+		}
+	}
+	replaced.Projects = append(replaced.Projects, scan)
+
+	if err != nil {
+		replaced.Warnings = append(replaced.Warnings, matrix.Warnings...)
+		warning := newScanWarning(ProjectCollector, err, "resourcemanager.projects.getIamPolicy")
+		warning.Project = c.ProjectID
+		replaced.Warnings = append(replaced.Warnings, warning)
+		return replaced
+	}
+
+	for _, warning := range matrix.Warnings {
+		if warning.Project != c.ProjectID {
+			replaced.Warnings = append(replaced.Warnings, warning)
+		}
+	}
+	for _, warning := range scanned.Warnings {
+		warning.Project = c.ProjectID
+		replaced.Warnings = append(replaced.Warnings, warning)
+	}
+
+	// Resources of the project, and any the new scan holds, are replaced
+	dropped := make(map[string]bool)
+	for _, resource := range scanned.Resources {
+		dropped[resource.ID] = true
+	}
+	replaced.Resources = []Resource{}
+	for _, resource := range matrix.Resources {
+		if c.IsScannedProject(resource.Project) || dropped[resource.ID] {
+			dropped[resource.ID] = true
+			continue
+		}
+		replaced.Resources = append(replaced.Resources, resource)
+	}
+	replaced.Resources = append(replaced.Resources, scanned.Resources...)
+
+	replaced.Access = []AccessEntry{}
+	for _, entry := range matrix.Access {
+		if !dropped[entry.ResourceID] {
+			replaced.Access = append(replaced.Access, entry)
+		}
+	}
+	replaced.Access = append(replaced.Access, scanned.Access...)
+
+	replaced.Eligible = nil
+	for _, eligible := range matrix.Eligible {
+		if !dropped[eligible.ResourceID] {
+			replaced.Eligible = append(replaced.Eligible, eligible)
+		}
+	}
+	replaced.Eligible = append(replaced.Eligible, scanned.Eligible...)
+
+	// Principals keep their entry until a full scan finds them bound nowhere;
+	// those the project scan found take its version
+	users := make(map[string]int, len(matrix.Users))
+	replaced.Users = make([]User, 0, len(matrix.Users))
+	for _, user := range matrix.Users {
+		users[user.Email] = len(replaced.Users)
+		replaced.Users = append(replaced.Users, user)
+	}
+	for _, user := range scanned.Users {
+		if i, ok := users[user.Email]; ok {
+			replaced.Users[i] = user
+			continue
+		}
+		users[user.Email] = len(replaced.Users)
+		replaced.Users = append(replaced.Users, user)
+	}
+	return replaced
+}
+
+// binds reports whether a principal is bound to any role on the resource
+func (r *Resource) binds(email string) bool {
+	for role, members := range r.IAM {
+		if role == "inherited" {
+			continue
+		}
+		for _, member := range members {
+			if ParseMember(member).Email == email {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// projectClient returns the client of the scanned project a "projects/..."
+// name refers to, or nil when the project is not scanned
+func (c *Client) projectClient(project string) *Client {
+	if c.Discovery == nil {
+		if c.IsScannedProject(project) {
+			return c
+		}
+		return nil
+	}
+	id, ok := c.ScannedProjectID(project)
+	if !ok {
+		return nil
+	}
+	for _, discovered := range c.ScannedProjects() {
+		if discovered.ID == id {
+			return c.forProject(discovered)
+		}
+	}
+	return nil
+}
+
+// searchPolicies runs Asset Inventory IAM policy searches over the project
+func (c *Client) searchPolicies(queries []string) ([]*assetpb.IamPolicySearchResult, error) {
+	ctx := context.Background()
+	assetClient, err := asset.NewClient(ctx, meteredGRPCOptions(c.usage, serviceAsset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset client: %w", err)
+	}
+	defer assetClient.Close()
+
+	var policies []*assetpb.IamPolicySearchResult
+	for _, query := range queries {
+		it := assetClient.SearchAllIamPolicies(ctx, &assetpb.SearchAllIamPoliciesRequest{
+			Scope: fmt.Sprintf("projects/%s", c.ProjectID),
+			Query: query,
+		})
+		for {
+			policy, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to search IAM policies: %w", err)
+			}
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+// patchPolicies returns a copy of the matrix in which the resources selected
+// by reset have their bindings replaced by the given policies, and the access
+// of the client's project is rebuilt. Policies of resources the matrix does
+// not hold add them, as a full scan would.
+func (c *Client) patchPolicies(matrix *AccessMatrix, policies []*assetpb.IamPolicySearchResult, reset func(resource *Resource) bool) *AccessMatrix {
+	resourcesMap := make(map[string]*Resource)
+	known := make(map[string]bool, len(matrix.Resources))
+	for _, resource := range matrix.Resources {
+		known[resource.ID] = true
+		if !c.IsScannedProject(resource.Project) {
+			continue
+		}
+		r := resource
+		if reset(&r) {
+			// Bindings are replaced; "inherited" markers come from collectors
+			r.IAM = map[string][]string{}
+			if inherited, ok := resource.IAM["inherited"]; ok {
+				r.IAM["inherited"] = inherited
+			}
+			r.Conditions = nil
+		}
+		resourcesMap[r.ID] = &r
+	}
+
+	scanScope := c.currentScope()
+	var added []string
+	for _, policy := range policies {
+		c.applyPolicy(resourcesMap, policy, scanScope)
+	}
+	for id, resource := range resourcesMap {
+		if !known[id] {
+			resource.Project = c.projectName()
+			added = append(added, id)
+		}
+	}
+	sort.Strings(added)
+
+	patched := &AccessMatrix{
+		Users:     appendBoundUsers(append([]User{}, matrix.Users...), resourcesMap),
+		Resources: make([]Resource, 0, len(matrix.Resources)+len(added)),
+		Access:    []AccessEntry{},
+		Eligible:  matrix.Eligible,
+		Warnings:  matrix.Warnings,
+		Projects:  matrix.Projects,
+	}
+	// Resources and access of other projects are kept as they are
+	for _, resource := range matrix.Resources {
+		if patchedResource, ok := resourcesMap[resource.ID]; ok {
+			resource = *patchedResource
+		}
+		patched.Resources = append(patched.Resources, resource)
+	}
+	for _, id := range added {
+		patched.Resources = append(patched.Resources, *resourcesMap[id])
+	}
+	for _, entry := range matrix.Access {
+		if _, ok := resourcesMap[entry.ResourceID]; !ok {
+			patched.Access = append(patched.Access, entry)
+		}
+	}
+	patched.Access = append(patched.Access, c.buildAccess(resourcesMap)...)
+	return patched
+}
@@ -0,0 +1,68 @@
+package resourcename
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Location kinds
+const (
+	LocationGlobal      = "global"
+	LocationMultiRegion = "multi-region"
+	LocationRegion      = "region"
+	LocationZone        = "zone"
+)
+
+var (
+	// zonePattern matches zones such as us-central1-a or europe-west4-b
+	zonePattern = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+	// regionPattern matches regions such as us-central1 or northamerica-northeast2
+	regionPattern = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+)
+
+// multiRegions are multi-region and dual-region locations used by Storage,
+// BigQuery, Spanner, Artifact Registry, and Firestore
+var multiRegions = map[string]bool{
+	"us": true, "eu": true, "asia": true,
+	"nam4": true, "eur4": true, "asia1": true, "eur3": true, "nam3": true,
+	"nam5": true, "nam6": true, "nam7": true, "eur5": true, "eur6": true,
+	"nam-eur-asia1": true,
+}
+
+// Location is a normalized GCP location
+type Location struct {
+	Name   string `json:"name"`   // lowercased location, e.g. "us-central1-a"
+	Kind   string `json:"kind"`   // "zone", "region", "multi-region", "global"
+	Region string `json:"region"` // enclosing region for zones, the region itself otherwise
+}
+
+// NormalizeLocation classifies a raw location string
+func NormalizeLocation(raw string) Location {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	switch {
+	case name == "" || name == "global" || name == "-":
+		return Location{Name: "global", Kind: LocationGlobal}
+	case zonePattern.MatchString(name):
+		return Location{Name: name, Kind: LocationZone, Region: name[:strings.LastIndex(name, "-")]}
+	case regionPattern.MatchString(name):
+		return Location{Name: name, Kind: LocationRegion, Region: name}
+	case multiRegions[name]:
+		return Location{Name: name, Kind: LocationMultiRegion, Region: name}
+	}
+	// Unknown formats are kept verbatim rather than guessed
+	return Location{Name: name, Kind: LocationRegion, Region: name}
+}
+
+// LocationFromResourceName extracts the location segment of a resource name such as
+// projects/P/locations/L/services/S or //compute.googleapis.com/projects/P/zones/Z/instances/I.
+// It returns "global" when the name carries no location.
+func LocationFromResourceName(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "//"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "locations", "zones", "regions":
+			return NormalizeLocation(parts[i+1]).Name
+		}
+	}
+	return "global"
+}
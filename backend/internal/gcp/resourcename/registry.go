@@ -0,0 +1,126 @@
+package resourcename
+
+import "strings"
+
+// AssetType describes a known Cloud Asset Inventory asset type and its name format
+type AssetType struct {
+	AssetType    string `json:"assetType"`
+	FriendlyType string `json:"friendlyType"`
+	Pattern      string `json:"pattern"`
+
+	segments []string
+}
+
+// registry lists known asset types. More specific patterns come first.
+var registry []AssetType
+
+func init() {
+	// Each entry is asset type, friendly type, name pattern
+	for _, def := range [][3]string{
+		{"cloudresourcemanager.googleapis.com/Organization", "organization", "//cloudresourcemanager.googleapis.com/organizations/{organization}"},
+		{"cloudresourcemanager.googleapis.com/Folder", "folder", "//cloudresourcemanager.googleapis.com/folders/{folder}"},
+		{"cloudresourcemanager.googleapis.com/Project", "project", "//cloudresourcemanager.googleapis.com/projects/{project}"},
+
+		{"compute.googleapis.com/Instance", "vm", "//compute.googleapis.com/projects/{project}/zones/{zone}/instances/{instance}"},
+		{"compute.googleapis.com/Disk", "disk", "//compute.googleapis.com/projects/{project}/zones/{zone}/disks/{disk}"},
+		{"compute.googleapis.com/Image", "image", "//compute.googleapis.com/projects/{project}/global/images/{image}"},
+		{"compute.googleapis.com/Snapshot", "snapshot", "//compute.googleapis.com/projects/{project}/global/snapshots/{snapshot}"},
+		{"compute.googleapis.com/Network", "network", "//compute.googleapis.com/projects/{project}/global/networks/{network}"},
+		{"compute.googleapis.com/Subnetwork", "subnet", "//compute.googleapis.com/projects/{project}/regions/{region}/subnetworks/{subnetwork}"},
+		{"compute.googleapis.com/Firewall", "firewall", "//compute.googleapis.com/projects/{project}/global/firewalls/{firewall}"},
+		{"compute.googleapis.com/BackendService", "backendservice", "//compute.googleapis.com/projects/{project}/global/backendServices/{backendService}"},
+		{"compute.googleapis.com/RegionBackendService", "backendservice", "//compute.googleapis.com/projects/{project}/regions/{region}/backendServices/{backendService}"},
+
+		{"container.googleapis.com/Cluster", "gke", "//container.googleapis.com/projects/{project}/locations/{location}/clusters/{cluster}"},
+		{"container.googleapis.com/Cluster", "gke", "//container.googleapis.com/projects/{project}/zones/{zone}/clusters/{cluster}"},
+		{"run.googleapis.com/Service", "cloudrun", "//run.googleapis.com/projects/{project}/locations/{location}/services/{service}"},
+		{"run.googleapis.com/Job", "cloudrun", "//run.googleapis.com/projects/{project}/locations/{location}/jobs/{job}"},
+		{"cloudfunctions.googleapis.com/CloudFunction", "cloudfunction", "//cloudfunctions.googleapis.com/projects/{project}/locations/{location}/functions/{function}"},
+
+		{"storage.googleapis.com/Bucket", "storage", "//storage.googleapis.com/projects/_/buckets/{bucket}"},
+		{"storage.googleapis.com/Bucket", "storage", "//storage.googleapis.com/{bucket}"},
+		{"bigquery.googleapis.com/Dataset", "bigquery", "//bigquery.googleapis.com/projects/{project}/datasets/{dataset}"},
+		{"bigquery.googleapis.com/Table", "bigquery", "//bigquery.googleapis.com/projects/{project}/datasets/{dataset}/tables/{table}"},
+
+		{"iam.googleapis.com/ServiceAccount", "serviceaccount", "//iam.googleapis.com/projects/{project}/serviceAccounts/{serviceAccount}"},
+		{"iam.googleapis.com/Role", "role", "//iam.googleapis.com/projects/{project}/roles/{role}"},
+		{"secretmanager.googleapis.com/Secret", "secret", "//secretmanager.googleapis.com/projects/{project}/secrets/{secret}"},
+		{"cloudkms.googleapis.com/KeyRing", "kms", "//cloudkms.googleapis.com/projects/{project}/locations/{location}/keyRings/{keyRing}"},
+		{"cloudkms.googleapis.com/CryptoKey", "kms", "//cloudkms.googleapis.com/projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}"},
+
+		{"pubsub.googleapis.com/Topic", "pubsub", "//pubsub.googleapis.com/projects/{project}/topics/{topic}"},
+		{"pubsub.googleapis.com/Subscription", "pubsub", "//pubsub.googleapis.com/projects/{project}/subscriptions/{subscription}"},
+
+		{"spanner.googleapis.com/Instance", "spanner", "//spanner.googleapis.com/projects/{project}/instances/{instance}"},
+		{"spanner.googleapis.com/Database", "spanner", "//spanner.googleapis.com/projects/{project}/instances/{instance}/databases/{database}"},
+		{"bigtableadmin.googleapis.com/Instance", "bigtable", "//bigtableadmin.googleapis.com/projects/{project}/instances/{instance}"},
+		{"bigtableadmin.googleapis.com/Table", "bigtable", "//bigtableadmin.googleapis.com/projects/{project}/instances/{instance}/tables/{table}"},
+		{"firestore.googleapis.com/Database", "firestore", "//firestore.googleapis.com/projects/{project}/databases/{database}"},
+
+		{"artifactregistry.googleapis.com/Repository", "artifactregistry", "//artifactregistry.googleapis.com/projects/{project}/locations/{location}/repositories/{repository}"},
+		{"dataproc.googleapis.com/Cluster", "dataproc", "//dataproc.googleapis.com/projects/{project}/regions/{region}/clusters/{cluster}"},
+		{"dataflow.googleapis.com/Job", "dataflow", "//dataflow.googleapis.com/projects/{project}/locations/{location}/jobs/{job}"},
+		{"composer.googleapis.com/Environment", "composer", "//composer.googleapis.com/projects/{project}/locations/{location}/environments/{environment}"},
+		{"apikeys.googleapis.com/Key", "apikey", "//apikeys.googleapis.com/projects/{project}/locations/{location}/keys/{key}"},
+	} {
+		Register(AssetType{AssetType: def[0], FriendlyType: def[1], Pattern: def[2]})
+	}
+}
+
+// assetTypeAliases maps asset types without a registered name pattern to friendly types
+var assetTypeAliases = map[string]string{
+	"sqladmin.googleapis.com/Instance":                   "cloudsql",
+	"cloudbuild.googleapis.com/BuildTrigger":             "cloudbuild",
+	"containerregistry.googleapis.com/Image":             "artifactregistry",
+	"artifactregistry.googleapis.com/DockerImage":        "artifactregistry",
+	"dataproc.googleapis.com/AutoscalingPolicy":          "dataproc",
+	"dataproc.googleapis.com/Job":                        "dataproc",
+	"spanner.googleapis.com/Backup":                      "spanner",
+	"bigtableadmin.googleapis.com/Cluster":               "bigtable",
+	"bigtableadmin.googleapis.com/Backup":                "bigtable",
+	"cloudfunctions.googleapis.com/Function":             "cloudfunction",
+	"iap.googleapis.com/Web":                             "iap",
+	"iap.googleapis.com/WebType":                         "iap",
+	"iap.googleapis.com/TunnelInstance":                  "iap",
+	"billingbudgets.googleapis.com/Budget":               "billing",
+	"cloudbilling.googleapis.com/BillingAccount":         "billing",
+	"cloudscheduler.googleapis.com/Job":                  "scheduler",
+	"cloudtasks.googleapis.com/Queue":                    "tasks",
+	"eventarc.googleapis.com/Trigger":                    "eventarc",
+	"notebooks.googleapis.com/Instance":                  "notebook",
+	"aiplatform.googleapis.com/Endpoint":                 "vertexai",
+	"logging.googleapis.com/LogBucket":                   "logging",
+	"servicedirectory.googleapis.com/Namespace":          "servicedirectory",
+	"datacatalog.googleapis.com/TagTemplate":             "datacatalog",
+	"healthcare.googleapis.com/Dataset":                  "healthcare",
+	"gkehub.googleapis.com/Membership":                   "gke",
+	"privilegedaccessmanager.googleapis.com/Entitlement": "pam",
+}
+
+// FriendlyTypeForAssetType maps an official asset type to a friendly resource type.
+// Unknown asset types fall back to the service prefix, e.g. "workflows".
+func FriendlyTypeForAssetType(assetType string) string {
+	for _, known := range registry {
+		if known.AssetType == assetType {
+			return known.FriendlyType
+		}
+	}
+	if friendly, ok := assetTypeAliases[assetType]; ok {
+		return friendly
+	}
+	if service := strings.Split(assetType, "/")[0]; service != "" {
+		return strings.Split(service, ".")[0]
+	}
+	return "other"
+}
+
+// Register adds an asset type to the registry
+func Register(assetType AssetType) {
+	assetType.segments = strings.Split(strings.TrimPrefix(assetType.Pattern, "//"), "/")
+	registry = append(registry, assetType)
+}
+
+// KnownAssetTypes returns the registered asset types
+func KnownAssetTypes() []AssetType {
+	return append([]AssetType(nil), registry...)
+}
@@ -0,0 +1,107 @@
+// Package resourcename parses Cloud Asset Inventory full resource names such as
+// //compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/vm-1
+package resourcename
+
+import (
+	"strings"
+)
+
+// ResourceName is the parsed form of a full resource name
+type ResourceName struct {
+	Raw          string `json:"raw"`
+	Service      string `json:"service"`      // e.g. "compute.googleapis.com"
+	AssetType    string `json:"assetType"`    // e.g. "compute.googleapis.com/Instance"; empty if unknown
+	FriendlyType string `json:"friendlyType"` // e.g. "vm"
+	Project      string `json:"project"`      // project ID or number; empty if not part of the name
+	Location     string `json:"location"`     // normalized location, "global" if absent
+	DisplayName  string `json:"displayName"`  // last identifier in the name
+}
+
+// Parse parses a full resource name. Names matching a registered asset type get
+// its asset and friendly types; other names are parsed generically.
+func Parse(name string) ResourceName {
+	parsed := ResourceName{
+		Raw:      name,
+		Location: "global",
+	}
+
+	trimmed := strings.TrimPrefix(name, "//")
+	segments := strings.Split(trimmed, "/")
+	parsed.Service = segments[0]
+	parsed.DisplayName = segments[len(segments)-1]
+
+	if assetType, vars, ok := match(segments); ok {
+		parsed.AssetType = assetType.AssetType
+		parsed.FriendlyType = assetType.FriendlyType
+		parsed.Project = vars["project"]
+		for _, key := range []string{"location", "zone", "region"} {
+			if value, ok := vars[key]; ok {
+				parsed.Location = NormalizeLocation(value).Name
+			}
+		}
+		return parsed
+	}
+
+	// Generic fallback for unregistered formats
+	parsed.FriendlyType = strings.Split(parsed.Service, ".")[0]
+	if parsed.FriendlyType == "" {
+		parsed.FriendlyType = "other"
+	}
+	for i := 1; i+1 < len(segments); i++ {
+		if segments[i] == "projects" && parsed.Project == "" {
+			parsed.Project = segments[i+1]
+		}
+	}
+	parsed.Location = LocationFromResourceName(name)
+	return parsed
+}
+
+// match finds the registered asset type whose pattern matches the name segments
+func match(segments []string) (AssetType, map[string]string, bool) {
+	for _, assetType := range registry {
+		if vars, ok := matchPattern(assetType.segments, segments); ok {
+			return assetType, vars, true
+		}
+	}
+	return AssetType{}, nil, false
+}
+
+// matchPattern matches name segments against pattern segments, capturing {variables}
+func matchPattern(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	vars := make(map[string]string)
+	for i, part := range pattern {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			vars[part[1:len(part)-1]] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+// ReplaceProject rewrites the project segment of a resource name, e.g.
+// "//storage.googleapis.com/projects/123/buckets/b" with from "123" and to
+// "my-project". Asset Inventory names some resources by project number and
+// others by project ID; rewriting one to the other makes them comparable.
+func ReplaceProject(name, from, to string) string {
+	if from == "" || from == to {
+		return name
+	}
+	parts := strings.Split(name, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "projects" && parts[i+1] == from {
+			parts[i+1] = to
+			return strings.Join(parts, "/")
+		}
+	}
+	return name
+}
@@ -0,0 +1,158 @@
+package resourcename
+
+import "testing"
+
+func TestParseKnownFormats(t *testing.T) {
+	tests := []struct {
+		name         string
+		assetType    string
+		friendlyType string
+		project      string
+		location     string
+		displayName  string
+	}{
+		{"//cloudresourcemanager.googleapis.com/organizations/123", "cloudresourcemanager.googleapis.com/Organization", "organization", "", "global", "123"},
+		{"//cloudresourcemanager.googleapis.com/folders/456", "cloudresourcemanager.googleapis.com/Folder", "folder", "", "global", "456"},
+		{"//cloudresourcemanager.googleapis.com/projects/my-project", "cloudresourcemanager.googleapis.com/Project", "project", "my-project", "global", "my-project"},
+		{"//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/vm-1", "compute.googleapis.com/Instance", "vm", "my-project", "us-central1-a", "vm-1"},
+		{"//compute.googleapis.com/projects/my-project/zones/us-central1-a/disks/disk-1", "compute.googleapis.com/Disk", "disk", "my-project", "us-central1-a", "disk-1"},
+		{"//compute.googleapis.com/projects/my-project/global/images/img", "compute.googleapis.com/Image", "image", "my-project", "global", "img"},
+		{"//compute.googleapis.com/projects/my-project/global/snapshots/snap", "compute.googleapis.com/Snapshot", "snapshot", "my-project", "global", "snap"},
+		{"//compute.googleapis.com/projects/my-project/global/networks/default", "compute.googleapis.com/Network", "network", "my-project", "global", "default"},
+		{"//compute.googleapis.com/projects/host/regions/europe-west1/subnetworks/shared", "compute.googleapis.com/Subnetwork", "subnet", "host", "europe-west1", "shared"},
+		{"//compute.googleapis.com/projects/my-project/global/firewalls/allow-ssh", "compute.googleapis.com/Firewall", "firewall", "my-project", "global", "allow-ssh"},
+		{"//compute.googleapis.com/projects/my-project/global/backendServices/web", "compute.googleapis.com/BackendService", "backendservice", "my-project", "global", "web"},
+		{"//compute.googleapis.com/projects/my-project/regions/us-east1/backendServices/internal", "compute.googleapis.com/RegionBackendService", "backendservice", "my-project", "us-east1", "internal"},
+		{"//container.googleapis.com/projects/my-project/locations/us-central1/clusters/prod", "container.googleapis.com/Cluster", "gke", "my-project", "us-central1", "prod"},
+		{"//container.googleapis.com/projects/my-project/zones/us-central1-b/clusters/dev", "container.googleapis.com/Cluster", "gke", "my-project", "us-central1-b", "dev"},
+		{"//run.googleapis.com/projects/my-project/locations/europe-west4/services/api", "run.googleapis.com/Service", "cloudrun", "my-project", "europe-west4", "api"},
+		{"//run.googleapis.com/projects/my-project/locations/europe-west4/jobs/batch", "run.googleapis.com/Job", "cloudrun", "my-project", "europe-west4", "batch"},
+		{"//cloudfunctions.googleapis.com/projects/my-project/locations/us-central1/functions/fn", "cloudfunctions.googleapis.com/CloudFunction", "cloudfunction", "my-project", "us-central1", "fn"},
+		{"//storage.googleapis.com/projects/_/buckets/my-bucket", "storage.googleapis.com/Bucket", "storage", "", "global", "my-bucket"},
+		{"//storage.googleapis.com/legacy-bucket", "storage.googleapis.com/Bucket", "storage", "", "global", "legacy-bucket"},
+		{"//bigquery.googleapis.com/projects/my-project/datasets/sales", "bigquery.googleapis.com/Dataset", "bigquery", "my-project", "global", "sales"},
+		{"//bigquery.googleapis.com/projects/my-project/datasets/sales/tables/orders", "bigquery.googleapis.com/Table", "bigquery", "my-project", "global", "orders"},
+		{"//iam.googleapis.com/projects/my-project/serviceAccounts/sa@my-project.iam.gserviceaccount.com", "iam.googleapis.com/ServiceAccount", "serviceaccount", "my-project", "global", "sa@my-project.iam.gserviceaccount.com"},
+		{"//iam.googleapis.com/projects/my-project/roles/custom", "iam.googleapis.com/Role", "role", "my-project", "global", "custom"},
+		{"//secretmanager.googleapis.com/projects/123456/secrets/db-password", "secretmanager.googleapis.com/Secret", "secret", "123456", "global", "db-password"},
+		{"//cloudkms.googleapis.com/projects/my-project/locations/global/keyRings/ring", "cloudkms.googleapis.com/KeyRing", "kms", "my-project", "global", "ring"},
+		{"//cloudkms.googleapis.com/projects/my-project/locations/us/keyRings/ring/cryptoKeys/key", "cloudkms.googleapis.com/CryptoKey", "kms", "my-project", "us", "key"},
+		{"//pubsub.googleapis.com/projects/my-project/topics/events", "pubsub.googleapis.com/Topic", "pubsub", "my-project", "global", "events"},
+		{"//pubsub.googleapis.com/projects/my-project/subscriptions/events-sub", "pubsub.googleapis.com/Subscription", "pubsub", "my-project", "global", "events-sub"},
+		{"//spanner.googleapis.com/projects/my-project/instances/main", "spanner.googleapis.com/Instance", "spanner", "my-project", "global", "main"},
+		{"//spanner.googleapis.com/projects/my-project/instances/main/databases/orders", "spanner.googleapis.com/Database", "spanner", "my-project", "global", "orders"},
+		{"//bigtableadmin.googleapis.com/projects/my-project/instances/bt", "bigtableadmin.googleapis.com/Instance", "bigtable", "my-project", "global", "bt"},
+		{"//bigtableadmin.googleapis.com/projects/my-project/instances/bt/tables/t1", "bigtableadmin.googleapis.com/Table", "bigtable", "my-project", "global", "t1"},
+		{"//firestore.googleapis.com/projects/my-project/databases/(default)", "firestore.googleapis.com/Database", "firestore", "my-project", "global", "(default)"},
+		{"//artifactregistry.googleapis.com/projects/my-project/locations/us/repositories/images", "artifactregistry.googleapis.com/Repository", "artifactregistry", "my-project", "us", "images"},
+		{"//dataproc.googleapis.com/projects/my-project/regions/us-east4/clusters/etl", "dataproc.googleapis.com/Cluster", "dataproc", "my-project", "us-east4", "etl"},
+		{"//dataflow.googleapis.com/projects/my-project/locations/us-central1/jobs/2024-job", "dataflow.googleapis.com/Job", "dataflow", "my-project", "us-central1", "2024-job"},
+		{"//composer.googleapis.com/projects/my-project/locations/europe-west1/environments/airflow", "composer.googleapis.com/Environment", "composer", "my-project", "europe-west1", "airflow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.name)
+			if got.AssetType != tt.assetType {
+				t.Errorf("AssetType = %q, want %q", got.AssetType, tt.assetType)
+			}
+			if got.FriendlyType != tt.friendlyType {
+				t.Errorf("FriendlyType = %q, want %q", got.FriendlyType, tt.friendlyType)
+			}
+			if got.Project != tt.project {
+				t.Errorf("Project = %q, want %q", got.Project, tt.project)
+			}
+			if got.Location != tt.location {
+				t.Errorf("Location = %q, want %q", got.Location, tt.location)
+			}
+			if got.DisplayName != tt.displayName {
+				t.Errorf("DisplayName = %q, want %q", got.DisplayName, tt.displayName)
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	got := Parse("//newservice.googleapis.com/projects/my-project/locations/asia-east1/widgets/w1")
+
+	if got.AssetType != "" {
+		t.Errorf("AssetType = %q, want empty", got.AssetType)
+	}
+	if got.Service != "newservice.googleapis.com" {
+		t.Errorf("Service = %q, want newservice.googleapis.com", got.Service)
+	}
+	if got.FriendlyType != "newservice" {
+		t.Errorf("FriendlyType = %q, want newservice", got.FriendlyType)
+	}
+	if got.Project != "my-project" {
+		t.Errorf("Project = %q, want my-project", got.Project)
+	}
+	if got.Location != "asia-east1" {
+		t.Errorf("Location = %q, want asia-east1", got.Location)
+	}
+	if got.DisplayName != "w1" {
+		t.Errorf("DisplayName = %q, want w1", got.DisplayName)
+	}
+}
+
+func TestNormalizeLocation(t *testing.T) {
+	tests := []struct {
+		raw    string
+		name   string
+		kind   string
+		region string
+	}{
+		{"", "global", LocationGlobal, ""},
+		{"global", "global", LocationGlobal, ""},
+		{"us-central1-a", "us-central1-a", LocationZone, "us-central1"},
+		{"northamerica-northeast2", "northamerica-northeast2", LocationRegion, "northamerica-northeast2"},
+		{"US", "us", LocationMultiRegion, "us"},
+		{"eur4", "eur4", LocationMultiRegion, "eur4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got := NormalizeLocation(tt.raw)
+			if got.Name != tt.name || got.Kind != tt.kind || got.Region != tt.region {
+				t.Errorf("NormalizeLocation(%q) = %+v, want {%s %s %s}", tt.raw, got, tt.name, tt.kind, tt.region)
+			}
+		})
+	}
+}
+
+func TestFriendlyTypeForAssetType(t *testing.T) {
+	tests := map[string]string{
+		"compute.googleapis.com/Instance":             "vm",
+		"spanner.googleapis.com/Database":             "spanner",
+		"dataproc.googleapis.com/Cluster":             "dataproc",
+		"artifactregistry.googleapis.com/Repository":  "artifactregistry",
+		"artifactregistry.googleapis.com/DockerImage": "artifactregistry",
+		"sqladmin.googleapis.com/Instance":            "cloudsql",
+		"workflows.googleapis.com/Workflow":           "workflows",
+	}
+
+	for assetType, want := range tests {
+		if got := FriendlyTypeForAssetType(assetType); got != want {
+			t.Errorf("FriendlyTypeForAssetType(%q) = %q, want %q", assetType, got, want)
+		}
+	}
+}
+
+func TestReplaceProject(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"//cloudresourcemanager.googleapis.com/projects/123", "//cloudresourcemanager.googleapis.com/projects/my-project"},
+		{"//storage.googleapis.com/projects/123/buckets/b", "//storage.googleapis.com/projects/my-project/buckets/b"},
+		{"projects/123/locations/global", "projects/my-project/locations/global"},
+		{"//storage.googleapis.com/projects/1234/buckets/b", "//storage.googleapis.com/projects/1234/buckets/b"},
+		{"//storage.googleapis.com/buckets/123", "//storage.googleapis.com/buckets/123"},
+	}
+
+	for _, tt := range tests {
+		if got := ReplaceProject(tt.name, "123", "my-project"); got != tt.want {
+			t.Errorf("ReplaceProject(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,49 +2,71 @@ package gcp
 
 import (
 	"fmt"
+	"strings"
+
+	"gcp-access-visualizer/internal/gcp/resourcename"
 
 	computepb "cloud.google.com/go/compute/apiv1/computepb"
 	containerpb "cloud.google.com/go/container/apiv1/containerpb"
 	iampb "cloud.google.com/go/iam/apiv1/iampb"
 	runpb "cloud.google.com/go/run/apiv2/runpb"
 	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
 )
 
 // Resource represents a GCP resource
 type Resource struct {
-	ID       string              `json:"id"`
-	Name     string              `json:"name"`
-	Type     string              `json:"type"` // "gke", "vm", "cloudrun"
-	Location string              `json:"location"`
-	IAM      map[string][]string `json:"iam"` // role -> []members
+	ID           string              `json:"id"`
+	Name         string              `json:"name"`
+	Type         string              `json:"type"` // "gke", "vm", "cloudrun"
+	Location     string              `json:"location"`
+	LocationType string              `json:"locationType"` // "zone", "region", "multi-region", "global"
+	Region       string              `json:"region,omitempty"`
+	AssetType    string              `json:"assetType,omitempty"` // official Asset Inventory type, when known
+	Project      string              `json:"project,omitempty"`   // owning project, "projects/NUMBER"
+	RunAs        string              `json:"runAs,omitempty"`     // service account the workload runs as, when known
+	Invokes      string              `json:"invokes,omitempty"`   // URL or resource the workload calls with that identity
+	IAM          map[string][]string `json:"iam"`                 // role -> []members
+	// Conditions lists the conditional bindings; their members are also in IAM
+	Conditions []ConditionalBinding `json:"conditions,omitempty"`
+	// SSH is how users log in to a VM; nil for other resources or when unknown
+	SSH *SSHConfig `json:"ssh,omitempty"`
+	// Instance holds the network placement and identity of a VM
+	Instance *InstanceDetails `json:"instance,omitempty"`
+	// Firewall holds the rule of a firewall resource
+	Firewall *FirewallRule `json:"firewall,omitempty"`
 }
 
-// GetResources fetches all resources (GKE, VMs, Cloud Run)
-func (c *Client) GetResources() ([]Resource, error) {
-	var resources []Resource
-
-	// Fetch GKE clusters
-	gkeClusters, err := c.getGKEClusters()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get GKE clusters: %w", err)
-	}
-	resources = append(resources, gkeClusters...)
+// normalizeLocation fills in the normalized location, location type, and region
+func (r *Resource) normalizeLocation() {
+	location := resourcename.NormalizeLocation(r.Location)
+	r.Location = location.Name
+	r.LocationType = location.Kind
+	r.Region = location.Region
+}
 
-	// Fetch VMs
-	vms, err := c.getVMs()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get VMs: %w", err)
+// addBinding records members of a role in the IAM map, skipping ones already present
+func (r *Resource) addBinding(role string, members []string) {
+	for _, member := range members {
+		if !contains(r.IAM[role], member) {
+			r.IAM[role] = append(r.IAM[role], member)
+		}
 	}
-	resources = append(resources, vms...)
+}
 
-	// Fetch Cloud Run services
-	cloudRunServices, err := c.getCloudRunServices()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Cloud Run services: %w", err)
-	}
-	resources = append(resources, cloudRunServices...)
+func init() {
+	RegisterCollector(Collector{Name: "gke", Collect: (*Client).getGKEClusters, Services: []string{"container.googleapis.com"}, Permission: "container.clusters.list"})
+	RegisterCollector(Collector{Name: "vm", Collect: (*Client).getVMs, Services: []string{"compute.googleapis.com"}, Permission: "compute.instances.list"})
+	RegisterCollector(Collector{Name: "cloudrun", Collect: (*Client).getCloudRunServices, Services: []string{"run.googleapis.com"}, Permission: "run.services.list"})
+}
 
-	return resources, nil
+// GetResources fetches all resources from the enabled collectors (GKE, VMs, Cloud Run, ...).
+// Collectors that fail are skipped and reported as warnings. Resources are
+// sorted by ID.
+func (c *Client) GetResources() ([]Resource, []ScanWarning) {
+	resources, warnings := c.runCollectors()
+	SortResources(resources)
+	return resources, warnings
 }
 
 func (c *Client) getGKEClusters() ([]Resource, error) {
@@ -62,11 +84,12 @@ func (c *Client) getGKEClusters() ([]Resource, error) {
 
 	for _, cluster := range resp.Clusters {
 		resource := Resource{
-			ID:       cluster.SelfLink,
-			Name:     cluster.Name,
-			Type:     "gke",
-			Location: cluster.Location,
-			IAM:      make(map[string][]string),
+			ID:        cluster.SelfLink,
+			Name:      cluster.Name,
+			Type:      "gke",
+			Location:  cluster.Location,
+			AssetType: "container.googleapis.com/Cluster",
+			IAM:       make(map[string][]string),
 		}
 
 		// Get IAM policy for the cluster (note: GKE uses project-level IAM)
@@ -81,50 +104,76 @@ func (c *Client) getGKEClusters() ([]Resource, error) {
 
 func (c *Client) getVMs() ([]Resource, error) {
 	var resources []Resource
+	var keys []string
+	fetcher := c.newPolicyFetcher()
 
-	// List all zones
-	zones := []string{"us-central1-a", "us-central1-b", "us-east1-b", "us-west1-a", "europe-west1-b"}
+	// Instance metadata inherits OS Login and SSH keys from the project; without
+	// the project metadata the SSH configuration of the instances is unknown
+	projectMetadata, projectErr := c.getProjectMetadata()
 
-	for _, zone := range zones {
-		req := &computepb.ListInstancesRequest{
-			Project: c.ProjectID,
-			Zone:    zone,
+	// List instances across all zones in one aggregated call
+	req := &computepb.AggregatedListInstancesRequest{
+		Project: c.ProjectID,
+	}
+
+	it := c.ComputeClient.AggregatedList(c.ctx, req)
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
 
-		it := c.ComputeClient.List(c.ctx, req)
-		for {
-			instance, err := it.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				// Continue to next zone if this zone has an error
-				break
-			}
+		// Keys look like "zones/us-central1-a"
+		zone := strings.TrimPrefix(pair.Key, "zones/")
+		if !c.Scope.LocationAllowed(zone) {
+			continue
+		}
 
+		for _, instance := range pair.Value.GetInstances() {
 			resource := Resource{
-				ID:       fmt.Sprintf("%d", instance.GetId()),
-				Name:     instance.GetName(),
-				Type:     "vm",
-				Location: zone,
-				IAM:      make(map[string][]string),
+				ID:        fmt.Sprintf("%d", instance.GetId()),
+				Name:      instance.GetName(),
+				Type:      "vm",
+				Location:  zone,
+				AssetType: "compute.googleapis.com/Instance",
+				IAM:       make(map[string][]string),
+			}
+			if projectErr == nil {
+				resource.SSH = instanceSSHConfig(instance, projectMetadata)
 			}
+			resource.Instance = instanceDetails(instance)
+			resources = append(resources, resource)
 
-			// Get IAM policy for the instance
+			// Fetch the instance's IAM policy in the background while listing continues
 			iamReq := &computepb.GetIamPolicyInstanceRequest{
-				Project:  c.ProjectID,
-				Zone:     zone,
-				Resource: instance.GetName(),
+				Project:                       c.ProjectID,
+				Zone:                          zone,
+				Resource:                      instance.GetName(),
+				OptionsRequestedPolicyVersion: proto.Int32(PolicyVersion),
 			}
-
-			policy, err := c.ComputeClient.GetIamPolicy(c.ctx, iamReq)
-			if err == nil && policy != nil {
-				for _, binding := range policy.Bindings {
-					resource.IAM[binding.GetRole()] = binding.Members
+			key := zone + "/" + instance.GetName()
+			keys = append(keys, key)
+			fetcher.Fetch(key, func() (map[string][]string, error) {
+				policy, err := c.ComputeClient.GetIamPolicy(c.ctx, iamReq)
+				if err != nil {
+					return nil, err
 				}
-			}
+				bindings := make(map[string][]string)
+				for _, binding := range policy.GetBindings() {
+					bindings[binding.GetRole()] = append(bindings[binding.GetRole()], binding.Members...)
+				}
+				return bindings, nil
+			})
+		}
+	}
 
-			resources = append(resources, resource)
+	policies := fetcher.Wait()
+	for i := range resources {
+		if bindings, ok := policies[keys[i]]; ok {
+			resources[i].IAM = bindings
 		}
 	}
 
@@ -133,6 +182,7 @@ func (c *Client) getVMs() ([]Resource, error) {
 
 func (c *Client) getCloudRunServices() ([]Resource, error) {
 	var resources []Resource
+	fetcher := c.newPolicyFetcher()
 
 	// List Cloud Run services
 	req := &runpb.ListServicesRequest{
@@ -149,27 +199,38 @@ func (c *Client) getCloudRunServices() ([]Resource, error) {
 			return nil, err
 		}
 
-		resource := Resource{
-			ID:       service.Name,
-			Name:     service.Name,
-			Type:     "cloudrun",
-			Location: extractLocation(service.Name),
-			IAM:      make(map[string][]string),
-		}
+		resources = append(resources, Resource{
+			ID:        service.Name,
+			Name:      service.Name,
+			Type:      "cloudrun",
+			Location:  extractLocation(service.Name),
+			AssetType: "run.googleapis.com/Service",
+			IAM:       make(map[string][]string),
+		})
 
-		// Get IAM policy for the Cloud Run service
+		// Fetch the service's IAM policy in the background while listing continues
 		iamReq := &iampb.GetIamPolicyRequest{
 			Resource: service.Name,
+			Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion},
 		}
-
-		policy, err := c.RunClient.GetIamPolicy(c.ctx, iamReq)
-		if err == nil && policy != nil {
+		fetcher.Fetch(service.Name, func() (map[string][]string, error) {
+			policy, err := c.RunClient.GetIamPolicy(c.ctx, iamReq)
+			if err != nil {
+				return nil, err
+			}
+			bindings := make(map[string][]string)
 			for _, binding := range policy.Bindings {
-				resource.IAM[binding.Role] = binding.Members
+				bindings[binding.Role] = append(bindings[binding.Role], binding.Members...)
 			}
-		}
+			return bindings, nil
+		})
+	}
 
-		resources = append(resources, resource)
+	policies := fetcher.Wait()
+	for i := range resources {
+		if bindings, ok := policies[resources[i].ID]; ok {
+			resources[i].IAM = bindings
+		}
 	}
 
 	return resources, nil
@@ -178,7 +239,5 @@ func (c *Client) getCloudRunServices() ([]Resource, error) {
 // extractLocation extracts the location from a Cloud Run service name
 // Format: projects/PROJECT/locations/LOCATION/services/SERVICE
 func extractLocation(name string) string {
-	// Simple parsing - in production, use proper parsing
-	// For now, return a placeholder
-	return "us-central1"
+	return resourcename.LocationFromResourceName(name)
 }
@@ -0,0 +1,197 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/iam/admin/apiv1/adminpb"
+)
+
+// Privilege tiers, from least to most privileged
+const (
+	TierRead  = "read"
+	TierWrite = "write"
+	TierAdmin = "admin"
+	TierOwner = "owner"
+)
+
+// tierRanks orders privilege tiers for comparison
+var tierRanks = map[string]int{
+	TierRead:  1,
+	TierWrite: 2,
+	TierAdmin: 3,
+	TierOwner: 4,
+}
+
+// TierRank returns the rank of a tier; higher is more privileged, unknown tiers are 0
+func TierRank(tier string) int {
+	return tierRanks[tier]
+}
+
+// MaxTier returns the more privileged of two tiers
+func MaxTier(a, b string) string {
+	if TierRank(b) > TierRank(a) {
+		return b
+	}
+	return a
+}
+
+// roleCacheTTL is how long role definitions are cached; they rarely change
+const roleCacheTTL = 24 * time.Hour
+
+// RoleInfo describes an IAM role and its computed privilege tier
+type RoleInfo struct {
+	Name            string   `json:"name"`
+	Title           string   `json:"title"`
+	Description     string   `json:"description"`
+	Stage           string   `json:"stage"` // "GA", "BETA", "ALPHA", "DEPRECATED", ...
+	Kind            string   `json:"kind"`  // "basic", "predefined", "custom"
+	Tier            string   `json:"tier"`
+	PermissionCount int      `json:"permissionCount"`
+	Permissions     []string `json:"-"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// roleCache caches role definitions fetched from the IAM API
+type roleCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedRole
+}
+
+type cachedRole struct {
+	info      RoleInfo
+	fetchedAt time.Time
+}
+
+// sharedRole is the encoding of a role definition in the shared cache, which
+// unlike the API response must carry the permissions
+type sharedRole struct {
+	RoleInfo
+	Permissions []string `json:"permissions"`
+}
+
+// GetRole returns the definition of a role, served from cache when fresh.
+// If the IAM API cannot be queried, the role is classified from its name alone
+// and the error is recorded on the result.
+func (c *Client) GetRole(name string) RoleInfo {
+	c.roles.mu.Lock()
+	if cached, ok := c.roles.entries[name]; ok && time.Since(cached.fetchedAt) < roleCacheTTL {
+		c.roles.mu.Unlock()
+		return cached.info
+	}
+	c.roles.mu.Unlock()
+
+	if c.Cache != nil {
+		if data, ok := c.Cache.Get("role:" + name); ok {
+			var shared sharedRole
+			if err := json.Unmarshal(data, &shared); err == nil {
+				info := shared.RoleInfo
+				info.Permissions = shared.Permissions
+				c.cacheRole(name, info)
+				return info
+			}
+		}
+	}
+
+	info := RoleInfo{
+		Name: name,
+		Kind: RoleKind(name),
+	}
+
+	role, err := c.IAMAdminClient.GetRole(c.ctx, &adminpb.GetRoleRequest{Name: name})
+	if err != nil {
+		info.Tier = TierFromName(name)
+		info.Error = fmt.Sprintf("failed to get role: %v", err)
+		// Don't cache failures so a transient error doesn't stick for a day
+		return info
+	}
+
+	info.Title = role.GetTitle()
+	info.Description = role.GetDescription()
+	info.Stage = role.GetStage().String()
+	info.Permissions = role.GetIncludedPermissions()
+	info.PermissionCount = len(info.Permissions)
+	info.Tier = TierFromPermissions(name, info.Permissions)
+
+	c.cacheRole(name, info)
+	if c.Cache != nil {
+		if data, err := json.Marshal(sharedRole{RoleInfo: info, Permissions: info.Permissions}); err == nil {
+			c.Cache.Set("role:"+name, data, roleCacheTTL)
+		}
+	}
+
+	return info
+}
+
+// cacheRole stores a role definition in the in-process cache
+func (c *Client) cacheRole(name string, info RoleInfo) {
+	c.roles.mu.Lock()
+	c.roles.entries[name] = cachedRole{info: info, fetchedAt: time.Now()}
+	c.roles.mu.Unlock()
+}
+
+// RoleTier returns the privilege tier of a role, using the cached role catalog
+func (c *Client) RoleTier(name string) string {
+	return c.GetRole(name).Tier
+}
+
+// RoleKind classifies a role name as basic, predefined, or custom
+func RoleKind(name string) string {
+	switch {
+	case name == "roles/owner" || name == "roles/editor" || name == "roles/viewer":
+		return "basic"
+	case strings.HasPrefix(name, "projects/") || strings.HasPrefix(name, "organizations/"):
+		return "custom"
+	}
+	return "predefined"
+}
+
+// TierFromPermissions computes a role's privilege tier from its permissions.
+// The basic owner role is owner; roles that can change IAM policy are admin;
+// roles with any non-read verb are write.
+func TierFromPermissions(name string, permissions []string) string {
+	if name == "roles/owner" || len(permissions) == 0 {
+		return TierFromName(name)
+	}
+
+	tier := TierRead
+	for _, permission := range permissions {
+		verb := permission[strings.LastIndex(permission, ".")+1:]
+		if verb == "setIamPolicy" {
+			return TierAdmin
+		}
+		if !isReadVerb(verb) {
+			tier = TierWrite
+		}
+	}
+	return tier
+}
+
+// TierFromName estimates a role's privilege tier from its name when its
+// permissions are unknown
+func TierFromName(name string) string {
+	lower := strings.ToLower(name[strings.LastIndex(name, "/")+1:])
+	switch {
+	case name == "roles/owner":
+		return TierOwner
+	case strings.HasSuffix(lower, "admin"):
+		return TierAdmin
+	case name == "roles/viewer", strings.HasSuffix(lower, "viewer"), strings.HasSuffix(lower, "reader"),
+		strings.HasSuffix(lower, "browser"), strings.HasSuffix(lower, "securityreviewer"):
+		return TierRead
+	}
+	return TierWrite
+}
+
+// isReadVerb reports whether a permission verb only reads data or metadata
+func isReadVerb(verb string) bool {
+	for _, prefix := range []string{"get", "list", "search", "read", "view", "watch", "lookup", "query", "export", "analyze"} {
+		if strings.HasPrefix(verb, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	asset "cloud.google.com/go/asset/apiv1"
+	"cloud.google.com/go/asset/apiv1/assetpb"
+	"google.golang.org/api/iterator"
+)
+
+// ErrInvalidSearch is returned for policy searches without a filter or with a
+// value that would change the meaning of the query
+var ErrInvalidSearch = errors.New("invalid policy search")
+
+// MemberTypes are the member types a policy search can filter on
+var MemberTypes = []string{"user", "serviceAccount", "group", "domain", "allUsers", "allAuthenticatedUsers", "principal", "principalSet"}
+
+// PolicySearch is a constrained Asset Inventory IAM policy query: each set
+// field adds one filter, and all filters must match
+type PolicySearch struct {
+	Role       string
+	Principal  string // email or member, e.g. "alice@example.com" or "group:admins@example.com"
+	MemberType string // one of MemberTypes
+	Permission string // bindings whose role grants it
+	Resource   string // resource names containing this, e.g. a bucket name
+	AssetType  string // e.g. "storage.googleapis.com/Bucket"
+	// Project narrows the search to one scanned project; empty searches every
+	// scanned project, or the discovery parent at once
+	Project string
+	Limit   int
+}
+
+// PolicyMatch is a resource whose IAM policy matched a search, with the
+// bindings that matched
+type PolicyMatch struct {
+	Resource  string          `json:"resource"`
+	AssetType string          `json:"assetType"`
+	Project   string          `json:"project"`
+	Bindings  []PolicyBinding `json:"bindings"`
+}
+
+// PolicyBinding is one binding of a matched policy
+type PolicyBinding struct {
+	Role      string     `json:"role"`
+	Members   []string   `json:"members"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// Query renders the search as an Asset Inventory query, e.g.
+// `policy:roles/owner memberTypes:user`. Values are quoted; values holding
+// quotes or backslashes are rejected rather than escaped.
+func (s PolicySearch) Query() (string, error) {
+	var terms []string
+	add := func(field, value string) error {
+		if value == "" {
+			return nil
+		}
+		if strings.ContainsAny(value, "\"\\") {
+			return fmt.Errorf("%w: %s must not contain quotes or backslashes", ErrInvalidSearch, field)
+		}
+		terms = append(terms, fmt.Sprintf("%s:%q", field, value))
+		return nil
+	}
+	if s.MemberType != "" && !contains(MemberTypes, s.MemberType) {
+		return "", fmt.Errorf("%w: unknown member type %q", ErrInvalidSearch, s.MemberType)
+	}
+	for _, term := range []struct{ field, value string }{
+		{"policy", s.Role},
+		{"policy", s.Principal},
+		{"memberTypes", s.MemberType},
+		{"policy.role.permissions", s.Permission},
+		{"resource", s.Resource},
+	} {
+		if err := add(term.field, term.value); err != nil {
+			return "", err
+		}
+	}
+	if len(terms) == 0 && s.AssetType == "" {
+		return "", fmt.Errorf("%w: at least one filter is required", ErrInvalidSearch)
+	}
+	return strings.Join(terms, " "), nil
+}
+
+// SearchIAMPolicies runs a policy search through Asset Inventory without
+// touching the cached matrix. Only the bindings matching the role, principal,
+// member type, and permission filters are returned. The result is truncated
+// at the search limit, which is reported.
+func (c *Client) SearchIAMPolicies(search PolicySearch) ([]PolicyMatch, bool, error) {
+	query, err := search.Query()
+	if err != nil {
+		return nil, false, err
+	}
+
+	scope := "projects/" + c.ProjectID
+	switch {
+	case search.Project != "":
+		projectID, ok := c.ScannedProjectID(search.Project)
+		if !ok {
+			return nil, false, ErrProjectNotScanned
+		}
+		scope = "projects/" + projectID
+	case c.Discovery != nil:
+		scope = c.Discovery.Parent
+	}
+	limit := search.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	ctx := context.Background()
+	assetClient, err := asset.NewClient(ctx, meteredGRPCOptions(c.usage, serviceAsset)...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create asset client: %w", err)
+	}
+	defer assetClient.Close()
+
+	req := &assetpb.SearchAllIamPoliciesRequest{Scope: scope, Query: query, PageSize: int32(min(limit, 500))}
+	if search.AssetType != "" {
+		req.AssetTypes = []string{search.AssetType}
+	}
+
+	matches := []PolicyMatch{}
+	it := assetClient.SearchAllIamPolicies(ctx, req)
+	for {
+		result, err := it.Next()
+		if err == iterator.Done {
+			return matches, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to search IAM policies: %w", err)
+		}
+		if len(matches) == limit {
+			return matches, true, nil
+		}
+		match := PolicyMatch{
+			Resource:  c.normalizeResourceName(result.GetResource()),
+			AssetType: result.GetAssetType(),
+			Project:   result.GetProject(),
+			Bindings:  []PolicyBinding{},
+		}
+		granting := result.GetExplanation().GetMatchedPermissions()
+		for _, binding := range result.GetPolicy().GetBindings() {
+			if !search.matchesBinding(binding.GetRole(), binding.GetMembers()) {
+				continue
+			}
+			if search.Permission != "" && granting != nil && granting[binding.GetRole()] == nil {
+				continue
+			}
+			matched := PolicyBinding{Role: binding.GetRole(), Members: binding.GetMembers()}
+			if cond := binding.GetCondition(); cond.GetExpression() != "" {
+				matched.Condition = &Condition{Title: cond.GetTitle(), Description: cond.GetDescription(), Expression: cond.GetExpression()}
+			}
+			match.Bindings = append(match.Bindings, matched)
+		}
+		if len(match.Bindings) > 0 {
+			matches = append(matches, match)
+		}
+	}
+}
+
+// matchesBinding reports whether a binding satisfies the role, principal, and
+// member type filters. Asset Inventory matches them per policy, so a policy
+// granting the role to one member and holding the principal in another
+// binding also comes back.
+func (s PolicySearch) matchesBinding(role string, members []string) bool {
+	if s.Role != "" && role != s.Role {
+		return false
+	}
+	if s.Principal == "" && s.MemberType == "" {
+		return true
+	}
+	for _, member := range members {
+		memberType, _, _ := strings.Cut(member, ":")
+		if s.MemberType != "" && memberType != s.MemberType {
+			continue
+		}
+		if s.Principal != "" && member != s.Principal && !strings.EqualFold(ParseMember(member).Email, s.Principal) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/iam/admin/apiv1/adminpb"
+	"google.golang.org/api/iterator"
+)
+
+// ServiceAccount describes a service account defined in the project
+type ServiceAccount struct {
+	Email       string `json:"email"`
+	UniqueID    string `json:"uniqueId"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// GetServiceAccounts lists the service accounts defined in the project
+func (c *Client) GetServiceAccounts() ([]ServiceAccount, error) {
+	req := &adminpb.ListServiceAccountsRequest{
+		Name: fmt.Sprintf("projects/%s", c.ProjectID),
+	}
+
+	var accounts []ServiceAccount
+	it := c.IAMAdminClient.ListServiceAccounts(c.ctx, req)
+	for {
+		sa, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list service accounts: %w", err)
+		}
+
+		accounts = append(accounts, ServiceAccount{
+			Email:       sa.GetEmail(),
+			UniqueID:    sa.GetUniqueId(),
+			DisplayName: sa.GetDisplayName(),
+			Description: sa.GetDescription(),
+			Disabled:    sa.GetDisabled(),
+		})
+	}
+
+	return accounts, nil
+}
+
+// ServiceAccountKey describes a user-managed key of a service account
+type ServiceAccountKey struct {
+	ServiceAccount string    `json:"serviceAccount"`
+	KeyID          string    `json:"keyId"`
+	ValidAfter     time.Time `json:"validAfter"`
+	ValidBefore    time.Time `json:"validBefore"` // far future when the key never expires
+	Disabled       bool      `json:"disabled"`
+}
+
+// GetServiceAccountKeys lists user-managed keys of every service account in the project
+func (c *Client) GetServiceAccountKeys() ([]ServiceAccountKey, error) {
+	accounts, err := c.GetServiceAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []ServiceAccountKey
+	for _, account := range accounts {
+		req := &adminpb.ListServiceAccountKeysRequest{
+			Name:     fmt.Sprintf("projects/%s/serviceAccounts/%s", c.ProjectID, account.Email),
+			KeyTypes: []adminpb.ListServiceAccountKeysRequest_KeyType{adminpb.ListServiceAccountKeysRequest_USER_MANAGED},
+		}
+
+		resp, err := c.IAMAdminClient.ListServiceAccountKeys(c.ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys for %s: %w", account.Email, err)
+		}
+
+		for _, key := range resp.GetKeys() {
+			name := key.GetName()
+			keys = append(keys, ServiceAccountKey{
+				ServiceAccount: account.Email,
+				KeyID:          name[strings.LastIndex(name, "/")+1:],
+				ValidAfter:     key.GetValidAfterTime().AsTime(),
+				ValidBefore:    key.GetValidBeforeTime().AsTime(),
+				Disabled:       key.GetDisabled(),
+			})
+		}
+	}
+
+	return keys, nil
+}
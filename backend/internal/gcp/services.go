@@ -0,0 +1,131 @@
+package gcp
+
+import (
+	"context"
+
+	accesscontextmanager "google.golang.org/api/accesscontextmanager/v1"
+	apikeys "google.golang.org/api/apikeys/v2"
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+	bigtableadmin "google.golang.org/api/bigtableadmin/v2"
+	cloudbilling "google.golang.org/api/cloudbilling/v1"
+	cloudidentity "google.golang.org/api/cloudidentity/v1"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+	cloudscheduler "google.golang.org/api/cloudscheduler/v1"
+	cloudtasks "google.golang.org/api/cloudtasks/v2"
+	composer "google.golang.org/api/composer/v1"
+	computev1 "google.golang.org/api/compute/v1"
+	dataflow "google.golang.org/api/dataflow/v1b3"
+	dataproc "google.golang.org/api/dataproc/v1"
+	essentialcontacts "google.golang.org/api/essentialcontacts/v1"
+	eventarc "google.golang.org/api/eventarc/v1"
+	firestore "google.golang.org/api/firestore/v1"
+	iam "google.golang.org/api/iam/v1"
+	iap "google.golang.org/api/iap/v1"
+	logging "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+	policyanalyzer "google.golang.org/api/policyanalyzer/v1"
+	spanner "google.golang.org/api/spanner/v1"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// RESTServices groups the discovery-based REST clients used by collectors.
+// Unlike the Cloud Client libraries they hold no connections that need closing.
+type RESTServices struct {
+	ArtifactRegistry *artifactregistry.Service
+	Storage          *storage.Service
+	Spanner          *spanner.Service
+	Firestore        *firestore.Service
+	Bigtable         *bigtableadmin.Service
+	Compute          *computev1.Service
+	Dataflow         *dataflow.Service
+	Dataproc         *dataproc.Service
+	Composer         *composer.Service
+	Billing          *cloudbilling.APIService
+	Contacts         *essentialcontacts.Service
+	IAP              *iap.Service
+	Scheduler        *cloudscheduler.Service
+	Tasks            *cloudtasks.Service
+	Eventarc         *eventarc.Service
+	APIKeys          *apikeys.Service
+	IAM              *iam.Service
+	PolicyAnalyzer   *policyanalyzer.Service
+	CloudIdentity    *cloudidentity.Service
+	AccessContext    *accesscontextmanager.Service
+	Ancestry         *cloudresourcemanager.Service
+	Logging          *logging.Service
+}
+
+// newRESTServices initializes every REST service with the given client options
+func newRESTServices(ctx context.Context, opts ...option.ClientOption) (*RESTServices, error) {
+	var services RESTServices
+	var err error
+
+	if services.ArtifactRegistry, err = artifactregistry.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Storage, err = storage.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Spanner, err = spanner.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Firestore, err = firestore.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Bigtable, err = bigtableadmin.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Compute, err = computev1.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Dataflow, err = dataflow.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Dataproc, err = dataproc.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Composer, err = composer.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Billing, err = cloudbilling.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Contacts, err = essentialcontacts.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.IAP, err = iap.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Scheduler, err = cloudscheduler.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Tasks, err = cloudtasks.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Eventarc, err = eventarc.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.APIKeys, err = apikeys.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.IAM, err = iam.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.PolicyAnalyzer, err = policyanalyzer.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.CloudIdentity, err = cloudidentity.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.AccessContext, err = accesscontextmanager.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Ancestry, err = cloudresourcemanager.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if services.Logging, err = logging.NewService(ctx, opts...); err != nil {
+		return nil, err
+	}
+
+	return &services, nil
+}
@@ -0,0 +1,173 @@
+package gcp
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+)
+
+// SSHConfig is the login configuration of a VM, combining its metadata with
+// the project metadata it inherits
+type SSHConfig struct {
+	// OSLogin is set when enable-oslogin is true on the instance, or on the
+	// project and not overridden. Metadata SSH keys are then ignored.
+	OSLogin    bool `json:"osLogin"`
+	OSLogin2FA bool `json:"osLogin2fa,omitempty"`
+	// BlockProjectKeys is set when the instance ignores project-wide SSH keys
+	BlockProjectKeys bool `json:"blockProjectKeys,omitempty"`
+	InstanceKeys     int  `json:"instanceKeys"`
+	ProjectKeys      int  `json:"projectKeys"`
+	// Keys are the metadata SSH keys of the instance and, unless blocked, of
+	// the project. With OS Login enabled they are ignored.
+	Keys []SSHKey `json:"keys,omitempty"`
+	// ExternalIP is set when a network interface has an external address; VMs
+	// without one are reached through IAP TCP forwarding or from the VPC
+	ExternalIP bool `json:"externalIp"`
+}
+
+// getProjectMetadata reads the project-wide metadata shared by all instances
+func (c *Client) getProjectMetadata() (map[string]string, error) {
+	project, err := c.Compute.Projects.Get(c.ProjectID).Context(c.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string)
+	if project.CommonInstanceMetadata != nil {
+		for _, item := range project.CommonInstanceMetadata.Items {
+			if item.Value != nil {
+				metadata[item.Key] = *item.Value
+			}
+		}
+	}
+	return metadata, nil
+}
+
+// instanceSSHConfig resolves the login configuration of an instance
+func instanceSSHConfig(instance *computepb.Instance, projectMetadata map[string]string) *SSHConfig {
+	metadata := make(map[string]string)
+	for _, item := range instance.GetMetadata().GetItems() {
+		metadata[item.GetKey()] = item.GetValue()
+	}
+	setting := func(key string) bool {
+		value, ok := metadata[key]
+		if !ok {
+			value = projectMetadata[key]
+		}
+		return metadataTrue(value)
+	}
+
+	config := &SSHConfig{
+		OSLogin:          setting("enable-oslogin"),
+		OSLogin2FA:       setting("enable-oslogin-2fa"),
+		BlockProjectKeys: metadataTrue(metadata["block-project-ssh-keys"]),
+		InstanceKeys:     len(metadataSSHKeys(metadata)),
+		ProjectKeys:      len(metadataSSHKeys(projectMetadata)),
+	}
+	for _, line := range metadataSSHKeys(metadata) {
+		config.Keys = append(config.Keys, ParseSSHKey(line, SSHKeyInstance))
+	}
+	if !config.BlockProjectKeys {
+		for _, line := range metadataSSHKeys(projectMetadata) {
+			config.Keys = append(config.Keys, ParseSSHKey(line, SSHKeyProject))
+		}
+	}
+	for _, iface := range instance.GetNetworkInterfaces() {
+		for _, access := range iface.GetAccessConfigs() {
+			if access.GetNatIP() != "" {
+				config.ExternalIP = true
+			}
+		}
+	}
+	return config
+}
+
+// metadataTrue parses a boolean metadata value such as "TRUE" or "true"
+func metadataTrue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// metadataSSHKeys returns the SSH key lines of the ssh-keys and legacy sshKeys
+// metadata entries
+func metadataSSHKeys(metadata map[string]string) []string {
+	var keys []string
+	for _, entry := range []string{"ssh-keys", "sshKeys"} {
+		for _, line := range strings.Split(metadata[entry], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				keys = append(keys, line)
+			}
+		}
+	}
+	return keys
+}
+
+// Scopes of metadata SSH keys
+const (
+	SSHKeyInstance = "instance"
+	SSHKeyProject  = "project"
+)
+
+// SSHKey is a public key from an ssh-keys metadata entry. The key material is
+// not kept, only its fingerprint.
+type SSHKey struct {
+	Scope       string `json:"scope"`    // "instance" or "project"
+	Username    string `json:"username"` // Linux account the key logs in as
+	Type        string `json:"type"`     // e.g. "ssh-ed25519"
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+	// Email is the Google account that added the key, from the google-ssh
+	// metadata gcloud and the console write
+	Email    string    `json:"email,omitempty"`
+	ExpireOn time.Time `json:"expireOn,omitempty"`
+}
+
+// ParseSSHKey parses an ssh-keys metadata line:
+// "USERNAME:TYPE KEY COMMENT", where COMMENT may be
+// google-ssh {"userName":"EMAIL","expireOn":"TIMESTAMP"}
+func ParseSSHKey(line, scope string) SSHKey {
+	key := SSHKey{Scope: scope}
+	username, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		rest = line
+	} else {
+		key.Username = username
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(rest), " ", 3)
+	if len(fields) > 0 {
+		key.Type = fields[0]
+	}
+	if len(fields) > 1 {
+		if blob, err := base64.StdEncoding.DecodeString(fields[1]); err == nil {
+			sum := sha256.Sum256(blob)
+			key.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+		}
+	}
+	if len(fields) > 2 {
+		key.Comment = strings.TrimSpace(fields[2])
+	}
+
+	if payload, ok := strings.CutPrefix(key.Comment, "google-ssh "); ok {
+		var google struct {
+			UserName string `json:"userName"`
+			ExpireOn string `json:"expireOn"` // e.g. "2024-01-01T00:00:00+0000"
+		}
+		if json.Unmarshal([]byte(payload), &google) == nil {
+			key.Email = google.UserName
+			for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05-0700"} {
+				if expireOn, err := time.Parse(layout, google.ExpireOn); err == nil {
+					key.ExpireOn = expireOn
+					break
+				}
+			}
+		}
+	}
+	return key
+}
@@ -0,0 +1,252 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+	"google.golang.org/grpc"
+)
+
+// ErrCallBudgetExceeded is returned for API calls made after a scan used up its call budget
+var ErrCallBudgetExceeded = errors.New("scan API call budget exceeded")
+
+// ErrScanDeadlineExceeded is returned for API calls made after a scan's deadline
+var ErrScanDeadlineExceeded = errors.New("scan deadline exceeded")
+
+// Services every scan calls regardless of the enabled collectors: the project
+// policy, the Asset Inventory search, and role definitions for tiering
+const (
+	serviceResourceManager = "cloudresourcemanager.googleapis.com"
+	serviceAsset           = "cloudasset.googleapis.com"
+	serviceIAM             = "iam.googleapis.com"
+)
+
+var coreServices = []string{serviceResourceManager, serviceAsset, serviceIAM}
+
+// cloudPlatformScope covers every API the collectors call
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// APIUsage counts GCP API calls per service, e.g. "compute.googleapis.com"
+type APIUsage map[string]int
+
+// Total returns the number of calls across all services
+func (u APIUsage) Total() int {
+	total := 0
+	for _, calls := range u {
+		total += calls
+	}
+	return total
+}
+
+// UsageMeter counts the API calls made during one scan and rejects calls beyond
+// its budget or after its deadline. A zero budget only counts.
+type UsageMeter struct {
+	budget   int
+	deadline time.Time
+
+	mu       sync.Mutex
+	calls    APIUsage
+	rejected int
+}
+
+// NewUsageMeter creates a meter that allows at most budget calls (zero for no limit)
+func NewUsageMeter(budget int) *UsageMeter {
+	return &UsageMeter{budget: budget, calls: make(APIUsage)}
+}
+
+// SetDeadline makes the meter reject calls from deadline on and cuts off gRPC
+// calls still running then; the zero time removes the deadline
+func (m *UsageMeter) SetDeadline(deadline time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deadline = deadline
+}
+
+// record counts a call to service, or rejects it when the budget is used up or
+// the deadline has passed
+func (m *UsageMeter) record(service string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+		m.rejected++
+		return fmt.Errorf("%w (%s)", ErrScanDeadlineExceeded, m.deadline.Format(time.RFC3339))
+	}
+	if m.budget > 0 && m.calls.Total() >= m.budget {
+		m.rejected++
+		return fmt.Errorf("%w (%d calls)", ErrCallBudgetExceeded, m.budget)
+	}
+	m.calls[service]++
+	return nil
+}
+
+// Calls returns a copy of the calls counted so far
+func (m *UsageMeter) Calls() APIUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls := make(APIUsage, len(m.calls))
+	for service, n := range m.calls {
+		calls[service] = n
+	}
+	return calls
+}
+
+// Rejected returns the number of calls refused because the budget was used up
+// or the deadline had passed
+func (m *UsageMeter) Rejected() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rejected
+}
+
+// usageTracker routes API calls to the active meter, if any. Calls made while no
+// meter is active (e.g. handlers between scans) are not counted.
+type usageTracker struct {
+	mu    sync.Mutex
+	meter *UsageMeter
+}
+
+// record counts a call against the active meter
+func (t *usageTracker) record(service string) error {
+	t.mu.Lock()
+	meter := t.meter
+	t.mu.Unlock()
+
+	if meter == nil {
+		return nil
+	}
+	return meter.record(service)
+}
+
+// deadline returns the deadline of the active meter, or the zero time
+func (t *usageTracker) deadline() time.Time {
+	t.mu.Lock()
+	meter := t.meter
+	t.mu.Unlock()
+
+	if meter == nil {
+		return time.Time{}
+	}
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	return meter.deadline
+}
+
+// Meter counts every API call the client makes against meter until stop is
+// called. Calls from concurrent requests during a scan are counted too.
+func (c *Client) Meter(meter *UsageMeter) (stop func()) {
+	c.usage.mu.Lock()
+	c.usage.meter = meter
+	c.usage.mu.Unlock()
+
+	return func() {
+		c.usage.mu.Lock()
+		if c.usage.meter == meter {
+			c.usage.meter = nil
+		}
+		c.usage.mu.Unlock()
+	}
+}
+
+// EstimateUsage predicts the API calls per service of the next scan with the
+// current scope: the previous scan's consumption for each service it will call,
+// or a single call for services the previous scan did not use
+func (c *Client) EstimateUsage(previous APIUsage) APIUsage {
+	estimate := make(APIUsage)
+	add := func(service string) {
+		if _, ok := estimate[service]; ok {
+			return
+		}
+		if calls := previous[service]; calls > 0 {
+			estimate[service] = calls
+		} else {
+			estimate[service] = 1
+		}
+	}
+
+	for _, service := range coreServices {
+		add(service)
+	}
+	scope := c.currentScope()
+	for _, collector := range collectorRegistry {
+		if collectorActive(scope, collector) {
+			for _, service := range collector.Services {
+				add(service)
+			}
+		}
+	}
+	return estimate
+}
+
+// meteredTransport counts each HTTP request against the tracker by API host
+type meteredTransport struct {
+	base    http.RoundTripper
+	tracker *usageTracker
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.tracker.record(req.URL.Host); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// meteredOptions returns client options that route HTTP clients through the
+// tracker. The authenticated HTTP client is shared by every REST client.
+func meteredOptions(ctx context.Context, tracker *usageTracker) ([]option.ClientOption, error) {
+	httpClient, err := meteredHTTPClient(ctx, tracker)
+	if err != nil {
+		return nil, err
+	}
+	return []option.ClientOption{option.WithHTTPClient(httpClient)}, nil
+}
+
+// meteredHTTPClient returns an authenticated HTTP client whose requests count
+// against the tracker, for APIs without a client library
+func meteredHTTPClient(ctx context.Context, tracker *usageTracker) (*http.Client, error) {
+	httpClient, _, err := htransport.NewClient(ctx, option.WithScopes(cloudPlatformScope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	httpClient.Transport = &meteredTransport{base: httpClient.Transport, tracker: tracker}
+	return httpClient, nil
+}
+
+// meteredGRPCOptions returns client options that count each gRPC call against
+// the tracker as a call to service
+func meteredGRPCOptions(tracker *usageTracker, service string) []option.ClientOption {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := tracker.record(service); err != nil {
+			return err
+		}
+		if deadline := tracker.deadline(); !deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := tracker.record(service); err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	return []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(unary)),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(stream)),
+	}
+}
@@ -2,6 +2,7 @@ package gcp
 
 import (
 	"fmt"
+	"strings"
 
 	iampb "cloud.google.com/go/iam/apiv1/iampb"
 )
@@ -9,7 +10,35 @@ import (
 // User represents a GCP principal (user, service account, or group)
 type User struct {
 	Email string `json:"email"`
-	Type  string `json:"type"` // "user", "serviceAccount", "group", "domain"
+	// "user", "serviceAccount", "group", "domain", "allUsers", "allAuthenticatedUsers",
+	// "projectOwner", "projectEditor", "projectViewer", or "other"
+	Type string `json:"type"`
+	// Deleted marks a "deleted:" member left behind in bindings after the
+	// principal was deleted; Email then holds the full member string
+	Deleted bool `json:"deleted,omitempty"`
+
+	// HR metadata attached by enrichment, if known
+	DisplayName string `json:"displayName,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Manager     string `json:"manager,omitempty"`
+
+	// Owning team or system of a service account, and how it was attributed
+	Owner       string `json:"owner,omitempty"`
+	OwnerSource string `json:"ownerSource,omitempty"` // "manual", "description", "displayName"
+
+	// BlastRadius is attached from the current snapshot, if one exists
+	BlastRadius *BlastRadius `json:"blastRadius,omitempty"`
+}
+
+// BlastRadius summarizes what a principal could affect if it were compromised
+type BlastRadius struct {
+	Resources int `json:"resources"` // distinct resources reachable in total
+	Direct    int `json:"direct"`    // resources reachable through the principal's own grants
+	// ViaImpersonation counts resources only reachable by acting as a service account
+	ViaImpersonation int    `json:"viaImpersonation"`
+	ServiceAccounts  int    `json:"serviceAccounts"` // service accounts the principal can impersonate
+	HighestTier      string `json:"highestTier,omitempty"`
+	CanModifyIAM     bool   `json:"canModifyIam"` // admin tier or above on any reachable resource
 }
 
 // GetUsers fetches all unique IAM principals from the project
@@ -17,6 +46,7 @@ func (c *Client) GetUsers() ([]User, error) {
 	// Get the project IAM policy
 	req := &iampb.GetIamPolicyRequest{
 		Resource: fmt.Sprintf("projects/%s", c.ProjectID),
+		Options:  &iampb.GetPolicyOptions{RequestedPolicyVersion: PolicyVersion},
 	}
 
 	policy, err := c.ResourceManager.GetIamPolicy(c.ctx, req)
@@ -29,7 +59,7 @@ func (c *Client) GetUsers() ([]User, error) {
 	for _, binding := range policy.Bindings {
 		for _, member := range binding.Members {
 			if _, exists := usersMap[member]; !exists {
-				user := parseUser(member)
+				user := ParseMember(member)
 				usersMap[member] = user
 			}
 		}
@@ -40,13 +70,23 @@ func (c *Client) GetUsers() ([]User, error) {
 	for _, user := range usersMap {
 		users = append(users, user)
 	}
+	SortUsers(users)
 
 	return users, nil
 }
 
-// parseUser parses a member string into a User struct
-func parseUser(member string) User {
+// ParseMember parses an IAM member string such as "user:a@example.com" into a User
+func ParseMember(member string) User {
 	// Member format: "user:email@example.com", "serviceAccount:sa@project.iam.gserviceaccount.com", etc.
+	if _, _, _, ok := DeletedMember(member); ok {
+		// Keep the full member as the key: a principal recreated under the same
+		// email is a different identity that does not inherit these bindings
+		user := ParseMember(strings.TrimPrefix(member, "deleted:"))
+		user.Email = member
+		user.Deleted = true
+		return user
+	}
+
 	var userType, email string
 
 	switch {
@@ -62,6 +102,13 @@ func parseUser(member string) User {
 	case len(member) > 7 && member[:7] == "domain:":
 		userType = "domain"
 		email = member[7:]
+	case member == "allUsers" || member == "allAuthenticatedUsers":
+		userType = member
+		email = member
+	case strings.HasPrefix(member, "projectOwner:"), strings.HasPrefix(member, "projectEditor:"), strings.HasPrefix(member, "projectViewer:"):
+		// Convenience members keep their prefix: the bare project ID would be ambiguous
+		userType = member[:strings.Index(member, ":")]
+		email = member
 	default:
 		userType = "other"
 		email = member
@@ -72,3 +119,57 @@ func parseUser(member string) User {
 		Type:  userType,
 	}
 }
+
+// convenienceRoles maps convenience member types to the basic role whose
+// holders they stand for
+var convenienceRoles = map[string]string{
+	"projectOwner":  "roles/owner",
+	"projectEditor": "roles/editor",
+	"projectViewer": "roles/viewer",
+}
+
+// ConvenienceRole returns the basic project role behind a projectOwner,
+// projectEditor, or projectViewer principal type, e.g. "roles/editor"
+func ConvenienceRole(principalType string) (string, bool) {
+	role, ok := convenienceRoles[principalType]
+	return role, ok
+}
+
+// ConvenienceMemberFor returns the convenience member covering holders of a
+// basic role on a project, e.g. "projectEditor:my-project" for "roles/editor",
+// or "" if role is not a basic role
+func ConvenienceMemberFor(role, project string) string {
+	for principalType, basic := range convenienceRoles {
+		if basic == role {
+			return principalType + ":" + project
+		}
+	}
+	return ""
+}
+
+// ConvenienceProject returns the project of a convenience member such as
+// "projectEditor:my-project", or "" for any other member
+func ConvenienceProject(member string) string {
+	prefix, project, ok := strings.Cut(member, ":")
+	if _, convenience := convenienceRoles[prefix]; !ok || !convenience {
+		return ""
+	}
+	return project
+}
+
+// DeletedMember splits a deleted member such as
+// "deleted:user:alice@example.com?uid=123456789" into the type and email of the
+// principal it referred to and its unique ID
+func DeletedMember(member string) (memberType, email, uid string, ok bool) {
+	rest, found := strings.CutPrefix(member, "deleted:")
+	if !found {
+		return "", "", "", false
+	}
+	rest, query, _ := strings.Cut(rest, "?")
+	memberType, email, found = strings.Cut(rest, ":")
+	if !found {
+		return "", "", "", false
+	}
+	uid = strings.TrimPrefix(query, "uid=")
+	return memberType, email, uid, true
+}
@@ -0,0 +1,133 @@
+package gcp
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AssetInventoryCollector names the Asset Inventory IAM search in scan warnings
+const AssetInventoryCollector = "assetinventory"
+
+// ProjectCollector names a discovered project that could not be scanned at all
+const ProjectCollector = "project"
+
+// ScanWarning reports a part of a scan that failed while the rest succeeded
+type ScanWarning struct {
+	Collector string `json:"collector"`
+	Error     string `json:"error"`
+	// Permission is the IAM permission that was denied, when the failure was a
+	// permission error and the permission is known
+	Permission string `json:"permission,omitempty"`
+	// Project is the project the collector failed in, set when scanning
+	// projects discovered under an organization or folder
+	Project string `json:"project,omitempty"`
+}
+
+// deniedPermissionPatterns extract the permission named in GCP permission errors, e.g.
+// "Required 'compute.instances.list' permission", "Permission 'run.services.list' denied",
+// "Permission iam.serviceAccounts.list is required", "does not have storage.buckets.get access"
+var deniedPermissionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:permission|required) '([a-z][a-z0-9]*(?:\.[a-zA-Z0-9]+){2,})'`),
+	regexp.MustCompile(`(?i)permission ([a-z][a-z0-9]*(?:\.[a-zA-Z0-9]+){2,}) is required`),
+	regexp.MustCompile(`does not have ([a-z][a-z0-9]*(?:\.[a-zA-Z0-9]+){2,}) access`),
+}
+
+// newScanWarning describes a failed collector. fallbackPermission is reported
+// for permission errors that do not name the denied permission.
+func newScanWarning(collector string, err error, fallbackPermission string) ScanWarning {
+	warning := ScanWarning{Collector: collector, Error: err.Error()}
+	if permission, ok := DeniedPermission(err); ok {
+		warning.Permission = permission
+		if permission == "" {
+			warning.Permission = fallbackPermission
+		}
+	}
+	return warning
+}
+
+// DeniedPermission reports whether err is a GCP permission error and returns
+// the denied permission, or "" when the error does not name it
+func DeniedPermission(err error) (string, bool) {
+	if !isPermissionDenied(err) {
+		return "", false
+	}
+
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if permission := apiErr.Metadata()["permission"]; permission != "" {
+			return permission, true
+		}
+	}
+	for _, pattern := range deniedPermissionPatterns {
+		if match := pattern.FindStringSubmatch(err.Error()); match != nil {
+			return match[1], true
+		}
+	}
+	return "", true
+}
+
+// IsNotFound reports whether err is a GCP "not found" error (HTTP 404 or gRPC NotFound)
+func IsNotFound(err error) bool {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPCode() == http.StatusNotFound || apiErr.GRPCStatus().Code() == codes.NotFound
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code == http.StatusNotFound
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.NotFound
+	}
+	return false
+}
+
+// IsTransient reports whether err is a GCP error worth retrying: rate limiting,
+// an unavailable service, or a deadline
+func IsTransient(err error) bool {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.HTTPCode() {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		switch apiErr.GRPCStatus().Code() {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+		return false
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code == http.StatusTooManyRequests || googleErr.Code == http.StatusServiceUnavailable || googleErr.Code == http.StatusGatewayTimeout
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
+
+// isPermissionDenied reports whether err is an HTTP 403 or a gRPC PermissionDenied
+func isPermissionDenied(err error) bool {
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPCode() == http.StatusForbidden || apiErr.GRPCStatus().Code() == codes.PermissionDenied
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code == http.StatusForbidden
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.PermissionDenied
+	}
+	return false
+}
@@ -0,0 +1,365 @@
+// Package gitops keeps rules, the expected-access baseline, and finding
+// exceptions in sync with a Git repository, so policy changes go through code
+// review. The repository is only ever read.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/rules"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Actor is recorded as the creator of the exceptions the sync manages; only
+// those are updated or removed by later syncs
+const Actor = "gitops"
+
+// File names read from the synced directory; each is optional
+const (
+	RulesFile      = "rules.yaml"
+	BaselineFile   = "baseline.yaml"
+	ExceptionsFile = "exceptions.yaml"
+)
+
+// exceptionFile is one exception as written in exceptions.yaml
+type exceptionFile struct {
+	Rule          string `yaml:"rule"`
+	Principal     string `yaml:"principal"`
+	Resource      string `yaml:"resource"`
+	Role          string `yaml:"role"`
+	Justification string `yaml:"justification"`
+	ExpiresAt     string `yaml:"expiresAt"` // a date (YYYY-MM-DD) or RFC 3339 time
+}
+
+// Status reports the outcome of the latest sync
+type Status struct {
+	Repo        string    `json:"repo"`
+	Ref         string    `json:"ref"`
+	Path        string    `json:"path,omitempty"`
+	Commit      string    `json:"commit,omitempty"` // last commit applied
+	LastAttempt time.Time `json:"lastAttempt,omitempty"`
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+	// Error is why the last attempt failed; what was applied before stays in effect
+	Error string `json:"error,omitempty"`
+	// Files maps each file name to "applied", "unchanged", or "absent"
+	Files           map[string]string `json:"files"`
+	SoDRules        int               `json:"sodRules"`
+	ToxicRules      int               `json:"toxicRules"`
+	BaselineEntries int               `json:"baselineEntries"`
+	Exceptions      int               `json:"exceptions"`
+}
+
+// Syncer pulls the repository into Dir and applies its files
+type Syncer struct {
+	Repo string
+	Ref  string
+	Path string // directory within the repository holding the files
+	Dir  string // local checkout
+
+	Store                store.Store
+	Rules                *rules.Holder
+	ExceptionMaxDuration time.Duration
+
+	mu     sync.Mutex // serializes syncs
+	status Status
+}
+
+// Status returns the outcome of the latest sync
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Repo, status.Ref, status.Path = redactURL(s.Repo), s.Ref, s.Path
+	if status.Files == nil {
+		status.Files = map[string]string{}
+	}
+	return status
+}
+
+// Run syncs immediately and then every interval until ctx is cancelled
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			log.Printf("Warning: GitOps sync of %s failed: %v", redactURL(s.Repo), err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync fetches the ref and, once every file present validates, applies them
+// all. Nothing is applied when any file is invalid.
+func (s *Syncer) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.LastAttempt = time.Now()
+	err := s.sync(ctx)
+	if err != nil {
+		s.status.Error = err.Error()
+		return err
+	}
+	s.status.Error = ""
+	s.status.LastSuccess = s.status.LastAttempt
+	return nil
+}
+
+// sync does the work of Sync with s.mu held
+func (s *Syncer) sync(ctx context.Context) error {
+	commit, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(s.Dir, filepath.Clean("/"+s.Path))
+
+	files := map[string]string{RulesFile: "absent", BaselineFile: "absent", ExceptionsFile: "absent"}
+
+	var ruleSet *rules.RuleSet
+	if data, ok, err := readFile(dir, RulesFile); err != nil {
+		return err
+	} else if ok {
+		ruleSet = &rules.RuleSet{}
+		if err := yaml.Unmarshal(data, ruleSet); err != nil {
+			return fmt.Errorf("%s: %w", RulesFile, err)
+		}
+		if err := ruleSet.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", RulesFile, err)
+		}
+	}
+
+	var baseline rules.Baseline
+	if data, ok, err := readFile(dir, BaselineFile); err != nil {
+		return err
+	} else if ok {
+		if baseline, err = rules.ParseBaseline(data); err != nil {
+			return fmt.Errorf("%s: %w", BaselineFile, err)
+		}
+	}
+
+	var exceptions []store.Exception
+	exceptionsPresent := false
+	if data, ok, err := readFile(dir, ExceptionsFile); err != nil {
+		return err
+	} else if ok {
+		exceptionsPresent = true
+		if exceptions, err = s.parseExceptions(data); err != nil {
+			return fmt.Errorf("%s: %w", ExceptionsFile, err)
+		}
+	}
+
+	// Everything validated: apply
+	if ruleSet != nil {
+		s.Rules.Set(ruleSet)
+		files[RulesFile] = "applied"
+	}
+	current := s.Rules.Get()
+	s.status.SoDRules, s.status.ToxicRules = len(current.SoD), len(current.Toxic)
+
+	if baseline != nil {
+		data, err := json.Marshal(baseline)
+		if err != nil {
+			return err
+		}
+		if err := s.Store.PutSetting(rules.BaselineSetting, data); err != nil {
+			return err
+		}
+		files[BaselineFile] = "applied"
+		s.status.BaselineEntries = len(baseline.Entries())
+	}
+
+	if exceptionsPresent {
+		changed, err := s.applyExceptions(exceptions)
+		if err != nil {
+			return err
+		}
+		files[ExceptionsFile] = "unchanged"
+		if changed {
+			files[ExceptionsFile] = "applied"
+		}
+		s.status.Exceptions = len(exceptions)
+	}
+
+	if commit != s.status.Commit {
+		log.Printf("GitOps: applied %s@%s (%s)", redactURL(s.Repo), commit, summarize(files))
+	}
+	s.status.Commit = commit
+	s.status.Files = files
+	return nil
+}
+
+// fetch updates the checkout to the tip of the ref and returns its commit
+func (s *Syncer) fetch(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create checkout directory: %w", err)
+		}
+		if _, err := s.git(ctx, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+	if _, err := s.git(ctx, "fetch", "--quiet", "--depth", "1", s.Repo, s.Ref); err != nil {
+		return "", err
+	}
+	if _, err := s.git(ctx, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	commit, err := s.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// git runs a git command in the checkout
+func (s *Syncer) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.Dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.ReplaceAll(strings.TrimSpace(stderr.String()), s.Repo, redactURL(s.Repo))
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, output)
+	}
+	return stdout.String(), nil
+}
+
+// parseExceptions reads and validates exceptions.yaml
+func (s *Syncer) parseExceptions(data []byte) ([]store.Exception, error) {
+	var entries []exceptionFile
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	exceptions := make([]store.Exception, 0, len(entries))
+	for i, entry := range entries {
+		exception := store.Exception{
+			Rule:          strings.TrimSpace(entry.Rule),
+			Principal:     strings.TrimSpace(entry.Principal),
+			Resource:      strings.TrimSpace(entry.Resource),
+			Role:          strings.TrimSpace(entry.Role),
+			Justification: strings.TrimSpace(entry.Justification),
+			CreatedBy:     Actor,
+		}
+		switch {
+		case exception.Rule == "":
+			return nil, fmt.Errorf("exception %d: rule is required", i)
+		case exception.Principal == "" && exception.Resource == "":
+			return nil, fmt.Errorf("exception %d: a principal, a resource, or both are required", i)
+		case exception.Justification == "":
+			return nil, fmt.Errorf("exception %d: justification is required", i)
+		}
+
+		expiresAt, err := time.Parse(time.RFC3339, entry.ExpiresAt)
+		if err != nil {
+			if expiresAt, err = time.Parse("2006-01-02", entry.ExpiresAt); err != nil {
+				return nil, fmt.Errorf("exception %d: expiresAt must be a date (YYYY-MM-DD) or RFC 3339 time", i)
+			}
+		}
+		// Lapsed exceptions stay in the file harmlessly until removed; only
+		// overlong ones are rejected
+		if expiresAt.Sub(now) > s.ExceptionMaxDuration {
+			return nil, fmt.Errorf("exception %d: expiresAt is more than %s away", i, s.ExceptionMaxDuration)
+		}
+		exception.ExpiresAt = expiresAt
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
+
+// applyExceptions makes the stored exceptions created by the sync match the
+// file, leaving ones created through the API alone
+func (s *Syncer) applyExceptions(wanted []store.Exception) (bool, error) {
+	existing, err := s.Store.ListExceptions()
+	if err != nil {
+		return false, err
+	}
+
+	remaining := make(map[string]int)
+	for _, exception := range wanted {
+		remaining[exceptionKey(exception)]++
+	}
+	changed := false
+	for _, exception := range existing {
+		if exception.CreatedBy != Actor {
+			continue
+		}
+		key := exceptionKey(exception)
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		if err := s.Store.DeleteException(exception.ID); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	now := time.Now()
+	for _, exception := range wanted {
+		key := exceptionKey(exception)
+		if remaining[key] == 0 {
+			continue
+		}
+		remaining[key]--
+		exception.CreatedAt = now
+		if err := s.Store.CreateException(&exception); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// exceptionKey identifies an exception by its content
+func exceptionKey(e store.Exception) string {
+	return strings.Join([]string{e.Rule, e.Principal, e.Resource, e.Role, e.Justification, e.ExpiresAt.UTC().Format(time.RFC3339)}, "\x00")
+}
+
+// readFile reads a file of the synced directory; ok is false when it is absent
+func readFile(dir, name string) (data []byte, ok bool, err error) {
+	data, err = os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// redactURL hides credentials embedded in a repository URL
+func redactURL(repo string) string {
+	if parsed, err := url.Parse(repo); err == nil && parsed.User != nil {
+		return parsed.Redacted()
+	}
+	return repo
+}
+
+// summarize lists the file outcomes for the log
+func summarize(files map[string]string) string {
+	var parts []string
+	for _, name := range []string{RulesFile, BaselineFile, ExceptionsFile} {
+		parts = append(parts, name+" "+files[name])
+	}
+	return strings.Join(parts, ", ")
+}
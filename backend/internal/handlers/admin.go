@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/redact"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prune handles POST /api/admin/prune
+// Applies the configured retention policy to stored history immediately
+func (h *Handler) Prune(c *gin.Context) {
+	policy := store.RetentionPolicy{
+		Daily:   h.cfg.RetentionDaily,
+		Weekly:  h.cfg.RetentionWeekly,
+		Monthly: h.cfg.RetentionMonthly,
+		Audit:   h.cfg.AuditRetention,
+	}
+
+	result, err := h.store.Prune(policy)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy":  policy,
+		"removed": result,
+	})
+}
+
+// GetAudit handles GET /api/admin/audit
+// Lists recorded API calls, newest first, filtered by actor, path prefix, and
+// time range (RFC 3339 since/until)
+func (h *Handler) GetAudit(c *gin.Context) {
+	filter := store.AuditFilter{
+		Actor: c.Query("actor"),
+		Path:  c.Query("path"),
+		Limit: 100,
+	}
+	if value := c.Query("limit"); value != "" {
+		filter.Limit, _ = strconv.Atoi(value)
+	}
+	if value := c.Query("since"); value != "" {
+		filter.Since, _ = time.Parse(time.RFC3339, value)
+	}
+	if value := c.Query("until"); value != "" {
+		filter.Until, _ = time.Parse(time.RFC3339, value)
+	}
+
+	events, err := h.store.ListAudit(filter)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// runtimeConfigPatch is the body of PUT /api/admin/config; omitted fields are unchanged
+type runtimeConfigPatch struct {
+	ScanInterval      *string   `json:"scanInterval"`
+	EnabledCollectors *[]string `json:"enabledCollectors"`
+	TrustedDomains    *[]string `json:"trustedDomains"`
+	WatchlistRoles    *[]string `json:"watchlistRoles"`
+	Redaction         *string   `json:"redaction"`
+}
+
+// GetRuntimeConfig handles GET /api/admin/config
+func (h *Handler) GetRuntimeConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"settings":   h.cfg.Runtime.Get(),
+		"collectors": gcp.CollectorNames(),
+	})
+}
+
+// UpdateRuntimeConfig handles PUT /api/admin/config
+// Applies the given settings immediately and persists them across restarts
+func (h *Handler) UpdateRuntimeConfig(c *gin.Context) {
+	var patch runtimeConfigPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	settings := h.cfg.Runtime.Get()
+	if patch.ScanInterval != nil {
+		interval, err := time.ParseDuration(*patch.ScanInterval)
+		if err != nil || interval < 0 {
+			problem.Respond(c, problem.InvalidParameter("scanInterval", "scanInterval must be a non-negative duration such as 15m (0 disables background scans)"))
+			return
+		}
+		if interval > 0 && h.cfg.CloudRun() {
+			problem.Respond(c, problem.InvalidParameter("scanInterval", "background scans are not available in Cloud Run mode; schedule POST /api/scans instead"))
+			return
+		}
+		settings.ScanInterval = interval
+	}
+	if patch.EnabledCollectors != nil {
+		known := gcp.CollectorNames()
+		for _, name := range *patch.EnabledCollectors {
+			if !slices.Contains(known, name) {
+				problem.Respond(c, problem.InvalidParameter("enabledCollectors", "unknown collector %q", name))
+				return
+			}
+		}
+		settings.EnabledCollectors = *patch.EnabledCollectors
+	}
+	if patch.TrustedDomains != nil {
+		settings.TrustedDomains = *patch.TrustedDomains
+	}
+	if patch.WatchlistRoles != nil {
+		settings.WatchlistRoles = *patch.WatchlistRoles
+	}
+	if patch.Redaction != nil {
+		if !redact.ValidMode(*patch.Redaction) {
+			problem.Respond(c, problem.InvalidParameter("redaction", "redaction must be one of: %s", strings.Join(redact.Modes, ", ")))
+			return
+		}
+		settings.Redaction = *patch.Redaction
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if err := h.store.PutSetting(config.RuntimeSettingsKey, data); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.cfg.Runtime.Set(settings)
+
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// ExportState handles GET /api/admin/state
+// Downloads everything the store holds (saved views, principal profiles,
+// service account owners, score, metrics, and usage history, audit log, and
+// runtime settings) with the current snapshot as a gzipped JSON archive
+func (h *Handler) ExportState(c *gin.Context) {
+	state, err := h.store.ExportState()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if state.Snapshot, err = h.scanner.Export(); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	// The archive is built before responding, so a failure is still reported
+	// as an error rather than as a truncated backup
+	var buf bytes.Buffer
+	archive := gzip.NewWriter(&buf)
+	err = json.NewEncoder(archive).Encode(state)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		problem.RespondError(c, fmt.Errorf("failed to write state archive: %w", err))
+		return
+	}
+
+	name := fmt.Sprintf("gcp-access-visualizer-state-%s.json.gz", state.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
+
+// ImportState handles POST /api/admin/state
+// Restores an archive from GET /api/admin/state (gzipped or plain JSON, as
+// multipart field "file" or the raw request body), replacing everything the
+// store holds. Runtime settings and the archived snapshot take effect at once.
+func (h *Handler) ImportState(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	buffered := bufio.NewReader(body)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		archive, err := gzip.NewReader(buffered)
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "invalid archive: %v", err))
+			return
+		}
+		defer archive.Close()
+		body = archive
+	} else {
+		body = buffered
+	}
+
+	var state store.State
+	if err := json.NewDecoder(body).Decode(&state); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "invalid archive: %v", err))
+		return
+	}
+	if err := h.store.ImportState(&state); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	if data, err := h.store.GetSetting(config.RuntimeSettingsKey); err == nil {
+		var settings config.RuntimeSettings
+		if err := json.Unmarshal(data, &settings); err == nil {
+			h.cfg.Runtime.Set(settings)
+		}
+	}
+	if data, err := h.store.GetSetting(config.FlagsSettingKey); err == nil {
+		var overrides map[string]bool
+		if err := json.Unmarshal(data, &overrides); err == nil {
+			h.cfg.Flags.SetOverrides(overrides)
+		}
+	}
+	if state.Snapshot != nil {
+		if err := h.scanner.Restore(*state.Snapshot); err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+	} else {
+		h.scanner.Reapply()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exportedAt": state.ExportedAt,
+		"views":      len(state.Views),
+		"profiles":   len(state.Profiles),
+		"owners":     len(state.Owners),
+		"scores":     len(state.Scores),
+		"metrics":    len(state.Metrics),
+		"usage":      len(state.Usage),
+		"audit":      len(state.Audit),
+		"settings":   len(state.Settings),
+		"snapshot":   state.Snapshot != nil,
+	})
+}
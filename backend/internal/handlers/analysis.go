@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/compliance"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGraph handles GET /api/graph
+// Pass ?bundle=N to bundle the edges of principals with more than N resources
+func (h *Handler) GetGraph(c *gin.Context) {
+	threshold, err := strconv.Atoi(c.DefaultQuery("bundle", "0"))
+	if err != nil || threshold < 0 {
+		problem.Respond(c, problem.InvalidParameter("bundle", "bundle must be a non-negative integer"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis.BuildGraph(snapshot.Matrix, analysis.GraphOptions{
+		BundleThreshold: threshold,
+	}))
+}
+
+// GetFlows handles GET /api/flows
+func (h *Handler) GetFlows(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis.BuildFlows(snapshot.Matrix))
+}
+
+// GetTopReport handles GET /api/reports/top
+// Query parameters: metric (principals, roles, resources, owners, regions) and limit (default 10)
+func (h *Handler) GetTopReport(c *gin.Context) {
+	metric := c.DefaultQuery("metric", analysis.TopPrincipals)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		problem.Respond(c, problem.InvalidParameter("limit", "limit must be a positive integer"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	items, ok := analysis.TopN(snapshot.Matrix, metric, limit)
+	if !ok {
+		problem.Respond(c, problem.InvalidParameter("metric", "metric must be one of: principals, roles, resources, owners, regions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"metric":     metric,
+		"limit":      limit,
+		"snapshotId": snapshot.ID,
+		"takenAt":    snapshot.TakenAt,
+		"items":      items,
+	})
+}
+
+// GetComplianceReport handles GET /api/reports/compliance?framework=
+// Fills the access review report template of a framework (soc2, iso27001):
+// user and privileged access listings, the terminated user check against the
+// HR feed, and review attestation records
+func (h *Handler) GetComplianceReport(c *gin.Context) {
+	framework := c.Query("framework")
+	if _, ok := compliance.Frameworks[framework]; !ok {
+		problem.Respond(c, problem.InvalidParameter("framework", "framework must be one of: soc2, iso27001"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	inputs := compliance.Inputs{
+		SnapshotID:     snapshot.ID,
+		TakenAt:        snapshot.TakenAt,
+		Matrix:         snapshot.Matrix,
+		TrustedDomains: h.cfg.Runtime.TrustedDomains(),
+	}
+	if inputs.Profiles, err = h.store.ListProfiles(); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if inputs.FindingStates, err = h.store.ListFindingStates(); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if inputs.Exceptions, err = h.store.ListExceptions(); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	report, err := compliance.Build(framework, inputs, time.Now())
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetHeatmap handles GET /api/heatmap
+// Aggregates the current snapshot into a principal type (or, with ?rows=team,
+// team) × resource type grid with entry, principal, and resource counts and
+// the highest tier per cell. Heatmaps are built once per snapshot.
+func (h *Handler) GetHeatmap(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	rowsBy := c.DefaultQuery("rows", analysis.HeatmapByType)
+	c.JSON(http.StatusOK, h.heatmaps.Get(snapshot.ID, snapshot.Matrix, rowsBy))
+}
+
+// HeatmapListener builds the heatmaps of every new snapshot ahead of the first request
+func (h *Handler) HeatmapListener() scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		for _, rowsBy := range []string{analysis.HeatmapByType, analysis.HeatmapByTeam} {
+			h.heatmaps.Get(current.ID, current.Matrix, rowsBy)
+		}
+	}
+}
+
+// GetRoles handles GET /api/roles
+// Returns metadata for every role granted in the current snapshot
+func (h *Handler) GetRoles(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range snapshot.Matrix.Access {
+		for _, role := range entry.Roles {
+			if !seen[role] {
+				seen[role] = true
+				names = append(names, role)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	roles := make([]gcp.RoleInfo, 0, len(names))
+	for _, name := range names {
+		roles = append(roles, h.gcpClient.GetRole(name))
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// GetWatchlist handles GET /api/watchlist
+// Lists every grant of a configured high-risk role
+func (h *Handler) GetWatchlist(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	roles := h.cfg.Runtime.WatchlistRoles()
+	c.JSON(http.StatusOK, gin.H{
+		"roles":  roles,
+		"grants": analysis.Watchlist(snapshot.Matrix, roles),
+	})
+}
+
+// GetSoD handles GET /api/sod
+// Evaluates the separation-of-duties rules against the current snapshot
+func (h *Handler) GetSoD(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	sod := h.rules.Get().SoD
+	c.JSON(http.StatusOK, gin.H{
+		"rules":      sod,
+		"violations": analysis.EvaluateSoD(snapshot.Matrix, sod),
+	})
+}
+
+// GetToxicCombinations handles GET /api/toxic-combinations
+// Evaluates the toxic permission combination rules against each principal's
+// effective permissions, reporting the roles that contribute to each condition
+func (h *Handler) GetToxicCombinations(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	permissions := func(role string) []string {
+		return h.gcpClient.GetRole(role).Permissions
+	}
+	toxic := h.rules.Get().Toxic
+	c.JSON(http.StatusOK, gin.H{
+		"rules":        toxic,
+		"combinations": analysis.EvaluateToxic(snapshot.Matrix, toxic, permissions),
+	})
+}
+
+// GetAnomalies handles GET /api/anomalies
+// Clusters principals by the similarity of their role sets and reports the
+// write-or-higher grants that few of a principal's cluster peers share, with
+// an explanation of why each is unusual
+func (h *Handler) GetAnomalies(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	clusters, anomalies := analysis.RoleAnomalies(snapshot.Matrix, h.gcpClient.RoleTier)
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":  clusters,
+		"anomalies": anomalies,
+	})
+}
+
+// GetSSHAccess handles GET /api/ssh-access
+// Derives for each VM who can SSH to it: OS Login roles, or, with OS Login
+// off, permission to add keys to instance or project metadata, combined with
+// IAP TCP forwarding permission. ?resource= narrows to one VM and ?principal=
+// to one principal's grants.
+func (h *Handler) GetSSHAccess(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	permissions := func(role string) []string {
+		return h.gcpClient.GetRole(role).Permissions
+	}
+	resourceID := c.Query("resource")
+	principal := c.Query("principal")
+	vms := []analysis.VMSSHAccess{}
+	for _, vm := range analysis.SSHAccess(snapshot.Matrix, permissions) {
+		if resourceID != "" && vm.ResourceID != resourceID {
+			continue
+		}
+		if principal != "" {
+			grants := []analysis.SSHGrant{}
+			for _, grant := range vm.Principals {
+				if grant.Principal == principal {
+					grants = append(grants, grant)
+				}
+			}
+			if len(grants) == 0 {
+				continue
+			}
+			vm.Principals = grants
+		}
+		vms = append(vms, vm)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"vms":        vms,
+	})
+}
+
+// GetSSHKeys handles GET /api/ssh-keys
+// Lists the project-wide and per-instance metadata SSH keys of the VMs, each
+// attributed to a principal where its google-ssh email, comment, or username
+// allows, with the VMs it logs in to despite IAM
+func (h *Handler) GetSSHKeys(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"keys":       analysis.SSHKeyInventory(snapshot.Matrix, time.Now()),
+	})
+}
+
+// GetExposedVMs handles GET /api/exposed-vms
+// Lists VMs reachable from the internet, with the ports public firewall rules
+// open to them and the access of the service accounts they run as; VMs whose
+// service account is sensitive carry a risk
+func (h *Handler) GetExposedVMs(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"vms":        analysis.ExposedVMs(snapshot.Matrix),
+	})
+}
+
+// GetScore handles GET /api/score
+// Returns the least-privilege score of the current snapshot with its breakdown
+// and the score history recorded for earlier snapshots
+func (h *Handler) GetScore(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	history, err := h.store.ListScores(h.gcpClient.ProjectID)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"current":    h.scorer.Compute(snapshot),
+		"history":    history,
+	})
+}
+
+// GetTrends handles GET /api/trends
+// Returns the posture metrics and finding counts recorded for each snapshot over
+// the last ?days= days (default 30), oldest first
+func (h *Handler) GetTrends(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 {
+		problem.Respond(c, problem.InvalidParameter("days", "days must be a positive integer"))
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	points, err := h.store.ListMetrics(h.gcpClient.ProjectID, since)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project": h.gcpClient.ProjectID,
+		"since":   since,
+		"points":  points,
+	})
+}
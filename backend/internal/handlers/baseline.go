@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/rules"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBaselineSize caps uploaded baseline documents
+const maxBaselineSize = 4 << 20
+
+// GetBaselineDrift handles GET /api/baseline/drift
+// Compares the bindings of the current snapshot with the expected-access
+// baseline: bindings no entry allows, and entries no binding matches
+func (h *Handler) GetBaselineDrift(c *gin.Context) {
+	baseline, err := h.loadBaseline()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"drift":      analysis.BaselineDrift(snapshot.Matrix, baseline, h.gcpClient.RoleTier),
+	})
+}
+
+// GetBaseline handles GET /api/admin/baseline
+func (h *Handler) GetBaseline(c *gin.Context) {
+	baseline, err := h.loadBaseline()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, baseline)
+}
+
+// PutBaseline handles PUT /api/admin/baseline
+// Replaces the baseline with a YAML or JSON document of principal -> role ->
+// resource patterns, sent as the body or as a "file" form upload
+func (h *Handler) PutBaseline(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxBaselineSize))
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	baseline, err := rules.ParseBaseline(data)
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+	if err := h.saveBaseline(baseline); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": len(baseline.Entries())})
+}
+
+// loadBaseline returns the stored baseline; without one, a not-found problem
+func (h *Handler) loadBaseline() (rules.Baseline, error) {
+	baseline, err := h.storedBaseline()
+	if err == nil && baseline == nil {
+		return nil, problem.NotFound("no baseline is defined; PUT one to /api/admin/baseline")
+	}
+	return baseline, err
+}
+
+// storedBaseline returns the stored baseline, or nil when none is defined
+func (h *Handler) storedBaseline() (rules.Baseline, error) {
+	data, err := h.store.GetSetting(rules.BaselineSetting)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var baseline rules.Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// saveBaseline persists the baseline
+func (h *Handler) saveBaseline(baseline rules.Baseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return err
+	}
+	return h.store.PutSetting(rules.BaselineSetting, data)
+}
+
+// GetGitOpsStatus handles GET /api/admin/gitops
+// Reports the last GitOps sync: the commit applied, what each file did, and
+// the error of the last attempt if it failed
+func (h *Handler) GetGitOpsStatus(c *gin.Context) {
+	if h.gitOps == nil {
+		problem.Respond(c, problem.New(http.StatusNotImplemented, problem.CodeNotImplemented, "GitOps sync is not configured; set GITOPS_REPO"))
+		return
+	}
+	c.JSON(http.StatusOK, h.gitOps.Status())
+}
+
+// SyncGitOps handles POST /api/admin/gitops/sync
+// Syncs from the repository now instead of waiting for the interval
+func (h *Handler) SyncGitOps(c *gin.Context) {
+	if h.gitOps == nil {
+		problem.Respond(c, problem.New(http.StatusNotImplemented, problem.CodeNotImplemented, "GitOps sync is not configured; set GITOPS_REPO"))
+		return
+	}
+	if err := h.gitOps.Sync(c.Request.Context()); err != nil {
+		problem.Respond(c, problem.New(http.StatusUnprocessableEntity, problem.CodeInvalidArgument, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, h.gitOps.Status())
+}
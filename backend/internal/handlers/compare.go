@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompareUsers handles GET /api/compare/users?a=&b=
+// Returns the symmetric difference of two principals' effective access: the
+// roles a reaches that b does not, e.g. what a new hire needs to match a
+// teammate, and the roles b reaches beyond a. Principals without bindings
+// of their own, such as a new hire, are compared through their groups.
+func (h *Handler) CompareUsers(c *gin.Context) {
+	a, b := c.Query("a"), c.Query("b")
+	if a == "" || b == "" {
+		problem.Respond(c, problem.InvalidParameter("", "a and b are required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+	c.JSON(http.StatusOK, analysis.ComparePrincipals(snapshot.Matrix, index, a, b, h.gcpClient.RoleTier))
+}
+
+// CompareProjects handles GET /api/compare/projects?a=&b=
+// Diffs the IAM posture of two scanned projects, given by ID or number, e.g.
+// staging vs. prod: the (principal, role) bindings in one but not the other,
+// and the principals and roles only one of them uses. Each project's own
+// service accounts are compared by name.
+func (h *Handler) CompareProjects(c *gin.Context) {
+	a, b := c.Query("a"), c.Query("b")
+	if a == "" || b == "" {
+		problem.Respond(c, problem.InvalidParameter("", "a and b are required"))
+		return
+	}
+	projectA, okA := h.gcpClient.ScannedProjectID(a)
+	projectB, okB := h.gcpClient.ScannedProjectID(b)
+	if !okA || !okB {
+		problem.RespondError(c, gcp.ErrProjectNotScanned)
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis.CompareProjects(snapshot.Matrix, projectA, projectB, h.gcpClient.RoleTier))
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"gcp-access-visualizer/internal/enrichment"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListProfiles handles GET /api/enrichment/principals
+func (h *Handler) ListProfiles(c *gin.Context) {
+	profiles, err := h.store.ListProfiles()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// UploadProfiles handles POST /api/admin/enrichment/principals
+// Accepts a CSV file (multipart field "file" or the raw request body) with
+// columns email, displayName, team, manager. Replaces previously uploaded CSV data.
+func (h *Handler) UploadProfiles(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	profiles, err := enrichment.ParseCSV(body)
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	if err := h.store.ReplaceProfiles("csv", profiles); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.scanner.Reapply()
+
+	c.JSON(http.StatusOK, gin.H{"imported": len(profiles)})
+}
+
+// SyncProfiles handles POST /api/admin/enrichment/sync
+// Pulls profiles from the configured directory connector (SCIM)
+func (h *Handler) SyncProfiles(c *gin.Context) {
+	if h.enrichmentSource == nil {
+		problem.Respond(c, problem.New(http.StatusNotImplemented, problem.CodeNotImplemented, "no directory connector configured"))
+		return
+	}
+
+	count, err := enrichment.Sync(c.Request.Context(), h.enrichmentSource, h.store)
+	if err != nil {
+		problem.Respond(c, problem.Upstream(err))
+		return
+	}
+	h.scanner.Reapply()
+
+	c.JSON(http.StatusOK, gin.H{"source": h.enrichmentSource.Name(), "imported": count})
+}
+
+// ListOwners handles GET /api/service-accounts/owners
+func (h *Handler) ListOwners(c *gin.Context) {
+	owners, err := h.store.ListOwners()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, owners)
+}
+
+// SetOwner handles PUT /api/admin/service-accounts/:email/owner
+func (h *Handler) SetOwner(c *gin.Context) {
+	var req struct {
+		Owner string `json:"owner" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	owner := &store.ServiceAccountOwner{Email: c.Param("email"), Owner: req.Owner}
+	if err := h.store.SetOwner(owner); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.scanner.Reapply()
+
+	c.JSON(http.StatusOK, owner)
+}
+
+// DeleteOwner handles DELETE /api/admin/service-accounts/:email/owner
+func (h *Handler) DeleteOwner(c *gin.Context) {
+	if err := h.store.DeleteOwner(c.Param("email")); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.scanner.Reapply()
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEscalationPaths handles GET /api/escalation-paths
+// Lists chains of service account takeovers (token creation, key creation, actAs)
+// that lead to IAM-modifying roles, shortest first. ?principal= limits the start.
+func (h *Handler) GetEscalationPaths(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+	paths := analysis.FindEscalationPaths(snapshot.Matrix, index)
+	if principal := c.Query("principal"); principal != "" {
+		filtered := []analysis.EscalationPath{}
+		for _, path := range paths {
+			if path.Principal == principal {
+				filtered = append(filtered, path)
+			}
+		}
+		paths = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"paths":      paths,
+		"total":      len(paths),
+	})
+}
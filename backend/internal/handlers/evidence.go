@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/middleware"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// evidenceFile is one file of an evidence bundle as listed in its manifest
+type evidenceFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SHA256      string `json:"sha256"`
+}
+
+// evidenceScan is the scan metadata of an evidence bundle
+type evidenceScan struct {
+	ID        string            `json:"id"`
+	TakenAt   time.Time         `json:"takenAt"`
+	PatchedAt time.Time         `json:"patchedAt"`
+	Warnings  []gcp.ScanWarning `json:"warnings"`
+}
+
+// evidenceBundle collects the files of an evidence bundle and what could not
+// be collected
+type evidenceBundle struct {
+	files    []evidenceFile
+	contents [][]byte
+	missing  map[string]string
+	// mask applies the redaction mode, which cannot read the zip, to each file
+	mask func(content []byte) []byte
+}
+
+// add adds a file holding value as indented JSON
+func (b *evidenceBundle) add(name, description string, value interface{}) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	content = b.mask(content)
+	sum := sha256.Sum256(content)
+	b.files = append(b.files, evidenceFile{Name: name, Description: description, SHA256: hex.EncodeToString(sum[:])})
+	b.contents = append(b.contents, content)
+	return nil
+}
+
+// GetFindingEvidence handles GET /api/findings/evidence?id=
+// Downloads a zip of compliance evidence for a current finding: the finding
+// and its triage state, the scan that found it, the resource metadata, its
+// policy bindings for the principal as scanned and as Asset Inventory returns
+// them now, and the audit log events that granted them. Evidence that cannot
+// be collected is listed in manifest.json rather than failing the bundle.
+func (h *Handler) GetFindingEvidence(c *gin.Context) {
+	id := c.Query("id")
+	if id == "" {
+		problem.Respond(c, problem.InvalidParameter("id", "id is required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	all, exceptions := h.findings.EvaluateAll(snapshot)
+	var finding *analysis.Finding
+	for i := range all {
+		if all[i].ID == id {
+			finding = &all[i]
+			break
+		}
+	}
+	if finding == nil {
+		problem.Respond(c, problem.NotFound("no current finding "+id))
+		return
+	}
+
+	now := time.Now().UTC()
+	bundle := &evidenceBundle{
+		missing: make(map[string]string),
+		mask:    func(content []byte) []byte { return middleware.MaskBytes(c, content) },
+	}
+	add := func(name, description string, value interface{}) bool {
+		if err := bundle.add(name, description, value); err != nil {
+			problem.RespondError(c, err)
+			return false
+		}
+		return true
+	}
+
+	states, err := h.store.ListFindingStates()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	tracked := findings.Track([]analysis.Finding{*finding}, states, exceptions, now)[0]
+	if !add("finding.json", "The finding with its triage status, state, and exception", tracked) ||
+		!add("scan.json", "The scan the finding was evaluated on, with its warnings", evidenceScan{
+			ID:        snapshot.ID,
+			TakenAt:   snapshot.TakenAt,
+			PatchedAt: snapshot.PatchedAt,
+			Warnings:  snapshot.Matrix.Warnings,
+		}) {
+		return
+	}
+
+	principal := findings.Principal(*finding)
+	resource := findEvidenceResource(snapshot.Matrix, findings.ResourceID(*finding))
+	switch {
+	case resource == nil:
+		bundle.missing["asset.json"] = "the finding is not about a scanned resource"
+	case !add("asset.json", "Metadata of the resource as scanned", resource):
+		return
+	}
+
+	if resource != nil && principal != "" {
+		if !add("bindings.json", "Bindings of the principal on the resource as scanned", scannedBindings(resource, principal)) {
+			return
+		}
+
+		matches, _, err := h.gcpClient.SearchIAMPolicies(gcp.PolicySearch{Principal: principal, Resource: resource.Name, Limit: 100})
+		if err != nil {
+			bundle.missing["policy.json"] = err.Error()
+		} else if !add("policy.json", "Bindings of the principal as Asset Inventory returns them at export time", matches) {
+			return
+		}
+
+		if !h.cfg.Flags.Enabled(config.FlagAuditLogOverlay) {
+			bundle.missing["grant-events.json"] = "the " + config.FlagAuditLogOverlay + " feature is disabled on this deployment"
+		} else if events, err := h.gcpClient.GrantEvents(*resource, principal); err != nil {
+			bundle.missing["grant-events.json"] = err.Error()
+		} else if !add("grant-events.json", fmt.Sprintf("Admin Activity audit log entries of SetIamPolicy calls granting the principal, from the last %d days", int(gcp.GrantAuditWindow.Hours()/24)), events) {
+			return
+		}
+	} else {
+		for _, name := range []string{"bindings.json", "policy.json", "grant-events.json"} {
+			bundle.missing[name] = "the finding is not about a principal's binding on a scanned resource"
+		}
+	}
+
+	manifest := gin.H{
+		"findingId":   finding.ID,
+		"generatedAt": now,
+		"scan":        gin.H{"snapshotId": snapshot.ID, "takenAt": snapshot.TakenAt, "patchedAt": snapshot.PatchedAt},
+		"files":       bundle.files,
+		"missing":     bundle.missing,
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	content = bundle.mask(content)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name string, content []byte) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+	err = write("manifest.json", content)
+	for i, file := range bundle.files {
+		if err == nil {
+			err = write(file.Name, bundle.contents[i])
+		}
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		problem.RespondError(c, fmt.Errorf("failed to write evidence bundle: %w", err))
+		return
+	}
+
+	name := fmt.Sprintf("evidence-%s-%s.zip", finding.Kind, now.Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// findEvidenceResource returns the scanned resource with an ID, or nil
+func findEvidenceResource(matrix *gcp.AccessMatrix, id string) *gcp.Resource {
+	if id == "" {
+		return nil
+	}
+	for i := range matrix.Resources {
+		if matrix.Resources[i].ID == id {
+			return &matrix.Resources[i]
+		}
+	}
+	return nil
+}
+
+// scannedBindings returns the bindings of a resource that name principal,
+// with their conditions
+func scannedBindings(resource *gcp.Resource, principal string) []gcp.PolicyBinding {
+	email := gcp.ParseMember(principal).Email
+	bindings := []gcp.PolicyBinding{}
+	for role, members := range resource.IAM {
+		for _, member := range members {
+			if member != principal && !strings.EqualFold(gcp.ParseMember(member).Email, email) {
+				continue
+			}
+			conditions := resource.MemberConditions(role, gcp.ParseMember(member).Email)
+			if len(conditions) == 0 {
+				bindings = append(bindings, gcp.PolicyBinding{Role: role, Members: []string{member}})
+			}
+			for i := range conditions {
+				bindings = append(bindings, gcp.PolicyBinding{Role: role, Members: []string{member}, Condition: &conditions[i]})
+			}
+			break
+		}
+	}
+	sort.SliceStable(bindings, func(i, j int) bool { return bindings[i].Role < bindings[j].Role })
+	return bindings
+}
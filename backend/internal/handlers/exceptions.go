@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/middleware"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exceptionRequest is the body accepted when creating an exception
+type exceptionRequest struct {
+	Rule          string `json:"rule" binding:"required"`
+	Principal     string `json:"principal"`
+	Resource      string `json:"resource"`
+	Role          string `json:"role"`
+	Justification string `json:"justification" binding:"required"`
+	ExpiresAt     string `json:"expiresAt" binding:"required"`
+}
+
+// ListExceptions handles GET /api/admin/exceptions
+// Lists every exception, soonest expiring first, with whether it is still active
+func (h *Handler) ListExceptions(c *gin.Context) {
+	exceptions, err := h.store.ListExceptions()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	now := time.Now()
+	response := make([]gin.H, 0, len(exceptions))
+	for _, exception := range exceptions {
+		response = append(response, gin.H{
+			"exception": exception,
+			"active":    now.Before(exception.ExpiresAt),
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateException handles POST /api/admin/exceptions
+// Exempts the findings of one rule about a principal, a resource, or both
+// until expiresAt (a date or RFC 3339 time), after which they reopen. Responds
+// with the exception and the current findings it covers.
+func (h *Handler) CreateException(c *gin.Context) {
+	var req exceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	exception := &store.Exception{
+		Rule:          strings.TrimSpace(req.Rule),
+		Principal:     strings.TrimSpace(req.Principal),
+		Resource:      strings.TrimSpace(req.Resource),
+		Role:          strings.TrimSpace(req.Role),
+		Justification: strings.TrimSpace(req.Justification),
+		CreatedBy:     middleware.Actor(c, h.cfg.AdminToken),
+	}
+	if exception.Principal == "" && exception.Resource == "" {
+		problem.Respond(c, problem.InvalidParameter("principal", "an exception names a principal, a resource, or both"))
+		return
+	}
+	if exception.Justification == "" {
+		problem.Respond(c, problem.InvalidParameter("justification", "a justification is required"))
+		return
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+	if err != nil {
+		if expiresAt, err = time.Parse("2006-01-02", req.ExpiresAt); err != nil {
+			problem.Respond(c, problem.InvalidParameter("expiresAt", "expiresAt must be a date (YYYY-MM-DD) or RFC 3339 time"))
+			return
+		}
+	}
+	now := time.Now()
+	if !expiresAt.After(now) {
+		problem.Respond(c, problem.InvalidParameter("expiresAt", "expiresAt must be in the future"))
+		return
+	}
+	if expiresAt.Sub(now) > h.cfg.ExceptionMaxDuration {
+		problem.Respond(c, problem.InvalidParameter("expiresAt", "exceptions may run for at most %s", h.cfg.ExceptionMaxDuration))
+		return
+	}
+	exception.ExpiresAt = expiresAt.UTC()
+
+	if err := h.store.CreateException(exception); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	response := gin.H{"exception": exception}
+	if snapshot, err := h.scanner.Current(); err == nil {
+		all, _ := h.findings.EvaluateAll(snapshot)
+		covered := []string{}
+		for _, finding := range all {
+			if findings.Excepts(*exception, finding) {
+				covered = append(covered, finding.ID)
+			}
+		}
+		response["findings"] = covered
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// DeleteException handles DELETE /api/admin/exceptions/:id
+// Revokes an exception before it expires; its findings reopen at the next scan
+func (h *Handler) DeleteException(c *gin.Context) {
+	if err := h.store.DeleteException(c.Param("id")); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/conditions"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Explain handles GET /api/explain?principal=&resource=
+// Returns every distinct path (direct binding, group membership, project
+// inheritance, service account impersonation) as an ordered chain of steps.
+// Principal Access Boundary policies and access levels are optional; failure
+// to read them is reported, not fatal.
+func (h *Handler) Explain(c *gin.Context) {
+	principal := c.Query("principal")
+	resourceID := c.Query("resource")
+	if principal == "" || resourceID == "" {
+		problem.Respond(c, problem.InvalidParameter("", "principal and resource are required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if !hasResource(snapshot.Matrix, resourceID) {
+		problem.Respond(c, problem.NotFound("resource not found in the current snapshot"))
+		return
+	}
+
+	index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+	response := gin.H{"snapshotId": snapshot.ID}
+	controls := h.controls(snapshot.Matrix, response)
+	response["explanation"] = analysis.Explain(index, controls, principal, resourceID)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetEffectiveAccess handles GET /api/effective-access?principal=&resource=&at=
+// Like Explain, but evaluates the IAM conditions on each path as of ?at=
+// (RFC 3339, default now) and reports whether access applies
+func (h *Handler) GetEffectiveAccess(c *gin.Context) {
+	principal := c.Query("principal")
+	resourceID := c.Query("resource")
+	if principal == "" || resourceID == "" {
+		problem.Respond(c, problem.InvalidParameter("", "principal and resource are required"))
+		return
+	}
+	at := time.Now().UTC()
+	if value := c.Query("at"); value != "" {
+		at, _ = time.Parse(time.RFC3339, value)
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if !hasResource(snapshot.Matrix, resourceID) {
+		problem.Respond(c, problem.NotFound("resource not found in the current snapshot"))
+		return
+	}
+
+	index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+	response := gin.H{"snapshotId": snapshot.ID}
+	controls := h.controls(snapshot.Matrix, response)
+	response["effective"] = analysis.EvaluateAccess(index, controls, principal, resourceID, at)
+	c.JSON(http.StatusOK, response)
+}
+
+// EvaluateCondition handles POST /api/conditions/evaluate
+// Evaluates a condition expression against a resource and request time, to
+// try out a condition before binding it
+func (h *Handler) EvaluateCondition(c *gin.Context) {
+	var req struct {
+		Expression string    `json:"expression" binding:"required"`
+		Resource   string    `json:"resource"` // full resource name
+		AssetType  string    `json:"assetType"`
+		At         time.Time `json:"at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+	if req.At.IsZero() {
+		req.At = time.Now().UTC()
+	}
+
+	ctx := conditions.ContextFor(req.Resource, req.AssetType, req.At)
+	result, err := conditions.Evaluate(req.Expression, ctx)
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("expression", "%v", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"result":  result,
+		"context": ctx,
+	})
+}
+
+// GetAccessBoundaries handles GET /api/access-boundaries
+// Lists the Principal Access Boundary policies bound at the project and its
+// parent, with the resources each lets its principals access
+func (h *Handler) GetAccessBoundaries(c *gin.Context) {
+	boundaries, scope, err := h.gcpClient.GetAccessBoundaries()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scope":      scope,
+		"boundaries": boundaries,
+	})
+}
+
+// controls reads the Principal Access Boundary policies and, when conditions
+// refer to any, the access levels for path evaluation. A control that cannot
+// be read is left out and its error added to response.
+func (h *Handler) controls(matrix *gcp.AccessMatrix, response gin.H) analysis.Controls {
+	var controls analysis.Controls
+	boundaries, scope, err := h.gcpClient.GetAccessBoundaries()
+	if err != nil {
+		log.Printf("Warning: failed to read principal access boundaries: %v", err)
+		response["accessBoundariesError"] = err.Error()
+	} else {
+		controls.Boundaries = analysis.NewBoundaryIndex(boundaries, *scope)
+	}
+
+	if referenced := analysis.ReferencedAccessLevels(matrix); len(referenced) > 0 {
+		accessContext, err := h.gcpClient.GetAccessContext(referenced)
+		if err != nil {
+			log.Printf("Warning: failed to read access levels: %v", err)
+			response["accessLevelsError"] = err.Error()
+		} else {
+			controls.AccessContext = accessContext
+		}
+	}
+	return controls
+}
+
+// GetAccessLevels handles GET /api/access-levels
+// Resolves the Access Context Manager access levels referenced by IAM
+// conditions and by the VPC Service Controls perimeters around the project to
+// their IP ranges, regions, and device policies
+func (h *Handler) GetAccessLevels(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	accessContext, err := h.gcpClient.GetAccessContext(analysis.ReferencedAccessLevels(snapshot.Matrix))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	requirements := make(map[string]string, len(accessContext.Levels))
+	for _, level := range accessContext.Levels {
+		requirements[level.Name] = analysis.DescribeAccessLevel(level, accessContext)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId":    snapshot.ID,
+		"accessContext": accessContext,
+		"requirements":  requirements,
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/favorites"
+	"gcp-access-visualizer/internal/middleware"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// favoriteRequest is the body of POST /api/favorites
+type favoriteRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=principal resource"`
+	ID   string `json:"id" binding:"required"`
+}
+
+// Favorites are per user: their routes require a verified identity
+// (middleware.RequireIdentity), which keys the user's favorites
+
+// GetFavorites handles GET /api/favorites
+// Returns the caller's starred principals and resources with the grants on
+// them added and removed since the caller's last visit, and records this
+// visit as the baseline of the next one
+func (h *Handler) GetFavorites(c *gin.Context) {
+	user := middleware.Identity(c, h.cfg.AdminToken)
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	feed, err := favorites.Visit(h.store, user, snapshot.Matrix, snapshot.TakenAt)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+// StarFavorite handles POST /api/favorites
+// Stars a principal or resource of the current snapshot for the caller
+func (h *Handler) StarFavorite(c *gin.Context) {
+	user := middleware.Identity(c, h.cfg.AdminToken)
+	var req favoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	found := false
+	if req.Kind == favorites.KindPrincipal {
+		for _, principal := range snapshot.Matrix.Users {
+			found = found || strings.EqualFold(principal.Email, req.ID)
+		}
+	} else {
+		for _, resource := range snapshot.Matrix.Resources {
+			found = found || resource.ID == req.ID
+		}
+	}
+	if !found {
+		problem.Respond(c, problem.NotFound(req.Kind+" "+req.ID+" is not in the current snapshot"))
+		return
+	}
+
+	item := favorites.Item{Kind: req.Kind, ID: req.ID, AddedAt: time.Now()}
+	if err := favorites.Star(h.store, user, item); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// UnstarFavorite handles DELETE /api/favorites?kind=&id=
+func (h *Handler) UnstarFavorite(c *gin.Context) {
+	user := middleware.Identity(c, h.cfg.AdminToken)
+	kind, id := c.Query("kind"), c.Query("id")
+	if kind == "" || id == "" {
+		problem.Respond(c, problem.InvalidParameter("", "kind and id are required"))
+		return
+	}
+
+	if err := favorites.Unstar(h.store, user, kind, id); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/middleware"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFindings handles GET /api/findings
+// Each finding carries its triage status. Optional filters: ?severity= (minimum
+// severity), ?kind=, and ?status= (comma-separated, e.g. open,acknowledged)
+func (h *Handler) GetFindings(c *gin.Context) {
+	minSeverity := c.Query("severity")
+	if minSeverity != "" && analysis.SeverityRank(minSeverity) == 0 {
+		problem.Respond(c, problem.InvalidParameter("severity", "severity must be one of: low, medium, high, critical"))
+		return
+	}
+	kind := c.Query("kind")
+	statuses := make(map[string]bool)
+	if value := c.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			if !findingStatuses[status] {
+				problem.Respond(c, problem.InvalidParameter("status", "status must be one of: open, acknowledged, snoozed, false-positive, excepted"))
+				return
+			}
+			statuses[status] = true
+		}
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	states, err := h.store.ListFindingStates()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	all, exceptions := h.findings.EvaluateAll(snapshot)
+	tracked := []findings.Tracked{}
+	for _, finding := range findings.Track(all, states, exceptions, time.Now()) {
+		if analysis.SeverityRank(finding.Severity) < analysis.SeverityRank(minSeverity) {
+			continue
+		}
+		if kind != "" && finding.Kind != kind {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[finding.Status] {
+			continue
+		}
+		tracked = append(tracked, finding)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"findings":   tracked,
+	})
+}
+
+// findingStatuses are the statuses a current finding can have; resolved
+// findings are no longer current
+var findingStatuses = map[string]bool{
+	store.FindingOpen:          true,
+	store.FindingAcknowledged:  true,
+	store.FindingSnoozed:       true,
+	store.FindingFalsePositive: true,
+	findings.StatusExcepted:    true,
+}
+
+// findingStateRequest is the body of the finding triage endpoints
+type findingStateRequest struct {
+	ID            string `json:"id" binding:"required"`
+	Assignee      string `json:"assignee"`
+	Until         string `json:"until"`
+	Justification string `json:"justification"`
+	Note          string `json:"note"`
+}
+
+// ListFindingStates handles GET /api/findings/states
+// Lists the triage state of every tracked finding, resolved ones included
+func (h *Handler) ListFindingStates(c *gin.Context) {
+	states, err := h.store.ListFindingStates()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, states)
+}
+
+// AcknowledgeFinding handles POST /api/findings/ack
+// Marks a finding as seen and being handled, with an optional note
+func (h *Handler) AcknowledgeFinding(c *gin.Context) {
+	h.updateFinding(c, func(state *store.FindingState, req findingStateRequest) *problem.Problem {
+		state.Status = store.FindingAcknowledged
+		state.SnoozedUntil = nil
+		state.Note = req.Note
+		return nil
+	})
+}
+
+// AssignFinding handles POST /api/findings/assign
+// Sets who handles a finding without changing its status; an empty assignee
+// unassigns it
+func (h *Handler) AssignFinding(c *gin.Context) {
+	h.updateFinding(c, func(state *store.FindingState, req findingStateRequest) *problem.Problem {
+		state.Assignee = strings.TrimSpace(req.Assignee)
+		return nil
+	})
+}
+
+// SnoozeFinding handles POST /api/findings/snooze
+// Hides a finding from the open findings until a date (YYYY-MM-DD) or
+// RFC 3339 time in the future
+func (h *Handler) SnoozeFinding(c *gin.Context) {
+	h.updateFinding(c, func(state *store.FindingState, req findingStateRequest) *problem.Problem {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			if until, err = time.Parse("2006-01-02", req.Until); err != nil {
+				return problem.InvalidParameter("until", "until must be a date (YYYY-MM-DD) or RFC 3339 time")
+			}
+		}
+		if !until.After(time.Now()) {
+			return problem.InvalidParameter("until", "until must be in the future")
+		}
+		until = until.UTC()
+		state.Status = store.FindingSnoozed
+		state.SnoozedUntil = &until
+		state.Note = req.Note
+		return nil
+	})
+}
+
+// MarkFalsePositive handles POST /api/findings/false-positive
+// Dismisses a finding for good; a justification is required
+func (h *Handler) MarkFalsePositive(c *gin.Context) {
+	h.updateFinding(c, func(state *store.FindingState, req findingStateRequest) *problem.Problem {
+		justification := strings.TrimSpace(req.Justification)
+		if justification == "" {
+			return problem.InvalidParameter("justification", "a justification is required to mark a false positive")
+		}
+		state.Status = store.FindingFalsePositive
+		state.SnoozedUntil = nil
+		state.Justification = justification
+		return nil
+	})
+}
+
+// ReopenFinding handles POST /api/findings/reopen
+// Returns an acknowledged, snoozed, or false-positive finding to open, keeping
+// its assignee
+func (h *Handler) ReopenFinding(c *gin.Context) {
+	h.updateFinding(c, func(state *store.FindingState, req findingStateRequest) *problem.Problem {
+		state.Status = store.FindingOpen
+		state.SnoozedUntil = nil
+		state.Justification = ""
+		state.Note = req.Note
+		return nil
+	})
+}
+
+// updateFinding applies a triage change to the state of a current finding
+// and responds with the new state
+func (h *Handler) updateFinding(c *gin.Context, apply func(*store.FindingState, findingStateRequest) *problem.Problem) {
+	var req findingStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	found := false
+	all, _ := h.findings.EvaluateAll(snapshot)
+	for _, finding := range all {
+		if finding.ID == req.ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		problem.Respond(c, problem.NotFound("no current finding "+req.ID))
+		return
+	}
+
+	state, err := h.store.GetFindingState(req.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		state = &store.FindingState{ID: req.ID, Status: store.FindingOpen}
+	} else if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	// A lapsed snooze or a resolved finding that came back is open again
+	state.Status = findings.Status(*state, time.Now())
+	state.ResolvedAt = nil
+
+	if p := apply(state, req); p != nil {
+		problem.Respond(c, p)
+		return
+	}
+	state.UpdatedBy = middleware.Identity(c, h.cfg.AdminToken)
+	if err := h.store.SetFindingState(state); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// GetAPIKeys handles GET /api/api-keys
+// Lists API keys with their restrictions and the IAM OAuth clients of the project.
+// OAuth clients are optional; failure to list them is reported, not fatal.
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.gcpClient.GetAPIKeys()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	response := gin.H{"keys": keys}
+	if clients, err := h.gcpClient.GetOAuthClients(); err != nil {
+		response["oauthClientsError"] = err.Error()
+	} else {
+		response["oauthClients"] = clients
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDormantServiceAccounts handles GET /api/service-accounts/dormant
+// Lists grant-holding service accounts without recent authentication, with the
+// resources they can access and a suggested disable/remove plan
+func (h *Handler) GetDormantServiceAccounts(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	dormant, err := h.findings.DormantServiceAccounts(snapshot)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId":      snapshot.ID,
+		"inactiveAfter":   h.cfg.DormantSAAfter.String(),
+		"serviceAccounts": dormant,
+	})
+}
+
+// GetDeletedPrincipals handles GET /api/deleted-principals
+// Lists deleted principals still named in resource policies with each
+// binding and the gcloud command that removes it
+func (h *Handler) GetDeletedPrincipals(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"principals": analysis.DeletedPrincipals(snapshot.Matrix),
+	})
+}
+
+// GetDeletedPrincipalPlan handles GET /api/deleted-principals/plan
+// Downloads a shell script that purges every binding of a deleted principal
+func (h *Handler) GetDeletedPrincipalPlan(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	plan := analysis.DeletedPrincipalPlan(analysis.DeletedPrincipals(snapshot.Matrix))
+	c.Header("Content-Disposition", `attachment; filename="purge-deleted-principals.sh"`)
+	c.String(http.StatusOK, plan)
+}
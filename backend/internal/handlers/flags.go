@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// flagsPatch is the body of PUT /api/admin/flags: a value per flag to
+// override, or null to drop the override and fall back to FEATURE_FLAGS or
+// the default
+type flagsPatch struct {
+	Flags map[string]*bool `json:"flags" binding:"required"`
+}
+
+// GetFlags handles GET /api/admin/flags
+// Lists every feature flag with its default, current value, and source
+func (h *Handler) GetFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"flags": h.cfg.Flags.List()})
+}
+
+// UpdateFlags handles PUT /api/admin/flags
+// Overrides flags immediately and persists the overrides across restarts
+func (h *Handler) UpdateFlags(c *gin.Context) {
+	var patch flagsPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	overrides := h.cfg.Flags.Overrides()
+	for name, value := range patch.Flags {
+		if !config.KnownFlag(name) {
+			problem.Respond(c, problem.InvalidParameter("flags", "unknown feature flag %q", name))
+			return
+		}
+		if value == nil {
+			delete(overrides, name)
+		} else {
+			overrides[name] = *value
+		}
+	}
+
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if err := h.store.PutSetting(config.FlagsSettingKey, data); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if err := h.cfg.Flags.SetOverrides(overrides); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": h.cfg.Flags.List()})
+}
+
+// requireFlag responds 501 and returns false when the feature flag is off
+func (h *Handler) requireFlag(c *gin.Context, name string) bool {
+	if h.cfg.Flags.Enabled(name) {
+		return true
+	}
+	problem.Respond(c, problem.New(http.StatusNotImplemented, problem.CodeNotImplemented, "the "+name+" feature is disabled on this deployment"))
+	return false
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListGroups handles GET /api/groups
+// Lists the group principals bound in the current snapshot with member counts
+// and direct vs. inherited grants, largest and most privileged first
+func (h *Handler) ListGroups(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	memberships := h.gcpClient.GetGroupMemberships(snapshot.Matrix)
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"groups":     analysis.SummarizeGroups(snapshot.Matrix, memberships),
+	})
+}
+
+// GetGroup handles GET /api/groups/:email
+// Returns the group's transitive membership as a tree, with the flattened
+// members, nested groups, and any membership loops
+func (h *Handler) GetGroup(c *gin.Context) {
+	if !h.requireFlag(c, config.FlagGroupExpansion) {
+		return
+	}
+	expansion, err := h.gcpClient.ExpandGroup(c.Param("email"))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, expansion)
+}
+
+// GetPeerGroups handles GET /api/peer-groups
+// Computes each peer group's baseline access, the grants held by at least
+// ?baseline= percent of its members (default 50), and reports every member's
+// direct grants above it. ?by=team groups principals by enriched team
+// metadata (the default) and ?by=group by Google group membership; ?name=
+// narrows to one team or group.
+func (h *Handler) GetPeerGroups(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	kind := c.DefaultQuery("by", analysis.PeersByTeam)
+	baseline := 50
+	if value := c.Query("baseline"); value != "" {
+		baseline, _ = strconv.Atoi(value)
+	}
+
+	var memberships map[string][]string
+	if kind == analysis.PeersByGroup {
+		memberships = h.gcpClient.GetGroupMemberships(snapshot.Matrix)
+	}
+	groups := analysis.PeerGroups(snapshot.Matrix, kind, c.Query("name"), memberships, float64(baseline)/100, h.gcpClient.RoleTier)
+	if name := c.Query("name"); name != "" && len(groups) == 0 {
+		problem.Respond(c, problem.NotFound(fmt.Sprintf("no %s peer group %q with at least two members", kind, name)))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"by":         kind,
+		"baseline":   baseline,
+		"groups":     groups,
+	})
+}
+
+// GetDomains handles GET /api/domains
+// Lists domain: principals with everything each grants to every account in
+// the domain, most privileged first
+func (h *Handler) GetDomains(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"domains":    analysis.DomainExposures(snapshot.Matrix),
+	})
+}
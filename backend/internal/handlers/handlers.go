@@ -1,29 +1,79 @@
 package handlers
 
 import (
+	"errors"
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/enrichment"
+	"gcp-access-visualizer/internal/findings"
 	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/gitops"
+	"gcp-access-visualizer/internal/posture"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/rules"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
+	cfg       *config.Config
 	gcpClient *gcp.Client
+	scanner   *scanner.Scanner
+	store     store.Store
+	rules     *rules.Holder
+	scorer    *posture.Scorer
+	findings  *findings.Engine
+
+	enrichmentSource enrichment.Source
+	gitOps           *gitops.Syncer
+	heatmaps         analysis.HeatmapCache
+	searchIndex      searchIndexCache
+	health           healthCache
 }
 
-// NewHandler creates a new handler
-func NewHandler(gcpClient *gcp.Client) *Handler {
+// NewHandler creates a new handler. enrichmentSource and gitOps may be nil.
+func NewHandler(cfg *config.Config, gcpClient *gcp.Client, scanner *scanner.Scanner, store store.Store, ruleSet *rules.Holder, scorer *posture.Scorer, findingsEngine *findings.Engine, enrichmentSource enrichment.Source, gitOps *gitops.Syncer) *Handler {
 	return &Handler{
-		gcpClient: gcpClient,
+		cfg:              cfg,
+		gcpClient:        gcpClient,
+		scanner:          scanner,
+		store:            store,
+		rules:            ruleSet,
+		scorer:           scorer,
+		findings:         findingsEngine,
+		enrichmentSource: enrichmentSource,
+		gitOps:           gitOps,
 	}
 }
 
 // GetUsers handles GET /api/users
+// Each principal carries its blast radius from the current snapshot.
+// Pass ?sort=blastRadius or ?sort=tier to rank the most dangerous principals first.
 func (h *Handler) GetUsers(c *gin.Context) {
 	users, err := h.gcpClient.GetUsers()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		problem.RespondError(c, err)
+		return
+	}
+
+	if profiles, err := h.store.ListProfiles(); err == nil {
+		enrichment.Apply(users, profiles)
+	}
+
+	if snapshot, err := h.scanner.Current(); err == nil {
+		index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+		analysis.ApplyBlastRadius(users, snapshot.Matrix, index)
+	}
+
+	if order := c.Query("sort"); order != "" && !analysis.SortUsers(users, order) {
+		problem.Respond(c, problem.InvalidParameter("sort", "sort must be one of: email, blastRadius, tier"))
 		return
 	}
 
@@ -31,31 +81,111 @@ func (h *Handler) GetUsers(c *gin.Context) {
 }
 
 // GetResources handles GET /api/resources
+// Resources from collectors that failed are missing; /api/access reports why.
 func (h *Handler) GetResources(c *gin.Context) {
-	resources, err := h.gcpClient.GetResources()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	resources, _ := h.gcpClient.GetResources()
 
 	c.JSON(http.StatusOK, resources)
 }
 
 // GetAccess handles GET /api/access
+// Pass ?format=compact to receive index-based entries instead of repeated emails and IDs.
+// Filters can be given directly (project, resourceType, role, principal) or via ?view=<id>.
+// Pass ?asOf=<RFC 3339 time> to rebuild access from the IAM policies each resource
+// held then, read from the Asset Inventory history of the last 35 days.
 func (h *Handler) GetAccess(c *gin.Context) {
-	accessMatrix, err := h.gcpClient.GetAccessMatrix()
+	format := c.DefaultQuery("format", "full")
+	if format != "full" && format != "compact" {
+		problem.Respond(c, problem.InvalidParameter("format", "format must be 'full' or 'compact'"))
+		return
+	}
+
+	filter, err := h.matrixFilter(c)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	matrix, err := h.accessMatrix(c.Query("asOf"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, gcp.ErrAsOfOutOfRange) {
+			problem.Respond(c, problem.InvalidParameter("asOf", "%v", err))
+			return
+		}
+		problem.RespondError(c, err)
+		return
+	}
+	accessMatrix := analysis.FilterMatrix(matrix, filter)
+
+	if format == "compact" {
+		c.JSON(http.StatusOK, accessMatrix.Compact())
 		return
 	}
 
 	c.JSON(http.StatusOK, accessMatrix)
 }
 
-// HealthCheck handles GET /api/health
-func (h *Handler) HealthCheck(c *gin.Context) {
+// GetAccessAggregate handles GET /api/access/aggregate
+// Pivots the grants of the access matrix by ?groupBy= (a comma-separated list
+// of dimensions, e.g. role,resourceType) and returns ?metric= per combination:
+// count (grants, the default), principals, or resources. Accepts the
+// /api/access filters and ?asOf=; ?limit= keeps the largest rows.
+func (h *Handler) GetAccessAggregate(c *gin.Context) {
+	groupBy := c.Query("groupBy")
+	if groupBy == "" {
+		problem.Respond(c, problem.InvalidParameter("groupBy", "groupBy is required"))
+		return
+	}
+	metric := c.DefaultQuery("metric", analysis.AggregateCount)
+
+	filter, err := h.matrixFilter(c)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	matrix, err := h.accessMatrix(c.Query("asOf"))
+	if err != nil {
+		if errors.Is(err, gcp.ErrAsOfOutOfRange) {
+			problem.Respond(c, problem.InvalidParameter("asOf", "%v", err))
+			return
+		}
+		problem.RespondError(c, err)
+		return
+	}
+
+	dimensions := strings.Split(groupBy, ",")
+	rows, ok := analysis.Aggregate(analysis.FilterMatrix(matrix, filter), dimensions, metric, h.gcpClient.RoleTier)
+	if !ok {
+		problem.Respond(c, problem.InvalidParameter("metric", "metric must be one of: count, principals, resources"))
+		return
+	}
+	total := len(rows)
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit < len(rows) {
+		rows = rows[:limit]
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "gcp-access-visualizer",
+		"groupBy": dimensions,
+		"metric":  metric,
+		"total":   total,
+		"rows":    rows,
+	})
+}
+
+// accessMatrix returns the current matrix, or the matrix rebuilt as of asOf
+// when given
+func (h *Handler) accessMatrix(asOf string) (*gcp.AccessMatrix, error) {
+	if asOf == "" {
+		snapshot, err := h.scanner.Current()
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.Matrix, nil
+	}
+	at, _ := time.Parse(time.RFC3339, asOf)
+	_, matrix, err := h.scanner.Derive(func(raw *gcp.AccessMatrix) (*gcp.AccessMatrix, error) {
+		historical, _, err := h.gcpClient.AccessMatrixAsOf(raw, at)
+		return historical, err
 	})
+	return matrix, err
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dependency statuses
+const (
+	dependencyOK       = "ok"
+	dependencyDegraded = "degraded"
+	dependencyDown     = "down"
+)
+
+// healthTimeout bounds each dependency check and healthTTL is how long results
+// are reused, so frequent probes do not call GCP on every request
+const (
+	healthTimeout = 5 * time.Second
+	healthTTL     = 30 * time.Second
+)
+
+// errNoScanYet reports that no snapshot has been scanned since startup
+var errNoScanYet = errors.New("no scan has succeeded yet")
+
+// dependencyHealth is the outcome of checking one dependency
+type dependencyHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Critical dependencies being down makes the service unhealthy (503);
+	// the others only degrade it
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// healthCache holds the latest dependency checks
+type healthCache struct {
+	mu           sync.Mutex
+	checkedAt    time.Time
+	dependencies []dependencyHealth
+}
+
+// HealthCheck handles GET /api/health
+// Reports the status of each dependency: GCP credentials, the Asset Inventory
+// API, the database, and the age of the last successful scan. Responds 503
+// when a critical dependency is down; results are cached for healthTTL.
+func (h *Handler) HealthCheck(c *gin.Context) {
+	checkedAt, dependencies := h.checkDependencies(c.Request.Context())
+
+	status, code := "healthy", http.StatusOK
+	for _, dependency := range dependencies {
+		switch {
+		case dependency.Status == dependencyOK:
+		case dependency.Critical:
+			status, code = "unhealthy", http.StatusServiceUnavailable
+		case status == "healthy":
+			status = "degraded"
+		}
+	}
+	c.JSON(code, gin.H{
+		"status":       status,
+		"service":      "gcp-access-visualizer",
+		"checkedAt":    checkedAt,
+		"dependencies": dependencies,
+	})
+}
+
+// LivenessCheck handles GET /api/health/live
+// Reports only that the process serves requests; restarting it would not fix
+// an unreachable dependency, so liveness probes should use this endpoint
+func (h *Handler) LivenessCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// checkDependencies runs the dependency checks concurrently, or returns the
+// cached results while they are fresh
+func (h *Handler) checkDependencies(ctx context.Context) (time.Time, []dependencyHealth) {
+	h.health.mu.Lock()
+	defer h.health.mu.Unlock()
+	if h.health.dependencies != nil && time.Since(h.health.checkedAt) < healthTTL {
+		return h.health.checkedAt, h.health.dependencies
+	}
+
+	checks := []struct {
+		name     string
+		critical bool
+		check    func(ctx context.Context) (detail string, err error)
+	}{
+		{"gcpCredentials", true, func(ctx context.Context) (string, error) {
+			return "", h.gcpClient.CheckCredentials(ctx)
+		}},
+		{"assetApi", true, func(ctx context.Context) (string, error) {
+			return "", h.gcpClient.CheckAssetAPI(ctx)
+		}},
+		{"database", true, h.checkDatabase},
+		{"scanFreshness", false, h.checkScanFreshness},
+	}
+
+	dependencies := make([]dependencyHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, name string, critical bool, check func(context.Context) (string, error)) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), healthTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(ctx)
+			dependency := dependencyHealth{
+				Name:      name,
+				Status:    dependencyOK,
+				Critical:  critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Detail:    detail,
+			}
+			if err != nil {
+				dependency.Status = dependencyDown
+				if !critical {
+					dependency.Status = dependencyDegraded
+				}
+				dependency.Error = err.Error()
+			}
+			dependencies[i] = dependency
+		}(i, check.name, check.critical, check.check)
+	}
+	wg.Wait()
+
+	h.health.checkedAt, h.health.dependencies = time.Now(), dependencies
+	return h.health.checkedAt, dependencies
+}
+
+// checkDatabase pings stores that support it and otherwise reads a setting
+func (h *Handler) checkDatabase(ctx context.Context) (string, error) {
+	if pinger, ok := h.store.(store.Pinger); ok {
+		return h.cfg.StoreDriver, pinger.Ping(ctx)
+	}
+	if _, err := h.store.GetSetting("health"); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return h.cfg.StoreDriver, err
+	}
+	return h.cfg.StoreDriver, nil
+}
+
+// checkScanFreshness reports the age of the last successful scan. With
+// scheduled scans, a snapshot older than two intervals or a failing project
+// is degraded; with on-demand scans, age alone is not a problem.
+func (h *Handler) checkScanFreshness(ctx context.Context) (string, error) {
+	var lastSuccess time.Time
+	failing := 0
+	for _, status := range h.scanner.Status() {
+		if status.LastSuccess.After(lastSuccess) {
+			lastSuccess = status.LastSuccess
+		}
+		if status.Error != "" {
+			failing++
+		}
+	}
+	if lastSuccess.IsZero() {
+		return "", errNoScanYet
+	}
+
+	age := time.Since(lastSuccess).Round(time.Second)
+	detail := "last successful scan " + age.String() + " ago"
+	if failing > 0 {
+		return detail, fmt.Errorf("the last scan of %d project(s) failed", failing)
+	}
+	if interval := h.cfg.Runtime.ScanInterval(); interval > 0 && age > 2*interval {
+		return detail, fmt.Errorf("no successful scan in %s (scan interval %s)", age, interval)
+	}
+	return detail, nil
+}
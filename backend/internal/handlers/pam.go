@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEntitlements handles GET /api/entitlements
+// Lists the Privileged Access Manager entitlements of the project: who may
+// request which roles on which resource, for how long, and whether requests
+// need approval. eligible repeats the entitlements as access entries from the
+// current snapshot, and standingEligible lists those the principal already
+// holds as standing access.
+func (h *Handler) GetEntitlements(c *gin.Context) {
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	entitlements, err := h.gcpClient.GetEntitlements()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId":       snapshot.ID,
+		"entitlements":     entitlements,
+		"eligible":         snapshot.Matrix.Eligible,
+		"standingEligible": analysis.StandingEligibility(snapshot.Matrix),
+	})
+}
+
+// GetElevations handles GET /api/elevations
+// Returns the Privileged Access Manager grants requested, activated, or ended
+// over the last ?days= days (default 7), read from the audit logs, newest
+// first. ?active=true keeps only grants still in effect.
+func (h *Handler) GetElevations(c *gin.Context) {
+	if !h.requireFlag(c, config.FlagAuditLogOverlay) {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days < 1 {
+		problem.Respond(c, problem.InvalidParameter("days", "days must be a positive integer"))
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	elevations, err := h.gcpClient.GetElevations(since)
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	active := 0
+	kept := elevations[:0]
+	for _, elevation := range elevations {
+		if elevation.Active {
+			active++
+		}
+		if c.Query("active") != "true" || elevation.Active {
+			kept = append(kept, elevation)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":      since,
+		"active":     active,
+		"elevations": kept,
+	})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListProjects handles GET /api/projects
+func (h *Handler) ListProjects(c *gin.Context) {
+	projects, err := h.gcpClient.GetProjects()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProject handles GET /api/projects/:id
+// The id may be a project ID or number.
+func (h *Handler) GetProject(c *gin.Context) {
+	project, err := h.gcpClient.GetProject(c.Param("id"))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
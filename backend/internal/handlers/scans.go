@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetScanEstimate handles GET /api/scans/estimate
+// Predicts the GCP API calls per service of the next scan with the current
+// collector scope, alongside the configured call budget
+func (h *Handler) GetScanEstimate(c *gin.Context) {
+	estimate := h.scanner.Estimate()
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget":         estimate.Budget,
+		"estimated":      estimate.Estimated,
+		"estimatedTotal": estimate.Estimated.Total(),
+		"withinBudget":   estimate.Budget == 0 || estimate.Estimated.Total() <= estimate.Budget,
+	})
+}
+
+// GetScanUsage handles GET /api/scans/:id/usage
+// Returns the GCP API calls per service a scan made, next to its pre-scan estimate.
+// The ID is the snapshotId reported by the analysis endpoints.
+func (h *Handler) GetScanUsage(c *gin.Context) {
+	record, err := h.store.GetUsage(c.Param("id"))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"usage":          record,
+		"total":          gcp.APIUsage(record.Calls).Total(),
+		"estimatedTotal": gcp.APIUsage(record.Estimated).Total(),
+	})
+}
+
+// GetScanStatus handles GET /api/scans/status
+// Summarizes scan coverage per project: when it was last scanned and last
+// scanned successfully, how long it took, the error of a failed attempt, its
+// resource and entry counts, and when the next scheduled scan starts
+func (h *Handler) GetScanStatus(c *gin.Context) {
+	projects := h.scanner.Status()
+	failing := 0
+	for _, project := range projects {
+		if project.Error != "" {
+			failing++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects": projects,
+		"failing":  failing,
+	})
+}
+
+// RunScan handles POST /api/scans
+// Runs a full scan within the request, for schedulers such as Cloud Scheduler
+// in Cloud Run mode, and responds once the scan's listeners (alerts, finding
+// lifecycle, digest) have finished. The scan stops calling GCP at the scan
+// timeout or the request deadline; collectors cut off are reported as warnings.
+func (h *Handler) RunScan(c *gin.Context) {
+	snapshot, err := h.scanner.Scan(requestDeadline(c))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.respondScan(c, snapshot, nil)
+}
+
+// scanTrigger selects what POST /api/scans/trigger scans
+type scanTrigger struct {
+	// Projects to rescan by ID, number, or "projects/..." name; all when empty
+	Projects []string `json:"projects"`
+}
+
+// TriggerScan handles POST /api/scans/trigger
+// Scans on behalf of an external orchestrator authenticated with a Google OIDC
+// token: Cloud Scheduler posting a scanTrigger as JSON, or a Pub/Sub push
+// subscription delivering one as message data. Listed projects are rescanned
+// into the current snapshot, an empty list scans everything. Responds like
+// POST /api/scans; Pub/Sub redelivers messages that get an error.
+func (h *Handler) TriggerScan(c *gin.Context) {
+	var body struct {
+		scanTrigger
+		// Message is set on Pub/Sub push deliveries; Data is base64 in JSON
+		Message *struct {
+			Data      []byte `json:"data"`
+			MessageID string `json:"messageId"`
+		} `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+	trigger := body.scanTrigger
+	if body.Message != nil && len(body.Message.Data) > 0 {
+		if err := json.Unmarshal(body.Message.Data, &trigger); err != nil {
+			problem.Respond(c, problem.InvalidParameter("message.data", "message %s is not a scan trigger: %v", body.Message.MessageID, err))
+			return
+		}
+	}
+
+	var snapshot *scanner.Snapshot
+	var err error
+	if len(trigger.Projects) == 0 {
+		snapshot, err = h.scanner.Scan(requestDeadline(c))
+	} else {
+		snapshot, err = h.scanner.ScanProjects(trigger.Projects, requestDeadline(c))
+	}
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	h.respondScan(c, snapshot, trigger.Projects)
+}
+
+// requestDeadline returns the deadline of the request, or the zero time
+func requestDeadline(c *gin.Context) time.Time {
+	deadline, _ := c.Request.Context().Deadline()
+	return deadline
+}
+
+// respondScan waits for the listeners of a scan, or the end of the request,
+// and reports the scan
+func (h *Handler) respondScan(c *gin.Context, snapshot *scanner.Snapshot, projects []string) {
+	notified := true
+	select {
+	case <-snapshot.Notified():
+	case <-c.Request.Context().Done():
+		notified = false
+	}
+
+	response := gin.H{
+		"snapshotId": snapshot.ID,
+		"takenAt":    snapshot.TakenAt,
+		"duration":   snapshot.Duration,
+		"usage":      snapshot.Usage,
+		"warnings":   snapshot.Matrix.Warnings,
+		"notified":   notified,
+	}
+	if len(projects) > 0 {
+		response["projects"] = projects
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Rescan handles POST /api/rescan
+// Refreshes the IAM policies of one resource, or every policy that mentions a
+// principal, and patches the cached matrix instead of running a full scan
+func (h *Handler) Rescan(c *gin.Context) {
+	var req struct {
+		Resource  string `json:"resource"`  // full resource name
+		Principal string `json:"principal"` // email
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+	if (req.Resource == "") == (req.Principal == "") {
+		problem.Respond(c, problem.InvalidParameter("", "exactly one of resource or principal is required"))
+		return
+	}
+
+	var result *gcp.RescanResult
+	snapshot, err := h.scanner.Patch(func(raw *gcp.AccessMatrix) (*gcp.AccessMatrix, error) {
+		var patched *gcp.AccessMatrix
+		var err error
+		if req.Resource != "" {
+			patched, result, err = h.gcpClient.RescanResource(raw, req.Resource)
+		} else {
+			patched, result, err = h.gcpClient.RescanPrincipal(raw, req.Principal)
+		}
+		return patched, err
+	})
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"patchedAt":  snapshot.PatchedAt,
+		"rescan":     result,
+	})
+}
+
+// ImportPolicies handles POST /api/admin/import
+// Builds the matrix from an Asset Inventory IAM policy export (NDJSON from
+// `gcloud asset export --content-type=iam-policy`, as multipart field "file"
+// or the raw request body) without calling GCP, and serves it in place of
+// scans until DELETE /api/admin/import
+func (h *Handler) ImportPolicies(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	matrix, result, err := h.gcpClient.ImportIAMPolicies(body)
+	if err != nil {
+		if errors.Is(err, gcp.ErrInvalidImport) {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		problem.RespondError(c, err)
+		return
+	}
+	snapshot := h.scanner.Import(matrix, result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"import":     result,
+		"users":      len(matrix.Users),
+		"resources":  len(matrix.Resources),
+		"entries":    len(matrix.Access),
+	})
+}
+
+// ClearImport handles DELETE /api/admin/import
+// Drops the imported matrix; the next request scans GCP again
+func (h *Handler) ClearImport(c *gin.Context) {
+	if !h.scanner.ClearImport() {
+		problem.Respond(c, problem.NotFound("no import is being served"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
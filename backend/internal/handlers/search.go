@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchPolicies handles GET /api/search
+// Looks up IAM bindings directly in Asset Inventory, without the cached matrix,
+// by role, principal, memberType, permission, resource, and assetType.
+// Filters are validated and combined; at least one is required.
+func (h *Handler) SearchPolicies(c *gin.Context) {
+	search := gcp.PolicySearch{
+		Role:       c.Query("role"),
+		Principal:  c.Query("principal"),
+		MemberType: c.Query("memberType"),
+		Permission: c.Query("permission"),
+		Resource:   c.Query("resource"),
+		AssetType:  c.Query("assetType"),
+		Project:    c.Query("project"),
+	}
+	search.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	query, err := search.Query()
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+	matches, truncated, err := h.gcpClient.SearchIAMPolicies(search)
+	if err != nil {
+		if errors.Is(err, gcp.ErrInvalidSearch) {
+			problem.Respond(c, problem.InvalidParameter("", "%v", err))
+			return
+		}
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":     query,
+		"results":   matches,
+		"truncated": truncated,
+	})
+}
+
+// GlobalSearch handles GET /api/search/global?q=
+// Searches the principals, resources, roles, and projects of the current
+// snapshot by name, email, title, or label, best matches first, each with a
+// link to its details. ?type= restricts the result type; ?limit= defaults to 20.
+func (h *Handler) GlobalSearch(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		problem.Respond(c, problem.InvalidParameter("q", "q is required"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	var types []string
+	if value := c.Query("type"); value != "" {
+		types = []string{value}
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": h.searchIndex.Get(snapshot, h.buildSearchIndex).Search(query, types, limit),
+	})
+}
+
+// SearchIndexListener indexes every new snapshot ahead of the first search
+func (h *Handler) SearchIndexListener() scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		h.searchIndex.Get(current, h.buildSearchIndex)
+	}
+}
+
+// buildSearchIndex indexes the snapshot together with the scanned projects;
+// projects are left out when their metadata cannot be read
+func (h *Handler) buildSearchIndex(snapshot *scanner.Snapshot) *analysis.SearchIndex {
+	projects, err := h.gcpClient.GetProjects()
+	if err != nil {
+		log.Printf("Warning: search index built without projects: %v", err)
+	}
+	roleTitle := func(role string) string {
+		return h.gcpClient.GetRole(role).Title
+	}
+	return analysis.NewSearchIndex(snapshot.Matrix, projects, roleTitle)
+}
+
+// searchIndexCache holds the search index of the latest snapshot
+type searchIndexCache struct {
+	mu         sync.Mutex
+	snapshotID string
+	index      *analysis.SearchIndex
+}
+
+// Get returns the snapshot's index, building it on first use
+func (c *searchIndexCache) Get(snapshot *scanner.Snapshot, build func(*scanner.Snapshot) *analysis.SearchIndex) *analysis.SearchIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index == nil || c.snapshotID != snapshot.ID {
+		c.snapshotID = snapshot.ID
+		c.index = build(snapshot)
+	}
+	return c.index
+}
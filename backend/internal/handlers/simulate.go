@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SimulateRemovePrincipal handles GET /api/simulate/remove-principal?email=
+// Reports what breaks if the principal is removed, without changing anything
+func (h *Handler) SimulateRemovePrincipal(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		problem.Respond(c, problem.InvalidParameter("email", "email is required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"impact":     analysis.SimulateRemovePrincipal(snapshot.Matrix, email),
+	})
+}
+
+// SimulateRemoveBinding handles GET /api/simulate/remove-binding?principal=&role=&resource=
+// Reports whether the principal still reaches the resource through another path
+// (group membership, inheritance, impersonation) once the binding is revoked
+func (h *Handler) SimulateRemoveBinding(c *gin.Context) {
+	binding := analysis.Binding{
+		Principal:  c.Query("principal"),
+		Role:       c.Query("role"),
+		ResourceID: c.Query("resource"),
+	}
+	if binding.Principal == "" || binding.Role == "" || binding.ResourceID == "" {
+		problem.Respond(c, problem.InvalidParameter("", "principal, role, and resource are required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	index := analysis.NewPathIndex(snapshot.Matrix, h.gcpClient.GetGroupMemberships(snapshot.Matrix))
+	removal := analysis.SimulateRemoveBinding(snapshot.Matrix, index, binding)
+	if !removal.Exists {
+		problem.Respond(c, problem.NotFound("binding not found in the current snapshot"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"removal":    removal,
+	})
+}
+
+// SimulateGrant handles GET /api/simulate/grant?principal=&role=&resource=
+// Shows the resources and privilege tiers a proposed binding would add and the
+// watchlist and separation-of-duties rules it would trip. resource may be the project.
+func (h *Handler) SimulateGrant(c *gin.Context) {
+	binding := analysis.Binding{
+		Principal:  c.Query("principal"),
+		Role:       c.Query("role"),
+		ResourceID: c.Query("resource"),
+	}
+	if binding.Principal == "" || binding.Role == "" || binding.ResourceID == "" {
+		problem.Respond(c, problem.InvalidParameter("", "principal, role, and resource are required"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	if !hasResource(snapshot.Matrix, binding.ResourceID) {
+		problem.Respond(c, problem.NotFound("resource not found in the current snapshot"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"impact":     analysis.SimulateGrant(snapshot.Matrix, binding, h.gcpClient.RoleTier(binding.Role), h.rules.Get().SoD, h.cfg.Runtime.WatchlistRoles()),
+	})
+}
+
+// checkRequest is the body of POST /api/check
+type checkRequest struct {
+	Bindings []checkBinding `json:"bindings" binding:"required,min=1,dive"`
+	// MaxTier fails bindings granting a higher tier; empty allows any
+	MaxTier string `json:"maxTier" binding:"omitempty,oneof=read write admin owner"`
+}
+
+// checkBinding is one proposed binding. principal may carry a "user:" style
+// prefix; resource is a full resource name or, for a project, its ID or number.
+type checkBinding struct {
+	Principal string `json:"principal" binding:"required"`
+	Role      string `json:"role" binding:"required"`
+	Resource  string `json:"resource" binding:"required"`
+}
+
+// CheckBindings handles POST /api/check
+// Checks a proposed set of bindings, e.g. extracted from a Terraform plan in
+// CI, before they are applied: the separation-of-duties conflicts and toxic
+// combinations they would introduce, watchlisted roles, bindings outside the
+// baseline (when one is defined), and the access they add. The response is
+// 200 either way; "passed" is the gate.
+func (h *Handler) CheckBindings(c *gin.Context) {
+	var req checkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+	baseline, err := h.storedBaseline()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	bindings := make([]analysis.Binding, 0, len(req.Bindings))
+	for _, proposed := range req.Bindings {
+		resource := proposed.Resource
+		if !hasResource(snapshot.Matrix, resource) {
+			if project, ok := h.gcpClient.ScannedProjectID(resource); ok {
+				resource = "//cloudresourcemanager.googleapis.com/projects/" + project
+			}
+		}
+		bindings = append(bindings, analysis.Binding{
+			Principal:  gcp.ParseMember(proposed.Principal).Email,
+			Role:       proposed.Role,
+			ResourceID: resource,
+		})
+	}
+
+	ruleSet := h.rules.Get()
+	c.JSON(http.StatusOK, gin.H{
+		"snapshotId": snapshot.ID,
+		"result": analysis.CheckBindings(snapshot.Matrix, bindings, analysis.CheckPolicy{
+			Tier: h.gcpClient.RoleTier,
+			Permissions: func(role string) []string {
+				return h.gcpClient.GetRole(role).Permissions
+			},
+			SoD:       ruleSet.SoD,
+			Toxic:     ruleSet.Toxic,
+			Watchlist: h.cfg.Runtime.WatchlistRoles(),
+			Baseline:  baseline,
+			MaxTier:   req.MaxTier,
+		}),
+	})
+}
+
+// hasResource reports whether the matrix contains the resource
+func hasResource(matrix *gcp.AccessMatrix, resourceID string) bool {
+	for _, res := range matrix.Resources {
+		if res.ID == resourceID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/enrichment"
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconcileTerminatedUsers handles POST /api/terminated-users
+// Accepts a CSV of terminated employee emails (multipart field "file" or the
+// raw request body; an email column, or one email per line) and returns the
+// bindings, group memberships, and attributed service account keys each still
+// holds, with a remediation plan. Group membership is only followed with
+// ?expandGroups=true, which reads every bound group from Cloud Identity.
+func (h *Handler) ReconcileTerminatedUsers(c *gin.Context) {
+	var body io.Reader = c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			problem.Respond(c, problem.InvalidParameter("file", "%v", err))
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	emails, err := enrichment.ParseEmailList(body)
+	if err != nil {
+		problem.Respond(c, problem.InvalidParameter("file", "%v", err))
+		return
+	}
+	if len(emails) == 0 {
+		problem.Respond(c, problem.InvalidParameter("file", "the list holds no emails"))
+		return
+	}
+
+	snapshot, err := h.scanner.Current()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	response := gin.H{"snapshotId": snapshot.ID, "checked": len(emails)}
+	var memberships map[string][]string
+	if c.Query("expandGroups") == "true" {
+		memberships = h.gcpClient.GetGroupMemberships(snapshot.Matrix)
+	}
+	response["groupsExpanded"] = memberships != nil
+	keys, err := h.gcpClient.GetServiceAccountKeys()
+	if err != nil {
+		response["keysError"] = err.Error()
+	}
+
+	users := analysis.TerminatedUsers(snapshot.Matrix, emails, memberships, keys)
+	response["users"] = users
+	response["clean"] = len(emails) - len(users)
+	c.JSON(http.StatusOK, response)
+}
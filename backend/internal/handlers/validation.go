@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"strings"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/favorites"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/middleware"
+)
+
+// QueryRules validates the query parameters the handlers accept. Parameters
+// keep one meaning across endpoints, so a single table covers every route.
+var QueryRules = middleware.Rules{
+	"email":        middleware.Email,
+	"principal":    middleware.Principal,
+	"resource":     middleware.ResourceID,
+	"role":         middleware.Role,
+	"project":      middleware.Project,
+	"permission":   middleware.Permission,
+	"assetType":    middleware.AssetType,
+	"memberType":   middleware.Enum(gcp.MemberTypes...),
+	"view":         middleware.Identifier,
+	"format":       middleware.Enum("full", "compact"),
+	"sort":         middleware.Enum(analysis.SortByEmail, analysis.SortByBlastRadius, analysis.SortByTier),
+	"severity":     middleware.Enum(analysis.SeverityLow, analysis.SeverityMedium, analysis.SeverityHigh, analysis.SeverityCritical),
+	"framework":    middleware.Enum("soc2", "iso27001"),
+	"metric":       middleware.Enum(analysis.TopPrincipals, analysis.TopRoles, analysis.TopResources, analysis.TopOwners, analysis.TopRegions, analysis.AggregateCount),
+	"groupBy":      groupBy,
+	"expandGroups": middleware.Enum("true", "false"),
+	"by":           middleware.Enum(analysis.PeersByTeam, analysis.PeersByGroup),
+	"rows":         middleware.Enum(analysis.HeatmapByType, analysis.HeatmapByTeam),
+	"type":         middleware.Enum(analysis.ResultTypes...),
+	"kind":         middleware.Enum(favorites.KindPrincipal, favorites.KindResource),
+	"limit":        middleware.IntRange(1, 1000),
+	"bundle":       middleware.IntRange(0, 1000000),
+	"days":         middleware.IntRange(1, 3650),
+	"baseline":     middleware.IntRange(1, 100),
+	"since":        middleware.Timestamp,
+	"until":        middleware.Timestamp,
+	"at":           middleware.Timestamp,
+	"asOf":         middleware.Timestamp,
+}
+
+// groupBy accepts a comma-separated list of aggregation dimensions
+var groupBy = middleware.Check{
+	Valid: analysis.ValidGroupBy,
+	Want:  "a comma-separated list of distinct dimensions: " + strings.Join(analysis.AggregateDimensions, ", "),
+}
+
+// PathRules validates route parameters: project, view, and snapshot IDs, and
+// service account emails
+var PathRules = middleware.Rules{
+	"id":    middleware.Identifier,
+	"email": middleware.Email,
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gcp-access-visualizer/internal/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersion handles GET /api/version
+// Reports the build (version, commit, build date) and the configuration it
+// runs with: the collectors the next scan runs, the optional features that
+// are enabled, the feature flags, the run mode, and the store driver, so support can
+// confirm what a deployment is running without access to its environment
+func (h *Handler) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"build":       buildinfo.Get(),
+		"collectors":  h.gcpClient.ActiveCollectors(),
+		"features":    h.cfg.Features(),
+		"flags":       h.cfg.Flags.Values(),
+		"runMode":     h.cfg.RunMode,
+		"storeDriver": h.cfg.StoreDriver,
+		"redaction":   h.cfg.Runtime.Redaction(),
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/problem"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// savedViewRequest is the body accepted when creating or updating a saved view
+type savedViewRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Filters     store.ViewFilters `json:"filters"`
+}
+
+// bindViewRequest parses the body of a view request with its name trimmed,
+// responding with a problem when it is invalid or the name is blank
+func bindViewRequest(c *gin.Context) (*savedViewRequest, bool) {
+	var req savedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidParameter("", "%v", err))
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		problem.Respond(c, problem.InvalidParameter("name", "name must not be blank"))
+		return nil, false
+	}
+	return &req, true
+}
+
+// ListViews handles GET /api/views
+func (h *Handler) ListViews(c *gin.Context) {
+	views, err := h.store.ListViews()
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// GetView handles GET /api/views/:id
+func (h *Handler) GetView(c *gin.Context) {
+	view, err := h.store.GetView(c.Param("id"))
+	if err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// CreateView handles POST /api/views
+func (h *Handler) CreateView(c *gin.Context) {
+	req, ok := bindViewRequest(c)
+	if !ok {
+		return
+	}
+
+	view := &store.SavedView{
+		Name:        req.Name,
+		Description: req.Description,
+		Filters:     req.Filters,
+	}
+	if err := h.store.SaveView(view); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateView handles PUT /api/views/:id
+func (h *Handler) UpdateView(c *gin.Context) {
+	req, ok := bindViewRequest(c)
+	if !ok {
+		return
+	}
+
+	view := &store.SavedView{
+		ID:          c.Param("id"),
+		Name:        req.Name,
+		Description: req.Description,
+		Filters:     req.Filters,
+	}
+	if err := h.store.SaveView(view); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView handles DELETE /api/views/:id
+func (h *Handler) DeleteView(c *gin.Context) {
+	if err := h.store.DeleteView(c.Param("id")); err != nil {
+		problem.RespondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// matrixFilter builds an access matrix filter from the request.
+// A ?view=<id> parameter loads a saved view; explicit filter parameters override its fields.
+func (h *Handler) matrixFilter(c *gin.Context) (analysis.Filter, error) {
+	var filter analysis.Filter
+
+	if id := c.Query("view"); id != "" {
+		view, err := h.store.GetView(id)
+		if err != nil {
+			return filter, err
+		}
+		filter = analysis.Filter(view.Filters)
+	}
+
+	if value := c.Query("project"); value != "" {
+		filter.Project = value
+	}
+	// Resource IDs carry the project ID, also when the filter names the number
+	if projectID, ok := h.gcpClient.ScannedProjectID(filter.Project); ok {
+		filter.Project = projectID
+	}
+	if value := c.Query("resourceType"); value != "" {
+		filter.ResourceType = value
+	}
+	if value := c.Query("role"); value != "" {
+		filter.Role = value
+	}
+	if value := c.Query("principal"); value != "" {
+		filter.Principal = value
+	}
+	if value := c.Query("location"); value != "" {
+		filter.Location = value
+	}
+
+	return filter, nil
+}
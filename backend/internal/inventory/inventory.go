@@ -0,0 +1,86 @@
+// Package inventory reads the live GCP inventories that findings and scores
+// need beyond the access matrix (API keys, service account keys, service
+// accounts and their activity) once per snapshot. The scan listeners and the
+// finding and score endpoints evaluate the same snapshot many times; listing
+// keys makes a call per service account, so repeating it each time spent the
+// IAM quota.
+package inventory
+
+import (
+	"sync"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/scanner"
+)
+
+// lazy holds a value fetched at most once
+type lazy[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+// get returns the value, fetching it on the first call. Errors are kept as
+// well, so a disabled API is not retried until the next snapshot.
+func (l *lazy[T]) get(fetch func() (T, error)) (T, error) {
+	l.once.Do(func() {
+		l.value, l.err = fetch()
+	})
+	return l.value, l.err
+}
+
+// inventories are the inventories read for one snapshot
+type inventories struct {
+	apiKeys            lazy[[]gcp.APIKey]
+	serviceAccountKeys lazy[[]gcp.ServiceAccountKey]
+	serviceAccounts    lazy[[]gcp.ServiceAccount]
+	activity           lazy[[]gcp.ServiceAccountActivity]
+}
+
+// Cache keeps the inventories of the most recent snapshot they were read for
+type Cache struct {
+	client *gcp.Client
+
+	mu         sync.Mutex
+	snapshotID string
+	current    *inventories
+}
+
+// New creates a cache that reads inventories with client
+func New(client *gcp.Client) *Cache {
+	return &Cache{client: client}
+}
+
+// forSnapshot returns the inventories of a snapshot, dropping those of the
+// previous one
+func (c *Cache) forSnapshot(snapshot *scanner.Snapshot) *inventories {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current == nil || c.snapshotID != snapshot.ID {
+		c.snapshotID = snapshot.ID
+		c.current = &inventories{}
+	}
+	return c.current
+}
+
+// APIKeys returns the API keys of the project as of the snapshot
+func (c *Cache) APIKeys(snapshot *scanner.Snapshot) ([]gcp.APIKey, error) {
+	return c.forSnapshot(snapshot).apiKeys.get(c.client.GetAPIKeys)
+}
+
+// ServiceAccountKeys returns the user-managed service account keys as of the snapshot
+func (c *Cache) ServiceAccountKeys(snapshot *scanner.Snapshot) ([]gcp.ServiceAccountKey, error) {
+	return c.forSnapshot(snapshot).serviceAccountKeys.get(c.client.GetServiceAccountKeys)
+}
+
+// ServiceAccounts returns the service accounts of the project as of the snapshot
+func (c *Cache) ServiceAccounts(snapshot *scanner.Snapshot) ([]gcp.ServiceAccount, error) {
+	return c.forSnapshot(snapshot).serviceAccounts.get(c.client.GetServiceAccounts)
+}
+
+// ServiceAccountActivity returns the authentication activity of the service
+// accounts as of the snapshot
+func (c *Cache) ServiceAccountActivity(snapshot *scanner.Snapshot) ([]gcp.ServiceAccountActivity, error) {
+	return c.forSnapshot(snapshot).activity.get(c.client.GetServiceAccountActivity)
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// iapUserHeader carries the signed-in user behind Identity-Aware Proxy, e.g.
+// "accounts.google.com:alice@example.com". Any client can set it, so it is
+// only recorded as an unverified claim; see VerifyIAP.
+const iapUserHeader = "X-Goog-Authenticated-User-Email"
+
+// Audit records every API call with its caller, route, query parameters, and
+// response, including rejected requests. Health checks and CORS preflights are
+// not recorded. A failed write is logged and does not fail the request.
+func Audit(st store.Store, adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if c.Request.Method == http.MethodOptions || strings.HasPrefix(c.FullPath(), "/api/health") {
+			return
+		}
+
+		event := store.AuditEvent{
+			Time:     start.UTC(),
+			Actor:    Actor(c, adminToken),
+			ClientIP: c.ClientIP(),
+			Method:   c.Request.Method,
+			Route:    c.FullPath(),
+			Path:     c.Request.URL.Path,
+			Status:   c.Writer.Status(),
+			Bytes:    max(c.Writer.Size(), 0),
+			Export:   exportName(c.Writer.Header().Get("Content-Disposition")),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			event.Query = make(map[string]string, len(query))
+			for name, values := range query {
+				event.Query[name] = strings.Join(values, ",")
+			}
+		}
+		if err := st.AppendAudit(event); err != nil {
+			log.Printf("Warning: failed to record audit event: %v", err)
+		}
+	}
+}
+
+// Actor names the caller in audit records: its verified Identity, else the
+// IAP user it claims marked "unverified:", else "anonymous". Unverified
+// callers are told apart by the client IP recorded alongside.
+func Actor(c *gin.Context, adminToken string) string {
+	if identity := Identity(c, adminToken); identity != "" {
+		return identity
+	}
+	if user := c.GetHeader(iapUserHeader); user != "" {
+		return "unverified:" + strings.TrimPrefix(user, "accounts.google.com:")
+	}
+	return "anonymous"
+}
+
+// exportName returns the file name of an attachment response, or ""
+func exportName(disposition string) string {
+	kind, params, err := mime.ParseMediaType(disposition)
+	if err != nil || kind != "attachment" {
+		return ""
+	}
+	if name := params["filename"]; name != "" {
+		return name
+	}
+	return "attachment"
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/api/idtoken"
+)
+
+// oidcEmailKey is the context key holding the email of a verified OIDC token
+const oidcEmailKey = "oidcEmail"
+
+// iapAssertionHeader carries the JWT Identity-Aware Proxy signs for every
+// request it lets through; iapIssuer is the issuer of those JWTs
+const (
+	iapAssertionHeader = "X-Goog-IAP-JWT-Assertion"
+	iapIssuer          = "https://cloud.google.com/iap"
+)
+
+// iapEmailKey is the context key holding the email of a verified IAP assertion
+const iapEmailKey = "iapEmail"
+
+// VerifyIAP identifies IAP users by the signed assertion IAP adds to their
+// requests for audience. Unlike the plain user header, it cannot be forged by
+// a client that reaches the server without going through the proxy. Requests
+// without a valid assertion go on unidentified; an empty audience identifies
+// no one.
+func VerifyIAP(audience string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if assertion := c.GetHeader(iapAssertionHeader); audience != "" && assertion != "" {
+			payload, err := idtoken.Validate(c.Request.Context(), assertion, audience)
+			switch {
+			case err != nil:
+				log.Printf("Warning: rejected IAP assertion from %s: %v", c.ClientIP(), err)
+			case payload.Issuer != iapIssuer:
+				log.Printf("Warning: rejected IAP assertion from %s: issued by %q", c.ClientIP(), payload.Issuer)
+			default:
+				if email, _ := payload.Claims["email"].(string); email != "" {
+					c.Set(iapEmailKey, email)
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireIdentity rejects requests from callers without a verified Identity,
+// for routes that record who made a change
+func RequireIdentity(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Identity(c, adminToken) == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			problem.Respond(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "requires a verified IAP user or the admin token"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Identity returns the verified identity of the caller: the IAP user of a
+// verified assertion, the service account of a verified OIDC token, or
+// "admin" for a valid admin token. It is empty for everyone else.
+func Identity(c *gin.Context, adminToken string) string {
+	if email := c.GetString(iapEmailKey); email != "" {
+		return email
+	}
+	if email := c.GetString(oidcEmailKey); email != "" {
+		return email
+	}
+	provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if adminToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1 {
+		return "admin"
+	}
+	return ""
+}
+
+// RequireToken rejects requests without "Authorization: Bearer <token>". An
+// empty token disables the protected routes entirely rather than leaving them open.
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			problem.Respond(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "admin API is disabled; set ADMIN_TOKEN to enable it"))
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			problem.Respond(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "invalid or missing admin token"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOIDC rejects requests without a Google-signed OIDC ID token for
+// audience in "Authorization: Bearer <token>", as sent by Cloud Scheduler and
+// Pub/Sub push subscriptions. The token's verified email must be one of
+// principals: any Google account can mint a token for an audience, so an empty
+// audience or principals list disables the protected routes.
+func RequireOIDC(audience string, principals []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if audience == "" || len(principals) == 0 {
+			problem.Respond(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "scan triggers are disabled; set SCAN_TRIGGER_AUDIENCE and SCAN_TRIGGER_SERVICE_ACCOUNTS to enable them"))
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			problem.Respond(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "missing OIDC token"))
+			return
+		}
+		payload, err := idtoken.Validate(c.Request.Context(), token, audience)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			problem.Respond(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, fmt.Sprintf("invalid OIDC token: %v", err)))
+			return
+		}
+
+		email, _ := payload.Claims["email"].(string)
+		verified, _ := payload.Claims["email_verified"].(bool)
+		if !verified || !slices.Contains(principals, email) {
+			problem.Respond(c, problem.New(http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("%q may not trigger scans", email)))
+			return
+		}
+		c.Set(oidcEmailKey, email)
+		c.Next()
+	}
+}
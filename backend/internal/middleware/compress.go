@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// compressWriter wraps gin's ResponseWriter and routes the body through an
+// encoder. The encoder starts with the first write, once the status is known:
+// responses that carry no body (204, 304, 1xx) are passed through untouched.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	encoder  io.WriteCloser
+	// passthrough is set once the response is known not to be compressed
+	passthrough bool
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.encoder == nil && !w.passthrough {
+		w.start()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// start creates the encoder, or falls back to passing the body through when
+// the status carries no body or the headers were already sent
+func (w *compressWriter) start() {
+	if w.ResponseWriter.Written() || !bodyAllowed(w.Status()) {
+		w.passthrough = true
+		return
+	}
+
+	// The compressed length is unknown until the encoder is flushed
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", w.encoding)
+	switch w.encoding {
+	case "br":
+		w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+	default:
+		w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+	}
+}
+
+// close flushes the encoder, if the response was compressed
+func (w *compressWriter) close() {
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// bodyAllowed reports whether a response with the status may carry a body
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// Compress returns a middleware that compresses responses with brotli or gzip,
+// depending on what the client advertises in Accept-Encoding
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = writer
+		defer writer.close()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the preferred supported encoding from an Accept-Encoding header
+func negotiateEncoding(header string) string {
+	supported := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		// Honour explicit refusals such as "gzip;q=0" or "br; q=0.0"
+		if refused(fields[1:]) {
+			continue
+		}
+		supported[name] = true
+	}
+
+	switch {
+	case supported["br"]:
+		return "br"
+	case supported["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// refused reports whether the parameters of an Accept-Encoding entry give it
+// a quality of zero; an unparsable quality counts as a refusal
+func refused(params []string) bool {
+	for _, param := range params {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+		quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err != nil || quality <= 0
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// clientIdleTTL is how long an idle client's rate limiter is kept
+const clientIdleTTL = 10 * time.Minute
+
+// Guard protects expensive endpoints, the ones that read the access matrix or
+// call GCP directly, from refresh loops: each client gets a token bucket and
+// a global limit caps how many of these requests run at once
+type Guard struct {
+	limit rate.Limit
+	burst int
+	slots chan struct{}
+
+	mu        sync.Mutex
+	clients   map[string]*guardClient
+	lastSweep time.Time
+}
+
+// guardClient is the token bucket of one client IP
+type guardClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewGuard allows each client perMinute requests with bursts of up to burst, and
+// at most concurrency requests in flight overall. Zero disables either limit.
+func NewGuard(perMinute, burst, concurrency int) *Guard {
+	g := &Guard{
+		limit:   rate.Inf,
+		clients: make(map[string]*guardClient),
+	}
+	if perMinute > 0 {
+		g.limit = rate.Limit(float64(perMinute) / 60)
+		g.burst = max(burst, 1)
+	}
+	if concurrency > 0 {
+		g.slots = make(chan struct{}, concurrency)
+	}
+	return g
+}
+
+// Handler returns the middleware. Throttled clients get a 429 and requests
+// beyond the concurrency limit a 503, both retryable with Retry-After.
+func (g *Guard) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wait, ok := g.allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			p := problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "too many requests to this endpoint; slow down the refresh rate")
+			p.Retryable = true
+			problem.Respond(c, p)
+			return
+		}
+
+		if g.slots != nil {
+			select {
+			case g.slots <- struct{}{}:
+				defer func() { <-g.slots }()
+			default:
+				c.Header("Retry-After", "1")
+				p := problem.New(http.StatusServiceUnavailable, problem.CodeOverloaded, "too many expensive requests in flight; retry shortly")
+				p.Retryable = true
+				problem.Respond(c, p)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// allow takes a token from the client's bucket, or returns how long until one is available
+func (g *Guard) allow(client string) (time.Duration, bool) {
+	if g.limit == rate.Inf {
+		return 0, true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(g.lastSweep) > clientIdleTTL {
+		for key, state := range g.clients {
+			if now.Sub(state.lastSeen) > clientIdleTTL {
+				delete(g.clients, key)
+			}
+		}
+		g.lastSweep = now
+	}
+
+	state, ok := g.clients[client]
+	if !ok {
+		state = &guardClient{limiter: rate.NewLimiter(g.limit, g.burst)}
+		g.clients[client] = state
+	}
+	state.lastSeen = now
+
+	reservation := state.limiter.ReserveN(now, 1)
+	if wait := reservation.DelayFrom(now); wait > 0 {
+		reservation.CancelAt(now)
+		return wait, false
+	}
+	return 0, true
+}
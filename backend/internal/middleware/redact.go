@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"mime"
+
+	"gcp-access-visualizer/internal/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maskKey is the context key holding the masking function of the request's
+// redaction mode
+const maskKey = "redactMask"
+
+// redactWriter holds the response body back until it has been masked
+type redactWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *redactWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *redactWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Redact masks principal emails in JSON, CSV, and text responses while mode
+// returns partial or hash. Hashed emails in query and path parameters are
+// mapped back first, so masked principals can be used as filters.
+func Redact(masker *redact.Masker, mode func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := mode()
+		if current == "" || current == redact.ModeOff {
+			c.Next()
+			return
+		}
+
+		unmaskParams(c, masker)
+		c.Set(maskKey, func(text []byte) []byte { return masker.MaskText(current, text) })
+
+		writer := &redactWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if maskable(c.Writer.Header().Get("Content-Type")) {
+			body = masker.MaskText(current, body)
+		}
+		c.Writer.Header().Del("Content-Length")
+		c.Writer.WriteHeaderNow()
+		c.Writer.Write(body)
+	}
+}
+
+// MaskBytes masks principal emails in content as Redact masks response
+// bodies, for handlers responding with formats it cannot read, such as
+// archives. Without redaction, content is returned as it is.
+func MaskBytes(c *gin.Context, content []byte) []byte {
+	if value, ok := c.Get(maskKey); ok {
+		return value.(func([]byte) []byte)(content)
+	}
+	return content
+}
+
+// unmaskParams replaces hashed emails in the query string and path parameters
+func unmaskParams(c *gin.Context, masker *redact.Masker) {
+	query := c.Request.URL.Query()
+	changed := false
+	for _, values := range query {
+		for i, value := range values {
+			if email, ok := masker.Unmask(value); ok {
+				values[i] = email
+				changed = true
+			}
+		}
+	}
+	if changed {
+		c.Request.URL.RawQuery = query.Encode()
+	}
+
+	for i, param := range c.Params {
+		if email, ok := masker.Unmask(param.Value); ok {
+			c.Params[i].Value = email
+		}
+	}
+}
+
+// maskable reports whether a response of this content type is text that may hold emails
+func maskable(contentType string) bool {
+	kind, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch kind {
+	case "application/json", "application/problem+json", "text/csv", "text/plain":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxParamLength bounds every query and path parameter, with or without a rule
+const maxParamLength = 1024
+
+// Check validates one parameter value. Want completes the sentence
+// "<name> must be ..." in the 400 response.
+type Check struct {
+	Valid func(value string) bool
+	Want  string
+}
+
+// Rules maps parameter names to their checks
+type Rules map[string]Check
+
+// Validate rejects requests whose query or path parameters break their rule
+// with a 400 problem naming the parameter, before a malformed filter reaches
+// the handlers or GCP. Every parameter is also checked for length, valid
+// UTF-8, and control characters.
+func Validate(query, path Rules) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for name, values := range c.Request.URL.Query() {
+			for _, value := range values {
+				if !validParam(c, name, value, query) {
+					return
+				}
+			}
+		}
+		for _, param := range c.Params {
+			if !validParam(c, param.Key, param.Value, path) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// validParam checks one value and responds with a problem when it is invalid
+func validParam(c *gin.Context, name, value string, rules Rules) bool {
+	if len(value) > maxParamLength {
+		problem.Respond(c, problem.InvalidParameter(name, "%s must be at most %d characters", name, maxParamLength))
+		return false
+	}
+	if !utf8.ValidString(value) || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		problem.Respond(c, problem.InvalidParameter(name, "%s must not contain control characters", name))
+		return false
+	}
+	// Empty values mean "not set" to the handlers, which report missing required parameters
+	if check, ok := rules[name]; ok && value != "" && !check.Valid(value) {
+		problem.Respond(c, problem.InvalidParameter(name, "%s must be %s", name, check.Want))
+		return false
+	}
+	return true
+}
+
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+	projectIDPattern  = regexp.MustCompile(`^([a-z][a-z0-9-]{4,28}[a-z0-9]|[0-9]{1,20})$`)
+	rolePattern       = regexp.MustCompile(`^((projects|organizations)/[A-Za-z0-9._:-]+/)?roles/[A-Za-z0-9._]+$`)
+	permissionPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*(\.[a-zA-Z0-9]+){2}$`)
+	assetTypePattern  = regexp.MustCompile(`^[a-z][a-z0-9.-]*\.googleapis\.com/[A-Za-z]+$`)
+)
+
+// Email accepts a bare email address such as "alice@example.com"
+var Email = Check{
+	Valid: func(value string) bool {
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	},
+	Want: "an email address",
+}
+
+// Principal accepts a principal as it appears in the access matrix: an email
+// address, a domain, allUsers/allAuthenticatedUsers, or another member string
+// without whitespace
+var Principal = Check{
+	Valid: func(value string) bool {
+		if strings.ContainsFunc(value, unicode.IsSpace) {
+			return false
+		}
+		if strings.Contains(value, "@") && !strings.Contains(value, ":") {
+			return Email.Valid(value)
+		}
+		return true
+	},
+	Want: "an email address, a domain, or an IAM member without whitespace",
+}
+
+// ResourceID accepts resource IDs as listed by /api/resources: numeric IDs and
+// full resource names without whitespace
+var ResourceID = Check{
+	Valid: func(value string) bool {
+		return !strings.ContainsFunc(value, unicode.IsSpace)
+	},
+	Want: "a resource ID without whitespace",
+}
+
+// Role accepts predefined and custom role names, e.g. "roles/viewer" or
+// "projects/my-project/roles/deployer"
+var Role = Check{
+	Valid: rolePattern.MatchString,
+	Want:  "a role name such as roles/viewer or projects/<id>/roles/<name>",
+}
+
+// Permission accepts an IAM permission such as "storage.buckets.get"
+var Permission = Check{
+	Valid: permissionPattern.MatchString,
+	Want:  "a permission such as storage.buckets.get",
+}
+
+// AssetType accepts an Asset Inventory type such as "storage.googleapis.com/Bucket"
+var AssetType = Check{
+	Valid: assetTypePattern.MatchString,
+	Want:  "an asset type such as storage.googleapis.com/Bucket",
+}
+
+// Project accepts a project ID or project number
+var Project = Check{
+	Valid: projectIDPattern.MatchString,
+	Want:  "a project ID or project number",
+}
+
+// Identifier accepts the IDs the visualizer generates for views and snapshots
+var Identifier = Check{
+	Valid: identifierPattern.MatchString,
+	Want:  "an identifier of letters, digits, '.', '_', ':', or '-'",
+}
+
+// Timestamp accepts RFC 3339 times such as "2024-05-01T00:00:00Z"
+var Timestamp = Check{
+	Valid: func(value string) bool {
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	},
+	Want: "an RFC 3339 timestamp such as 2024-05-01T00:00:00Z",
+}
+
+// Enum accepts exactly one of values
+func Enum(values ...string) Check {
+	return Check{
+		Valid: func(value string) bool {
+			for _, allowed := range values {
+				if value == allowed {
+					return true
+				}
+			}
+			return false
+		},
+		Want: "one of: " + strings.Join(values, ", "),
+	}
+}
+
+// IntRange accepts integers between min and max inclusive
+func IntRange(min, max int) Check {
+	return Check{
+		Valid: func(value string) bool {
+			n, err := strconv.Atoi(value)
+			return err == nil && n >= min && n <= max
+		},
+		Want: "an integer between " + strconv.Itoa(min) + " and " + strconv.Itoa(max),
+	}
+}
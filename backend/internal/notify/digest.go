@@ -0,0 +1,366 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// digestStateKey is the store setting holding the grants of the last digest
+const digestStateKey = "digest"
+
+// digestListLimit caps the grants and findings listed in a digest; the
+// counts always cover all of them
+const digestListLimit = 50
+
+// DigestGrant is a role binding of a principal on a resource
+type DigestGrant struct {
+	Principal    string `json:"principal"`
+	Role         string `json:"role"`
+	ResourceID   string `json:"resourceId"`
+	ResourceName string `json:"resourceName"`
+	ResourceType string `json:"resourceType"`
+}
+
+// key uniquely identifies a grant
+func (g DigestGrant) key() string {
+	return g.Principal + "::" + g.Role + "::" + g.ResourceID
+}
+
+// Digest is a periodic summary of access changes and open findings
+type Digest struct {
+	Project string    `json:"project"`
+	Since   time.Time `json:"since"` // zero for the first digest, which sets the baseline
+	Until   time.Time `json:"until"`
+
+	NewGrants     []DigestGrant `json:"newGrants"`
+	RemovedGrants []DigestGrant `json:"removedGrants"`
+	// NewCount and RemovedCount count every change; the lists are capped
+	NewCount     int `json:"newCount"`
+	RemovedCount int `json:"removedCount"`
+
+	// Findings counts open findings by severity; TopFindings lists the most
+	// severe
+	Findings     map[string]int     `json:"findings"`
+	OpenFindings int                `json:"openFindings"`
+	TopFindings  []analysis.Finding `json:"topFindings"`
+
+	Review DigestReview `json:"review"`
+}
+
+// DigestReview is the progress of the finding review: findings triaged since
+// the last digest by outcome, and current findings nobody has triaged yet
+type DigestReview struct {
+	Acknowledged  int `json:"acknowledged"`
+	Assigned      int `json:"assigned"`
+	FalsePositive int `json:"falsePositive"`
+	Snoozed       int `json:"snoozed"`
+	StillOpen     int `json:"stillOpen"`
+}
+
+// digestState is what the last digest saw, persisted so changes are reported
+// once across restarts
+type digestState struct {
+	SentAt time.Time     `json:"sentAt"`
+	Grants []DigestGrant `json:"grants"`
+}
+
+// DigestSender renders and emails the digest to a distribution list. Templates
+// named digest.html and digest.txt in TemplateDir replace the built-in ones.
+type DigestSender struct {
+	Project     string
+	Scanner     *scanner.Scanner
+	Engine      *findings.Engine
+	Store       store.Store
+	Mailer      Mailer
+	From        string
+	Recipients  []string
+	TemplateDir string
+}
+
+// Build summarizes the changes since the last digest without recording it
+func (d *DigestSender) Build() (*Digest, []DigestGrant, error) {
+	snapshot, err := d.Scanner.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+	previous, err := d.lastState()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grants := digestGrants(snapshot.Matrix)
+	digest := &Digest{
+		Project:       d.Project,
+		Until:         snapshot.TakenAt,
+		NewGrants:     []DigestGrant{},
+		RemovedGrants: []DigestGrant{},
+	}
+	if previous != nil {
+		digest.Since = previous.SentAt
+		seen := make(map[string]bool, len(previous.Grants))
+		for _, grant := range previous.Grants {
+			seen[grant.key()] = true
+		}
+		current := make(map[string]bool, len(grants))
+		for _, grant := range grants {
+			current[grant.key()] = true
+			if !seen[grant.key()] {
+				digest.NewGrants = append(digest.NewGrants, grant)
+			}
+		}
+		for _, grant := range previous.Grants {
+			if !current[grant.key()] {
+				digest.RemovedGrants = append(digest.RemovedGrants, grant)
+			}
+		}
+	}
+	digest.NewCount = len(digest.NewGrants)
+	digest.RemovedCount = len(digest.RemovedGrants)
+	digest.NewGrants = digest.NewGrants[:min(len(digest.NewGrants), digestListLimit)]
+	digest.RemovedGrants = digest.RemovedGrants[:min(len(digest.RemovedGrants), digestListLimit)]
+
+	open := d.Engine.Evaluate(snapshot)
+	sort.SliceStable(open, func(i, j int) bool {
+		return analysis.SeverityRank(open[i].Severity) > analysis.SeverityRank(open[j].Severity)
+	})
+	digest.Findings = analysis.CountBySeverity(open)
+	digest.OpenFindings = len(open)
+	digest.TopFindings = open[:min(len(open), digestListLimit)]
+
+	states, err := d.Store.ListFindingStates()
+	if err != nil {
+		return nil, nil, err
+	}
+	digest.Review = reviewProgress(open, states, digest.Since, time.Now())
+	return digest, grants, nil
+}
+
+// reviewProgress counts the triage changes made after since and the findings
+// still open at now
+func reviewProgress(open []analysis.Finding, states []store.FindingState, since, now time.Time) DigestReview {
+	var review DigestReview
+	for _, state := range states {
+		if !state.UpdatedAt.After(since) {
+			continue
+		}
+		if state.Assignee != "" {
+			review.Assigned++
+		}
+		switch state.Status {
+		case store.FindingAcknowledged:
+			review.Acknowledged++
+		case store.FindingFalsePositive:
+			review.FalsePositive++
+		case store.FindingSnoozed:
+			review.Snoozed++
+		}
+	}
+	for _, tracked := range findings.Track(open, states, nil, now) {
+		if tracked.Status == store.FindingOpen {
+			review.StillOpen++
+		}
+	}
+	return review
+}
+
+// Send builds the digest, emails it, and records its grants as the baseline
+// of the next one
+func (d *DigestSender) Send(ctx context.Context) (*Digest, error) {
+	digest, grants, err := d.Build()
+	if err != nil {
+		return nil, err
+	}
+	message, err := d.render(digest)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Mailer.Send(ctx, message); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(digestState{SentAt: digest.Until, Grants: grants})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode digest state: %w", err)
+	}
+	if err := d.Store.PutSetting(digestStateKey, data); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// lastState returns what the last digest saw, or nil before the first one
+func (d *DigestSender) lastState() (*digestState, error) {
+	data, err := d.Store.GetSetting(digestStateKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state digestState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode digest state: %w", err)
+	}
+	return &state, nil
+}
+
+// render fills the subject and bodies from the templates
+func (d *DigestSender) render(digest *Digest) (Message, error) {
+	message := Message{
+		From:    d.From,
+		To:      d.Recipients,
+		Subject: fmt.Sprintf("Access digest for %s: %d new grants, %d removed, %d open findings", digest.Project, digest.NewCount, digest.RemovedCount, digest.OpenFindings),
+	}
+
+	text, err := d.templateSource("digest.txt")
+	if err != nil {
+		return message, err
+	}
+	textTemplate, err := texttemplate.New("digest.txt").Parse(text)
+	if err != nil {
+		return message, fmt.Errorf("invalid digest.txt template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := textTemplate.Execute(&buf, digest); err != nil {
+		return message, fmt.Errorf("failed to render digest.txt: %w", err)
+	}
+	message.Text = buf.String()
+
+	html, err := d.templateSource("digest.html")
+	if err != nil {
+		return message, err
+	}
+	htmlTemplate, err := htmltemplate.New("digest.html").Parse(html)
+	if err != nil {
+		return message, fmt.Errorf("invalid digest.html template: %w", err)
+	}
+	buf.Reset()
+	if err := htmlTemplate.Execute(&buf, digest); err != nil {
+		return message, fmt.Errorf("failed to render digest.html: %w", err)
+	}
+	message.HTML = buf.String()
+	return message, nil
+}
+
+// templateSource reads a template from TemplateDir, or the built-in one
+func (d *DigestSender) templateSource(name string) (string, error) {
+	if d.TemplateDir != "" {
+		data, err := os.ReadFile(filepath.Join(d.TemplateDir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read %s template: %w", name, err)
+		}
+	}
+	data, err := defaultTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RunDigest sends the digest every interval until ctx is cancelled. The first
+// one goes out an interval after the last digest sent, or after startup.
+func RunDigest(ctx context.Context, sender *DigestSender, interval time.Duration) {
+	wait := interval
+	if state, err := sender.lastState(); err == nil && state != nil {
+		wait = max(time.Until(state.SentAt.Add(interval)), time.Minute)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = interval
+
+		sender.sendAndLog(ctx)
+	}
+}
+
+// DigestListener sends the digest after a scan once interval has passed since
+// the last one (or when none was sent yet), for deployments such as Cloud Run
+// that have no long-running process to schedule it
+func DigestListener(sender *DigestSender, interval time.Duration) scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		state, err := sender.lastState()
+		if err != nil {
+			log.Printf("Warning: failed to read digest state: %v", err)
+			return
+		}
+		if state != nil && current.TakenAt.Before(state.SentAt.Add(interval)) {
+			return
+		}
+		sender.sendAndLog(context.Background())
+	}
+}
+
+// sendAndLog sends the digest, logging the outcome
+func (d *DigestSender) sendAndLog(ctx context.Context) {
+	sendCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	digest, err := d.Send(sendCtx)
+	if err != nil {
+		log.Printf("Warning: failed to send access digest: %v", err)
+		return
+	}
+	log.Printf("Sent access digest to %d recipients: %d new grants, %d removed, %d open findings",
+		len(d.Recipients), digest.NewCount, digest.RemovedCount, digest.OpenFindings)
+}
+
+// digestGrants lists the role bindings in the matrix, ordered by principal,
+// role, and resource. Project roles are listed once, not per resource they
+// extend to.
+func digestGrants(matrix *gcp.AccessMatrix) []DigestGrant {
+	grants := []DigestGrant{}
+	for _, resource := range matrix.Resources {
+		for role, members := range resource.IAM {
+			if role == "inherited" {
+				continue
+			}
+			for _, member := range members {
+				grants = append(grants, DigestGrant{
+					Principal:    gcp.ParseMember(member).Email,
+					Role:         role,
+					ResourceID:   resource.ID,
+					ResourceName: resource.Name,
+					ResourceType: resource.Type,
+				})
+			}
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		a, b := grants[i], grants[j]
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.ResourceID < b.ResourceID
+	})
+	return grants
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/scanner"
+)
+
+// findingSeverities maps finding severities to alert severities
+var findingSeverities = map[string]string{
+	analysis.SeverityLow:      SeverityInfo,
+	analysis.SeverityMedium:   SeverityWarning,
+	analysis.SeverityHigh:     SeverityHigh,
+	analysis.SeverityCritical: SeverityCritical,
+}
+
+// FindingsListener returns a scanner listener that evaluates findings after every
+// scan and alerts on findings that were not present at the previous evaluation.
+// The first scan only establishes a baseline.
+func FindingsListener(engine *findings.Engine, notifier Notifier) scanner.Listener {
+	var mu sync.Mutex
+	var seen map[string]bool
+
+	return func(previous, current *scanner.Snapshot) {
+		evaluated := engine.Evaluate(current)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		baseline := seen == nil
+		next := make(map[string]bool, len(evaluated))
+		for _, finding := range evaluated {
+			next[finding.ID] = true
+			if baseline || seen[finding.ID] {
+				continue
+			}
+
+			alert := Alert{
+				Kind:      "finding." + finding.Kind,
+				Key:       finding.ID,
+				Severity:  findingSeverities[finding.Severity],
+				Title:     finding.Title,
+				Message:   finding.Description,
+				Details:   finding,
+				Timestamp: current.TakenAt,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := notifier.Notify(ctx, alert); err != nil {
+				log.Printf("Warning: failed to send finding alert: %v", err)
+			}
+			cancel()
+		}
+		seen = next
+	}
+}
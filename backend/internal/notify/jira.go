@@ -0,0 +1,344 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/scanner"
+)
+
+// JiraDefaultLabel marks the issues the Jira integration manages
+const JiraDefaultLabel = "gcp-access-visualizer"
+
+// jiraPageSize is how many issues a Jira search returns per page
+const jiraPageSize = 100
+
+// errJiraNotFound is returned for 404 responses, e.g. a search API the Jira
+// deployment does not have
+var errJiraNotFound = errors.New("not found")
+
+// JiraClient creates, updates, and closes Jira issues for findings through the
+// REST API v2. With Email set it authenticates as a Jira Cloud user with an API
+// token; otherwise Token is sent as a Data Center personal access token.
+type JiraClient struct {
+	URL       string
+	Email     string
+	Token     string
+	Project   string // project key
+	IssueType string
+	// Label marks managed issues; deployments sharing a Jira project need
+	// distinct labels. Defaults to JiraDefaultLabel.
+	Label string
+	// CloseTransition names the workflow transition that closes an issue;
+	// empty takes the first transition into a done status
+	CloseTransition string
+	Client          *http.Client
+}
+
+// JiraIssue is a managed issue open in Jira
+type JiraIssue struct {
+	Key         string
+	Fingerprint string
+	Summary     string
+	Description string
+}
+
+// FindingFingerprint identifies a finding's issue across scans. The finding ID
+// is hashed because Jira labels cannot hold spaces or arbitrary characters.
+func FindingFingerprint(finding analysis.Finding) string {
+	sum := sha256.Sum256([]byte(finding.ID))
+	return "gcpav-" + hex.EncodeToString(sum[:8])
+}
+
+// OpenIssues lists the managed issues that are not done, keyed by fingerprint
+func (j *JiraClient) OpenIssues(ctx context.Context) (map[string]JiraIssue, error) {
+	jql := fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done", j.Project, j.label())
+	issues := make(map[string]JiraIssue)
+	for token, startAt := "", 0; ; {
+		var page struct {
+			Issues []struct {
+				Key    string `json:"key"`
+				Fields struct {
+					Summary     string   `json:"summary"`
+					Description string   `json:"description"`
+					Labels      []string `json:"labels"`
+				} `json:"fields"`
+			} `json:"issues"`
+			Total         int    `json:"total"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		query := map[string]interface{}{
+			"jql":        jql,
+			"fields":     []string{"summary", "description", "labels"},
+			"maxResults": jiraPageSize,
+		}
+		if token != "" {
+			query["nextPageToken"] = token
+		}
+
+		// Jira Cloud replaced the search API with search/jql, which Data
+		// Center does not have
+		err := j.do(ctx, http.MethodPost, "/rest/api/2/search/jql", query, &page)
+		paged := errors.Is(err, errJiraNotFound)
+		if paged {
+			delete(query, "nextPageToken")
+			query["startAt"] = startAt
+			err = j.do(ctx, http.MethodPost, "/rest/api/2/search", query, &page)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to search Jira issues: %w", err)
+		}
+
+		for _, issue := range page.Issues {
+			for _, label := range issue.Fields.Labels {
+				if strings.HasPrefix(label, "gcpav-") {
+					issues[label] = JiraIssue{
+						Key:         issue.Key,
+						Fingerprint: label,
+						Summary:     issue.Fields.Summary,
+						Description: issue.Fields.Description,
+					}
+				}
+			}
+		}
+
+		if paged {
+			startAt += len(page.Issues)
+			if len(page.Issues) == 0 || startAt >= page.Total {
+				return issues, nil
+			}
+		} else {
+			if page.NextPageToken == "" {
+				return issues, nil
+			}
+			token = page.NextPageToken
+		}
+	}
+}
+
+// CreateIssue opens an issue for a finding and returns its key
+func (j *JiraClient) CreateIssue(ctx context.Context, finding analysis.Finding) (string, error) {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": j.Project},
+		"issuetype":   map[string]string{"name": j.IssueType},
+		"summary":     jiraSummary(finding),
+		"description": jiraDescription(finding),
+		"labels":      []string{j.label(), FindingFingerprint(finding), "severity-" + finding.Severity},
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &created); err != nil {
+		return "", fmt.Errorf("failed to create Jira issue: %w", err)
+	}
+	return created.Key, nil
+}
+
+// UpdateIssue brings an open issue's summary and description up to date with
+// the finding, leaving it untouched when nothing changed
+func (j *JiraClient) UpdateIssue(ctx context.Context, issue JiraIssue, finding analysis.Finding) error {
+	summary, description := jiraSummary(finding), jiraDescription(finding)
+	if issue.Summary == summary && issue.Description == description {
+		return nil
+	}
+	fields := map[string]interface{}{"summary": summary, "description": description}
+	if err := j.do(ctx, http.MethodPut, "/rest/api/2/issue/"+issue.Key, map[string]interface{}{"fields": fields}, nil); err != nil {
+		return fmt.Errorf("failed to update Jira issue %s: %w", issue.Key, err)
+	}
+	return nil
+}
+
+// CloseIssue comments why an issue is resolved and transitions it to done
+func (j *JiraClient) CloseIssue(ctx context.Context, key, comment string) error {
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+key+"/transitions", nil, &available); err != nil {
+		return fmt.Errorf("failed to list transitions of Jira issue %s: %w", key, err)
+	}
+
+	transition := ""
+	for _, t := range available.Transitions {
+		if j.CloseTransition != "" && strings.EqualFold(t.Name, j.CloseTransition) ||
+			j.CloseTransition == "" && t.To.StatusCategory.Key == "done" {
+			transition = t.ID
+			break
+		}
+	}
+	if transition == "" {
+		return fmt.Errorf("no transition closes Jira issue %s", key)
+	}
+
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/comment", map[string]string{"body": comment}, nil); err != nil {
+		return fmt.Errorf("failed to comment on Jira issue %s: %w", key, err)
+	}
+	body := map[string]interface{}{"transition": map[string]string{"id": transition}}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/transitions", body, nil); err != nil {
+		return fmt.Errorf("failed to close Jira issue %s: %w", key, err)
+	}
+	return nil
+}
+
+// label returns the label of managed issues
+func (j *JiraClient) label() string {
+	if j.Label == "" {
+		return JiraDefaultLabel
+	}
+	return j.Label
+}
+
+// do sends a JSON request to the Jira API and decodes the response into out
+func (j *JiraClient) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	client := j.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(j.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if j.Email != "" {
+		req.SetBasicAuth(j.Email, j.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errJiraNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Jira returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jiraSummary is the issue summary of a finding, within Jira's 255 characters
+func jiraSummary(finding analysis.Finding) string {
+	return truncate(fmt.Sprintf("[%s] %s", finding.Severity, finding.Title), 255)
+}
+
+// jiraDescription is the issue description of a finding in Jira wiki markup
+func jiraDescription(finding analysis.Finding) string {
+	var b strings.Builder
+	b.WriteString(finding.Description)
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "*Finding:* %s\n", finding.ID)
+	fmt.Fprintf(&b, "*Severity:* %s\n", finding.Severity)
+	for _, key := range sortedKeys(finding.Details) {
+		fmt.Fprintf(&b, "*%s:* %s\n", key, finding.Details[key])
+	}
+	b.WriteString("\n_Managed by GCP Access Visualizer: closed automatically when the finding resolves._")
+	return b.String()
+}
+
+// sortedKeys returns the keys of details in order, for a stable description
+func sortedKeys(details map[string]string) []string {
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JiraListener returns a scanner listener that keeps one open Jira issue per
+// finding at or above minSeverity: new findings get an issue, changed ones are
+// updated, and issues whose finding is gone are closed. Nothing is closed
+// after a scan with collector warnings, whose findings may be incomplete.
+func JiraListener(engine *findings.Engine, jira *JiraClient, minSeverity string) scanner.Listener {
+	var mu sync.Mutex
+
+	return func(previous, current *scanner.Snapshot) {
+		evaluated := engine.Evaluate(current)
+
+		// Serialize syncs so overlapping scans cannot open duplicate issues
+		mu.Lock()
+		defer mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		open, err := jira.OpenIssues(ctx)
+		if err != nil {
+			log.Printf("Warning: failed to sync Jira issues: %v", err)
+			return
+		}
+
+		active := make(map[string]bool)
+		for _, finding := range evaluated {
+			if analysis.SeverityRank(finding.Severity) < analysis.SeverityRank(minSeverity) {
+				continue
+			}
+			fingerprint := FindingFingerprint(finding)
+			active[fingerprint] = true
+
+			if issue, ok := open[fingerprint]; ok {
+				err = jira.UpdateIssue(ctx, issue, finding)
+			} else {
+				_, err = jira.CreateIssue(ctx, finding)
+			}
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+
+		if len(current.Matrix.Warnings) > 0 {
+			return
+		}
+		comment := fmt.Sprintf("The finding was no longer present in the scan taken at %s.", current.TakenAt.UTC().Format(time.RFC3339))
+		for fingerprint, issue := range open {
+			if active[fingerprint] {
+				continue
+			}
+			if err := jira.CloseIssue(ctx, issue.Key, comment); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
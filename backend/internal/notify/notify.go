@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Alert severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Alert is a notification pushed to external channels
+type Alert struct {
+	Kind string `json:"kind"` // e.g. "watchlist.grant"
+	// Key identifies what the alert is about across scans, e.g. a finding ID,
+	// so paging channels can deduplicate repeated alerts
+	Key       string      `json:"key,omitempty"`
+	Severity  string      `json:"severity"`
+	Title     string      `json:"title"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Notifier delivers alerts to an external channel
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Multi fans an alert out to several notifiers
+type Multi []Notifier
+
+// Notify implements Notifier, returning the combined errors of all notifiers
+func (m Multi) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// alertSeverityRank orders alert severities from least to most severe
+var alertSeverityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityHigh:     2,
+	SeverityCritical: 3,
+}
+
+// MinSeverity passes on only alerts at least as severe as Severity, so paging
+// channels wake the on-call for what cannot wait
+type MinSeverity struct {
+	Notifier Notifier
+	Severity string
+}
+
+// Notify implements Notifier
+func (m MinSeverity) Notify(ctx context.Context, alert Alert) error {
+	if alertSeverityRank[alert.Severity] < alertSeverityRank[m.Severity] {
+		return nil
+	}
+	return m.Notifier.Notify(ctx, alert)
+}
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySeverities maps alert severities to PagerDuty event severities
+var pagerDutySeverities = map[string]string{
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityHigh:     "error",
+	SeverityCritical: "critical",
+}
+
+// PagerDutyNotifier triggers PagerDuty incidents through the Events API v2.
+// Alerts with the same key are deduplicated into one incident.
+type PagerDutyNotifier struct {
+	RoutingKey string
+	Client     *http.Client
+}
+
+// Notify implements Notifier
+func (p *PagerDutyNotifier) Notify(ctx context.Context, alert Alert) error {
+	event := map[string]interface{}{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"summary":        truncate(alert.Title, 1024),
+			"source":         "gcp-access-visualizer",
+			"severity":       pagerDutySeverities[alert.Severity],
+			"timestamp":      alert.Timestamp,
+			"class":          alert.Kind,
+			"custom_details": map[string]interface{}{"message": alert.Message, "details": alert.Details},
+		},
+	}
+	if alert.Key != "" {
+		event["dedup_key"] = alert.Key
+	}
+	if err := postJSON(ctx, p.Client, pagerDutyURL, event); err != nil {
+		return fmt.Errorf("failed to trigger PagerDuty incident: %w", err)
+	}
+	return nil
+}
+
+// OpsgenieDefaultURL is the Opsgenie API of the US instance; EU accounts use
+// https://api.eu.opsgenie.com
+const OpsgenieDefaultURL = "https://api.opsgenie.com"
+
+// opsgeniePriorities maps alert severities to Opsgenie priorities
+var opsgeniePriorities = map[string]string{
+	SeverityInfo:     "P5",
+	SeverityWarning:  "P3",
+	SeverityHigh:     "P2",
+	SeverityCritical: "P1",
+}
+
+// OpsgenieNotifier creates Opsgenie alerts. Alerts with the same key share
+// an alias, so Opsgenie deduplicates them while one is open.
+type OpsgenieNotifier struct {
+	APIKey string
+	URL    string // defaults to OpsgenieDefaultURL
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (o *OpsgenieNotifier) Notify(ctx context.Context, alert Alert) error {
+	url := o.URL
+	if url == "" {
+		url = OpsgenieDefaultURL
+	}
+	payload := map[string]interface{}{
+		"message":     truncate(alert.Title, 130),
+		"description": truncate(alert.Message, 15000),
+		"priority":    opsgeniePriorities[alert.Severity],
+		"source":      "gcp-access-visualizer",
+		"tags":        []string{alert.Kind},
+	}
+	if alert.Key != "" {
+		payload["alias"] = truncate(alert.Key, 512)
+	}
+	header := http.Header{"Authorization": {"GenieKey " + o.APIKey}}
+	if err := postJSONWithHeader(ctx, o.Client, strings.TrimSuffix(url, "/")+"/v2/alerts", header, payload); err != nil {
+		return fmt.Errorf("failed to create Opsgenie alert: %w", err)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes, the limits paging APIs enforce
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
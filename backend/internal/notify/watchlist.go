@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/scanner"
+)
+
+// WatchlistListener returns a scanner listener that alerts on new grants of watchlisted roles.
+// The first scan only establishes a baseline.
+func WatchlistListener(roles func() []string, notifier Notifier) scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		if previous == nil {
+			return
+		}
+
+		for _, grant := range analysis.NewWatchlistGrants(previous.Matrix, current.Matrix, roles()) {
+			alert := Alert{
+				Kind:     "watchlist.grant",
+				Key:      "watchlist:" + grant.Principal + ":" + grant.Role + ":" + grant.ResourceID,
+				Severity: SeverityHigh,
+				Title:    fmt.Sprintf("New %s grant", grant.Role),
+				Message: fmt.Sprintf("%s %s was granted %s on %s (%s)",
+					grant.PrincipalType, grant.Principal, grant.Role, grant.ResourceName, grant.ResourceType),
+				Details:   grant,
+				Timestamp: current.TakenAt,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := notifier.Notify(ctx, alert); err != nil {
+				log.Printf("Warning: failed to send watchlist alert: %v", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier posts alerts as JSON to an HTTP endpoint
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, w.Client, w.URL, alert)
+}
+
+// SlackNotifier posts alerts to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// Notify implements Notifier
+func (s *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	payload := map[string]string{
+		"text": fmt.Sprintf("*[%s] %s*\n%s", alert.Severity, alert.Title, alert.Message),
+	}
+	return postJSON(ctx, s.Client, s.WebhookURL, payload)
+}
+
+// postJSON sends payload as a JSON POST and checks for a 2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	return postJSONWithHeader(ctx, client, url, nil, payload)
+}
+
+// postJSONWithHeader is postJSON with extra request headers, e.g. credentials
+func postJSONWithHeader(ctx context.Context, client *http.Client, url string, header http.Header, payload interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
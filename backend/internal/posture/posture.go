@@ -0,0 +1,70 @@
+package posture
+
+import (
+	"log"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/inventory"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Scorer computes least-privilege scores and records them per snapshot
+type Scorer struct {
+	client         *gcp.Client
+	store          store.Store
+	inventories    *inventory.Cache
+	trustedDomains func() []string
+}
+
+// NewScorer creates a scorer that reads service account keys through
+// inventories. trustedDomains is read on every computation.
+func NewScorer(client *gcp.Client, st store.Store, inventories *inventory.Cache, trustedDomains func() []string) *Scorer {
+	return &Scorer{
+		client:         client,
+		store:          st,
+		inventories:    inventories,
+		trustedDomains: trustedDomains,
+	}
+}
+
+// Compute scores a snapshot with the service account keys listed for it
+func (s *Scorer) Compute(snapshot *scanner.Snapshot) analysis.Score {
+	inputs := analysis.ScoreInputs{
+		TrustedDomains:  s.trustedDomains(),
+		UserManagedKeys: -1,
+	}
+
+	keys, err := s.inventories.ServiceAccountKeys(snapshot)
+	if err != nil {
+		log.Printf("Warning: failed to list service account keys for scoring: %v", err)
+	} else {
+		active := 0
+		for _, key := range keys {
+			if !key.Disabled {
+				active++
+			}
+		}
+		inputs.UserManagedKeys = active
+	}
+
+	return analysis.ComputeScore(s.client.ProjectID, snapshot.Matrix, inputs)
+}
+
+// Listener returns a scanner listener that records a score for every snapshot
+func (s *Scorer) Listener() scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		score := s.Compute(current)
+		record := store.ScoreRecord{
+			SnapshotID: current.ID,
+			TakenAt:    current.TakenAt,
+			Project:    score.Project,
+			Score:      score.Score,
+			Breakdown:  score.Components,
+		}
+		if err := s.store.AppendScore(record); err != nil {
+			log.Printf("Warning: failed to record score: %v", err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package posture
+
+import (
+	"log"
+
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/findings"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+)
+
+// MetricsListener returns a scanner listener that records the posture metrics
+// and finding counts of every snapshot, feeding /api/trends
+func MetricsListener(project string, st store.Store, engine *findings.Engine, trustedDomains func() []string) scanner.Listener {
+	return func(previous, current *scanner.Snapshot) {
+		metrics := analysis.ComputeMetrics(current.Matrix, trustedDomains())
+		record := store.MetricsRecord{
+			SnapshotID:         current.ID,
+			TakenAt:            current.TakenAt,
+			Project:            project,
+			Principals:         metrics.Principals,
+			Owners:             metrics.Owners,
+			PublicResources:    metrics.PublicResources,
+			ExternalPrincipals: metrics.ExternalPrincipals,
+			Findings:           analysis.CountBySeverity(engine.Evaluate(current)),
+		}
+		if err := st.AppendMetrics(record); err != nil {
+			log.Printf("Warning: failed to record posture metrics: %v", err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package problem renders API errors as RFC 7807 problem details
+// (application/problem+json) with a stable code, a retryable flag, and hints
+// such as the GCP permission the visualizer is missing.
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// typePrefix namespaces problem type URIs; the code completes the URI
+const typePrefix = "urn:gcp-access-visualizer:problem:"
+
+// Problem codes
+const (
+	CodeInvalidArgument     = "invalid-argument"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not-found"
+	CodeNotImplemented      = "not-implemented"
+	CodeConflict            = "conflict"
+	CodeScanInProgress      = "scan-in-progress"
+	CodeCallBudgetExceeded  = "call-budget-exceeded"
+	CodeScanDeadline        = "scan-deadline-exceeded"
+	CodeGCPPermissionDenied = "gcp-permission-denied"
+	CodeGCPUnavailable      = "gcp-unavailable"
+	CodeUpstreamUnavailable = "upstream-unavailable"
+	CodeRateLimited         = "rate-limited"
+	CodeOverloaded          = "overloaded"
+	CodeInternal            = "internal"
+)
+
+// Problem is an RFC 7807 problem details object. Code, Retryable, Details, and
+// Permission are extension members; ErrorText repeats Detail for clients written
+// against the earlier {"error": "..."} responses.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+
+	Code      string            `json:"code"`
+	Retryable bool              `json:"retryable"`
+	Details   map[string]string `json:"details,omitempty"`
+	// Permission is the IAM permission the visualizer's identity lacks, when known
+	Permission string `json:"permission,omitempty"`
+
+	ErrorText string `json:"error"`
+}
+
+// New creates a problem with the standard title for status
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// InvalidParameter reports a malformed or missing query parameter or body field
+func InvalidParameter(name, format string, args ...interface{}) *Problem {
+	p := New(http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf(format, args...))
+	if name != "" {
+		p.Details = map[string]string{"parameter": name}
+	}
+	return p
+}
+
+// NotFound reports a missing resource
+func NotFound(detail string) *Problem {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+// Upstream reports a failure of an external service other than GCP, such as
+// the directory connector
+func Upstream(err error) *Problem {
+	p := New(http.StatusBadGateway, CodeUpstreamUnavailable, err.Error())
+	p.Retryable = true
+	return p
+}
+
+// FromError classifies an error returned by the scanner, the store, or a GCP API
+func FromError(err error) *Problem {
+	var p *Problem
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, gcp.ErrProjectNotScanned), errors.Is(err, gcp.ErrResourceNotScanned), gcp.IsNotFound(err):
+		return NotFound(err.Error())
+	case errors.Is(err, scanner.ErrScanInProgress):
+		p = New(http.StatusServiceUnavailable, CodeScanInProgress, err.Error())
+		p.Retryable = true
+		return p
+	case errors.Is(err, scanner.ErrImported):
+		return New(http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, scanner.ErrOverBudget), errors.Is(err, gcp.ErrCallBudgetExceeded):
+		return New(http.StatusServiceUnavailable, CodeCallBudgetExceeded, err.Error())
+	case errors.Is(err, gcp.ErrScanDeadlineExceeded):
+		p = New(http.StatusGatewayTimeout, CodeScanDeadline, err.Error())
+		p.Retryable = true
+		return p
+	}
+
+	if permission, ok := gcp.DeniedPermission(err); ok {
+		p = New(http.StatusBadGateway, CodeGCPPermissionDenied, err.Error())
+		p.Permission = permission
+		return p
+	}
+	if gcp.IsTransient(err) {
+		p = New(http.StatusServiceUnavailable, CodeGCPUnavailable, err.Error())
+		p.Retryable = true
+		return p
+	}
+	return New(http.StatusInternalServerError, CodeInternal, err.Error())
+}
+
+// Respond writes p as application/problem+json and aborts the request
+func Respond(c *gin.Context, p *Problem) {
+	body := *p
+	body.Instance = c.Request.URL.Path
+	body.ErrorText = body.Detail
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(body.Status, body)
+}
+
+// RespondError classifies err and writes it as a problem
+func RespondError(c *gin.Context, err error) {
+	Respond(c, FromError(err))
+}
+
+// Error implements error so handlers can return problems through error paths
+func (p *Problem) Error() string {
+	return p.Detail
+}
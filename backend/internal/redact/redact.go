@@ -0,0 +1,99 @@
+// Package redact masks principal emails in API responses and exports so that
+// screenshots and demos do not leak identities.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redaction modes
+const (
+	ModeOff     = "off"
+	ModePartial = "partial" // keep the first character of the local part: a***@example.com
+	ModeHash    = "hash"    // replace the local part with a keyed hash: p-1f3a9c2e@example.com
+)
+
+// Modes lists the valid redaction modes
+var Modes = []string{ModeOff, ModePartial, ModeHash}
+
+// ValidMode reports whether mode is a known redaction mode; empty means off
+func ValidMode(mode string) bool {
+	switch mode {
+	case "", ModeOff, ModePartial, ModeHash:
+		return true
+	}
+	return false
+}
+
+// emailPattern matches email addresses embedded in JSON, CSV, or plain text
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Masker masks emails consistently: an email always maps to the same masked
+// form, whichever endpoint returns it. Hashed emails can be mapped back so a
+// masked principal copied from one response works as a filter in the next.
+type Masker struct {
+	key []byte
+
+	mu       sync.Mutex
+	unmasked map[string]string // hashed form -> email
+}
+
+// NewMasker creates a masker whose hashes are keyed with key. An empty key
+// picks a random one, so hashes change on restart and differ between replicas.
+func NewMasker(key string) *Masker {
+	m := &Masker{key: []byte(key), unmasked: make(map[string]string)}
+	if key == "" {
+		m.key = make([]byte, 32)
+		rand.Read(m.key)
+	}
+	return m
+}
+
+// Mask returns the masked form of an email in the given mode
+func (m *Masker) Mask(mode, email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+
+	switch mode {
+	case ModePartial:
+		return local[:1] + "***" + domain
+	case ModeHash:
+		mac := hmac.New(sha256.New, m.key)
+		mac.Write([]byte(strings.ToLower(email)))
+		masked := "p-" + hex.EncodeToString(mac.Sum(nil)[:4]) + domain
+
+		m.mu.Lock()
+		m.unmasked[masked] = email
+		m.mu.Unlock()
+		return masked
+	}
+	return email
+}
+
+// MaskText masks every email in text
+func (m *Masker) MaskText(mode string, text []byte) []byte {
+	if mode == "" || mode == ModeOff {
+		return text
+	}
+	return emailPattern.ReplaceAllFunc(text, func(email []byte) []byte {
+		return []byte(m.Mask(mode, string(email)))
+	})
+}
+
+// Unmask returns the email behind a hashed form previously returned by Mask
+func (m *Masker) Unmask(masked string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	email, ok := m.unmasked[masked]
+	return email, ok
+}
@@ -0,0 +1,113 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Baseline is the expected access: principal pattern -> role pattern ->
+// resource patterns. Principals are emails without the "user:" style prefix;
+// resources are full resource names. In every pattern "*" matches any run of
+// characters, slashes included:
+//
+//	platform@example.com:
+//	  roles/container.developer: ["//container.googleapis.com/projects/prod/*"]
+//	"*@prod.iam.gserviceaccount.com":
+//	  roles/logging.logWriter: ["*"]
+type Baseline map[string]map[string][]string
+
+// BaselineEntry is one expected (principal, role, resource) pattern
+type BaselineEntry struct {
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+	Resource  string `json:"resource"`
+
+	principal, role, resource *regexp.Regexp
+}
+
+// ParseBaseline reads a baseline from YAML or JSON and validates it
+func ParseBaseline(data []byte) (Baseline, error) {
+	var baseline Baseline
+	if err := yaml.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	if err := baseline.Validate(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// Validate checks that no pattern is empty and every role lists a resource
+func (b Baseline) Validate() error {
+	for principal, roles := range b {
+		if strings.TrimSpace(principal) == "" {
+			return fmt.Errorf("baseline: empty principal pattern")
+		}
+		for role, resources := range roles {
+			if strings.TrimSpace(role) == "" {
+				return fmt.Errorf("baseline %q: empty role pattern", principal)
+			}
+			if len(resources) == 0 {
+				return fmt.Errorf("baseline %q: role %q lists no resources; use \"*\" for any", principal, role)
+			}
+			for _, resource := range resources {
+				if strings.TrimSpace(resource) == "" {
+					return fmt.Errorf("baseline %q: role %q has an empty resource pattern", principal, role)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Entries flattens the baseline, ordered by principal, role, and resource
+func (b Baseline) Entries() []BaselineEntry {
+	entries := []BaselineEntry{}
+	for principal, roles := range b {
+		for role, resources := range roles {
+			for _, resource := range resources {
+				entries = append(entries, BaselineEntry{
+					Principal: principal,
+					Role:      role,
+					Resource:  resource,
+					principal: glob(strings.ToLower(principal)),
+					role:      glob(role),
+					resource:  glob(resource),
+				})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Principal != b.Principal {
+			return a.Principal < b.Principal
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.Resource < b.Resource
+	})
+	return entries
+}
+
+// Matches reports whether a grant fits the entry; principals compare
+// case-insensitively
+func (e BaselineEntry) Matches(principal, role, resource string) bool {
+	return e.principal.MatchString(strings.ToLower(principal)) && e.role.MatchString(role) && e.resource.MatchString(resource)
+}
+
+// glob compiles a pattern in which "*" stands for any run of characters
+func glob(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// BaselineSetting is the store setting holding the baseline as JSON
+const BaselineSetting = "baseline"
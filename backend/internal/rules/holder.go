@@ -0,0 +1,25 @@
+package rules
+
+import "sync/atomic"
+
+// Holder holds the rule set in effect, which GitOps sync may replace at runtime
+type Holder struct {
+	current atomic.Pointer[RuleSet]
+}
+
+// NewHolder creates a holder of ruleSet
+func NewHolder(ruleSet *RuleSet) *Holder {
+	h := &Holder{}
+	h.Set(ruleSet)
+	return h
+}
+
+// Get returns the rule set in effect
+func (h *Holder) Get() *RuleSet {
+	return h.current.Load()
+}
+
+// Set replaces the rule set in effect
+func (h *Holder) Set(ruleSet *RuleSet) {
+	h.current.Store(ruleSet)
+}
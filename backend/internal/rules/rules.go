@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+)
+
+// SoDRule forbids one principal from holding a role from RolesA together with a role from RolesB
+type SoDRule struct {
+	ID          string   `yaml:"id" json:"id"`
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Severity    string   `yaml:"severity" json:"severity"`
+	RolesA      []string `yaml:"rolesA" json:"rolesA"`
+	RolesB      []string `yaml:"rolesB" json:"rolesB"`
+}
+
+// ToxicCondition is one half of a toxic combination: holding any of its permissions meets it
+type ToxicCondition struct {
+	Name        string   `yaml:"name" json:"name"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+// ToxicRule flags a principal whose effective permissions meet every condition at once
+type ToxicRule struct {
+	ID          string           `yaml:"id" json:"id"`
+	Name        string           `yaml:"name" json:"name"`
+	Description string           `yaml:"description" json:"description,omitempty"`
+	Severity    string           `yaml:"severity" json:"severity"`
+	Conditions  []ToxicCondition `yaml:"conditions" json:"conditions"`
+}
+
+// RuleSet is the collection of policy rules evaluated against the access matrix
+type RuleSet struct {
+	SoD   []SoDRule   `yaml:"sod" json:"sod"`
+	Toxic []ToxicRule `yaml:"toxic" json:"toxic"`
+}
+
+// Default returns the built-in rules used when no rules file is configured
+func Default() *RuleSet {
+	return &RuleSet{
+		SoD: []SoDRule{
+			{
+				ID:          "sa-admin-key-admin",
+				Name:        "Service account admin and key admin",
+				Description: "Managing service accounts and minting their keys lets one principal create and use credentials unchecked.",
+				Severity:    "high",
+				RolesA:      []string{"roles/iam.serviceAccountAdmin"},
+				RolesB:      []string{"roles/iam.serviceAccountKeyAdmin"},
+			},
+			{
+				ID:          "iam-admin-security-reviewer",
+				Name:        "IAM administrator and security reviewer",
+				Description: "The principal granting access should not also be the one reviewing it.",
+				Severity:    "medium",
+				RolesA:      []string{"roles/resourcemanager.projectIamAdmin", "roles/iam.securityAdmin"},
+				RolesB:      []string{"roles/iam.securityReviewer"},
+			},
+			{
+				ID:          "deployer-approver",
+				Name:        "Deployer and release approver",
+				Description: "Deploying code and approving rollouts should be held by different principals.",
+				Severity:    "high",
+				RolesA:      []string{"roles/clouddeploy.releaser", "roles/run.developer"},
+				RolesB:      []string{"roles/clouddeploy.approver"},
+			},
+		},
+		Toxic: []ToxicRule{
+			{
+				ID:          "deploy-and-approve",
+				Name:        "Deploy code and approve deploys",
+				Description: "A principal that can both ship code and approve its rollout bypasses release review.",
+				Severity:    "high",
+				Conditions: []ToxicCondition{
+					{Name: "deploy code", Permissions: []string{"run.services.update", "cloudfunctions.functions.update", "clouddeploy.releases.create", "cloudbuild.builds.create"}},
+					{Name: "approve deploys", Permissions: []string{"clouddeploy.rollouts.approve"}},
+				},
+			},
+			{
+				ID:          "secrets-and-egress",
+				Name:        "Read secrets and change network egress",
+				Description: "Reading secrets while controlling firewall rules and routes allows quiet exfiltration.",
+				Severity:    "high",
+				Conditions: []ToxicCondition{
+					{Name: "read secrets", Permissions: []string{"secretmanager.versions.access"}},
+					{Name: "change network egress", Permissions: []string{"compute.firewalls.create", "compute.firewalls.update", "compute.routes.create"}},
+				},
+			},
+			{
+				ID:          "mint-keys-and-grant",
+				Name:        "Create service account keys and change project IAM",
+				Description: "Minting long-lived credentials and granting them roles lets one principal create a persistent backdoor.",
+				Severity:    "critical",
+				Conditions: []ToxicCondition{
+					{Name: "create service account keys", Permissions: []string{"iam.serviceAccountKeys.create"}},
+					{Name: "change project IAM", Permissions: []string{"resourcemanager.projects.setIamPolicy"}},
+				},
+			},
+		},
+	}
+}
+
+// Load reads rules from a YAML or JSON file. An empty path returns Default().
+func Load(path string) (*RuleSet, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var ruleSet RuleSet
+	if err := yaml.Unmarshal(data, &ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+	if err := ruleSet.Validate(); err != nil {
+		return nil, err
+	}
+	return &ruleSet, nil
+}
+
+// Validate checks that every rule is well-formed
+func (r *RuleSet) Validate() error {
+	seen := make(map[string]bool)
+	for i, rule := range r.SoD {
+		if rule.ID == "" {
+			return fmt.Errorf("sod rule %d: id is required", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("sod rule %q: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+		if len(rule.RolesA) == 0 || len(rule.RolesB) == 0 {
+			return fmt.Errorf("sod rule %q: rolesA and rolesB must both be non-empty", rule.ID)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, rule := range r.Toxic {
+		if rule.ID == "" {
+			return fmt.Errorf("toxic rule %d: id is required", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("toxic rule %q: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+		if len(rule.Conditions) < 2 {
+			return fmt.Errorf("toxic rule %q: at least two conditions are required", rule.ID)
+		}
+		for _, condition := range rule.Conditions {
+			if len(condition.Permissions) == 0 {
+				return fmt.Errorf("toxic rule %q: condition %q has no permissions", rule.ID, condition.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,734 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/store"
+)
+
+// ErrScanInProgress is returned when another replica holds the scan lock and no
+// shared snapshot has been published yet
+var ErrScanInProgress = errors.New("a scan is in progress on another replica; retry shortly")
+
+// ErrImported is returned when changing an imported snapshot with live data
+var ErrImported = errors.New("the current snapshot was imported; clear the import to scan GCP again")
+
+// ErrOverBudget is returned when the estimated API calls of a scan exceed the call budget
+var ErrOverBudget = errors.New("estimated API calls exceed the scan call budget")
+
+// Snapshot is the access matrix captured by a single scan
+type Snapshot struct {
+	ID       string            `json:"id"`
+	TakenAt  time.Time         `json:"takenAt"`
+	Duration time.Duration     `json:"duration"`
+	Matrix   *gcp.AccessMatrix `json:"-"`
+	// Usage is the API consumption of the scan; nil for snapshots adopted from another replica
+	Usage *Usage `json:"usage,omitempty"`
+	// PatchedAt is set when policies were selectively rescanned into the
+	// snapshot after TakenAt
+	PatchedAt time.Time `json:"patchedAt,omitempty"`
+	// Imported is set on snapshots built from an uploaded IAM policy export,
+	// which are served until cleared instead of going stale
+	Imported *gcp.ImportResult `json:"imported,omitempty"`
+
+	// raw is the matrix as returned by GCP, before hooks were applied
+	raw *gcp.AccessMatrix
+	// notified is closed once the listeners of the scan have returned; nil
+	// when no listeners ran for this snapshot
+	notified chan struct{}
+}
+
+// Notified returns a channel closed once every listener notified of the
+// snapshot has returned. Deployments without background CPU, such as Cloud
+// Run, wait on it before responding so alerts are not cut off.
+func (s *Snapshot) Notified() <-chan struct{} {
+	if s.notified == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return s.notified
+}
+
+// Usage is the GCP API consumption of a scan
+type Usage struct {
+	// Budget is the call budget the scan ran with; zero means unlimited
+	Budget    int          `json:"budget"`
+	Estimated gcp.APIUsage `json:"estimated"`
+	Calls     gcp.APIUsage `json:"calls"`
+	// Rejected counts calls refused once the budget was used up or the
+	// deadline had passed
+	Rejected int `json:"rejected"`
+	// Deadline is when the scan stopped calling GCP, if it had one
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// Hook post-processes a freshly built matrix, e.g. to enrich principals.
+// Hooks receive their own copy of the Users slice and may modify its elements.
+type Hook func(matrix *gcp.AccessMatrix)
+
+// Listener is notified after every scan with the previous snapshot (nil on the
+// first scan) and the new one. Listeners run in their own goroutine.
+type Listener func(previous, current *Snapshot)
+
+// Scanner builds access matrix snapshots and caches the most recent one
+type Scanner struct {
+	client *gcp.Client
+	ttl    time.Duration
+
+	// scanMu lets one scan run at a time. It is taken before mu, which guards
+	// the fields below and is released while a scan calls GCP and runs hooks,
+	// so reads keep serving the previous snapshot meanwhile.
+	scanMu sync.Mutex
+
+	mu        sync.Mutex
+	current   *Snapshot
+	hooks     []Hook
+	listeners []Listener
+
+	// coordinator, when set, shares scanning and snapshots between replicas
+	coordinator store.Coordinator
+
+	// budget caps the API calls of a scan (zero for no limit); lastUsage is the
+	// consumption of the previous scan, which estimates the next one
+	budget    int
+	lastUsage gcp.APIUsage
+	// timeout bounds how long a scan calls GCP; zero for no limit
+	timeout time.Duration
+
+	// status is the scan coverage per project and nextScan when Run scans next
+	status   map[string]*ProjectStatus
+	nextScan time.Time
+}
+
+// New creates a scanner whose cached snapshot is considered fresh for ttl
+func New(client *gcp.Client, ttl time.Duration) *Scanner {
+	return &Scanner{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// SetCoordinator shares scanning with other replicas through the coordinator:
+// only the replica holding the scan lock scans and publishes its snapshot, and
+// the others serve that snapshot. It also lets a restarted replica start warm.
+func (s *Scanner) SetCoordinator(coordinator store.Coordinator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.coordinator = coordinator
+}
+
+// SetCallBudget caps the GCP API calls of each scan. A scan whose estimate
+// exceeds the budget is refused, and calls beyond it fail the running scan.
+func (s *Scanner) SetCallBudget(budget int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.budget = budget
+}
+
+// SetScanTimeout bounds how long each scan calls GCP. Calls after the timeout
+// are refused, so collectors still running fail as scan warnings and the scan
+// returns what it gathered in time.
+func (s *Scanner) SetScanTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timeout = timeout
+}
+
+// Estimate predicts the API calls of the next scan with the current scope
+func (s *Scanner) Estimate() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Usage{
+		Budget:    s.budget,
+		Estimated: s.client.EstimateUsage(s.lastUsage),
+	}
+}
+
+// AddHook registers a hook that runs on every new snapshot
+func (s *Scanner) AddHook(hook Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// AddListener registers a listener that is notified after every scan
+func (s *Scanner) AddListener(listener Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// Run scans every interval until ctx is cancelled, keeping the cache warm and
+// driving listeners such as alerting. interval is read before each wait so it
+// can change at runtime; while it is zero, no scans run.
+func (s *Scanner) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		wait := interval()
+		s.mu.Lock()
+		imported := s.current != nil && s.current.Imported != nil
+		s.mu.Unlock()
+		scheduled := wait > 0 && !imported
+		if scheduled {
+			// A snapshot another replica published within half an interval is reused
+			s.scanMu.Lock()
+			s.mu.Lock()
+			_, err := s.refreshLocked(wait/2, time.Time{})
+			s.mu.Unlock()
+			s.scanMu.Unlock()
+			if err != nil {
+				log.Printf("Scheduled scan failed: %v", err)
+			}
+		} else if wait <= 0 {
+			wait = time.Minute
+		}
+
+		s.mu.Lock()
+		s.nextScan = time.Time{}
+		if scheduled {
+			s.nextScan = time.Now().Add(wait)
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Current returns the cached snapshot, scanning first if it is missing or
+// stale. While another caller scans, a stale snapshot is returned as it is;
+// without one, Current waits for that scan.
+func (s *Scanner) Current() (*Snapshot, error) {
+	s.mu.Lock()
+	previous := s.current
+	fresh := s.freshLocked()
+	s.mu.Unlock()
+	if fresh {
+		return previous, nil
+	}
+
+	if previous == nil {
+		s.scanMu.Lock()
+	} else if !s.scanMu.TryLock() {
+		return previous, nil
+	}
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The scan this call waited for may have left a fresh snapshot
+	if s.freshLocked() {
+		return s.current, nil
+	}
+	return s.refreshLocked(s.ttl, time.Time{})
+}
+
+// freshLocked reports whether the cached snapshot can be served without scanning
+func (s *Scanner) freshLocked() bool {
+	return s.current != nil && (s.current.Imported != nil || time.Since(s.current.TakenAt) < s.ttl)
+}
+
+// Import replaces the cached snapshot with a matrix built from an IAM policy
+// export. It is served until ClearImport or a forced scan: scheduled scans
+// pause, listeners are not notified, and nothing is shared with replicas.
+func (s *Scanner) Import(raw *gcp.AccessMatrix, result *gcp.ImportResult) *Snapshot {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	raw = raw.Sorted()
+	imported := &Snapshot{
+		ID:       fmt.Sprintf("%d", result.ImportedAt.UnixNano()),
+		TakenAt:  result.ImportedAt,
+		Imported: result,
+		Matrix:   applyHooks(hooks, raw),
+		raw:      raw,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = imported
+	return s.current
+}
+
+// ClearImport drops an imported snapshot, so the next read scans GCP again.
+// It reports whether there was one.
+func (s *Scanner) ClearImport() bool {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.current.Imported == nil {
+		return false
+	}
+	s.current = nil
+	return true
+}
+
+// Scan forces a new scan and replaces the cached snapshot. The scan stops
+// calling GCP at deadline, or earlier when the scan timeout is shorter; the
+// zero time leaves only the timeout.
+func (s *Scanner) Scan(deadline time.Time) (*Snapshot, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.refreshLocked(0, deadline)
+}
+
+// ScanProjects scans the given projects again and merges them into the cached
+// snapshot, keeping the other projects as they were last scanned, so an
+// external orchestrator can spread scans of a large organization over time.
+// Otherwise it runs as a scan: calls count against the budget and stop at
+// deadline or the scan timeout, listeners are notified, and the snapshot is
+// shared with other replicas. Without a cached snapshot, it scans everything.
+func (s *Scanner) ScanProjects(projects []string, deadline time.Time) (*Snapshot, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && s.current.Imported != nil {
+		return nil, ErrImported
+	}
+	if s.coordinator == nil {
+		return s.scanProjectsLocked(projects, deadline)
+	}
+
+	// Merge into the latest snapshot of any replica
+	project := s.client.ProjectID
+	if shared := s.sharedLocked(project); shared != nil {
+		s.adoptLocked(shared)
+	}
+	unlock, ok, err := s.tryLockLocked(project)
+	if err != nil {
+		log.Printf("Warning: scan lock unavailable, scanning without coordination: %v", err)
+		return s.scanProjectsLocked(projects, deadline)
+	}
+	if !ok {
+		return nil, ErrScanInProgress
+	}
+	defer unlock()
+
+	snapshot, err := s.scanProjectsLocked(projects, deadline)
+	if err != nil {
+		return nil, err
+	}
+	s.publishLocked(project, snapshot)
+	return snapshot, nil
+}
+
+// scanProjectsLocked rescans projects into the cached snapshot
+func (s *Scanner) scanProjectsLocked(projects []string, deadline time.Time) (*Snapshot, error) {
+	if s.current == nil {
+		return s.scanLocked(deadline)
+	}
+	for _, project := range projects {
+		if _, ok := s.client.ScannedProjectID(project); !ok {
+			return nil, fmt.Errorf("%w: %s", gcp.ErrProjectNotScanned, project)
+		}
+	}
+	base := s.current.raw
+	return s.buildLocked(deadline, gcp.APIUsage{}, func() (*gcp.AccessMatrix, error) {
+		return s.client.RescanProjects(base, projects)
+	})
+}
+
+// refreshLocked replaces the cached snapshot. With a coordinator, a shared
+// snapshot younger than maxAge is adopted; otherwise the replica that gets the
+// scan lock scans and publishes, and the others adopt whatever was published last.
+func (s *Scanner) refreshLocked(maxAge time.Duration, deadline time.Time) (*Snapshot, error) {
+	if s.coordinator == nil {
+		return s.scanLocked(deadline)
+	}
+
+	project := s.client.ProjectID
+	shared := s.sharedLocked(project)
+	if shared != nil && time.Since(shared.TakenAt) < maxAge {
+		return s.adoptLocked(shared), nil
+	}
+	// A fresh replica adopts the last published snapshot before scanning, so
+	// listeners compare the new scan with it rather than with nothing
+	if s.current == nil && shared != nil {
+		s.adoptLocked(shared)
+	}
+
+	unlock, ok, err := s.tryLockLocked(project)
+	if err != nil {
+		log.Printf("Warning: scan lock unavailable, scanning without coordination: %v", err)
+		return s.scanLocked(deadline)
+	}
+	if !ok {
+		if shared := s.sharedLocked(project); shared != nil {
+			return s.adoptLocked(shared), nil
+		}
+		if s.current != nil {
+			return s.current, nil
+		}
+		return nil, ErrScanInProgress
+	}
+	defer unlock()
+
+	snapshot, err := s.scanLocked(deadline)
+	if err != nil {
+		return nil, err
+	}
+	s.publishLocked(project, snapshot)
+	return snapshot, nil
+}
+
+// tryLockLocked takes the coordinator's scan lock for the project. mu is
+// released during the round-trips to the coordinator, here and on unlock.
+func (s *Scanner) tryLockLocked(project string) (func(), bool, error) {
+	s.mu.Unlock()
+	unlock, ok, err := s.coordinator.TryLock("scan:" + project)
+	s.mu.Lock()
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return func() {
+		s.mu.Unlock()
+		defer s.mu.Lock()
+		unlock()
+	}, true, nil
+}
+
+// sharedLocked returns the snapshot published for the project, ready to be
+// adopted, if readable and newer than the cached one. mu is released while the
+// snapshot is read, decoded, and run through the hooks; like every method that
+// scans, it requires scanMu, so only Reapply may replace the cached snapshot
+// meanwhile.
+func (s *Scanner) sharedLocked(project string) *Snapshot {
+	current := s.current
+	hooks := s.hooks
+	s.mu.Unlock()
+	shared := s.fetchShared(project, current, hooks)
+	s.mu.Lock()
+
+	if shared == current {
+		return s.current
+	}
+	return shared
+}
+
+// fetchShared reads the snapshot published for the project and applies hooks
+// to it; it returns current when that is at least as new
+func (s *Scanner) fetchShared(project string, current *Snapshot, hooks []Hook) *Snapshot {
+	record, err := s.coordinator.LatestSnapshot(project)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Warning: failed to read shared snapshot: %v", err)
+		}
+		return nil
+	}
+	if current != nil && !record.TakenAt.After(current.TakenAt) {
+		return current
+	}
+
+	var matrix gcp.AccessMatrix
+	if err := json.Unmarshal(record.Matrix, &matrix); err != nil {
+		log.Printf("Warning: failed to decode shared snapshot: %v", err)
+		return nil
+	}
+	return prepareShared(hooks, &Snapshot{
+		ID:       record.ID,
+		TakenAt:  record.TakenAt,
+		Duration: record.Duration,
+		raw:      &matrix,
+	})
+}
+
+// prepareShared sorts the matrix of a snapshot scanned elsewhere and applies
+// hooks to it, so it can be adopted
+func prepareShared(hooks []Hook, shared *Snapshot) *Snapshot {
+	shared.raw = shared.raw.Sorted()
+	shared.Matrix = applyHooks(hooks, shared.raw)
+	return shared
+}
+
+// adoptLocked makes a snapshot prepared by prepareShared current. Listeners
+// are not notified; they already ran on the replica that scanned it.
+func (s *Scanner) adoptLocked(shared *Snapshot) *Snapshot {
+	if shared == s.current {
+		return s.current
+	}
+	s.recordStatusLocked(shared.raw.Projects)
+	s.current = shared
+	return s.current
+}
+
+// publishLocked shares a freshly scanned snapshot with the other replicas;
+// mu is released while the snapshot is encoded and written
+func (s *Scanner) publishLocked(project string, snapshot *Snapshot) {
+	s.mu.Unlock()
+	defer s.mu.Lock()
+
+	data, err := json.Marshal(snapshot.raw)
+	if err != nil {
+		log.Printf("Warning: failed to encode snapshot for sharing: %v", err)
+		return
+	}
+	record := store.SnapshotRecord{
+		ID:       snapshot.ID,
+		Project:  project,
+		TakenAt:  snapshot.TakenAt,
+		Duration: snapshot.Duration,
+		Matrix:   data,
+	}
+	if err := s.coordinator.PublishSnapshot(record); err != nil {
+		log.Printf("Warning: failed to publish snapshot: %v", err)
+	}
+}
+
+// Patch replaces the cached matrix with patch applied to it, e.g. a selective
+// rescan of some policies. The patched snapshot gets a new ID but keeps the
+// scan time, so a full scan still runs when the original goes stale. The patch
+// stays on this replica and listeners are not notified; alerts and other
+// replicas follow the next full scan.
+func (s *Scanner) Patch(patch func(raw *gcp.AccessMatrix) (*gcp.AccessMatrix, error)) (*Snapshot, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && s.current.Imported != nil {
+		return nil, ErrImported
+	}
+	if s.current == nil || time.Since(s.current.TakenAt) >= s.ttl {
+		if _, err := s.refreshLocked(s.ttl, time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	// scanMu keeps the snapshot in place while the patch calls GCP unlocked
+	snapshot := *s.current
+	hooks := s.hooks
+	s.mu.Unlock()
+	raw, err := patch(snapshot.raw)
+	var served *gcp.AccessMatrix
+	if err == nil {
+		raw = raw.Sorted()
+		served = applyHooks(hooks, raw)
+	}
+	s.mu.Lock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	snapshot.ID = fmt.Sprintf("%d", now.UnixNano())
+	snapshot.PatchedAt = now
+	snapshot.Usage = nil
+	snapshot.raw = raw
+	snapshot.Matrix = served
+	s.current = &snapshot
+	return s.current, nil
+}
+
+// Derive returns the current matrix with derive applied to it and the hooks
+// rerun, e.g. to rebuild access as of another point in time. The cached
+// snapshot is left as it is.
+func (s *Scanner) Derive(derive func(raw *gcp.AccessMatrix) (*gcp.AccessMatrix, error)) (*Snapshot, *gcp.AccessMatrix, error) {
+	snapshot, err := s.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := derive(snapshot.raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	return snapshot, applyHooks(hooks, raw.Sorted()), nil
+}
+
+// Export returns the current snapshot as a record for a state archive, or nil
+// when there is none or it was imported from a policy export
+func (s *Scanner) Export() (*store.SnapshotRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.current.Imported != nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(s.current.raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return &store.SnapshotRecord{
+		ID:       s.current.ID,
+		Project:  s.client.ProjectID,
+		TakenAt:  s.current.TakenAt,
+		Duration: s.current.Duration,
+		Matrix:   data,
+	}, nil
+}
+
+// Restore makes a snapshot from a state archive current. It is served until
+// it goes stale like a scanned one; listeners are not notified.
+func (s *Scanner) Restore(record store.SnapshotRecord) error {
+	var matrix gcp.AccessMatrix
+	if err := json.Unmarshal(record.Matrix, &matrix); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	restored := prepareShared(hooks, &Snapshot{
+		ID:       record.ID,
+		TakenAt:  record.TakenAt,
+		Duration: record.Duration,
+		raw:      &matrix,
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.adoptLocked(restored)
+	return nil
+}
+
+// Reapply reruns the hooks against the cached snapshot without rescanning,
+// so changes to hook inputs (such as uploaded metadata) show up immediately
+func (s *Scanner) Reapply() {
+	s.mu.Lock()
+	previous := s.current
+	hooks := s.hooks
+	s.mu.Unlock()
+	if previous == nil {
+		return
+	}
+	snapshot := *previous
+	snapshot.Matrix = applyHooks(hooks, snapshot.raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A scan that finished meanwhile already ran the hooks with their new inputs
+	if s.current == previous {
+		s.current = &snapshot
+	}
+}
+
+// scanLocked runs a full scan of GCP. Like every method that scans, it
+// requires scanMu as well as mu.
+func (s *Scanner) scanLocked(deadline time.Time) (*Snapshot, error) {
+	estimate := s.client.EstimateUsage(s.lastUsage)
+	if s.budget > 0 && estimate.Total() > s.budget {
+		return nil, fmt.Errorf("%w: estimated %d calls, budget is %d", ErrOverBudget, estimate.Total(), s.budget)
+	}
+
+	snapshot, err := s.buildLocked(deadline, estimate, s.client.GetAccessMatrix)
+	if err != nil {
+		return nil, err
+	}
+	s.lastUsage = snapshot.Usage.Calls
+	return snapshot, nil
+}
+
+// buildLocked makes the matrix returned by build, which calls GCP until
+// deadline or the scan timeout, whichever comes first, the new snapshot and
+// notifies the listeners. mu is released while build and the hooks run.
+func (s *Scanner) buildLocked(deadline time.Time, estimate gcp.APIUsage, build func() (*gcp.AccessMatrix, error)) (*Snapshot, error) {
+	start := time.Now()
+	if s.timeout > 0 && (deadline.IsZero() || start.Add(s.timeout).Before(deadline)) {
+		deadline = start.Add(s.timeout)
+	}
+	meter := gcp.NewUsageMeter(s.budget)
+	meter.SetDeadline(deadline)
+	hooks := s.hooks
+
+	s.mu.Unlock()
+	stop := s.client.Meter(meter)
+	matrix, err := build()
+	stop()
+	var served *gcp.AccessMatrix
+	if err == nil {
+		matrix = matrix.Sorted()
+		served = applyHooks(hooks, matrix)
+	}
+	s.mu.Lock()
+
+	if err != nil {
+		s.recordFailureLocked(start, err)
+		return nil, err
+	}
+	s.recordStatusLocked(matrix.Projects)
+
+	previous := s.current
+	s.current = &Snapshot{
+		ID:       fmt.Sprintf("%d", start.UnixNano()),
+		TakenAt:  start,
+		Duration: time.Since(start),
+		Matrix:   served,
+		Usage: &Usage{
+			Budget:    s.budget,
+			Estimated: estimate,
+			Calls:     meter.Calls(),
+			Rejected:  meter.Rejected(),
+			Deadline:  deadline,
+		},
+		raw: matrix,
+	}
+
+	if len(s.listeners) > 0 {
+		var wg sync.WaitGroup
+		s.current.notified = make(chan struct{})
+		for _, listener := range s.listeners {
+			wg.Add(1)
+			go func(listener Listener, current *Snapshot) {
+				defer wg.Done()
+				listener(previous, current)
+			}(listener, s.current)
+		}
+		go func(done chan struct{}) {
+			wg.Wait()
+			close(done)
+		}(s.current.notified)
+	}
+	return s.current, nil
+}
+
+// applyHooks returns a copy of raw with hooks applied. Matrices are put into
+// canonical order (gcp.AccessMatrix.Sorted) before they get here, so
+// responses list users, resources, and access the same way every time.
+func applyHooks(hooks []Hook, raw *gcp.AccessMatrix) *gcp.AccessMatrix {
+	if len(hooks) == 0 {
+		return raw
+	}
+
+	matrix := &gcp.AccessMatrix{
+		Users:     append([]gcp.User(nil), raw.Users...),
+		Resources: raw.Resources,
+		Access:    raw.Access,
+		Eligible:  raw.Eligible,
+		Warnings:  raw.Warnings,
+		Projects:  raw.Projects,
+		History:   raw.History,
+	}
+	for _, hook := range hooks {
+		hook(matrix)
+	}
+	return matrix
+}
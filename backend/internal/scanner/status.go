@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"sort"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+)
+
+// ProjectStatus is the scan coverage of one project across scans
+type ProjectStatus struct {
+	Project string `json:"project"`
+	// LastScan is when the project was last attempted, LastSuccess when it
+	// was last scanned without failing
+	LastScan    time.Time     `json:"lastScan"`
+	LastSuccess time.Time     `json:"lastSuccess,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	// Error is the failure of the last attempt; empty when it succeeded
+	Error     string `json:"error,omitempty"`
+	Resources int    `json:"resources"`
+	Entries   int    `json:"entries"`
+	Warnings  int    `json:"warnings"`
+	// NextScan is when the next scheduled scan starts; zero when scans run
+	// on demand only
+	NextScan time.Time `json:"nextScan,omitempty"`
+}
+
+// Status returns the scan status of every project scanned so far, failing
+// projects first
+func (s *Scanner) Status() []ProjectStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ProjectStatus, 0, len(s.status))
+	for _, status := range s.status {
+		status.NextScan = s.nextScan
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if (statuses[i].Error != "") != (statuses[j].Error != "") {
+			return statuses[i].Error != ""
+		}
+		return statuses[i].Project < statuses[j].Project
+	})
+	return statuses
+}
+
+// recordStatusLocked updates the per-project status from the outcome of each
+// project in a scan
+func (s *Scanner) recordStatusLocked(scans []gcp.ProjectScan) {
+	if s.status == nil {
+		s.status = make(map[string]*ProjectStatus)
+	}
+	for _, scan := range scans {
+		status := s.status[scan.Project]
+		if status == nil {
+			status = &ProjectStatus{Project: scan.Project}
+			s.status[scan.Project] = status
+		}
+		if !scan.StartedAt.After(status.LastScan) {
+			continue // already recorded, e.g. a snapshot adopted twice
+		}
+		status.LastScan = scan.StartedAt
+		status.Duration = scan.Duration
+		status.Error = scan.Error
+		if scan.Error == "" {
+			status.LastSuccess = scan.StartedAt
+			status.Resources = scan.Resources
+			status.Entries = scan.Entries
+			status.Warnings = scan.Warnings
+		}
+	}
+}
+
+// recordFailureLocked marks a whole scan as failed: the projects of the
+// previous scan, or the configured project before any scan succeeded
+func (s *Scanner) recordFailureLocked(start time.Time, err error) {
+	var scans []gcp.ProjectScan
+	for project := range s.status {
+		scans = append(scans, gcp.ProjectScan{Project: project, StartedAt: start, Duration: time.Since(start), Error: err.Error()})
+	}
+	if len(scans) == 0 {
+		scans = append(scans, gcp.ProjectScan{Project: s.client.ProjectID, StartedAt: start, Duration: time.Since(start), Error: err.Error()})
+	}
+	s.recordStatusLocked(scans)
+}
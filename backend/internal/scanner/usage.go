@@ -0,0 +1,29 @@
+package scanner
+
+import (
+	"log"
+
+	"gcp-access-visualizer/internal/store"
+)
+
+// UsageListener returns a listener that records the API usage of every scan,
+// feeding /api/scans/:id/usage
+func UsageListener(project string, st store.Store) Listener {
+	return func(previous, current *Snapshot) {
+		if current.Usage == nil {
+			return
+		}
+		record := store.UsageRecord{
+			SnapshotID: current.ID,
+			TakenAt:    current.TakenAt,
+			Project:    project,
+			Budget:     current.Usage.Budget,
+			Estimated:  current.Usage.Estimated,
+			Calls:      current.Usage.Calls,
+			Rejected:   current.Usage.Rejected,
+		}
+		if err := st.AppendUsage(record); err != nil {
+			log.Printf("Warning: failed to record scan usage: %v", err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package store
+
+import "time"
+
+// SnapshotRecord is a scanned access matrix shared between replicas. Matrix
+// holds the JSON-encoded matrix as returned by GCP, before enrichment hooks.
+type SnapshotRecord struct {
+	ID       string        `json:"id"`
+	Project  string        `json:"project"`
+	TakenAt  time.Time     `json:"takenAt"`
+	Duration time.Duration `json:"duration"`
+	Matrix   []byte        `json:"matrix"`
+}
+
+// Coordinator lets several replicas share one scanner: only the holder of a
+// lock scans, and every replica serves the snapshot it published
+type Coordinator interface {
+	// TryLock acquires the named lock without waiting. ok is false when another
+	// replica holds it; otherwise unlock must be called to release it.
+	TryLock(name string) (unlock func(), ok bool, err error)
+	// PublishSnapshot replaces the project's shared snapshot
+	PublishSnapshot(record SnapshotRecord) error
+	// LatestSnapshot returns the project's shared snapshot, or ErrNotFound
+	LatestSnapshot(project string) (*SnapshotRecord, error)
+}
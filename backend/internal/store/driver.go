@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store drivers selectable with STORE_DRIVER
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverFile     = "file"
+)
+
+// Options configures a driver. Drivers read what they need and ignore the rest.
+type Options struct {
+	DataDir     string // local directory for embedded databases and files
+	DatabaseURL string // connection string for networked databases
+	// Params holds driver-specific settings from STORE_OPTIONS (key=value pairs),
+	// e.g. a Firestore collection prefix or a BigQuery dataset
+	Params map[string]string
+}
+
+// Driver opens a Store. A store that also implements Coordinator lets
+// replicas share scanned snapshots through it, and one that implements
+// TaskQueue lets scan workers share the projects of a scan.
+type Driver interface {
+	Open(options Options) (Store, error)
+}
+
+// DriverFunc adapts a function to a Driver
+type DriverFunc func(options Options) (Store, error)
+
+// Open calls f
+func (f DriverFunc) Open(options Options) (Store, error) {
+	return f(options)
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+func init() {
+	Register(DriverSQLite, DriverFunc(func(options Options) (Store, error) {
+		return NewSQLiteStore(options.DataDir)
+	}))
+	Register(DriverPostgres, DriverFunc(func(options Options) (Store, error) {
+		if options.DatabaseURL == "" {
+			return nil, errors.New("DATABASE_URL is required for the postgres store driver")
+		}
+		return NewPostgresStore(options.DatabaseURL)
+	}))
+	Register(DriverFile, DriverFunc(func(options Options) (Store, error) {
+		return NewFileStore(options.DataDir)
+	}))
+}
+
+// Register makes a driver selectable by name. It is meant to be called from
+// the init function of the package implementing the driver, which the binary
+// then imports for its side effect; it panics if the name is taken.
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if driver == nil {
+		panic("store: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("store: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the registered drivers, sorted
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open creates the store for a registered driver; an empty name selects sqlite
+func Open(name string, options Options) (Store, error) {
+	if name == "" {
+		name = DriverSQLite
+	}
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store driver %q (want %s)", name, strings.Join(Drivers(), ", "))
+	}
+	return driver.Open(options)
+}
+
+// Pinger is implemented by stores backed by a server or file that can become
+// unreachable; health checks use it to verify connectivity
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,671 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileState is the on-disk layout of a FileStore
+type fileState struct {
+	Views      map[string]SavedView           `json:"views"`
+	Profiles   map[string]PrincipalProfile    `json:"profiles"`
+	Owners     map[string]ServiceAccountOwner `json:"owners"`
+	Findings   map[string]FindingState        `json:"findings"`
+	Exceptions map[string]Exception           `json:"exceptions"`
+	Scores     []ScoreRecord                  `json:"scores"`
+	Metrics    []MetricsRecord                `json:"metrics"`
+	Usage      []UsageRecord                  `json:"usage"`
+	Audit      []AuditEvent                   `json:"audit"`
+	Settings   map[string]json.RawMessage     `json:"settings"`
+}
+
+// FileStore is a Store that keeps all state in a single JSON file
+type FileStore struct {
+	path string
+
+	mu    sync.Mutex
+	state fileState
+}
+
+// NewFileStore opens (or creates) the JSON state file inside dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s := &FileStore{
+		path: filepath.Join(dir, "state.json"),
+		state: fileState{
+			Views:      make(map[string]SavedView),
+			Profiles:   make(map[string]PrincipalProfile),
+			Owners:     make(map[string]ServiceAccountOwner),
+			Findings:   make(map[string]FindingState),
+			Exceptions: make(map[string]Exception),
+			Settings:   make(map[string]json.RawMessage),
+		},
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.state); err != nil {
+			return nil, fmt.Errorf("failed to parse state file: %w", err)
+		}
+		if s.state.Views == nil {
+			s.state.Views = make(map[string]SavedView)
+		}
+		if s.state.Profiles == nil {
+			s.state.Profiles = make(map[string]PrincipalProfile)
+		}
+		if s.state.Owners == nil {
+			s.state.Owners = make(map[string]ServiceAccountOwner)
+		}
+		if s.state.Findings == nil {
+			s.state.Findings = make(map[string]FindingState)
+		}
+		if s.state.Exceptions == nil {
+			s.state.Exceptions = make(map[string]Exception)
+		}
+		if s.state.Settings == nil {
+			s.state.Settings = make(map[string]json.RawMessage)
+		}
+	}
+
+	return s, nil
+}
+
+// ListViews returns all saved views ordered by name
+func (s *FileStore) ListViews() ([]SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	views := make([]SavedView, 0, len(s.state.Views))
+	for _, view := range s.state.Views {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views, nil
+}
+
+// GetView returns the saved view with the given ID
+func (s *FileStore) GetView(id string) (*SavedView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	view, ok := s.state.Views[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &view, nil
+}
+
+// SaveView creates or replaces a saved view
+func (s *FileStore) SaveView(view *SavedView) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if view.ID == "" {
+		view.ID = newID()
+		view.CreatedAt = now
+	} else {
+		existing, ok := s.state.Views[view.ID]
+		if !ok {
+			return ErrNotFound
+		}
+		view.CreatedAt = existing.CreatedAt
+	}
+	view.UpdatedAt = now
+
+	return putEntry(s, s.state.Views, view.ID, *view)
+}
+
+// DeleteView removes a saved view
+func (s *FileStore) DeleteView(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return deleteEntry(s, s.state.Views, id)
+}
+
+// ListProfiles returns all principal profiles ordered by email
+func (s *FileStore) ListProfiles() ([]PrincipalProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles := make([]PrincipalProfile, 0, len(s.state.Profiles))
+	for _, profile := range s.state.Profiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Email < profiles[j].Email
+	})
+	return profiles, nil
+}
+
+// ReplaceProfiles replaces every profile previously loaded from source
+func (s *FileStore) ReplaceProfiles(source string, profiles []PrincipalProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := maps.Clone(s.state.Profiles)
+	for email, profile := range s.state.Profiles {
+		if profile.Source == source {
+			delete(s.state.Profiles, email)
+		}
+	}
+	for _, profile := range profiles {
+		profile.Email = strings.ToLower(profile.Email)
+		profile.Source = source
+		s.state.Profiles[profile.Email] = profile
+	}
+	if err := s.flushLocked(); err != nil {
+		s.state.Profiles = previous
+		return err
+	}
+	return nil
+}
+
+// ListOwners returns all manual service account owner mappings ordered by email
+func (s *FileStore) ListOwners() ([]ServiceAccountOwner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owners := make([]ServiceAccountOwner, 0, len(s.state.Owners))
+	for _, owner := range s.state.Owners {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].Email < owners[j].Email
+	})
+	return owners, nil
+}
+
+// SetOwner creates or replaces the owner mapping for a service account
+func (s *FileStore) SetOwner(owner *ServiceAccountOwner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner.Email = strings.ToLower(owner.Email)
+	owner.UpdatedAt = time.Now().UTC()
+	return putEntry(s, s.state.Owners, owner.Email, *owner)
+}
+
+// DeleteOwner removes the owner mapping for a service account
+func (s *FileStore) DeleteOwner(email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return deleteEntry(s, s.state.Owners, strings.ToLower(email))
+}
+
+// ListFindingStates returns the triage state of every tracked finding ordered by ID
+func (s *FileStore) ListFindingStates() ([]FindingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]FindingState, 0, len(s.state.Findings))
+	for _, state := range s.state.Findings {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states, nil
+}
+
+// GetFindingState returns the triage state of a finding
+func (s *FileStore) GetFindingState(id string) (*FindingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.state.Findings[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &state, nil
+}
+
+// SetFindingState creates or replaces the triage state of a finding
+func (s *FileStore) SetFindingState(state *FindingState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.UpdatedAt = time.Now().UTC()
+	return putEntry(s, s.state.Findings, state.ID, *state)
+}
+
+// DeleteFindingState forgets the triage state of a finding
+func (s *FileStore) DeleteFindingState(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return deleteEntry(s, s.state.Findings, id)
+}
+
+// ListExceptions returns every exception, soonest expiring first
+func (s *FileStore) ListExceptions() ([]Exception, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exceptions := make([]Exception, 0, len(s.state.Exceptions))
+	for _, exception := range s.state.Exceptions {
+		exceptions = append(exceptions, exception)
+	}
+	sortExceptions(exceptions)
+	return exceptions, nil
+}
+
+// CreateException stores a new exception
+func (s *FileStore) CreateException(exception *Exception) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exception.ID = newID()
+	exception.CreatedAt = time.Now().UTC()
+	return putEntry(s, s.state.Exceptions, exception.ID, *exception)
+}
+
+// DeleteException removes an exception
+func (s *FileStore) DeleteException(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return deleteEntry(s, s.state.Exceptions, id)
+}
+
+// AppendScore records a score for a snapshot
+func (s *FileStore) AppendScore(record ScoreRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return appendRecord(s, &s.state.Scores, record)
+}
+
+// ListScores returns score records for a project ordered by time, oldest first
+func (s *FileStore) ListScores(project string) ([]ScoreRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []ScoreRecord{}
+	for _, record := range s.state.Scores {
+		if record.Project == project {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].TakenAt.Before(records[j].TakenAt)
+	})
+	return records, nil
+}
+
+// AppendMetrics records posture metrics for a snapshot
+func (s *FileStore) AppendMetrics(record MetricsRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return appendRecord(s, &s.state.Metrics, record)
+}
+
+// ListMetrics returns metrics records for a project taken at or after since, oldest first
+func (s *FileStore) ListMetrics(project string, since time.Time) ([]MetricsRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []MetricsRecord{}
+	for _, record := range s.state.Metrics {
+		if record.Project == project && !record.TakenAt.Before(since) {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].TakenAt.Before(records[j].TakenAt)
+	})
+	return records, nil
+}
+
+// AppendUsage records the API usage of a scan
+func (s *FileStore) AppendUsage(record UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return appendRecord(s, &s.state.Usage, record)
+}
+
+// GetUsage returns the usage record of a snapshot, or ErrNotFound
+func (s *FileStore) GetUsage(snapshotID string) (*UsageRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range s.state.Usage {
+		if record.SnapshotID == snapshotID {
+			return &record, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// AppendAudit records an API call
+func (s *FileStore) AppendAudit(event AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	return appendRecord(s, &s.state.Audit, event)
+}
+
+// ListAudit returns the audit events matching filter, newest first
+func (s *FileStore) ListAudit(filter AuditFilter) ([]AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []AuditEvent{}
+	for i := len(s.state.Audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		if filter.matches(s.state.Audit[i]) {
+			events = append(events, s.state.Audit[i])
+		}
+	}
+	return events, nil
+}
+
+// GetSetting returns a persisted setting, or ErrNotFound
+func (s *FileStore) GetSetting(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.state.Settings[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// PutSetting persists a setting; the value must be JSON
+func (s *FileStore) PutSetting(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return putEntry(s, s.state.Settings, key, append(json.RawMessage(nil), value...))
+}
+
+// Prune drops score, metrics, and usage records the retention policy does not
+// keep, applying it to each project's history separately, and expired audit events
+func (s *FileStore) Prune(policy RetentionPolicy) (PruneResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Records are filtered into new slices, so previous is intact for a rollback
+	previous := s.state
+	scoreTimes := make(map[string][]time.Time)
+	for _, record := range s.state.Scores {
+		scoreTimes[record.Project] = append(scoreTimes[record.Project], record.TakenAt)
+	}
+	scoreKeep := keepByProject(policy, scoreTimes)
+
+	metricsTimes := make(map[string][]time.Time)
+	for _, record := range s.state.Metrics {
+		metricsTimes[record.Project] = append(metricsTimes[record.Project], record.TakenAt)
+	}
+	metricsKeep := keepByProject(policy, metricsTimes)
+
+	usageTimes := make(map[string][]time.Time)
+	for _, record := range s.state.Usage {
+		usageTimes[record.Project] = append(usageTimes[record.Project], record.TakenAt)
+	}
+	usageKeep := keepByProject(policy, usageTimes)
+
+	var result PruneResult
+	seen := make(map[string]int)
+	scores := make([]ScoreRecord, 0, len(s.state.Scores))
+	for _, record := range s.state.Scores {
+		i := seen[record.Project]
+		seen[record.Project]++
+		if scoreKeep[record.Project][i] {
+			scores = append(scores, record)
+		} else {
+			result.Scores++
+		}
+	}
+	s.state.Scores = scores
+
+	seen = make(map[string]int)
+	metrics := make([]MetricsRecord, 0, len(s.state.Metrics))
+	for _, record := range s.state.Metrics {
+		i := seen[record.Project]
+		seen[record.Project]++
+		if metricsKeep[record.Project][i] {
+			metrics = append(metrics, record)
+		} else {
+			result.Metrics++
+		}
+	}
+	s.state.Metrics = metrics
+
+	seen = make(map[string]int)
+	usage := make([]UsageRecord, 0, len(s.state.Usage))
+	for _, record := range s.state.Usage {
+		i := seen[record.Project]
+		seen[record.Project]++
+		if usageKeep[record.Project][i] {
+			usage = append(usage, record)
+		} else {
+			result.Usage++
+		}
+	}
+	s.state.Usage = usage
+
+	if cutoff := policy.auditCutoff(); !cutoff.IsZero() {
+		audit := make([]AuditEvent, 0, len(s.state.Audit))
+		for _, event := range s.state.Audit {
+			if event.Time.Before(cutoff) {
+				result.Audit++
+			} else {
+				audit = append(audit, event)
+			}
+		}
+		s.state.Audit = audit
+	}
+
+	if result.Scores == 0 && result.Metrics == 0 && result.Usage == 0 && result.Audit == 0 {
+		return result, nil
+	}
+	if err := s.flushLocked(); err != nil {
+		s.state = previous
+		return PruneResult{}, err
+	}
+	return result, nil
+}
+
+// keepByProject applies the policy to each project's record times
+func keepByProject(policy RetentionPolicy, times map[string][]time.Time) map[string][]bool {
+	keep := make(map[string][]bool, len(times))
+	for project, projectTimes := range times {
+		keep[project] = policy.keep(projectTimes)
+	}
+	return keep
+}
+
+// ExportState returns a copy of everything the store holds
+func (s *FileStore) ExportState() (*State, error) {
+	views, err := s.ListViews()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := s.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+	owners, err := s.ListOwners()
+	if err != nil {
+		return nil, err
+	}
+	findingStates, err := s.ListFindingStates()
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.ListExceptions()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := newState()
+	state.Views = views
+	state.Profiles = profiles
+	state.Owners = owners
+	state.FindingStates = findingStates
+	state.Exceptions = exceptions
+	state.Scores = append(state.Scores, s.state.Scores...)
+	state.Metrics = append(state.Metrics, s.state.Metrics...)
+	state.Usage = append(state.Usage, s.state.Usage...)
+	state.Audit = append(state.Audit, s.state.Audit...)
+	for key, value := range s.state.Settings {
+		state.Settings[key] = append(json.RawMessage(nil), value...)
+	}
+	return state, nil
+}
+
+// ImportState replaces everything the store holds with state
+func (s *FileStore) ImportState(state *State) error {
+	if err := state.check(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	imported := fileState{
+		Views:      make(map[string]SavedView, len(state.Views)),
+		Profiles:   make(map[string]PrincipalProfile, len(state.Profiles)),
+		Owners:     make(map[string]ServiceAccountOwner, len(state.Owners)),
+		Findings:   make(map[string]FindingState, len(state.FindingStates)),
+		Exceptions: make(map[string]Exception, len(state.Exceptions)),
+		Scores:     append([]ScoreRecord(nil), state.Scores...),
+		Metrics:    append([]MetricsRecord(nil), state.Metrics...),
+		Usage:      append([]UsageRecord(nil), state.Usage...),
+		Audit:      append([]AuditEvent(nil), state.Audit...),
+		Settings:   make(map[string]json.RawMessage, len(state.Settings)),
+	}
+	for _, view := range state.Views {
+		imported.Views[view.ID] = view
+	}
+	for _, profile := range state.Profiles {
+		profile.Email = strings.ToLower(profile.Email)
+		imported.Profiles[profile.Email] = profile
+	}
+	for _, owner := range state.Owners {
+		owner.Email = strings.ToLower(owner.Email)
+		imported.Owners[owner.Email] = owner
+	}
+	for _, finding := range state.FindingStates {
+		imported.Findings[finding.ID] = finding
+	}
+	for _, exception := range state.Exceptions {
+		if exception.ID == "" {
+			exception.ID = newID()
+		}
+		imported.Exceptions[exception.ID] = exception
+	}
+	// Audit events are kept oldest first
+	sort.SliceStable(imported.Audit, func(i, j int) bool { return imported.Audit[i].Time.Before(imported.Audit[j].Time) })
+	for key, value := range state.Settings {
+		imported.Settings[key] = value
+	}
+
+	previous := s.state
+	s.state = imported
+	if err := s.flushLocked(); err != nil {
+		s.state = previous
+		return err
+	}
+	return nil
+}
+
+// Close flushes pending state to disk
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushLocked()
+}
+
+// putEntry stores value under key in a map of the state and flushes it,
+// restoring the previous entry when the flush fails. s.mu must be held.
+func putEntry[T any](s *FileStore, entries map[string]T, key string, value T) error {
+	previous, existed := entries[key]
+	entries[key] = value
+	if err := s.flushLocked(); err != nil {
+		if existed {
+			entries[key] = previous
+		} else {
+			delete(entries, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteEntry removes key from a map of the state and flushes it, restoring
+// the entry when the flush fails. s.mu must be held.
+func deleteEntry[T any](s *FileStore, entries map[string]T, key string) error {
+	previous, ok := entries[key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(entries, key)
+	if err := s.flushLocked(); err != nil {
+		entries[key] = previous
+		return err
+	}
+	return nil
+}
+
+// appendRecord appends record to a list of the state and flushes it, dropping
+// the record again when the flush fails. s.mu must be held.
+func appendRecord[T any](s *FileStore, records *[]T, record T) error {
+	n := len(*records)
+	*records = append(*records, record)
+	if err := s.flushLocked(); err != nil {
+		*records = (*records)[:n]
+		return err
+	}
+	return nil
+}
+
+// flushLocked atomically rewrites the state file
+func (s *FileStore) flushLocked() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
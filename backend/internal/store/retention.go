@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy bounds per-snapshot history such as scores, posture metrics, and API usage.
+// The newest record of each of the last Daily days, Weekly ISO weeks, and Monthly
+// months is kept; everything else is pruned. A zero policy keeps everything.
+type RetentionPolicy struct {
+	Daily   int `json:"daily"`
+	Weekly  int `json:"weekly"`
+	Monthly int `json:"monthly"`
+
+	// Audit is how long audit events are kept; zero keeps them forever
+	Audit time.Duration `json:"audit"`
+}
+
+// IsZero reports whether the policy keeps all per-snapshot history
+func (p RetentionPolicy) IsZero() bool {
+	return p.Daily == 0 && p.Weekly == 0 && p.Monthly == 0
+}
+
+// PruneResult counts the records removed by a prune
+type PruneResult struct {
+	Scores  int `json:"scores"`
+	Metrics int `json:"metrics"`
+	Usage   int `json:"usage"`
+	Audit   int `json:"audit"`
+}
+
+// auditCutoff returns the time before which audit events are pruned, or the
+// zero time to keep them all
+func (p RetentionPolicy) auditCutoff() time.Time {
+	if p.Audit <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-p.Audit)
+}
+
+// keep reports, for each time, whether the policy retains the record taken then
+func (p RetentionPolicy) keep(times []time.Time) []bool {
+	kept := make([]bool, len(times))
+	if p.IsZero() {
+		for i := range kept {
+			kept[i] = true
+		}
+		return kept
+	}
+
+	order := make([]int, len(times))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return times[order[a]].After(times[order[b]]) })
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	months := make(map[string]bool)
+	for _, i := range order {
+		t := times[i].UTC()
+		year, week := t.ISOWeek()
+		day := t.Format("2006-01-02")
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		month := t.Format("2006-01")
+
+		// Walking newest first, the first record seen in a bucket is its newest
+		if !days[day] {
+			days[day] = true
+			kept[i] = kept[i] || len(days) <= p.Daily
+		}
+		if !weeks[weekKey] {
+			weeks[weekKey] = true
+			kept[i] = kept[i] || len(weeks) <= p.Weekly
+		}
+		if !months[month] {
+			months[month] = true
+			kept[i] = kept[i] || len(months) <= p.Monthly
+		}
+	}
+	return kept
+}
+
+// RunCompaction prunes st with the policy every interval until ctx is done
+func RunCompaction(ctx context.Context, st Store, policy RetentionPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			Compact(st, policy)
+		}
+	}
+}
+
+// Compact prunes st with the policy once, logging what was removed
+func Compact(st Store, policy RetentionPolicy) {
+	result, err := st.Prune(policy)
+	if err != nil {
+		log.Printf("Warning: compaction failed: %v", err)
+		return
+	}
+	if result.Scores > 0 || result.Metrics > 0 || result.Usage > 0 || result.Audit > 0 {
+		log.Printf("Compaction pruned %d score, %d metrics, %d usage, and %d audit records", result.Scores, result.Metrics, result.Usage, result.Audit)
+	}
+}
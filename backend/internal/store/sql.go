@@ -0,0 +1,1316 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order and recorded in schema_migrations. Never edit
+// a released migration; append a new one instead.
+var migrations = []string{
+	`CREATE TABLE views (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL,
+		filters TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL
+	);
+	CREATE TABLE profiles (
+		email TEXT PRIMARY KEY,
+		display_name TEXT NOT NULL,
+		team TEXT NOT NULL,
+		manager TEXT NOT NULL,
+		source TEXT NOT NULL
+	);
+	CREATE TABLE owners (
+		email TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	);
+	CREATE TABLE scores (
+		snapshot_id TEXT NOT NULL,
+		taken_at BIGINT NOT NULL,
+		project TEXT NOT NULL,
+		score DOUBLE PRECISION NOT NULL,
+		breakdown TEXT NOT NULL
+	);
+	CREATE INDEX scores_project_taken_at ON scores (project, taken_at);
+	CREATE TABLE metrics (
+		snapshot_id TEXT NOT NULL,
+		taken_at BIGINT NOT NULL,
+		project TEXT NOT NULL,
+		principals INTEGER NOT NULL,
+		owners INTEGER NOT NULL,
+		public_resources INTEGER NOT NULL,
+		external_principals INTEGER NOT NULL,
+		findings TEXT NOT NULL
+	);
+	CREATE INDEX metrics_project_taken_at ON metrics (project, taken_at);`,
+	`CREATE TABLE snapshots (
+		project TEXT PRIMARY KEY,
+		id TEXT NOT NULL,
+		taken_at BIGINT NOT NULL,
+		duration BIGINT NOT NULL,
+		matrix TEXT NOT NULL
+	);`,
+	`CREATE TABLE settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	);`,
+	`CREATE TABLE scan_usage (
+		snapshot_id TEXT NOT NULL,
+		taken_at BIGINT NOT NULL,
+		project TEXT NOT NULL,
+		budget INTEGER NOT NULL,
+		estimated TEXT NOT NULL,
+		calls TEXT NOT NULL,
+		rejected INTEGER NOT NULL
+	);
+	CREATE INDEX scan_usage_snapshot_id ON scan_usage (snapshot_id);`,
+	`CREATE TABLE audit_log (
+		id TEXT PRIMARY KEY,
+		at BIGINT NOT NULL,
+		actor TEXT NOT NULL,
+		client_ip TEXT NOT NULL,
+		method TEXT NOT NULL,
+		route TEXT NOT NULL,
+		path TEXT NOT NULL,
+		query TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		bytes INTEGER NOT NULL,
+		export TEXT NOT NULL
+	);
+	CREATE INDEX audit_log_at ON audit_log (at);`,
+	`CREATE TABLE finding_states (
+		id TEXT PRIMARY KEY,
+		status TEXT NOT NULL,
+		assignee TEXT NOT NULL,
+		snoozed_until BIGINT NOT NULL,
+		justification TEXT NOT NULL,
+		note TEXT NOT NULL,
+		resolved_at BIGINT NOT NULL,
+		updated_by TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	);`,
+	`CREATE TABLE exceptions (
+		id TEXT PRIMARY KEY,
+		rule TEXT NOT NULL,
+		principal TEXT NOT NULL,
+		resource TEXT NOT NULL,
+		role TEXT NOT NULL,
+		justification TEXT NOT NULL,
+		expires_at BIGINT NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at BIGINT NOT NULL
+	);`,
+	`CREATE TABLE scan_tasks (
+		id TEXT PRIMARY KEY,
+		scan_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		project TEXT NOT NULL,
+		state TEXT NOT NULL,
+		worker TEXT NOT NULL,
+		attempts INTEGER NOT NULL,
+		payload TEXT NOT NULL,
+		result TEXT NOT NULL,
+		error TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		claimed_at BIGINT NOT NULL,
+		finished_at BIGINT NOT NULL
+	);
+	CREATE INDEX scan_tasks_scan_id ON scan_tasks (scan_id, seq);
+	CREATE INDEX scan_tasks_state ON scan_tasks (state, created_at);`,
+}
+
+// SQLStore is a Store backed by SQLite (embedded, single replica) or Postgres
+// (shared by several replicas)
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+
+	// locks held in this process; SQLite stores are never shared between replicas
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database state.db inside dir
+func NewSQLiteStore(dir string) (*SQLStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	dsn := "file:" + filepath.Join(dir, "state.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite serializes writers; one connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	return newSQLStore(db, DriverSQLite)
+}
+
+// NewPostgresStore connects to the Postgres database at url
+func NewPostgresStore(url string) (*SQLStore, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres database: %w", err)
+	}
+
+	return newSQLStore(db, DriverPostgres)
+}
+
+// newSQLStore checks the connection and applies pending migrations
+func newSQLStore(db *sql.DB, dialect string) (*SQLStore, error) {
+	s := &SQLStore{db: db, dialect: dialect, locks: make(map[string]bool)}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect, err)
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so
+// replicas starting together do not apply the same migration twice
+const migrationLockKey = 0x6761762d6d696772
+
+// migrate applies the migrations that have not been recorded yet. Everything
+// runs on one connection, which on Postgres holds migrationLockKey throughout;
+// the schema version is read once the lock is held.
+func (s *SQLStore) migrate() error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	if s.dialect == DriverPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var applied int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for version := applied + 1; version <= len(migrations); version++ {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", version, err)
+		}
+		for _, statement := range strings.Split(migrations[version-1], ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d failed: %w", version, err)
+			}
+		}
+		if _, err := tx.Exec(s.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// rebind rewrites ? placeholders as $1, $2, ... for Postgres
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// exec runs a statement with ? placeholders
+func (s *SQLStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.rebind(query), args...)
+}
+
+// query runs a query with ? placeholders
+func (s *SQLStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.rebind(query), args...)
+}
+
+// fromNanos converts a stored Unix nanosecond timestamp to UTC time
+func fromNanos(nanos int64) time.Time {
+	return time.Unix(0, nanos).UTC()
+}
+
+// ListViews returns all saved views ordered by name
+func (s *SQLStore) ListViews() ([]SavedView, error) {
+	rows, err := s.query(`SELECT id, name, description, filters, created_at, updated_at FROM views ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []SavedView{}
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+// GetView returns the saved view with the given ID
+func (s *SQLStore) GetView(id string) (*SavedView, error) {
+	rows, err := s.query(`SELECT id, name, description, filters, created_at, updated_at FROM views WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get view: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return scanView(rows)
+}
+
+// scanView reads one views row
+func scanView(rows *sql.Rows) (*SavedView, error) {
+	var view SavedView
+	var filters string
+	var created, updated int64
+	if err := rows.Scan(&view.ID, &view.Name, &view.Description, &filters, &created, &updated); err != nil {
+		return nil, fmt.Errorf("failed to read view: %w", err)
+	}
+	if err := json.Unmarshal([]byte(filters), &view.Filters); err != nil {
+		return nil, fmt.Errorf("failed to decode view filters: %w", err)
+	}
+	view.CreatedAt = fromNanos(created)
+	view.UpdatedAt = fromNanos(updated)
+	return &view, nil
+}
+
+// SaveView creates or replaces a saved view
+func (s *SQLStore) SaveView(view *SavedView) error {
+	filters, err := json.Marshal(view.Filters)
+	if err != nil {
+		return fmt.Errorf("failed to encode view filters: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if view.ID == "" {
+		view.ID = newID()
+		view.CreatedAt = now
+		view.UpdatedAt = now
+		_, err := s.exec(`INSERT INTO views (id, name, description, filters, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			view.ID, view.Name, view.Description, string(filters), now.UnixNano(), now.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to save view: %w", err)
+		}
+		return nil
+	}
+
+	existing, err := s.GetView(view.ID)
+	if err != nil {
+		return err
+	}
+	view.CreatedAt = existing.CreatedAt
+	view.UpdatedAt = now
+	_, err = s.exec(`UPDATE views SET name = ?, description = ?, filters = ?, updated_at = ? WHERE id = ?`,
+		view.Name, view.Description, string(filters), now.UnixNano(), view.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save view: %w", err)
+	}
+	return nil
+}
+
+// DeleteView removes a saved view
+func (s *SQLStore) DeleteView(id string) error {
+	result, err := s.exec(`DELETE FROM views WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+	return requireAffected(result)
+}
+
+// ListProfiles returns all principal profiles ordered by email
+func (s *SQLStore) ListProfiles() ([]PrincipalProfile, error) {
+	rows, err := s.query(`SELECT email, display_name, team, manager, source FROM profiles ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []PrincipalProfile{}
+	for rows.Next() {
+		var p PrincipalProfile
+		if err := rows.Scan(&p.Email, &p.DisplayName, &p.Team, &p.Manager, &p.Source); err != nil {
+			return nil, fmt.Errorf("failed to read profile: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// ReplaceProfiles replaces every profile previously loaded from source
+func (s *SQLStore) ReplaceProfiles(source string, profiles []PrincipalProfile) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to replace profiles: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.rebind(`DELETE FROM profiles WHERE source = ?`), source); err != nil {
+		return fmt.Errorf("failed to replace profiles: %w", err)
+	}
+	upsert := s.rebind(`INSERT INTO profiles (email, display_name, team, manager, source) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (email) DO UPDATE SET display_name = excluded.display_name, team = excluded.team,
+		manager = excluded.manager, source = excluded.source`)
+	for _, p := range profiles {
+		if _, err := tx.Exec(upsert, strings.ToLower(p.Email), p.DisplayName, p.Team, p.Manager, source); err != nil {
+			return fmt.Errorf("failed to replace profiles: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListOwners returns all manual service account owner mappings ordered by email
+func (s *SQLStore) ListOwners() ([]ServiceAccountOwner, error) {
+	rows, err := s.query(`SELECT email, owner, updated_at FROM owners ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := []ServiceAccountOwner{}
+	for rows.Next() {
+		var o ServiceAccountOwner
+		var updated int64
+		if err := rows.Scan(&o.Email, &o.Owner, &updated); err != nil {
+			return nil, fmt.Errorf("failed to read owner: %w", err)
+		}
+		o.UpdatedAt = fromNanos(updated)
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// SetOwner creates or replaces the owner mapping for a service account
+func (s *SQLStore) SetOwner(owner *ServiceAccountOwner) error {
+	owner.Email = strings.ToLower(owner.Email)
+	owner.UpdatedAt = time.Now().UTC()
+	_, err := s.exec(`INSERT INTO owners (email, owner, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (email) DO UPDATE SET owner = excluded.owner, updated_at = excluded.updated_at`,
+		owner.Email, owner.Owner, owner.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to set owner: %w", err)
+	}
+	return nil
+}
+
+// DeleteOwner removes the owner mapping for a service account
+func (s *SQLStore) DeleteOwner(email string) error {
+	result, err := s.exec(`DELETE FROM owners WHERE email = ?`, strings.ToLower(email))
+	if err != nil {
+		return fmt.Errorf("failed to delete owner: %w", err)
+	}
+	return requireAffected(result)
+}
+
+// findingStateColumns are the finding_states columns in scanFindingState order
+const findingStateColumns = `id, status, assignee, snoozed_until, justification, note, resolved_at, updated_by, updated_at`
+
+// ListFindingStates returns the triage state of every tracked finding ordered by ID
+func (s *SQLStore) ListFindingStates() ([]FindingState, error) {
+	rows, err := s.query(`SELECT ` + findingStateColumns + ` FROM finding_states ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list finding states: %w", err)
+	}
+	defer rows.Close()
+
+	states := []FindingState{}
+	for rows.Next() {
+		state, err := scanFindingState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *state)
+	}
+	return states, rows.Err()
+}
+
+// GetFindingState returns the triage state of a finding
+func (s *SQLStore) GetFindingState(id string) (*FindingState, error) {
+	rows, err := s.query(`SELECT `+findingStateColumns+` FROM finding_states WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finding state: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return scanFindingState(rows)
+}
+
+// SetFindingState creates or replaces the triage state of a finding
+func (s *SQLStore) SetFindingState(state *FindingState) error {
+	state.UpdatedAt = time.Now().UTC()
+	_, err := s.exec(`INSERT INTO finding_states (`+findingStateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, assignee = excluded.assignee,
+		snoozed_until = excluded.snoozed_until, justification = excluded.justification, note = excluded.note,
+		resolved_at = excluded.resolved_at, updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		findingStateArgs(state)...)
+	if err != nil {
+		return fmt.Errorf("failed to set finding state: %w", err)
+	}
+	return nil
+}
+
+// DeleteFindingState forgets the triage state of a finding
+func (s *SQLStore) DeleteFindingState(id string) error {
+	result, err := s.exec(`DELETE FROM finding_states WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete finding state: %w", err)
+	}
+	return requireAffected(result)
+}
+
+// findingStateArgs returns the finding_states column values of a state;
+// unset times are stored as 0
+func findingStateArgs(state *FindingState) []interface{} {
+	return []interface{}{state.ID, state.Status, state.Assignee, optionalNanos(state.SnoozedUntil),
+		state.Justification, state.Note, optionalNanos(state.ResolvedAt), state.UpdatedBy, state.UpdatedAt.UnixNano()}
+}
+
+// scanFindingState reads one finding_states row
+func scanFindingState(rows *sql.Rows) (*FindingState, error) {
+	var state FindingState
+	var snoozed, resolved, updated int64
+	if err := rows.Scan(&state.ID, &state.Status, &state.Assignee, &snoozed, &state.Justification,
+		&state.Note, &resolved, &state.UpdatedBy, &updated); err != nil {
+		return nil, fmt.Errorf("failed to read finding state: %w", err)
+	}
+	state.SnoozedUntil = optionalTime(snoozed)
+	state.ResolvedAt = optionalTime(resolved)
+	state.UpdatedAt = fromNanos(updated)
+	return &state, nil
+}
+
+// optionalNanos converts an optional time for storage, 0 when unset
+func optionalNanos(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// optionalTime converts a stored optional time back, nil for 0
+func optionalTime(nanos int64) *time.Time {
+	if nanos == 0 {
+		return nil
+	}
+	t := fromNanos(nanos)
+	return &t
+}
+
+// ListExceptions returns every exception, soonest expiring first
+func (s *SQLStore) ListExceptions() ([]Exception, error) {
+	rows, err := s.query(`SELECT id, rule, principal, resource, role, justification, expires_at, created_by, created_at
+		FROM exceptions ORDER BY expires_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	exceptions := []Exception{}
+	for rows.Next() {
+		var e Exception
+		var expires, created int64
+		if err := rows.Scan(&e.ID, &e.Rule, &e.Principal, &e.Resource, &e.Role, &e.Justification, &expires, &e.CreatedBy, &created); err != nil {
+			return nil, fmt.Errorf("failed to read exception: %w", err)
+		}
+		e.ExpiresAt = fromNanos(expires)
+		e.CreatedAt = fromNanos(created)
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+// CreateException stores a new exception
+func (s *SQLStore) CreateException(exception *Exception) error {
+	exception.ID = newID()
+	exception.CreatedAt = time.Now().UTC()
+	_, err := s.exec(`INSERT INTO exceptions (id, rule, principal, resource, role, justification, expires_at, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		exception.ID, exception.Rule, exception.Principal, exception.Resource, exception.Role, exception.Justification,
+		exception.ExpiresAt.UnixNano(), exception.CreatedBy, exception.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to create exception: %w", err)
+	}
+	return nil
+}
+
+// DeleteException removes an exception
+func (s *SQLStore) DeleteException(id string) error {
+	result, err := s.exec(`DELETE FROM exceptions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete exception: %w", err)
+	}
+	return requireAffected(result)
+}
+
+// AppendScore records a score for a snapshot
+func (s *SQLStore) AppendScore(record ScoreRecord) error {
+	breakdown, err := json.Marshal(record.Breakdown)
+	if err != nil {
+		return fmt.Errorf("failed to encode score breakdown: %w", err)
+	}
+	_, err = s.exec(`INSERT INTO scores (snapshot_id, taken_at, project, score, breakdown) VALUES (?, ?, ?, ?, ?)`,
+		record.SnapshotID, record.TakenAt.UnixNano(), record.Project, record.Score, string(breakdown))
+	if err != nil {
+		return fmt.Errorf("failed to record score: %w", err)
+	}
+	return nil
+}
+
+// ListScores returns score records for a project ordered by time, oldest first
+func (s *SQLStore) ListScores(project string) ([]ScoreRecord, error) {
+	return s.listScores(`WHERE project = ?`, project)
+}
+
+// listScores returns the score records matching a WHERE clause, oldest first
+func (s *SQLStore) listScores(where string, args ...interface{}) ([]ScoreRecord, error) {
+	rows, err := s.query(`SELECT snapshot_id, taken_at, project, score, breakdown FROM scores `+where+` ORDER BY taken_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scores: %w", err)
+	}
+	defer rows.Close()
+
+	records := []ScoreRecord{}
+	for rows.Next() {
+		var r ScoreRecord
+		var taken int64
+		var breakdown string
+		if err := rows.Scan(&r.SnapshotID, &taken, &r.Project, &r.Score, &breakdown); err != nil {
+			return nil, fmt.Errorf("failed to read score: %w", err)
+		}
+		r.TakenAt = fromNanos(taken)
+		if err := json.Unmarshal([]byte(breakdown), &r.Breakdown); err != nil {
+			return nil, fmt.Errorf("failed to decode score breakdown: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// AppendMetrics records posture metrics for a snapshot
+func (s *SQLStore) AppendMetrics(record MetricsRecord) error {
+	findings, err := json.Marshal(record.Findings)
+	if err != nil {
+		return fmt.Errorf("failed to encode finding counts: %w", err)
+	}
+	_, err = s.exec(`INSERT INTO metrics (snapshot_id, taken_at, project, principals, owners, public_resources, external_principals, findings)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.SnapshotID, record.TakenAt.UnixNano(), record.Project, record.Principals, record.Owners,
+		record.PublicResources, record.ExternalPrincipals, string(findings))
+	if err != nil {
+		return fmt.Errorf("failed to record posture metrics: %w", err)
+	}
+	return nil
+}
+
+// ListMetrics returns metrics records for a project taken at or after since, oldest first
+func (s *SQLStore) ListMetrics(project string, since time.Time) ([]MetricsRecord, error) {
+	return s.listMetrics(`WHERE project = ? AND taken_at >= ?`, project, since.UnixNano())
+}
+
+// listMetrics returns the metrics records matching a WHERE clause, oldest first
+func (s *SQLStore) listMetrics(where string, args ...interface{}) ([]MetricsRecord, error) {
+	rows, err := s.query(`SELECT snapshot_id, taken_at, project, principals, owners, public_resources, external_principals, findings
+		FROM metrics `+where+` ORDER BY taken_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posture metrics: %w", err)
+	}
+	defer rows.Close()
+
+	records := []MetricsRecord{}
+	for rows.Next() {
+		var r MetricsRecord
+		var taken int64
+		var findings string
+		if err := rows.Scan(&r.SnapshotID, &taken, &r.Project, &r.Principals, &r.Owners, &r.PublicResources, &r.ExternalPrincipals, &findings); err != nil {
+			return nil, fmt.Errorf("failed to read posture metrics: %w", err)
+		}
+		r.TakenAt = fromNanos(taken)
+		if err := json.Unmarshal([]byte(findings), &r.Findings); err != nil {
+			return nil, fmt.Errorf("failed to decode finding counts: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// AppendUsage records the API usage of a scan
+func (s *SQLStore) AppendUsage(record UsageRecord) error {
+	estimated, err := json.Marshal(record.Estimated)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage estimate: %w", err)
+	}
+	calls, err := json.Marshal(record.Calls)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage: %w", err)
+	}
+	_, err = s.exec(`INSERT INTO scan_usage (snapshot_id, taken_at, project, budget, estimated, calls, rejected) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		record.SnapshotID, record.TakenAt.UnixNano(), record.Project, record.Budget, string(estimated), string(calls), record.Rejected)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage returns the usage record of a snapshot, or ErrNotFound
+func (s *SQLStore) GetUsage(snapshotID string) (*UsageRecord, error) {
+	rows, err := s.query(`SELECT snapshot_id, taken_at, project, budget, estimated, calls, rejected FROM scan_usage WHERE snapshot_id = ?`, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return scanUsage(rows)
+}
+
+// scanUsage reads one scan_usage row
+func scanUsage(rows *sql.Rows) (*UsageRecord, error) {
+	var r UsageRecord
+	var taken int64
+	var estimated, calls string
+	if err := rows.Scan(&r.SnapshotID, &taken, &r.Project, &r.Budget, &estimated, &calls, &r.Rejected); err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	r.TakenAt = fromNanos(taken)
+	if err := json.Unmarshal([]byte(estimated), &r.Estimated); err != nil {
+		return nil, fmt.Errorf("failed to decode usage estimate: %w", err)
+	}
+	if err := json.Unmarshal([]byte(calls), &r.Calls); err != nil {
+		return nil, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return &r, nil
+}
+
+// AppendAudit records an API call
+func (s *SQLStore) AppendAudit(event AuditEvent) error {
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	query, err := json.Marshal(event.Query)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit query: %w", err)
+	}
+	_, err = s.exec(`INSERT INTO audit_log (id, at, actor, client_ip, method, route, path, query, status, bytes, export)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, event.Time.UnixNano(), event.Actor, event.ClientIP, event.Method, event.Route, event.Path,
+		string(query), event.Status, event.Bytes, event.Export)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns the audit events matching filter, newest first
+func (s *SQLStore) ListAudit(filter AuditFilter) ([]AuditEvent, error) {
+	where := []string{"1 = 1"}
+	var args []interface{}
+	if filter.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Path != "" {
+		where = append(where, "substr(path, 1, ?) = ?")
+		args = append(args, len(filter.Path), filter.Path)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "at >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "at < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	stmt := `SELECT id, at, actor, client_ip, method, route, path, query, status, bytes, export FROM audit_log
+		WHERE ` + strings.Join(where, " AND ") + ` ORDER BY at DESC`
+	if filter.Limit > 0 {
+		stmt += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := s.query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		var e AuditEvent
+		var at int64
+		var query string
+		if err := rows.Scan(&e.ID, &at, &e.Actor, &e.ClientIP, &e.Method, &e.Route, &e.Path, &query, &e.Status, &e.Bytes, &e.Export); err != nil {
+			return nil, fmt.Errorf("failed to read audit event: %w", err)
+		}
+		e.Time = fromNanos(at)
+		if err := json.Unmarshal([]byte(query), &e.Query); err != nil {
+			return nil, fmt.Errorf("failed to decode audit query: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetSetting returns a persisted setting, or ErrNotFound
+func (s *SQLStore) GetSetting(key string) ([]byte, error) {
+	rows, err := s.query(`SELECT value FROM settings WHERE key = ?`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read setting: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	var value string
+	if err := rows.Scan(&value); err != nil {
+		return nil, fmt.Errorf("failed to read setting: %w", err)
+	}
+	return []byte(value), nil
+}
+
+// PutSetting persists a setting
+func (s *SQLStore) PutSetting(key string, value []byte) error {
+	_, err := s.exec(`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, string(value), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save setting: %w", err)
+	}
+	return nil
+}
+
+// Prune drops score, metrics, and usage records the retention policy does not
+// keep, applying it to each project's history separately, and expired audit events
+func (s *SQLStore) Prune(policy RetentionPolicy) (PruneResult, error) {
+	var result PruneResult
+	var err error
+	if result.Scores, err = s.pruneTable("scores", policy); err != nil {
+		return result, err
+	}
+	if result.Metrics, err = s.pruneTable("metrics", policy); err != nil {
+		return result, err
+	}
+	if result.Usage, err = s.pruneTable("scan_usage", policy); err != nil {
+		return result, err
+	}
+	if cutoff := policy.auditCutoff(); !cutoff.IsZero() {
+		res, err := s.exec(`DELETE FROM audit_log WHERE at < ?`, cutoff.UnixNano())
+		if err != nil {
+			return result, fmt.Errorf("failed to prune audit log: %w", err)
+		}
+		removed, _ := res.RowsAffected()
+		result.Audit = int(removed)
+	}
+	return result, nil
+}
+
+// pruneTable applies the retention policy to one history table
+func (s *SQLStore) pruneTable(table string, policy RetentionPolicy) (int, error) {
+	type row struct {
+		project  string
+		snapshot string
+		taken    int64
+	}
+
+	rows, err := s.query(`SELECT project, snapshot_id, taken_at FROM ` + table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s for pruning: %w", table, err)
+	}
+	byProject := make(map[string][]row)
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.project, &r.snapshot, &r.taken); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read %s for pruning: %w", table, err)
+		}
+		byProject[r.project] = append(byProject[r.project], r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune %s: %w", table, err)
+	}
+	defer tx.Rollback()
+
+	remove := s.rebind(`DELETE FROM ` + table + ` WHERE project = ? AND snapshot_id = ? AND taken_at = ?`)
+	removed := 0
+	for _, projectRows := range byProject {
+		times := make([]time.Time, len(projectRows))
+		for i, r := range projectRows {
+			times[i] = fromNanos(r.taken)
+		}
+		for i, keep := range policy.keep(times) {
+			if keep {
+				continue
+			}
+			r := projectRows[i]
+			if _, err := tx.Exec(remove, r.project, r.snapshot, r.taken); err != nil {
+				return 0, fmt.Errorf("failed to prune %s: %w", table, err)
+			}
+			removed++
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// ExportState returns a copy of everything the store holds
+func (s *SQLStore) ExportState() (*State, error) {
+	state := newState()
+	var err error
+	if state.Views, err = s.ListViews(); err != nil {
+		return nil, err
+	}
+	if state.Profiles, err = s.ListProfiles(); err != nil {
+		return nil, err
+	}
+	if state.Owners, err = s.ListOwners(); err != nil {
+		return nil, err
+	}
+	if state.FindingStates, err = s.ListFindingStates(); err != nil {
+		return nil, err
+	}
+	if state.Exceptions, err = s.ListExceptions(); err != nil {
+		return nil, err
+	}
+	if state.Scores, err = s.listScores(``); err != nil {
+		return nil, err
+	}
+	if state.Metrics, err = s.listMetrics(``); err != nil {
+		return nil, err
+	}
+	if state.Audit, err = s.ListAudit(AuditFilter{}); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.query(`SELECT snapshot_id, taken_at, project, budget, estimated, calls, rejected FROM scan_usage ORDER BY taken_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record, err := scanUsage(rows)
+		if err != nil {
+			return nil, err
+		}
+		state.Usage = append(state.Usage, *record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.query(`SELECT key, value FROM settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settings: %w", err)
+	}
+	defer settings.Close()
+	for settings.Next() {
+		var key, value string
+		if err := settings.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to read setting: %w", err)
+		}
+		state.Settings[key] = json.RawMessage(value)
+	}
+	return state, settings.Err()
+}
+
+// ImportState replaces everything the store holds with state in one transaction
+func (s *SQLStore) ImportState(state *State) error {
+	if err := state.check(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import state: %w", err)
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...interface{}) error {
+		if _, err := tx.Exec(s.rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to import state: %w", err)
+		}
+		return nil
+	}
+	for _, table := range []string{"views", "profiles", "owners", "finding_states", "exceptions", "scores", "metrics", "scan_usage", "audit_log", "settings"} {
+		if err := exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+
+	for _, view := range state.Views {
+		filters, err := json.Marshal(view.Filters)
+		if err != nil {
+			return fmt.Errorf("failed to encode view filters: %w", err)
+		}
+		if err := exec(`INSERT INTO views (id, name, description, filters, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			view.ID, view.Name, view.Description, string(filters), view.CreatedAt.UnixNano(), view.UpdatedAt.UnixNano()); err != nil {
+			return err
+		}
+	}
+	for _, p := range state.Profiles {
+		if err := exec(`INSERT INTO profiles (email, display_name, team, manager, source) VALUES (?, ?, ?, ?, ?)`,
+			strings.ToLower(p.Email), p.DisplayName, p.Team, p.Manager, p.Source); err != nil {
+			return err
+		}
+	}
+	for _, o := range state.Owners {
+		if err := exec(`INSERT INTO owners (email, owner, updated_at) VALUES (?, ?, ?)`,
+			strings.ToLower(o.Email), o.Owner, o.UpdatedAt.UnixNano()); err != nil {
+			return err
+		}
+	}
+	for _, f := range state.FindingStates {
+		if err := exec(`INSERT INTO finding_states (`+findingStateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			findingStateArgs(&f)...); err != nil {
+			return err
+		}
+	}
+	for _, e := range state.Exceptions {
+		if e.ID == "" {
+			e.ID = newID()
+		}
+		if err := exec(`INSERT INTO exceptions (id, rule, principal, resource, role, justification, expires_at, created_by, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, e.Rule, e.Principal, e.Resource, e.Role, e.Justification, e.ExpiresAt.UnixNano(), e.CreatedBy, e.CreatedAt.UnixNano()); err != nil {
+			return err
+		}
+	}
+	for _, r := range state.Scores {
+		breakdown, err := json.Marshal(r.Breakdown)
+		if err != nil {
+			return fmt.Errorf("failed to encode score breakdown: %w", err)
+		}
+		if err := exec(`INSERT INTO scores (snapshot_id, taken_at, project, score, breakdown) VALUES (?, ?, ?, ?, ?)`,
+			r.SnapshotID, r.TakenAt.UnixNano(), r.Project, r.Score, string(breakdown)); err != nil {
+			return err
+		}
+	}
+	for _, r := range state.Metrics {
+		findings, err := json.Marshal(r.Findings)
+		if err != nil {
+			return fmt.Errorf("failed to encode finding counts: %w", err)
+		}
+		if err := exec(`INSERT INTO metrics (snapshot_id, taken_at, project, principals, owners, public_resources, external_principals, findings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.SnapshotID, r.TakenAt.UnixNano(), r.Project, r.Principals, r.Owners, r.PublicResources, r.ExternalPrincipals, string(findings)); err != nil {
+			return err
+		}
+	}
+	for _, r := range state.Usage {
+		estimated, err := json.Marshal(r.Estimated)
+		if err != nil {
+			return fmt.Errorf("failed to encode usage estimate: %w", err)
+		}
+		calls, err := json.Marshal(r.Calls)
+		if err != nil {
+			return fmt.Errorf("failed to encode usage: %w", err)
+		}
+		if err := exec(`INSERT INTO scan_usage (snapshot_id, taken_at, project, budget, estimated, calls, rejected) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			r.SnapshotID, r.TakenAt.UnixNano(), r.Project, r.Budget, string(estimated), string(calls), r.Rejected); err != nil {
+			return err
+		}
+	}
+	for _, e := range state.Audit {
+		if e.ID == "" {
+			e.ID = newID()
+		}
+		query, err := json.Marshal(e.Query)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit query: %w", err)
+		}
+		if err := exec(`INSERT INTO audit_log (id, at, actor, client_ip, method, route, path, query, status, bytes, export)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, e.Time.UnixNano(), e.Actor, e.ClientIP, e.Method, e.Route, e.Path, string(query), e.Status, e.Bytes, e.Export); err != nil {
+			return err
+		}
+	}
+	now := time.Now().UnixNano()
+	for key, value := range state.Settings {
+		if err := exec(`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)`, key, string(value), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TryLock acquires a named lock: a session-level advisory lock on Postgres, held on
+// a dedicated connection until unlock, or an in-process lock on SQLite
+func (s *SQLStore) TryLock(name string) (func(), bool, error) {
+	if s.dialect != DriverPostgres {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.locks[name] {
+			return nil, false, nil
+		}
+		s.locks[name] = true
+		return func() {
+			s.mu.Lock()
+			delete(s.locks, name)
+			s.mu.Unlock()
+		}, true, nil
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	key := int64(hash.Sum64())
+
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %q: %w", name, err)
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire lock %q: %w", name, err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+	return func() {
+		conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key)
+		conn.Close()
+	}, true, nil
+}
+
+// PublishSnapshot replaces the project's shared snapshot
+func (s *SQLStore) PublishSnapshot(record SnapshotRecord) error {
+	_, err := s.exec(`INSERT INTO snapshots (project, id, taken_at, duration, matrix) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (project) DO UPDATE SET id = excluded.id, taken_at = excluded.taken_at,
+		duration = excluded.duration, matrix = excluded.matrix`,
+		record.Project, record.ID, record.TakenAt.UnixNano(), int64(record.Duration), string(record.Matrix))
+	if err != nil {
+		return fmt.Errorf("failed to publish snapshot: %w", err)
+	}
+	return nil
+}
+
+// LatestSnapshot returns the project's shared snapshot, or ErrNotFound
+func (s *SQLStore) LatestSnapshot(project string) (*SnapshotRecord, error) {
+	rows, err := s.query(`SELECT id, taken_at, duration, matrix FROM snapshots WHERE project = ?`, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	record := SnapshotRecord{Project: project}
+	var taken, duration int64
+	var matrix string
+	if err := rows.Scan(&record.ID, &taken, &duration, &matrix); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	record.TakenAt = fromNanos(taken)
+	record.Duration = time.Duration(duration)
+	record.Matrix = []byte(matrix)
+	return &record, nil
+}
+
+// scanTaskColumns are the scan_tasks columns in scanScanTask order
+const scanTaskColumns = `id, scan_id, project, state, worker, attempts, payload, result, error, created_at, claimed_at, finished_at`
+
+// EnqueueTasks adds pending tasks in one transaction
+func (s *SQLStore) EnqueueTasks(tasks []ScanTask) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to enqueue tasks: %w", err)
+	}
+	for i, task := range tasks {
+		_, err := tx.Exec(s.rebind(`INSERT INTO scan_tasks (id, scan_id, seq, project, state, worker, attempts, payload, result, error, created_at, claimed_at, finished_at)
+			VALUES (?, ?, ?, ?, ?, '', 0, ?, '', '', ?, 0, 0)`),
+			task.ID, task.ScanID, i, task.Project, TaskPending, string(task.Payload), task.CreatedAt.UnixNano())
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to enqueue task %s: %w", task.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimTask marks the oldest available task as running for worker. The state
+// is checked again in the UPDATE, so of two workers racing for a task only one
+// gets it; the other sees ErrNotFound and polls again.
+func (s *SQLStore) ClaimTask(worker string, leaseStart time.Time) (*ScanTask, error) {
+	now, lease := time.Now().UnixNano(), leaseStart.UnixNano()
+	available := `(state = 'pending' OR (state = 'running' AND claimed_at < ?))`
+	rows, err := s.query(`UPDATE scan_tasks SET state = 'running', worker = ?, attempts = attempts + 1, claimed_at = ?
+		WHERE id = (SELECT id FROM scan_tasks WHERE `+available+` ORDER BY created_at, seq LIMIT 1) AND `+available+`
+		RETURNING `+scanTaskColumns, worker, now, lease, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to claim task: %w", err)
+		}
+		return nil, ErrNotFound
+	}
+	return scanScanTask(rows)
+}
+
+// FinishTask records the outcome of a task still held by worker
+func (s *SQLStore) FinishTask(id, worker string, result []byte, errText string) error {
+	state := TaskDone
+	if errText != "" {
+		state = TaskFailed
+	}
+	res, err := s.exec(`UPDATE scan_tasks SET state = ?, result = ?, error = ?, finished_at = ?
+		WHERE id = ? AND worker = ? AND state = 'running'`,
+		state, string(result), errText, time.Now().UnixNano(), id, worker)
+	if err != nil {
+		return fmt.Errorf("failed to finish task %s: %w", id, err)
+	}
+	return requireAffected(res)
+}
+
+// ListTasks returns the tasks of a scan in the order they were enqueued
+func (s *SQLStore) ListTasks(scanID string) ([]ScanTask, error) {
+	rows, err := s.query(`SELECT `+scanTaskColumns+` FROM scan_tasks WHERE scan_id = ? ORDER BY seq`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []ScanTask{}
+	for rows.Next() {
+		task, err := scanScanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// DeleteTasks removes the tasks of a scan
+func (s *SQLStore) DeleteTasks(scanID string) error {
+	if _, err := s.exec(`DELETE FROM scan_tasks WHERE scan_id = ?`, scanID); err != nil {
+		return fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	return nil
+}
+
+// scanScanTask reads one scan_tasks row
+func scanScanTask(rows *sql.Rows) (*ScanTask, error) {
+	var task ScanTask
+	var payload, result string
+	var created, claimed, finished int64
+	if err := rows.Scan(&task.ID, &task.ScanID, &task.Project, &task.State, &task.Worker, &task.Attempts,
+		&payload, &result, &task.Error, &created, &claimed, &finished); err != nil {
+		return nil, fmt.Errorf("failed to read task: %w", err)
+	}
+	task.Payload = []byte(payload)
+	if result != "" {
+		task.Result = []byte(result)
+	}
+	task.CreatedAt = fromNanos(created)
+	if claimed != 0 {
+		task.ClaimedAt = fromNanos(claimed)
+	}
+	if finished != 0 {
+		task.FinishedAt = fromNanos(finished)
+	}
+	return &task, nil
+}
+
+// Ping verifies the database connection
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// requireAffected returns ErrNotFound when a statement changed no rows
+func requireAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StateVersion is the version of the State layout; archives of a newer
+// version are refused on import
+const StateVersion = 1
+
+// State is everything a Store holds, in a portable form for backups and
+// migrations between deployments and store drivers
+type State struct {
+	Version    int                   `json:"version"`
+	ExportedAt time.Time             `json:"exportedAt"`
+	Views      []SavedView           `json:"views"`
+	Profiles   []PrincipalProfile    `json:"profiles"`
+	Owners     []ServiceAccountOwner `json:"owners"`
+	// FindingStates is absent from archives that predate finding triage
+	FindingStates []FindingState  `json:"findingStates"`
+	Exceptions    []Exception     `json:"exceptions"`
+	Scores        []ScoreRecord   `json:"scores"`
+	Metrics       []MetricsRecord `json:"metrics"`
+	Usage         []UsageRecord   `json:"usage"`
+	Audit         []AuditEvent    `json:"audit"`
+	// Settings hold JSON values such as the runtime settings
+	Settings map[string]json.RawMessage `json:"settings"`
+	// Snapshot is the scanner's current snapshot; stores neither fill nor
+	// read it
+	Snapshot *SnapshotRecord `json:"snapshot,omitempty"`
+}
+
+// newState returns an empty State of the current version
+func newState() *State {
+	return &State{
+		Version:       StateVersion,
+		ExportedAt:    time.Now().UTC(),
+		Views:         []SavedView{},
+		Profiles:      []PrincipalProfile{},
+		Owners:        []ServiceAccountOwner{},
+		FindingStates: []FindingState{},
+		Exceptions:    []Exception{},
+		Scores:        []ScoreRecord{},
+		Metrics:       []MetricsRecord{},
+		Usage:         []UsageRecord{},
+		Audit:         []AuditEvent{},
+		Settings:      make(map[string]json.RawMessage),
+	}
+}
+
+// check refuses states this version cannot import
+func (s *State) check() error {
+	if s.Version < 1 || s.Version > StateVersion {
+		return fmt.Errorf("unsupported state version %d (want 1 to %d)", s.Version, StateVersion)
+	}
+	for key, value := range s.Settings {
+		if !json.Valid(value) {
+			return fmt.Errorf("setting %q is not valid JSON", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ViewFilters is a named combination of access matrix filters
+type ViewFilters struct {
+	Project      string `json:"project,omitempty"`
+	ResourceType string `json:"resourceType,omitempty"`
+	Role         string `json:"role,omitempty"`
+	Principal    string `json:"principal,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// SavedView is a persisted set of filters addressable by a stable ID
+type SavedView struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Filters     ViewFilters `json:"filters"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// PrincipalProfile holds HR metadata for a principal email
+type PrincipalProfile struct {
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName,omitempty"`
+	Team        string `json:"team,omitempty"`
+	Manager     string `json:"manager,omitempty"`
+	Source      string `json:"source"` // "csv", "scim", ...
+}
+
+// ServiceAccountOwner is a manual attribution of a service account to a team or system
+type ServiceAccountOwner struct {
+	Email     string    `json:"email"`
+	Owner     string    `json:"owner"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Finding lifecycle statuses
+const (
+	FindingOpen          = "open"
+	FindingAcknowledged  = "acknowledged"
+	FindingSnoozed       = "snoozed"
+	FindingFalsePositive = "false-positive"
+	FindingResolved      = "resolved"
+)
+
+// FindingState is the triage state of a finding, keyed by the finding ID
+type FindingState struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Assignee string `json:"assignee,omitempty"`
+	// SnoozedUntil is when a snoozed finding opens again
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"`
+	// Justification explains why a finding is a false positive
+	Justification string `json:"justification,omitempty"`
+	Note          string `json:"note,omitempty"`
+	// ResolvedAt is when a scan last found the finding gone
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	UpdatedBy  string     `json:"updatedBy"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// Exception exempts the findings of one rule about a principal, a resource,
+// or a binding of both from the findings list until it expires
+type Exception struct {
+	ID   string `json:"id"`
+	Rule string `json:"rule"` // finding kind, e.g. "public-binding"
+	// Principal, Resource, and Role narrow the findings excepted; at least
+	// one of Principal and Resource is set
+	Principal     string    `json:"principal,omitempty"`
+	Resource      string    `json:"resource,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	Justification string    `json:"justification"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	CreatedBy     string    `json:"createdBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ScoreRecord is a least-privilege score computed for one snapshot
+type ScoreRecord struct {
+	SnapshotID string      `json:"snapshotId"`
+	TakenAt    time.Time   `json:"takenAt"`
+	Project    string      `json:"project"`
+	Score      float64     `json:"score"`
+	Breakdown  interface{} `json:"breakdown"`
+}
+
+// MetricsRecord holds the posture metrics computed for one snapshot
+type MetricsRecord struct {
+	SnapshotID         string    `json:"snapshotId"`
+	TakenAt            time.Time `json:"takenAt"`
+	Project            string    `json:"project"`
+	Principals         int       `json:"principals"`
+	Owners             int       `json:"owners"`
+	PublicResources    int       `json:"publicResources"`
+	ExternalPrincipals int       `json:"externalPrincipals"`
+	// Findings counts findings by severity
+	Findings map[string]int `json:"findings"`
+}
+
+// UsageRecord is the GCP API consumption of one scan
+type UsageRecord struct {
+	SnapshotID string    `json:"snapshotId"`
+	TakenAt    time.Time `json:"takenAt"`
+	Project    string    `json:"project"`
+	// Budget is the call budget the scan ran with; zero means unlimited
+	Budget int `json:"budget"`
+	// Estimated and Calls count API calls per service, predicted before the scan
+	// and made during it
+	Estimated map[string]int `json:"estimated"`
+	Calls     map[string]int `json:"calls"`
+	// Rejected counts calls refused once the budget was used up
+	Rejected int `json:"rejected"`
+}
+
+// AuditEvent records one call to the visualizer API
+type AuditEvent struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor is the caller's identity from an identity-aware proxy, "admin" for a
+	// valid admin token, or "anonymous"
+	Actor    string `json:"actor"`
+	ClientIP string `json:"clientIp"`
+	Method   string `json:"method"`
+	// Route is the matched route pattern, e.g. /api/views/:id; Path is the requested path
+	Route string            `json:"route"`
+	Path  string            `json:"path"`
+	Query map[string]string `json:"query,omitempty"`
+	// Status and Bytes describe the response
+	Status int `json:"status"`
+	Bytes  int `json:"bytes"`
+	// Export is the file name of a downloaded export, if the response was one
+	Export string `json:"export,omitempty"`
+}
+
+// AuditFilter selects audit events; zero fields match everything
+type AuditFilter struct {
+	Actor string
+	// Path matches events whose requested path starts with it
+	Path  string
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of events returned, newest first
+	Limit int
+}
+
+// matches reports whether the event passes the filter, ignoring Limit
+func (f AuditFilter) matches(event AuditEvent) bool {
+	if f.Actor != "" && event.Actor != f.Actor {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(event.Path, f.Path) {
+		return false
+	}
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Store persists application state such as saved views
+type Store interface {
+	ListViews() ([]SavedView, error)
+	GetView(id string) (*SavedView, error)
+	// SaveView creates the view if its ID is empty, otherwise replaces it
+	SaveView(view *SavedView) error
+	DeleteView(id string) error
+
+	ListProfiles() ([]PrincipalProfile, error)
+	// ReplaceProfiles replaces every profile previously loaded from source
+	ReplaceProfiles(source string, profiles []PrincipalProfile) error
+
+	ListOwners() ([]ServiceAccountOwner, error)
+	SetOwner(owner *ServiceAccountOwner) error
+	DeleteOwner(email string) error
+
+	ListFindingStates() ([]FindingState, error)
+	// GetFindingState returns the state of a finding, or ErrNotFound
+	GetFindingState(id string) (*FindingState, error)
+	SetFindingState(state *FindingState) error
+	DeleteFindingState(id string) error
+
+	// ListExceptions returns every exception, expired ones included, soonest
+	// expiring first
+	ListExceptions() ([]Exception, error)
+	// CreateException stores a new exception, assigning its ID
+	CreateException(exception *Exception) error
+	DeleteException(id string) error
+
+	AppendScore(record ScoreRecord) error
+	// ListScores returns score records for a project ordered by time, oldest first
+	ListScores(project string) ([]ScoreRecord, error)
+
+	AppendMetrics(record MetricsRecord) error
+	// ListMetrics returns metrics records for a project taken at or after since, oldest first
+	ListMetrics(project string, since time.Time) ([]MetricsRecord, error)
+
+	AppendUsage(record UsageRecord) error
+	// GetUsage returns the usage record of a snapshot, or ErrNotFound
+	GetUsage(snapshotID string) (*UsageRecord, error)
+
+	AppendAudit(event AuditEvent) error
+	// ListAudit returns the audit events matching filter, newest first
+	ListAudit(filter AuditFilter) ([]AuditEvent, error)
+
+	// GetSetting returns a persisted setting, or ErrNotFound
+	GetSetting(key string) ([]byte, error)
+	PutSetting(key string, value []byte) error
+
+	// Prune drops score, metrics, and usage records the retention policy does not
+	// keep, and audit events older than its audit retention
+	Prune(policy RetentionPolicy) (PruneResult, error)
+
+	// ExportState returns a copy of everything the store holds
+	ExportState() (*State, error)
+	// ImportState replaces everything the store holds with state
+	ImportState(state *State) error
+
+	Close() error
+}
+
+// sortExceptions orders exceptions soonest expiring first, then by ID
+func sortExceptions(exceptions []Exception) {
+	sort.Slice(exceptions, func(i, j int) bool {
+		a, b := exceptions[i], exceptions[j]
+		if !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return a.ExpiresAt.Before(b.ExpiresAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func newID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
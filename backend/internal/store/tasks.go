@@ -0,0 +1,49 @@
+package store
+
+import "time"
+
+// Scan task states
+const (
+	TaskPending = "pending"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+)
+
+// ScanTask is one project of a distributed scan, queued for whichever worker
+// claims it first. Payload and Result are JSON the store does not interpret.
+type ScanTask struct {
+	ID      string `json:"id"`
+	ScanID  string `json:"scanId"`
+	Project string `json:"project"`
+	State   string `json:"state"`
+	// Worker is the worker that claimed the task last, and Attempts how many
+	// times it was claimed
+	Worker     string    `json:"worker,omitempty"`
+	Attempts   int       `json:"attempts"`
+	Payload    []byte    `json:"payload"`
+	Result     []byte    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ClaimedAt  time.Time `json:"claimedAt,omitempty"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// TaskQueue hands the projects of a scan to worker processes sharing the
+// store. A task whose worker died is claimed again once its lease runs out.
+type TaskQueue interface {
+	// EnqueueTasks adds pending tasks
+	EnqueueTasks(tasks []ScanTask) error
+	// ClaimTask marks the oldest pending task, or a running one claimed before
+	// leaseStart, as running for worker and returns it; ErrNotFound when none
+	// is available
+	ClaimTask(worker string, leaseStart time.Time) (*ScanTask, error)
+	// FinishTask records the outcome of a task the worker claimed: result when
+	// it succeeded, errText when it failed. ErrNotFound when the task is gone
+	// or was claimed again by another worker.
+	FinishTask(id, worker string, result []byte, errText string) error
+	// ListTasks returns the tasks of a scan in the order they were enqueued
+	ListTasks(scanID string) ([]ScanTask, error)
+	// DeleteTasks removes the tasks of a scan
+	DeleteTasks(scanID string) error
+}
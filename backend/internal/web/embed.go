@@ -0,0 +1,20 @@
+//go:build embedfrontend
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Assets returns the embedded frontend
+func Assets() (fs.FS, error) {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, err
+	}
+	return validate(assets)
+}
@@ -0,0 +1,10 @@
+//go:build !embedfrontend
+
+package web
+
+import "io/fs"
+
+// Assets returns ErrNotEmbedded: this build does not embed the frontend
+func Assets() (fs.FS, error) {
+	return nil, ErrNotEmbedded
+}
@@ -0,0 +1,62 @@
+// Package web serves the built frontend from the Go binary. The assets are
+// embedded only in builds with the embedfrontend tag, after the frontend build
+// output has been copied into dist:
+//
+//	(cd frontend && VITE_API_BASE_URL=/api npm run build)
+//	cp -r frontend/dist backend/internal/web/dist
+//	(cd backend && go build -tags embedfrontend)
+package web
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"gcp-access-visualizer/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the frontend for requests no API route matched. Existing
+// files are served as is, with long-lived caching for the hashed files under
+// assets/; any other path gets index.html so client-side routes survive a
+// reload. Unknown /api paths still get a JSON 404.
+func Handler(assets fs.FS) gin.HandlerFunc {
+	files := http.FileServer(http.FS(assets))
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") || c.Request.URL.Path == "/api" {
+			problem.Respond(c, problem.NotFound("no API route "+c.Request.URL.Path))
+			return
+		}
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Status(http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(c.Request.URL.Path), "/")
+		if info, err := fs.Stat(assets, name); name != "" && err == nil && !info.IsDir() {
+			if strings.HasPrefix(name, "assets/") {
+				c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			files.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+
+		// index.html must be revalidated so a deploy's new asset names are picked up
+		c.Header("Cache-Control", "no-cache")
+		c.FileFromFS("/", http.FS(assets))
+	}
+}
+
+// ErrNotEmbedded is returned by Assets in builds without the embedfrontend tag
+var ErrNotEmbedded = errors.New("the frontend is not embedded in this build; build with -tags embedfrontend")
+
+// validate checks that the assets contain the frontend entry point
+func validate(assets fs.FS) (fs.FS, error) {
+	if _, err := fs.Stat(assets, "index.html"); err != nil {
+		return nil, errors.New("the embedded frontend has no index.html; copy the frontend build output into internal/web/dist before building")
+	}
+	return assets, nil
+}
@@ -0,0 +1,272 @@
+// Package workers distributes the projects of an organization-wide scan over
+// worker processes through a task queue in the shared store. The replica that
+// scans enqueues one task per project and works on them too, so scans finish
+// with no workers running; each worker process (RUN_MODE=worker) claims tasks,
+// scans the project, and stores the result for the scanning replica to merge.
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/store"
+)
+
+// Defaults for zero Dispatcher and Worker settings
+const (
+	DefaultPollInterval = 2 * time.Second
+	DefaultLease        = 10 * time.Minute
+	DefaultTimeout      = 30 * time.Minute
+	// maxAttempts is how often a task is claimed before a worker that keeps
+	// dying on it fails the project
+	maxAttempts = 3
+)
+
+// ErrTimeout is the error of projects no worker finished in time
+var ErrTimeout = errors.New("no worker finished the project in time")
+
+// workItem is the payload of a scan task
+type workItem struct {
+	Project  gcp.DiscoveredProject `json:"project"`
+	Scope    gcp.ScanScope         `json:"scope"`
+	Deadline time.Time             `json:"deadline,omitempty"`
+}
+
+// workResult is the result of a finished scan task
+type workResult struct {
+	Matrix *gcp.AccessMatrix `json:"matrix,omitempty"`
+	Scan   gcp.ProjectScan   `json:"scan"`
+}
+
+// Dispatcher queues the projects of a scan as tasks and collects their
+// results. It implements gcp.ProjectDispatcher.
+type Dispatcher struct {
+	Queue store.TaskQueue
+	// Name identifies this replica as a worker
+	Name string
+	// LocalWorkers is how many tasks this replica works on at once while it
+	// waits; zero leaves all of them to worker processes
+	LocalWorkers int
+	// Timeout bounds a scan without a deadline; Lease is how long a claimed
+	// task may run before another worker takes it over
+	Timeout      time.Duration
+	Lease        time.Duration
+	PollInterval time.Duration
+}
+
+// Dispatch enqueues a task per project and waits until every task finished,
+// the deadline passed, or the timeout ran out. Unfinished projects fail with
+// ErrTimeout; the others are unaffected.
+func (d *Dispatcher) Dispatch(c *gcp.Client, projects []gcp.DiscoveredProject, scope gcp.ScanScope, deadline time.Time) []gcp.ProjectResult {
+	start := time.Now()
+	if deadline.IsZero() {
+		deadline = start.Add(orDefault(d.Timeout, DefaultTimeout))
+	}
+	scanID := fmt.Sprintf("%d", start.UnixNano())
+	results := make([]gcp.ProjectResult, len(projects))
+
+	tasks := make([]store.ScanTask, 0, len(projects))
+	for _, project := range projects {
+		payload, err := json.Marshal(workItem{Project: project, Scope: scope, Deadline: deadline})
+		if err != nil {
+			return failAll(projects, start, err)
+		}
+		tasks = append(tasks, store.ScanTask{
+			ID:        scanID + "/" + project.ID,
+			ScanID:    scanID,
+			Project:   project.ID,
+			Payload:   payload,
+			CreatedAt: start,
+		})
+	}
+	if err := d.Queue.EnqueueTasks(tasks); err != nil {
+		return failAll(projects, start, err)
+	}
+	defer func() {
+		if err := d.Queue.DeleteTasks(scanID); err != nil {
+			log.Printf("Warning: failed to delete scan tasks: %v", err)
+		}
+	}()
+	log.Printf("Queued %d projects for scan workers (scan %s)", len(tasks), scanID)
+
+	// Work on the queue here too until the scan is complete
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	local := &Worker{Queue: d.Queue, Client: c, Name: d.Name, Lease: d.Lease, PollInterval: d.PollInterval, inScan: true}
+	var wg sync.WaitGroup
+	for range d.LocalWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local.Run(ctx)
+		}()
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+	}
+	poll := time.NewTicker(orDefault(d.PollInterval, DefaultPollInterval))
+	defer poll.Stop()
+	for {
+		listed, err := d.Queue.ListTasks(scanID)
+		if err != nil {
+			log.Printf("Warning: failed to read scan tasks: %v", err)
+		}
+		pending := 0
+		for _, task := range listed {
+			i, ok := index[task.ID]
+			if !ok {
+				continue
+			}
+			switch task.State {
+			case store.TaskDone, store.TaskFailed:
+				results[i] = taskResult(task, projects[i])
+			default:
+				pending++
+			}
+		}
+		if err == nil && pending == 0 {
+			return results
+		}
+
+		select {
+		case <-ctx.Done():
+			for i, result := range results {
+				if result.Matrix == nil && result.Err == nil {
+					results[i] = failed(projects[i], start, ErrTimeout)
+				}
+			}
+			return results
+		case <-poll.C:
+		}
+	}
+}
+
+// Worker claims scan tasks from the queue and scans their projects, one at a
+// time: the client meters a single scan at once, so worker processes scale by
+// running more of them rather than more goroutines.
+type Worker struct {
+	Queue  store.TaskQueue
+	Client *gcp.Client
+	Name   string
+	// Lease is how long a claimed task may run before it is offered to other
+	// workers again, e.g. because this one died
+	Lease        time.Duration
+	PollInterval time.Duration
+	// inScan is set for the workers of a Dispatcher, which run during a scan
+	// of Client and count their calls against its meter
+	inScan bool
+}
+
+// Run works on tasks until ctx is done, polling while the queue is empty
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		if !w.work() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(orDefault(w.PollInterval, DefaultPollInterval)):
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// work claims and runs one task; it reports false when none was available
+func (w *Worker) work() bool {
+	task, err := w.Queue.ClaimTask(w.Name, time.Now().Add(-orDefault(w.Lease, DefaultLease)))
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Warning: failed to claim scan task: %v", err)
+		}
+		return false
+	}
+
+	var item workItem
+	var result []byte
+	var errText string
+	switch err := json.Unmarshal(task.Payload, &item); {
+	case err != nil:
+		errText = fmt.Sprintf("invalid task payload: %v", err)
+	case task.Attempts > maxAttempts:
+		errText = fmt.Sprintf("abandoned after %d attempts", maxAttempts)
+	case !item.Deadline.IsZero() && time.Now().After(item.Deadline):
+		errText = gcp.ErrScanDeadlineExceeded.Error()
+	default:
+		result, errText = w.scan(item)
+	}
+
+	if err := w.Queue.FinishTask(task.ID, w.Name, result, errText); err != nil {
+		log.Printf("Warning: failed to record scan task %s: %v", task.ID, err)
+	}
+	return true
+}
+
+// scan scans the project of a task, metering calls against its deadline
+func (w *Worker) scan(item workItem) ([]byte, string) {
+	if !w.inScan {
+		meter := gcp.NewUsageMeter(0)
+		meter.SetDeadline(item.Deadline)
+		defer w.Client.Meter(meter)()
+	}
+	scanned := w.Client.ScanProject(item.Project, item.Scope)
+	if scanned.Err != nil {
+		return nil, scanned.Err.Error()
+	}
+
+	data, err := json.Marshal(workResult{Matrix: scanned.Matrix, Scan: scanned.Scan})
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode result: %v", err)
+	}
+	return data, ""
+}
+
+// taskResult converts a finished task to the result of its project
+func taskResult(task store.ScanTask, project gcp.DiscoveredProject) gcp.ProjectResult {
+	if task.State == store.TaskFailed {
+		return failed(project, task.ClaimedAt, errors.New(task.Error))
+	}
+	var result workResult
+	if err := json.Unmarshal(task.Result, &result); err != nil || result.Matrix == nil {
+		return failed(project, task.ClaimedAt, fmt.Errorf("invalid result from worker %s: %v", task.Worker, err))
+	}
+	return gcp.ProjectResult{Matrix: result.Matrix, Scan: result.Scan}
+}
+
+// failed is the result of a project that could not be scanned
+func failed(project gcp.DiscoveredProject, start time.Time, err error) gcp.ProjectResult {
+	return gcp.ProjectResult{
+		Scan: gcp.ProjectScan{Project: project.ID, StartedAt: start, Duration: time.Since(start), Error: err.Error()},
+		Err:  err,
+	}
+}
+
+// failAll fails every project, e.g. when the queue is unavailable
+func failAll(projects []gcp.DiscoveredProject, start time.Time, err error) []gcp.ProjectResult {
+	log.Printf("Warning: failed to queue scan tasks: %v", err)
+	results := make([]gcp.ProjectResult, len(projects))
+	for i, project := range projects {
+		results[i] = failed(project, start, err)
+	}
+	return results
+}
+
+// orDefault returns value, or fallback when it is zero
+func orDefault(value, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -2,14 +2,33 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"strings"
+	"path/filepath"
 
 	"gcp-access-visualizer/config"
+	"gcp-access-visualizer/internal/analysis"
+	"gcp-access-visualizer/internal/buildinfo"
+	"gcp-access-visualizer/internal/cache"
+	"gcp-access-visualizer/internal/enrichment"
+	"gcp-access-visualizer/internal/findings"
 	"gcp-access-visualizer/internal/gcp"
+	"gcp-access-visualizer/internal/gitops"
 	"gcp-access-visualizer/internal/handlers"
+	"gcp-access-visualizer/internal/inventory"
+	"gcp-access-visualizer/internal/middleware"
+	"gcp-access-visualizer/internal/notify"
+	"gcp-access-visualizer/internal/posture"
+	"gcp-access-visualizer/internal/redact"
+	"gcp-access-visualizer/internal/rules"
+	"gcp-access-visualizer/internal/scanner"
+	"gcp-access-visualizer/internal/store"
+	"gcp-access-visualizer/internal/web"
+	"gcp-access-visualizer/internal/workers"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -29,38 +48,419 @@ func main() {
 		log.Fatalf("Failed to create GCP client: %v", err)
 	}
 	defer gcpClient.Close()
+	// PROJECT_ID may be a project number; resolve both so resource names match either
+	if err := gcpClient.ResolveProject(); err != nil {
+		log.Printf("Warning: %v; resources named by project number will not match the project", err)
+	}
+	gcpClient.Scope = gcp.ScanScope{
+		Enabled:  cfg.Runtime.Get().EnabledCollectors,
+		Disabled: cfg.DisabledCollectors,
+		Extra:    cfg.ExtraCollectors,
+		Regions:  cfg.ScanRegions,
+		Zones:    cfg.ScanZones,
+	}
+	gcpClient.PolicyWorkers = cfg.PolicyWorkers
+	gcpClient.GroupCacheTTL = cfg.GroupCacheTTL
+	gcpClient.GroupMaxDepth = cfg.GroupMaxDepth
+	gcpClient.GroupExpansion = func() bool { return cfg.Flags.Enabled(config.FlagGroupExpansion) }
+	gcpClient.Incremental = cfg.IncrementalScans
+	gcpClient.FullScanInterval = cfg.FullScanInterval
+	gcpClient.ProjectWorkers = cfg.ProjectScanWorkers
+	if cfg.ScanParent != "" {
+		gcpClient.Discovery = &gcp.ProjectDiscovery{Parent: cfg.ScanParent, Exclude: cfg.ExcludeProjects}
+	}
+
+	// Load policy rules (separation of duties, ...)
+	loadedRules, err := rules.Load(cfg.RulesFile)
+	if err != nil {
+		log.Fatalf("Failed to load rules: %v", err)
+	}
+	ruleSet := rules.NewHolder(loadedRules)
+
+	// Initialize the snapshot scanner that caches the access matrix
+	accessScanner := scanner.New(gcpClient, cfg.CacheTTL)
+	accessScanner.SetCallBudget(cfg.ScanCallBudget)
+	accessScanner.SetScanTimeout(cfg.ScanTimeout)
+
+	// Open the persistent store for saved views and other state
+	dataStore, err := store.Open(cfg.StoreDriver, store.Options{
+		DataDir:     cfg.DataDir,
+		DatabaseURL: cfg.DatabaseURL,
+		Params:      cfg.StoreOptions,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open data store: %v", err)
+	}
+	defer dataStore.Close()
+
+	// Settings changed via /api/admin/config are persisted and override the environment
+	cfg.Runtime.OnChange(func(settings config.RuntimeSettings) {
+		gcpClient.SetEnabledCollectors(settings.EnabledCollectors)
+	})
+	if data, err := dataStore.GetSetting(config.RuntimeSettingsKey); err == nil {
+		var settings config.RuntimeSettings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			log.Printf("Warning: ignoring invalid persisted runtime settings: %v", err)
+		} else {
+			cfg.Runtime.Set(settings)
+		}
+	}
+	if data, err := dataStore.GetSetting(config.FlagsSettingKey); err == nil {
+		var overrides map[string]bool
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			log.Printf("Warning: ignoring invalid persisted feature flags: %v", err)
+		} else if err := cfg.Flags.SetOverrides(overrides); err != nil {
+			log.Printf("Warning: ignoring persisted feature flags: %v", err)
+		}
+	}
+
+	// Database stores share scans between replicas: one scans, all serve reads.
+	// Redis, when configured, takes over that role and also shares role definitions.
+	if coordinator, ok := dataStore.(store.Coordinator); ok {
+		accessScanner.SetCoordinator(coordinator)
+	}
+	if cfg.RedisURL != "" {
+		redisCache, err := cache.NewRedis(ctx, cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		defer redisCache.Close()
+		gcpClient.Cache = redisCache
+		accessScanner.SetCoordinator(redisCache)
+	}
+
+	// Hand the projects of discovery scans to worker processes through the
+	// store; worker processes do nothing else
+	workerName, _ := os.Hostname()
+	workerName = fmt.Sprintf("%s-%d", workerName, os.Getpid())
+	if cfg.ScanDispatch == config.DispatchQueue || cfg.Worker() {
+		queue, ok := dataStore.(store.TaskQueue)
+		if !ok {
+			log.Fatalf("STORE_DRIVER %s has no task queue for scan workers", cfg.StoreDriver)
+		}
+		if cfg.Worker() {
+			runWorker(ctx, cfg, &workers.Worker{Queue: queue, Client: gcpClient, Name: workerName, Lease: cfg.ScanTaskLease})
+			return
+		}
+		if gcpClient.Discovery == nil {
+			log.Printf("Warning: SCAN_DISPATCH=%s has no effect without SCAN_PARENT", cfg.ScanDispatch)
+		}
+		gcpClient.Dispatcher = &workers.Dispatcher{
+			Queue:        queue,
+			Name:         workerName,
+			LocalWorkers: cfg.ProjectScanWorkers,
+			Timeout:      cfg.ScanTimeout,
+			Lease:        cfg.ScanTaskLease,
+		}
+	}
+
+	// Prune per-snapshot history so the store does not grow unbounded; in Cloud
+	// Run mode, after every scan instead of on a timer
+	if cfg.CompactionInterval > 0 {
+		retention := store.RetentionPolicy{Daily: cfg.RetentionDaily, Weekly: cfg.RetentionWeekly, Monthly: cfg.RetentionMonthly, Audit: cfg.AuditRetention}
+		if cfg.CloudRun() {
+			accessScanner.AddListener(func(_, _ *scanner.Snapshot) { store.Compact(dataStore, retention) })
+		} else {
+			go store.RunCompaction(ctx, dataStore, retention, cfg.CompactionInterval)
+		}
+	}
+
+	// Enrich principals with HR metadata from uploads and the directory connector
+	accessScanner.AddHook(enrichment.Hook(dataStore))
+	accessScanner.AddHook(enrichment.OwnershipHook(gcpClient, dataStore))
+	var enrichmentSource enrichment.Source
+	if cfg.SCIMURL != "" {
+		enrichmentSource = &enrichment.SCIMSource{BaseURL: cfg.SCIMURL, Token: cfg.SCIMToken}
+		go func() {
+			if _, err := enrichment.Sync(ctx, enrichmentSource, dataStore); err != nil {
+				log.Printf("Warning: initial SCIM sync failed: %v", err)
+			}
+		}()
+	}
+
+	// Findings and scores combine the matrix with live inventories such as API
+	// and SA keys, read once per snapshot however often it is evaluated
+	inventories := inventory.New(gcpClient)
+	findingsEngine := findings.NewEngine(gcpClient, dataStore, inventories, func() findings.Options {
+		return findings.Options{
+			KeyRotation:    analysis.KeyRotationPolicy{MaxAge: cfg.SAKeyMaxAge, MaxActive: cfg.SAKeyMaxActive},
+			DormantAfter:   cfg.DormantSAAfter,
+			TrustedDomains: cfg.Runtime.TrustedDomains(),
+		}
+	})
+
+	// Push alerts for new watchlist grants and findings to the configured channels
+	var notifiers notify.Multi
+	if cfg.AlertWebhookURL != "" {
+		notifiers = append(notifiers, &notify.WebhookNotifier{URL: cfg.AlertWebhookURL})
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, &notify.SlackNotifier{WebhookURL: cfg.SlackWebhookURL})
+	}
+	if cfg.PagerDutyRoutingKey != "" {
+		pager := &notify.PagerDutyNotifier{RoutingKey: cfg.PagerDutyRoutingKey}
+		notifiers = append(notifiers, notify.MinSeverity{Notifier: pager, Severity: cfg.PageMinSeverity})
+	}
+	if cfg.OpsgenieAPIKey != "" {
+		pager := &notify.OpsgenieNotifier{APIKey: cfg.OpsgenieAPIKey, URL: cfg.OpsgenieAPIURL}
+		notifiers = append(notifiers, notify.MinSeverity{Notifier: pager, Severity: cfg.PageMinSeverity})
+	}
+	if len(notifiers) > 0 {
+		accessScanner.AddListener(notify.WatchlistListener(cfg.Runtime.WatchlistRoles, notifiers))
+		accessScanner.AddListener(notify.FindingsListener(findingsEngine, notifiers))
+	}
+
+	// Resolve triaged findings that disappear and reopen those that come back
+	accessScanner.AddListener(findings.LifecycleListener(findingsEngine, dataStore))
+
+	// Keep a Jira issue open for every finding above the threshold
+	if cfg.JiraURL != "" {
+		jira := &notify.JiraClient{
+			URL:             cfg.JiraURL,
+			Email:           cfg.JiraEmail,
+			Token:           cfg.JiraAPIToken,
+			Project:         cfg.JiraProject,
+			IssueType:       cfg.JiraIssueType,
+			Label:           cfg.JiraLabel,
+			CloseTransition: cfg.JiraCloseTransition,
+		}
+		accessScanner.AddListener(notify.JiraListener(findingsEngine, jira, cfg.JiraMinSeverity))
+	}
+
+	// Email a weekly digest of grant changes and open findings
+	if len(cfg.DigestRecipients) > 0 {
+		var mailer notify.Mailer = &notify.SMTPMailer{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword}
+		if cfg.SendGridAPIKey != "" {
+			mailer = &notify.SendGridMailer{APIKey: cfg.SendGridAPIKey}
+		}
+		digest := &notify.DigestSender{
+			Project:     gcpClient.ProjectID,
+			Scanner:     accessScanner,
+			Engine:      findingsEngine,
+			Store:       dataStore,
+			Mailer:      mailer,
+			From:        cfg.DigestFrom,
+			Recipients:  cfg.DigestRecipients,
+			TemplateDir: cfg.DigestTemplateDir,
+		}
+		if cfg.CloudRun() {
+			accessScanner.AddListener(notify.DigestListener(digest, cfg.DigestInterval))
+		} else {
+			go notify.RunDigest(ctx, digest, cfg.DigestInterval)
+		}
+	}
+
+	// Record a least-privilege score and posture metrics for every snapshot so they can be trended
+	scorer := posture.NewScorer(gcpClient, dataStore, inventories, cfg.Runtime.TrustedDomains)
+	accessScanner.AddListener(scorer.Listener())
+	accessScanner.AddListener(posture.MetricsListener(gcpClient.ProjectID, dataStore, findingsEngine, cfg.Runtime.TrustedDomains))
+	accessScanner.AddListener(scanner.UsageListener(gcpClient.ProjectID, dataStore))
+
+	// Pull rules, the baseline, and exceptions from Git, if configured
+	var gitOps *gitops.Syncer
+	if cfg.GitOpsRepo != "" {
+		gitOps = &gitops.Syncer{
+			Repo:                 cfg.GitOpsRepo,
+			Ref:                  cfg.GitOpsRef,
+			Path:                 cfg.GitOpsPath,
+			Dir:                  filepath.Join(cfg.DataDir, "gitops"),
+			Store:                dataStore,
+			Rules:                ruleSet,
+			ExceptionMaxDuration: cfg.ExceptionMaxDuration,
+		}
+		if cfg.CloudRun() {
+			// Synced rules live in memory: load them before serving, and let
+			// Cloud Scheduler call POST /api/admin/gitops/sync for updates
+			if err := gitOps.Sync(ctx); err != nil {
+				log.Printf("Warning: GitOps sync failed: %v", err)
+			}
+		} else {
+			go gitOps.Run(ctx, cfg.GitOpsInterval)
+		}
+	}
 
 	// Initialize handlers
-	handler := handlers.NewHandler(gcpClient)
+	handler := handlers.NewHandler(cfg, gcpClient, accessScanner, dataStore, ruleSet, scorer, findingsEngine, enrichmentSource, gitOps)
+	accessScanner.AddListener(handler.HeatmapListener())
+	accessScanner.AddListener(handler.SearchIndexListener())
+
+	// Scan in the background so alerts fire without anyone opening the dashboard;
+	// the interval can be changed (or set to zero) at runtime. Cloud Run mode
+	// scans only when POST /api/scans is called, e.g. by Cloud Scheduler.
+	if cfg.CloudRun() {
+		log.Printf("Running in Cloud Run mode: no background scans, scan timeout %s", cfg.ScanTimeout)
+	} else {
+		go accessScanner.Run(ctx, cfg.Runtime.ScanInterval)
+	}
 
 	// Set up Gin router
 	router := gin.Default()
 
 	// Configure CORS
-	corsOrigins := []string{"http://localhost:5173", "http://localhost:3000"}
-	if allowedOrigins := os.Getenv("CORS_ALLOWED_ORIGINS"); allowedOrigins != "" {
-		corsOrigins = strings.Split(allowedOrigins, ",")
-	}
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     corsOrigins,
+		AllowOrigins:     cfg.CORSAllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	// API routes
+	// Compress responses; access matrices can serialize to tens of MB
+	router.Use(middleware.Compress())
+
+	// Expensive endpoints read the whole access matrix (refreshing a stale
+	// snapshot scans GCP) or call GCP directly
+	heavy := middleware.NewGuard(cfg.RateLimitPerMinute, cfg.RateLimitBurst, cfg.HeavyConcurrency).Handler()
+	// identified guards routes that record who made a change
+	identified := middleware.RequireIdentity(cfg.AdminToken)
+
+	// API routes; calls are audited, emails masked in redaction mode, and
+	// parameters validated before any handler runs
 	api := router.Group("/api")
+	api.Use(middleware.VerifyIAP(cfg.IAPAudience))
+	if cfg.AuditLog {
+		api.Use(middleware.Audit(dataStore, cfg.AdminToken))
+	}
+	api.Use(middleware.Redact(redact.NewMasker(cfg.RedactionKey), cfg.Runtime.Redaction))
+	api.Use(middleware.Validate(handlers.QueryRules, handlers.PathRules))
 	{
 		api.GET("/health", handler.HealthCheck)
-		api.GET("/users", handler.GetUsers)
-		api.GET("/resources", handler.GetResources)
-		api.GET("/access", handler.GetAccess)
+		api.GET("/health/live", handler.LivenessCheck)
+		api.GET("/version", handler.GetVersion)
+		api.GET("/users", heavy, handler.GetUsers)
+		api.GET("/resources", heavy, handler.GetResources)
+		api.GET("/projects", heavy, handler.ListProjects)
+		api.GET("/projects/:id", heavy, handler.GetProject)
+		api.GET("/access", heavy, handler.GetAccess)
+		api.GET("/access/aggregate", heavy, handler.GetAccessAggregate)
+		api.GET("/search", heavy, handler.SearchPolicies)
+		api.GET("/search/global", handler.GlobalSearch)
+		api.GET("/graph", heavy, handler.GetGraph)
+		api.GET("/flows", handler.GetFlows)
+		api.GET("/heatmap", handler.GetHeatmap)
+		api.GET("/reports/top", handler.GetTopReport)
+		api.GET("/reports/compliance", handler.GetComplianceReport)
+		api.GET("/roles", handler.GetRoles)
+		api.GET("/watchlist", handler.GetWatchlist)
+		api.GET("/sod", handler.GetSoD)
+		api.GET("/toxic-combinations", handler.GetToxicCombinations)
+		api.GET("/anomalies", handler.GetAnomalies)
+		api.GET("/ssh-access", handler.GetSSHAccess)
+		api.GET("/ssh-keys", handler.GetSSHKeys)
+		api.GET("/exposed-vms", handler.GetExposedVMs)
+		api.GET("/score", heavy, handler.GetScore)
+		api.GET("/trends", handler.GetTrends)
+		api.GET("/findings", heavy, handler.GetFindings)
+		api.GET("/findings/states", handler.ListFindingStates)
+		api.GET("/findings/evidence", heavy, handler.GetFindingEvidence)
+		api.POST("/findings/ack", identified, handler.AcknowledgeFinding)
+		api.POST("/findings/assign", identified, handler.AssignFinding)
+		api.POST("/findings/snooze", identified, handler.SnoozeFinding)
+		api.POST("/findings/false-positive", identified, handler.MarkFalsePositive)
+		api.POST("/findings/reopen", identified, handler.ReopenFinding)
+		api.GET("/api-keys", heavy, handler.GetAPIKeys)
+		scanTrigger := middleware.RequireOIDC(cfg.ScanTriggerAudience, cfg.ScanTriggerPrincipals)
+		api.POST("/scans", scanTrigger, heavy, handler.RunScan)
+		api.POST("/scans/trigger", scanTrigger, heavy, handler.TriggerScan)
+		api.GET("/scans/estimate", handler.GetScanEstimate)
+		api.GET("/scans/status", handler.GetScanStatus)
+		api.GET("/scans/:id/usage", handler.GetScanUsage)
+		api.POST("/rescan", heavy, handler.Rescan)
+
+		api.GET("/groups", heavy, handler.ListGroups)
+		api.GET("/peer-groups", heavy, handler.GetPeerGroups)
+		api.GET("/groups/:email", heavy, handler.GetGroup)
+		api.GET("/domains", handler.GetDomains)
+
+		api.GET("/explain", handler.Explain)
+		api.GET("/effective-access", handler.GetEffectiveAccess)
+		api.POST("/conditions/evaluate", handler.EvaluateCondition)
+		api.GET("/access-boundaries", handler.GetAccessBoundaries)
+		api.GET("/access-levels", handler.GetAccessLevels)
+		api.GET("/entitlements", handler.GetEntitlements)
+		api.GET("/elevations", handler.GetElevations)
+		api.GET("/escalation-paths", handler.GetEscalationPaths)
+		api.GET("/compare/users", heavy, handler.CompareUsers)
+		api.GET("/compare/projects", handler.CompareProjects)
+		api.GET("/baseline/drift", handler.GetBaselineDrift)
+		api.GET("/simulate/remove-principal", handler.SimulateRemovePrincipal)
+		api.GET("/simulate/remove-binding", handler.SimulateRemoveBinding)
+		api.GET("/simulate/grant", handler.SimulateGrant)
+		api.POST("/check", handler.CheckBindings)
+
+		api.GET("/favorites", identified, handler.GetFavorites)
+		api.POST("/favorites", identified, handler.StarFavorite)
+		api.DELETE("/favorites", identified, handler.UnstarFavorite)
+		api.GET("/views", handler.ListViews)
+		api.POST("/views", handler.CreateView)
+		api.GET("/views/:id", handler.GetView)
+		api.PUT("/views/:id", handler.UpdateView)
+		api.DELETE("/views/:id", handler.DeleteView)
+
+		api.GET("/enrichment/principals", handler.ListProfiles)
+
+		admin := api.Group("/admin", middleware.RequireToken(cfg.AdminToken))
+		admin.POST("/prune", handler.Prune)
+		admin.GET("/config", handler.GetRuntimeConfig)
+		admin.PUT("/config", handler.UpdateRuntimeConfig)
+		admin.GET("/flags", handler.GetFlags)
+		admin.PUT("/flags", handler.UpdateFlags)
+		admin.GET("/audit", handler.GetAudit)
+		admin.GET("/state", handler.ExportState)
+		admin.POST("/state", handler.ImportState)
+		admin.GET("/exceptions", handler.ListExceptions)
+		admin.POST("/exceptions", handler.CreateException)
+		admin.DELETE("/exceptions/:id", handler.DeleteException)
+		admin.GET("/baseline", handler.GetBaseline)
+		admin.PUT("/baseline", handler.PutBaseline)
+		admin.GET("/gitops", handler.GetGitOpsStatus)
+		admin.POST("/gitops/sync", handler.SyncGitOps)
+		admin.POST("/import", heavy, handler.ImportPolicies)
+		admin.DELETE("/import", handler.ClearImport)
+		admin.POST("/enrichment/principals", handler.UploadProfiles)
+		admin.POST("/enrichment/sync", handler.SyncProfiles)
+		admin.PUT("/service-accounts/:email/owner", handler.SetOwner)
+		admin.DELETE("/service-accounts/:email/owner", handler.DeleteOwner)
+
+		api.GET("/service-accounts/owners", handler.ListOwners)
+		api.GET("/service-accounts/dormant", heavy, handler.GetDormantServiceAccounts)
+		api.GET("/deleted-principals", handler.GetDeletedPrincipals)
+		api.GET("/deleted-principals/plan", handler.GetDeletedPrincipalPlan)
+		api.POST("/terminated-users", heavy, handler.ReconcileTerminatedUsers)
+	}
+
+	// Single-binary deployments serve the embedded frontend on every other path
+	if cfg.ServeFrontend {
+		assets, err := web.Assets()
+		switch {
+		case err == nil:
+			router.NoRoute(web.Handler(assets))
+			log.Printf("Serving the embedded frontend")
+		case !errors.Is(err, web.ErrNotEmbedded):
+			log.Fatalf("Failed to load the embedded frontend: %v", err)
+		}
 	}
 
 	// Start server
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("Starting server on %s", addr)
+	build := buildinfo.Get()
+	log.Printf("Starting server on %s (version %s, commit %s)", addr, build.Version, build.Commit)
+	if err := router.Run(addr); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// runWorker works on queued scan tasks until the process exits, serving only
+// liveness so orchestrators can tell the worker is up
+func runWorker(ctx context.Context, cfg *config.Config, worker *workers.Worker) {
+	log.Printf("Running as scan worker %s", worker.Name)
+	go worker.Run(ctx)
+
+	router := gin.Default()
+	router.GET("/api/health/live", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive", "worker": worker.Name})
+	})
+	addr := fmt.Sprintf(":%s", cfg.Port)
 	if err := router.Run(addr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
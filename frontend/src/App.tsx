@@ -1,6 +1,6 @@
 import { useState } from 'react';
 import { QueryClient, QueryClientProvider, useQuery } from '@tanstack/react-query';
-import { apiClient } from './api/client';
+import { apiClient, problemFromError } from './api/client';
 import { UsersList } from './components/UsersList';
 import { ResourcesList } from './components/ResourcesList';
 import { AccessMatrix } from './components/AccessMatrix';
@@ -34,10 +34,11 @@ function AppContent() {
     queryFn: apiClient.getResources,
   });
 
-  const { data: accessMatrix, isLoading: accessLoading } = useQuery({
+  const { data: accessMatrix, isLoading: accessLoading, error: accessError } = useQuery({
     queryKey: ['access'],
     queryFn: apiClient.getAccessMatrix,
   });
+  const accessProblem = problemFromError(accessError);
 
   const renderView = () => {
     switch (viewMode) {
@@ -113,6 +114,30 @@ function AppContent() {
       </header>
 
       <main className="app-main">
+        {accessProblem && (
+          <div className="api-problem">
+            <strong>{accessProblem.title}:</strong> {accessProblem.detail}
+            {accessProblem.permission && (
+              <div>
+                Grant <code>{accessProblem.permission}</code> to the visualizer's identity.
+              </div>
+            )}
+            {accessProblem.retryable && <div>This is temporary; try again shortly.</div>}
+          </div>
+        )}
+        {accessMatrix?.warnings && accessMatrix.warnings.length > 0 && (
+          <div className="scan-warnings">
+            <strong>Partial results:</strong> some collectors failed.
+            <ul>
+              {accessMatrix.warnings.map((warning) => (
+                <li key={warning.collector}>
+                  <code>{warning.collector}</code>
+                  {warning.permission ? ` — missing ${warning.permission}` : `: ${warning.error}`}
+                </li>
+              ))}
+            </ul>
+          </div>
+        )}
         {renderView()}
       </main>
     </div>
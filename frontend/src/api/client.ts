@@ -2,9 +2,19 @@ import axios from 'axios';
 
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api';
 
+export interface BlastRadius {
+  resources: number;
+  direct: number;
+  viaImpersonation: number;
+  serviceAccounts: number;
+  highestTier?: string;
+  canModifyIam: boolean;
+}
+
 export interface User {
   email: string;
   type: string;
+  blastRadius?: BlastRadius;
 }
 
 export interface Resource {
@@ -12,6 +22,10 @@ export interface Resource {
   name: string;
   type: string;
   location: string;
+  locationType: 'zone' | 'region' | 'multi-region' | 'global';
+  region?: string;
+  runAs?: string;
+  invokes?: string;
   iam: Record<string, string[]>;
 }
 
@@ -21,12 +35,50 @@ export interface AccessEntry {
   resourceName: string;
   resourceType: string;
   roles: string[];
+  tier: 'read' | 'write' | 'admin' | 'owner';
+}
+
+export interface ScanWarning {
+  collector: string;
+  error: string;
+  permission?: string;
 }
 
 export interface AccessMatrix {
   users: User[];
   resources: Resource[];
   access: AccessEntry[];
+  warnings: ScanWarning[] | null;
+}
+
+export interface SearchResult {
+  type: 'principal' | 'resource' | 'role' | 'project';
+  id: string;
+  title: string;
+  subtitle?: string;
+  link: string;
+  score: number;
+}
+
+// Problem is an RFC 7807 error body returned by every failing endpoint
+export interface Problem {
+  type: string;
+  title: string;
+  status: number;
+  detail: string;
+  instance?: string;
+  code: string;
+  retryable: boolean;
+  details?: Record<string, string>;
+  permission?: string;
+}
+
+// problemFromError extracts the problem body from a failed request, if any
+export function problemFromError(error: unknown): Problem | null {
+  if (axios.isAxiosError(error) && error.response?.data?.code) {
+    return error.response.data as Problem;
+  }
+  return null;
 }
 
 const api = axios.create({
@@ -52,6 +104,13 @@ export const apiClient = {
     return response.data;
   },
 
+  // globalSearch backs the command palette: typed matches across principals,
+  // resources, roles, and projects, best first
+  globalSearch: async (q: string, limit = 20): Promise<SearchResult[]> => {
+    const response = await api.get<{ results: SearchResult[] }>('/search/global', { params: { q, limit } });
+    return response.data.results;
+  },
+
   healthCheck: async (): Promise<{ status: string }> => {
     const response = await api.get<{ status: string }>('/health');
     return response.data;
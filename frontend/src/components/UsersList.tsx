@@ -27,6 +27,12 @@ export const UsersList = ({ users, isLoading, searchTerm, onSearchChange }: User
             case 'serviceAccount': return 'Service Account';
             case 'user': return 'User';
             case 'group': return 'Group';
+            case 'domain': return 'Domain';
+            case 'allUsers': return 'Public';
+            case 'allAuthenticatedUsers': return 'Any Google Account';
+            case 'projectOwner': return 'Project Owners';
+            case 'projectEditor': return 'Project Editors';
+            case 'projectViewer': return 'Project Viewers';
             default: return type;
         }
     };